@@ -10,6 +10,7 @@ import (
 	"records/internal/config"
 	"records/internal/database"
 	"records/internal/hotwords"
+	"records/internal/redact"
 	"records/pkg/logger"
 )
 
@@ -23,7 +24,15 @@ func main() {
 		log.Fatalf("load config: %v", err)
 	}
 
-	loggr := logger.New(cfg.Logging)
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		log.Fatalf("redaction config: %v", err)
+	}
+	var logOpts []logger.Option
+	if redactor != nil {
+		logOpts = append(logOpts, logger.WithRedactor(redactor.Mask))
+	}
+	loggr := logger.New(cfg.Logging, logOpts...)
 	db, err := database.New(cfg.Database)
 	if err != nil {
 		loggr.Fatal("connect database", "error", err)
@@ -42,6 +51,7 @@ func main() {
 		Temperature:         0.3,
 		MaxCompletionTokens: cfg.AI.OpenAI.MaxCompletionTokens,
 		SystemPrompt:        cfg.Prompts.HotwordsExtractor,
+		Redactor:            redactor,
 	}, loggr)
 
 	pipe := hotwords.NewPipeline(db, extractor, hotwords.PipelineConfig{
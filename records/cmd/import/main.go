@@ -0,0 +1,133 @@
+// 跟进记录导入 CLI：从 CSV / Excel 批量导入历史跟进记录，或回滚某个导入批次。默认仅预览校验结果，加 -commit 才写入。
+// 用法（在 records 目录下）：
+//
+//	go run ./cmd/import -user <导入人用户ID> -file records.xlsx [-map "客户=customer_name,备注=-"] [-skip-invalid] [-commit]
+//	go run ./cmd/import -rollback <batch_id>
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"records/internal/config"
+	"records/internal/database"
+	"records/internal/importer"
+	"records/internal/models"
+	"records/internal/phonecrypt"
+	"records/internal/repository"
+	"records/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+func main() {
+	cfgPath := flag.String("config", "config.yml", "配置文件路径")
+	file := flag.String("file", "", "待导入的 .csv 或 .xlsx 文件")
+	userID := flag.String("user", "", "执行导入的用户 ID；文件无销售列时记录归属该用户")
+	mapping := flag.String("map", "", "自定义列映射：表头=字段，逗号分隔；字段为 - 表示忽略该列")
+	skipInvalid := flag.Bool("skip-invalid", false, "提交时跳过校验失败的行")
+	commit := flag.Bool("commit", false, "写入数据库（默认仅预览）")
+	rollback := flag.String("rollback", "", "回滚指定导入批次")
+	flag.Parse()
+
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+	keyring, err := phonecrypt.New(cfg.PhoneEncryption)
+	if err != nil {
+		log.Fatalf("phone encryption config: %v", err)
+	}
+	phonecrypt.SetDefault(keyring)
+	loggr := logger.New(cfg.Logging)
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		loggr.Fatal("connect database", "error", err)
+	}
+	defer db.Close()
+	if err := database.InitDatabase(db); err != nil {
+		loggr.Fatal("init database", "error", err)
+	}
+
+	ctx := context.Background()
+	im := importer.New(db, loggr)
+
+	if *rollback != "" {
+		batchID, err := uuid.Parse(*rollback)
+		if err != nil {
+			log.Fatalf("invalid batch id: %v", err)
+		}
+		records, customers, err := im.Rollback(repository.WithAudit(ctx, *userID, models.RecordSourceImport), batchID)
+		if err != nil {
+			log.Fatalf("rollback: %v", err)
+		}
+		fmt.Printf("已回滚批次 %s：删除跟进记录 %d 条，删除客户 %d 个\n", batchID, records, customers)
+		return
+	}
+
+	if *file == "" || *userID == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts := importer.Options{
+		FileName:    filepath.Base(*file),
+		ImporterID:  *userID,
+		SkipInvalid: *skipInvalid,
+	}
+	if *mapping != "" {
+		opts.Mapping = make(map[string]string)
+		for _, pair := range strings.Split(*mapping, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || !importer.ValidField(strings.TrimSpace(kv[1])) {
+				log.Fatalf("invalid mapping: %s", pair)
+			}
+			opts.Mapping[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("open file: %v", err)
+	}
+	defer f.Close()
+
+	var res *importer.Result
+	if *commit {
+		res, err = im.Commit(ctx, f, opts)
+	} else {
+		res, err = im.Preview(ctx, f, opts)
+	}
+	if res != nil {
+		printResult(res)
+	}
+	if errors.Is(err, importer.ErrHasInvalidRows) {
+		log.Fatalf("存在校验失败的行，未导入；修正后重试或加 -skip-invalid")
+	}
+	if err != nil {
+		log.Fatalf("import: %v", err)
+	}
+	if !*commit {
+		fmt.Println("预览完成，未写入数据库；确认无误后加 -commit 提交")
+	}
+}
+
+func printResult(res *importer.Result) {
+	cols, _ := json.Marshal(res.Columns)
+	fmt.Printf("列映射：%s\n", cols)
+	fmt.Printf("共 %d 行，有效 %d 行，失败 %d 行，新客户 %d 个\n", res.TotalRows, res.ValidRows, res.InvalidRows, res.NewCustomers)
+	for _, row := range res.Rows {
+		if len(row.Errors) > 0 {
+			fmt.Printf("  第 %d 行（%s）：%s\n", row.Row, row.CustomerName, strings.Join(row.Errors, "；"))
+		}
+	}
+	if res.BatchID != nil {
+		fmt.Printf("已导入 %d 行，批次号 %s（回滚：-rollback %s）\n", res.ImportedRows, res.BatchID, res.BatchID)
+	}
+}
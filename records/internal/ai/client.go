@@ -6,14 +6,19 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"records/internal/config"
+	"records/internal/metrics"
 	"records/internal/models"
+	"records/internal/redact"
+	"records/internal/tracing"
 	"records/pkg/logger"
 
 	jsonrepair "github.com/RealAlexandreAI/json-repair"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // extractFinalContent 从模型输出中提取最终答案，忽略思考过程
@@ -50,6 +55,8 @@ type Client interface {
 	GenerateDialogue(ctx context.Context, stage, focusCustomer, expectedField, userInput, historyContext, summary, conversationHistory string) (string, error)
 	SummarizeCustomerInfo(ctx context.Context, customerFollowRecords string) (string, error)
 	EntityNormalization(ctx context.Context, request *models.NormalizationRequest) ([]models.NormalizationResult, error)
+	ExtractNextPlanTask(ctx context.Context, nextPlan string, followTime time.Time) (*models.NextPlanTask, error)
+	SummarizeCustomerReport(ctx context.Context, customerName, followRecords string) (*models.CustomerReportSummary, error)
 }
 
 // OpenAIClient OpenAI客户端实现
@@ -58,29 +65,58 @@ type OpenAIClient struct {
 	config   config.AI
 	messages config.Messages
 	prompts  config.Prompts
+	redactor *redact.Redactor
 	logger   logger.Logger
 }
 
-// NewOpenAIClient 创建OpenAI客户端
-func NewOpenAIClient(cfg config.AI, prompts config.Prompts, logger logger.Logger) *OpenAIClient {
+// NewOpenAIClient 创建OpenAI客户端；redactor 非空时用户消息发送前脱敏，返回内容中的占位符还原为原值
+func NewOpenAIClient(cfg config.AI, prompts config.Prompts, redactor *redact.Redactor, logger logger.Logger) *OpenAIClient {
 	client := openai.NewClient(
 		option.WithAPIKey(cfg.OpenAI.APIKey),
 		option.WithBaseURL(cfg.OpenAI.BaseURL),
 	)
 
 	return &OpenAIClient{
-		client:  &client,
-		config:  cfg,
-		prompts: prompts,
-		logger:  logger,
+		client:   &client,
+		config:   cfg,
+		prompts:  prompts,
+		redactor: redactor,
+		logger:   logger,
 	}
 }
 
+// complete 调用 Chat Completions，记录调用次数、耗时、错误与 token 用量，并为本次调用开启 span。
+// 用户消息中的手机号、身份证号等替换为占位符后发送，返回内容中的占位符还原为原值（解析出的字段即为原值）
+func (c *OpenAIClient) complete(ctx context.Context, method string, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	ctx, span := tracing.Start(ctx, "llm."+method, attribute.String("llm.model", string(params.Model)))
+	session := c.redactor.NewSession()
+	for _, m := range params.Messages {
+		if m.OfUser != nil && m.OfUser.Content.OfString.Valid() {
+			m.OfUser.Content.OfString = openai.String(session.Redact(m.OfUser.Content.OfString.Value))
+		}
+	}
+	start := time.Now()
+	response, err := c.client.Chat.Completions.New(ctx, params)
+	if response != nil {
+		for i := range response.Choices {
+			response.Choices[i].Message.Content = session.Restore(response.Choices[i].Message.Content)
+		}
+	}
+	var promptTokens, completionTokens int64
+	if response != nil {
+		promptTokens, completionTokens = response.Usage.PromptTokens, response.Usage.CompletionTokens
+	}
+	metrics.ObserveLLM(method, string(params.Model), start, promptTokens, completionTokens, err)
+	span.SetAttributes(attribute.Int64("llm.prompt_tokens", promptTokens), attribute.Int64("llm.completion_tokens", completionTokens))
+	tracing.End(span, err)
+	return response, err
+}
+
 // IsCustomerFollowRelated 判断对话是否和客户跟进相关
 func (c *OpenAIClient) IsCustomerFollowRelated(ctx context.Context, userInput string) (bool, error) {
 	systemPrompt := c.prompts.IsCustomerFollowRelated
 	userPrompt := fmt.Sprintf(`用户输入：%s`, userInput)
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	response, err := c.complete(ctx, "IsCustomerFollowRelated", openai.ChatCompletionNewParams{
 		Model: openai.ChatModel(c.config.Semantic.ModelName),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(systemPrompt),
@@ -91,7 +127,7 @@ func (c *OpenAIClient) IsCustomerFollowRelated(ctx context.Context, userInput st
 	})
 
 	if err != nil {
-		c.logger.Error("Is customer follow related failed", "error", err)
+		c.logger.WithContext(ctx).Error("Is customer follow related failed", "error", err)
 		return false, fmt.Errorf("is customer follow related failed: %w", err)
 	}
 
@@ -105,7 +141,7 @@ func (c *OpenAIClient) IsUserConfirmation(ctx context.Context, userInput string)
 	}
 	systemPrompt := c.prompts.IsUserConfirmation
 	userPrompt := fmt.Sprintf(`用户输入：%s`, userInput)
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	response, err := c.complete(ctx, "IsUserConfirmation", openai.ChatCompletionNewParams{
 		Model: openai.ChatModel(c.config.Semantic.ModelName),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(systemPrompt),
@@ -115,7 +151,7 @@ func (c *OpenAIClient) IsUserConfirmation(ctx context.Context, userInput string)
 		MaxCompletionTokens: openai.Int(c.config.Semantic.MaxCompletionTokens),
 	})
 	if err != nil {
-		c.logger.Error("Is user confirmation failed", "error", err)
+		c.logger.WithContext(ctx).Error("Is user confirmation failed", "error", err)
 		return false, fmt.Errorf("is user confirmation failed: %w", err)
 	}
 	return extractFinalContent(response.Choices[0].Message.Content) == "true", nil
@@ -128,7 +164,7 @@ func (c *OpenAIClient) IsUserNoMoreCustomers(ctx context.Context, userInput stri
 	}
 	systemPrompt := c.prompts.IsUserNoMoreCustomers
 	userPrompt := fmt.Sprintf(`用户输入：%s`, userInput)
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	response, err := c.complete(ctx, "IsUserNoMoreCustomers", openai.ChatCompletionNewParams{
 		Model: openai.ChatModel(c.config.Semantic.ModelName),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(systemPrompt),
@@ -138,7 +174,7 @@ func (c *OpenAIClient) IsUserNoMoreCustomers(ctx context.Context, userInput stri
 		MaxCompletionTokens: openai.Int(c.config.Semantic.MaxCompletionTokens),
 	})
 	if err != nil {
-		c.logger.Error("IsUserNoMoreCustomers failed", "error", err)
+		c.logger.WithContext(ctx).Error("IsUserNoMoreCustomers failed", "error", err)
 		return false, fmt.Errorf("is user no more customers failed: %w", err)
 	}
 	return extractFinalContent(response.Choices[0].Message.Content) == "true", nil
@@ -151,7 +187,7 @@ func (c *OpenAIClient) IsUserAbortCollecting(ctx context.Context, userInput stri
 	}
 	systemPrompt := c.prompts.IsUserAbortCollecting
 	userPrompt := fmt.Sprintf(`用户输入：%s`, userInput)
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	response, err := c.complete(ctx, "IsUserAbortCollecting", openai.ChatCompletionNewParams{
 		Model: openai.ChatModel(c.config.Semantic.ModelName),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(systemPrompt),
@@ -161,7 +197,7 @@ func (c *OpenAIClient) IsUserAbortCollecting(ctx context.Context, userInput stri
 		MaxCompletionTokens: openai.Int(c.config.Semantic.MaxCompletionTokens),
 	})
 	if err != nil {
-		c.logger.Error("IsUserAbortCollecting failed", "error", err)
+		c.logger.WithContext(ctx).Error("IsUserAbortCollecting failed", "error", err)
 		return false, fmt.Errorf("is user abort collecting failed: %w", err)
 	}
 	return extractFinalContent(response.Choices[0].Message.Content) == "true", nil
@@ -174,7 +210,7 @@ func (c *OpenAIClient) IsUserAbortConfirmation(ctx context.Context, userInput st
 	}
 	systemPrompt := c.prompts.IsUserAbortConfirmation
 	userPrompt := fmt.Sprintf(`用户输入：%s`, userInput)
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	response, err := c.complete(ctx, "IsUserAbortConfirmation", openai.ChatCompletionNewParams{
 		Model: openai.ChatModel(c.config.Semantic.ModelName),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(systemPrompt),
@@ -184,7 +220,7 @@ func (c *OpenAIClient) IsUserAbortConfirmation(ctx context.Context, userInput st
 		MaxCompletionTokens: openai.Int(c.config.Semantic.MaxCompletionTokens),
 	})
 	if err != nil {
-		c.logger.Error("IsUserAbortConfirmation failed", "error", err)
+		c.logger.WithContext(ctx).Error("IsUserAbortConfirmation failed", "error", err)
 		return false, fmt.Errorf("is user abort confirmation failed: %w", err)
 	}
 	return extractFinalContent(response.Choices[0].Message.Content) == "true", nil
@@ -209,9 +245,9 @@ User: %s`, stage, focusCustomer, conversationHistory, userInput)
 User: %s`, stage, focusCustomer, expectedField, conversationHistory, userInput)
 	}
 
-	c.logger.Debug("Semantic analysis user prompt", "userPrompt", userPrompt)
+	c.logger.WithContext(ctx).Debug("Semantic analysis user prompt", "userPrompt", userPrompt)
 
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	response, err := c.complete(ctx, "SemanticAnalysis", openai.ChatCompletionNewParams{
 		Model: openai.ChatModel(c.config.Semantic.ModelName),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(systemPrompt),
@@ -222,30 +258,30 @@ User: %s`, stage, focusCustomer, expectedField, conversationHistory, userInput)
 	})
 
 	if err != nil {
-		c.logger.Error("Semantic analysis failed", "error", err)
+		c.logger.WithContext(ctx).Error("Semantic analysis failed", "error", err)
 		return nil, fmt.Errorf("semantic analysis failed: %w", err)
 	}
 
 	content := extractFinalContent(response.Choices[0].Message.Content)
 
-	c.logger.Debug("Semantic analysis result:", "content", content)
+	c.logger.WithContext(ctx).Debug("Semantic analysis result:", "content", content)
 
 	var result models.SemanticAnalysisResult
 	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		c.logger.Error("Failed to parse semantic analysis result", "error", err, "content", content)
+		c.logger.WithContext(ctx).Error("Failed to parse semantic analysis result", "error", err, "content", content)
 		// 修复 JSON
 		repairedContent, err := jsonrepair.RepairJSON(content)
 		if err != nil {
-			c.logger.Error("Failed to repair semantic analysis result", "error", err, "content", content)
+			c.logger.WithContext(ctx).Error("Failed to repair semantic analysis result", "error", err, "content", content)
 			return nil, fmt.Errorf("failed to repair semantic analysis result: %w", err)
 		}
 		if err := json.Unmarshal([]byte(repairedContent), &result); err != nil {
-			c.logger.Error("Failed to parse semantic analysis result", "error", err, "content", content)
+			c.logger.WithContext(ctx).Error("Failed to parse semantic analysis result", "error", err, "content", content)
 			return nil, fmt.Errorf("failed to parse semantic analysis result: %w", err)
 		}
 	}
 
-	c.logger.Debug("Semantic analysis result", "result", result)
+	c.logger.WithContext(ctx).Debug("Semantic analysis result", "result", result)
 
 	return &result, nil
 }
@@ -290,9 +326,9 @@ User: %s
 		return "", fmt.Errorf("unsupported stage: %s", stage)
 	}
 
-	c.logger.Debug("Dialogue generating user prompt", "user_prompt", userPrompt)
+	c.logger.WithContext(ctx).Debug("Dialogue generating user prompt", "user_prompt", userPrompt)
 
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	response, err := c.complete(ctx, "GenerateDialogue", openai.ChatCompletionNewParams{
 		Model: openai.ChatModel(c.config.Dialogue.ModelName),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(systemPrompt),
@@ -303,11 +339,11 @@ User: %s
 	})
 
 	if err != nil {
-		c.logger.Error("Dialogue generation failed", "error", err)
+		c.logger.WithContext(ctx).Error("Dialogue generation failed", "error", err)
 		return "", fmt.Errorf("dialogue generation failed: %w", err)
 	}
 
-	c.logger.Debug("Dialogue generating result", "content", response.Choices[0].Message.Content)
+	c.logger.WithContext(ctx).Debug("Dialogue generating result", "content", response.Choices[0].Message.Content)
 
 	return extractFinalContent(response.Choices[0].Message.Content), nil
 }
@@ -319,9 +355,9 @@ func (c *OpenAIClient) SummarizeCustomerInfo(ctx context.Context, customerFollow
 	userPrompt := fmt.Sprintf(`以下是某一客户已经确认过的跟进事实，请将其整理为可用于对话中的自然复盘摘要。
 输入事实（JSON）：%s`, customerFollowRecords)
 
-	c.logger.Debug("Customer info summarization user prompt", "userPrompt", userPrompt)
+	c.logger.WithContext(ctx).Debug("Customer info summarization user prompt", "userPrompt", userPrompt)
 
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	response, err := c.complete(ctx, "SummarizeCustomerInfo", openai.ChatCompletionNewParams{
 		Model: openai.ChatModel(c.config.Dialogue.ModelName),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(systemPrompt),
@@ -332,11 +368,11 @@ func (c *OpenAIClient) SummarizeCustomerInfo(ctx context.Context, customerFollow
 	})
 
 	if err != nil {
-		c.logger.Error("Customer info summarization failed", "error", err)
+		c.logger.WithContext(ctx).Error("Customer info summarization failed", "error", err)
 		return "", fmt.Errorf("customer info summarization failed: %w", err)
 	}
 
-	c.logger.Debug("Customer info summarization result", "content", response.Choices[0].Message.Content)
+	c.logger.WithContext(ctx).Debug("Customer info summarization result", "content", response.Choices[0].Message.Content)
 
 	return extractFinalContent(response.Choices[0].Message.Content), nil
 }
@@ -359,9 +395,9 @@ func (c *OpenAIClient) EntityNormalization(ctx context.Context, request *models.
 候选客户/联系人实体：
 %s`, string(dialogContextJSON), string(mentionsJSON), string(candidatesJSON))
 
-	c.logger.Debug("Entity normalization user prompt", "userPrompt", userPrompt)
+	c.logger.WithContext(ctx).Debug("Entity normalization user prompt", "userPrompt", userPrompt)
 
-	response, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	response, err := c.complete(ctx, "EntityNormalization", openai.ChatCompletionNewParams{
 		Model: openai.ChatModel(c.config.Semantic.ModelName),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(systemPrompt),
@@ -372,17 +408,17 @@ func (c *OpenAIClient) EntityNormalization(ctx context.Context, request *models.
 	})
 
 	if err != nil {
-		c.logger.Error("Entity normalization failed", "error", err)
+		c.logger.WithContext(ctx).Error("Entity normalization failed", "error", err)
 		return nil, fmt.Errorf("entity normalization failed: %w", err)
 	}
 
 	content := extractFinalContent(response.Choices[0].Message.Content)
 
-	c.logger.Debug("Entity normalization result", "content", content)
+	c.logger.WithContext(ctx).Debug("Entity normalization result", "content", content)
 
 	var results []models.NormalizationResult
 	if err := json.Unmarshal([]byte(content), &results); err != nil {
-		c.logger.Error("Failed to parse entity normalization result", "error", err, "content", content)
+		c.logger.WithContext(ctx).Error("Failed to parse entity normalization result", "error", err, "content", content)
 		return nil, fmt.Errorf("failed to parse entity normalization result: %w", err)
 	}
 
@@ -406,3 +442,81 @@ func (c *OpenAIClient) EntityNormalization(ctx context.Context, request *models.
 
 	return results, nil
 }
+
+// ExtractNextPlanTask 从下一步计划中抽取带日期的待办；相对日期（如“下周二”）以跟进时间为基准换算。未配置提示词时返回 nil
+func (c *OpenAIClient) ExtractNextPlanTask(ctx context.Context, nextPlan string, followTime time.Time) (*models.NextPlanTask, error) {
+	if c.prompts.NextPlanTask == "" {
+		return nil, nil
+	}
+	weekdays := []string{"日", "一", "二", "三", "四", "五", "六"}
+	systemPrompt := c.prompts.NextPlanTask
+	userPrompt := fmt.Sprintf(`基准时间：%s（星期%s）
+下一步计划：%s`, followTime.Format("2006-01-02 15:04"), weekdays[followTime.Weekday()], nextPlan)
+
+	response, err := c.complete(ctx, "ExtractNextPlanTask", openai.ChatCompletionNewParams{
+		Model: openai.ChatModel(c.config.Semantic.ModelName),
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(systemPrompt),
+			openai.UserMessage(userPrompt),
+		},
+		Temperature:         openai.Float(c.config.Semantic.Temperature),
+		MaxCompletionTokens: openai.Int(c.config.Semantic.MaxCompletionTokens),
+	})
+	if err != nil {
+		c.logger.WithContext(ctx).Error("Extract next plan task failed", "error", err)
+		return nil, fmt.Errorf("extract next plan task failed: %w", err)
+	}
+
+	content := extractFinalContent(response.Choices[0].Message.Content)
+	c.logger.WithContext(ctx).Debug("Extract next plan task result", "content", content)
+
+	var result models.NextPlanTask
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		repairedContent, repairErr := jsonrepair.RepairJSON(content)
+		if repairErr != nil {
+			return nil, fmt.Errorf("failed to repair next plan task result: %w", repairErr)
+		}
+		if err := json.Unmarshal([]byte(repairedContent), &result); err != nil {
+			c.logger.WithContext(ctx).Error("Failed to parse next plan task result", "error", err, "content", content)
+			return nil, fmt.Errorf("failed to parse next plan task result: %w", err)
+		}
+	}
+	return &result, nil
+}
+
+// SummarizeCustomerReport 为周报总结单个客户在报告区间内的进展、风险与下一步；followRecords 为该客户区间内的跟进记录（JSON）
+func (c *OpenAIClient) SummarizeCustomerReport(ctx context.Context, customerName, followRecords string) (*models.CustomerReportSummary, error) {
+	systemPrompt := c.prompts.WeeklyReport
+	userPrompt := fmt.Sprintf(`客户：%s
+本期跟进记录（JSON，按时间升序）：%s`, customerName, followRecords)
+
+	response, err := c.complete(ctx, "SummarizeCustomerReport", openai.ChatCompletionNewParams{
+		Model: openai.ChatModel(c.config.Dialogue.ModelName),
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(systemPrompt),
+			openai.UserMessage(userPrompt),
+		},
+		Temperature:         openai.Float(c.config.Dialogue.Temperature),
+		MaxCompletionTokens: openai.Int(c.config.Dialogue.MaxCompletionTokens),
+	})
+	if err != nil {
+		c.logger.WithContext(ctx).Error("Summarize customer report failed", "error", err, "customer", customerName)
+		return nil, fmt.Errorf("summarize customer report failed: %w", err)
+	}
+
+	content := extractFinalContent(response.Choices[0].Message.Content)
+	c.logger.WithContext(ctx).Debug("Summarize customer report result", "content", content)
+
+	var result models.CustomerReportSummary
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		repairedContent, repairErr := jsonrepair.RepairJSON(content)
+		if repairErr != nil {
+			return nil, fmt.Errorf("failed to repair customer report result: %w", repairErr)
+		}
+		if err := json.Unmarshal([]byte(repairedContent), &result); err != nil {
+			c.logger.WithContext(ctx).Error("Failed to parse customer report result", "error", err, "content", content)
+			return nil, fmt.Errorf("failed to parse customer report result: %w", err)
+		}
+	}
+	return &result, nil
+}
@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// keyPrefix 明文密钥前缀，便于在日志、代码仓库扫描中识别
+const keyPrefix = "sk_"
+
+const keyColumns = `id, name, prefix, scopes, rate_limit, created_by, created_at, expires_at, last_used_at, revoked_at, revoked_by`
+
+// Repo API 密钥数据访问
+type Repo struct {
+	db *sqlx.DB
+}
+
+// NewRepo 创建 Repo
+func NewRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+// Create 生成并保存新密钥，返回明文（仅此一次）
+func (r *Repo) Create(ctx context.Context, k *Key) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate api key: %w", err)
+	}
+	plain := keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	k.ID = uuid.New()
+	k.Prefix = plain[:len(keyPrefix)+8]
+	query := `INSERT INTO api_keys (id, name, prefix, key_hash, scopes, rate_limit, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at`
+	if err := r.db.GetContext(ctx, &k.CreatedAt, query, k.ID, k.Name, k.Prefix, hashKey(plain), pq.Array([]string(k.Scopes)), k.RateLimit, k.CreatedBy, k.ExpiresAt); err != nil {
+		return "", fmt.Errorf("create api key name=%s: %w", k.Name, err)
+	}
+	return plain, nil
+}
+
+// Get 按 ID 查询密钥，不存在返回 nil
+func (r *Repo) Get(ctx context.Context, id uuid.UUID) (*Key, error) {
+	var k Key
+	if err := r.db.GetContext(ctx, &k, `SELECT `+keyColumns+` FROM api_keys WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get api key id=%s: %w", id, err)
+	}
+	return &k, nil
+}
+
+// FindByPlain 按明文密钥查询（比对哈希），不存在返回 nil；不校验是否有效
+func (r *Repo) FindByPlain(ctx context.Context, plain string) (*Key, error) {
+	var k Key
+	if err := r.db.GetContext(ctx, &k, `SELECT `+keyColumns+` FROM api_keys WHERE key_hash = $1`, hashKey(plain)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("find api key: %w", err)
+	}
+	return &k, nil
+}
+
+// List 列出全部密钥，最新在前
+func (r *Repo) List(ctx context.Context) ([]*Key, error) {
+	list := []*Key{}
+	if err := r.db.SelectContext(ctx, &list, `SELECT `+keyColumns+` FROM api_keys ORDER BY created_at DESC`); err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	return list, nil
+}
+
+// Update 修改名称、scope 与限流
+func (r *Repo) Update(ctx context.Context, k *Key) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE api_keys SET name = $2, scopes = $3, rate_limit = $4 WHERE id = $1 AND revoked_at IS NULL`,
+		k.ID, k.Name, pq.Array([]string(k.Scopes)), k.RateLimit)
+	if err != nil {
+		return false, fmt.Errorf("update api key id=%s: %w", k.ID, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Revoke 撤销密钥，已撤销的返回 false
+func (r *Repo) Revoke(ctx context.Context, id uuid.UUID, by string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = NOW(), revoked_by = $2 WHERE id = $1 AND revoked_at IS NULL`, id, by)
+	if err != nil {
+		return false, fmt.Errorf("revoke api key id=%s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// CountRequest 当前自然分钟的请求数加一并返回累计值与窗口结束时间；同时刷新 last_used_at（每分钟至多一次）
+func (r *Repo) CountRequest(ctx context.Context, id uuid.UUID) (count int, resetAt time.Time, err error) {
+	var row struct {
+		Requests    int       `db:"requests"`
+		WindowStart time.Time `db:"window_start"`
+	}
+	query := `INSERT INTO api_key_usage (key_id, window_start, requests) VALUES ($1, date_trunc('minute', NOW()), 1)
+		ON CONFLICT (key_id, window_start) DO UPDATE SET requests = api_key_usage.requests + 1
+		RETURNING requests, window_start`
+	if err := r.db.GetContext(ctx, &row, query, id); err != nil {
+		return 0, time.Time{}, fmt.Errorf("count api key request id=%s: %w", id, err)
+	}
+	if row.Requests == 1 {
+		if _, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, id); err != nil {
+			return 0, time.Time{}, fmt.Errorf("touch api key id=%s: %w", id, err)
+		}
+	}
+	return row.Requests, row.WindowStart.Add(time.Minute), nil
+}
+
+// Usage 最近 since 以来每个密钥的请求总数
+func (r *Repo) Usage(ctx context.Context, since time.Time) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		KeyID    uuid.UUID `db:"key_id"`
+		Requests int       `db:"requests"`
+	}
+	query := `SELECT key_id, SUM(requests)::int AS requests FROM api_key_usage WHERE window_start >= $1 GROUP BY key_id`
+	if err := r.db.SelectContext(ctx, &rows, query, since); err != nil {
+		return nil, fmt.Errorf("api key usage: %w", err)
+	}
+	m := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		m[row.KeyID] = row.Requests
+	}
+	return m, nil
+}
+
+// CleanupUsage 删除早于 before 的限流计数
+func (r *Repo) CleanupUsage(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM api_key_usage WHERE window_start < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup api key usage: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// hashKey 明文密钥的 SHA-256（十六进制）
+func hashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// 权限范围（scope）：资源:读写；写不隐含读
+const (
+	ScopeCustomersRead  = "customers:read"
+	ScopeCustomersWrite = "customers:write"
+	ScopeContactsRead   = "contacts:read"
+	ScopeContactsWrite  = "contacts:write"
+	ScopePhonesRead     = "phones:read"
+	ScopeRecordsRead    = "records:read"
+	ScopeRecordsWrite   = "records:write"
+)
+
+// AllScopes 全部可授予的 scope 及说明
+var AllScopes = []struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}{
+	{ScopeCustomersRead, "读取客户"},
+	{ScopeCustomersWrite, "新建、修改客户"},
+	{ScopeContactsRead, "读取客户联系人（联系电话脱敏）"},
+	{ScopeContactsWrite, "修改客户联系人"},
+	{ScopePhonesRead, "联系电话返回明文（需同时具备 contacts:read）"},
+	{ScopeRecordsRead, "读取跟进记录"},
+	{ScopeRecordsWrite, "新建、修改、删除跟进记录"},
+}
+
+// ValidScope 是否为已定义的 scope
+func ValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s.Name == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Key API 密钥；明文只在创建时返回一次
+type Key struct {
+	ID         uuid.UUID      `db:"id" json:"id"`
+	Name       string         `db:"name" json:"name"`
+	Prefix     string         `db:"prefix" json:"prefix"` // 明文前缀，便于辨认
+	Scopes     pq.StringArray `db:"scopes" json:"scopes"`
+	RateLimit  int            `db:"rate_limit" json:"rate_limit"` // 每分钟请求数上限
+	CreatedBy  string         `db:"created_by" json:"created_by"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time     `db:"expires_at" json:"expires_at"`
+	LastUsedAt *time.Time     `db:"last_used_at" json:"last_used_at"`
+	RevokedAt  *time.Time     `db:"revoked_at" json:"revoked_at"`
+	RevokedBy  *string        `db:"revoked_by" json:"revoked_by"`
+}
+
+// HasScope 是否授予了 scope
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active 未撤销且未过期
+func (k *Key) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(now))
+}
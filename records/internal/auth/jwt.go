@@ -7,14 +7,14 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// Claims JWT 声明，包含 user_id
+// Claims JWT 声明，包含 user_id；jti 为登录会话 ID（撤销以 auth_sessions 为准）
 type Claims struct {
 	UserID string `json:"uid"`
 	jwt.RegisteredClaims
 }
 
-// Issue 签发 JWT，有效期 24 小时
-func Issue(secret string, userID string) (string, error) {
+// Issue 签发访问令牌，有效期 ttl
+func Issue(secret, userID, sessionID string, ttl time.Duration) (string, error) {
 	if secret == "" {
 		return "", errors.New("jwt secret is empty")
 	}
@@ -22,7 +22,8 @@ func Issue(secret string, userID string) (string, error) {
 	claims := &Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(24 * time.Hour)),
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
@@ -30,20 +31,21 @@ func Issue(secret string, userID string) (string, error) {
 	return token.SignedString([]byte(secret))
 }
 
-// Validate 验证 JWT 并返回 user_id
-func Validate(secret, tokenString string) (userID string, err error) {
+// Validate 验证访问令牌并返回 user_id 与会话 ID
+func Validate(secret, tokenString string) (userID, sessionID string, err error) {
 	if secret == "" || tokenString == "" {
-		return "", errors.New("invalid token")
+		return "", "", errors.New("invalid token")
 	}
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	if !token.Valid {
-		return "", errors.New("token invalid")
+	// 分享令牌等不含 uid 的令牌不能作为会话；不含 jti 的为旧版长期令牌，需重新登录
+	if !token.Valid || claims.UserID == "" || claims.ID == "" {
+		return "", "", errors.New("token invalid")
 	}
-	return claims.UserID, nil
+	return claims.UserID, claims.ID, nil
 }
@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// 会话撤销原因（auth_sessions.revoked_reason）
+const (
+	RevokeLogout    = "logout"
+	RevokeLogoutAll = "logout_all"
+	RevokeReused    = "reused"
+	RevokeResigned  = "resigned"
+)
+
+// refreshGrace 刷新令牌轮换后的宽限期：期间内旧令牌再次出现视为并发刷新，仅拒绝不撤销会话
+const refreshGrace = 30 * time.Second
+
+var (
+	// ErrSessionInvalid 会话不存在、已撤销、已过期或用户已离职
+	ErrSessionInvalid = errors.New("session invalid")
+	// ErrRefreshReused 已轮换的刷新令牌被重放，会话已整体撤销
+	ErrRefreshReused = errors.New("refresh token reused")
+)
+
+// Tokens 登录或刷新后下发给页面的令牌
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int // 访问令牌有效秒数
+	SessionID    string
+}
+
+// Sessions 页面登录会话：签发短期访问令牌与服务端存储的刷新令牌，支持轮换与撤销
+type Sessions struct {
+	db         *sqlx.DB
+	secret     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewSessions 创建 Sessions
+func NewSessions(db *sqlx.DB, secret string, accessTTL, refreshTTL time.Duration) *Sessions {
+	return &Sessions{db: db, secret: secret, accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
+
+// Login 新建会话并签发令牌
+func (s *Sessions) Login(ctx context.Context, userID, ip, userAgent string) (*Tokens, error) {
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	id := uuid.New()
+	query := `INSERT INTO auth_sessions (id, user_id, refresh_hash, expires_at, ip, user_agent) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := s.db.ExecContext(ctx, query, id, userID, hash, time.Now().Add(s.refreshTTL), ip, userAgent); err != nil {
+		return nil, fmt.Errorf("create auth session user=%s: %w", userID, err)
+	}
+	return s.tokens(userID, id, refresh)
+}
+
+// Refresh 用刷新令牌换取新令牌并轮换刷新令牌；旧令牌在宽限期外被重放时撤销整条会话
+func (s *Sessions) Refresh(ctx context.Context, refreshToken, ip, userAgent string) (*Tokens, error) {
+	if refreshToken == "" {
+		return nil, ErrSessionInvalid
+	}
+	hash := hashToken(refreshToken)
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin refresh tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var row struct {
+		ID          uuid.UUID  `db:"id"`
+		UserID      string     `db:"user_id"`
+		RefreshHash string     `db:"refresh_hash"`
+		ExpiresAt   time.Time  `db:"expires_at"`
+		RotatedAt   *time.Time `db:"rotated_at"`
+		RevokedAt   *time.Time `db:"revoked_at"`
+		UserStatus  int        `db:"user_status"`
+	}
+	query := `SELECT s.id, s.user_id, s.refresh_hash, s.expires_at, s.rotated_at, s.revoked_at, COALESCE(u.status, 1) AS user_status
+		FROM auth_sessions s LEFT JOIN users u ON u.id = s.user_id
+		WHERE s.refresh_hash = $1 OR s.prev_refresh_hash = $1 FOR UPDATE OF s`
+	if err := tx.GetContext(ctx, &row, query, hash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionInvalid
+		}
+		return nil, fmt.Errorf("get auth session by refresh token: %w", err)
+	}
+	if row.RevokedAt != nil || !row.ExpiresAt.After(time.Now()) {
+		return nil, ErrSessionInvalid
+	}
+	if row.UserStatus != 0 {
+		if err := revokeTx(ctx, tx, row.ID, RevokeResigned); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("commit refresh tx: %w", err)
+		}
+		return nil, ErrSessionInvalid
+	}
+	if row.RefreshHash != hash {
+		if row.RotatedAt != nil && time.Since(*row.RotatedAt) < refreshGrace {
+			return nil, ErrSessionInvalid
+		}
+		if err := revokeTx(ctx, tx, row.ID, RevokeReused); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("commit refresh tx: %w", err)
+		}
+		return nil, ErrRefreshReused
+	}
+
+	refresh, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	update := `UPDATE auth_sessions SET prev_refresh_hash = refresh_hash, refresh_hash = $2, rotated_at = NOW(), ip = $3, user_agent = $4 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, update, row.ID, newHash, ip, userAgent); err != nil {
+		return nil, fmt.Errorf("rotate refresh token session=%s: %w", row.ID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit refresh tx: %w", err)
+	}
+	return s.tokens(row.UserID, row.ID, refresh)
+}
+
+// Authenticate 验证访问令牌并确认会话有效、用户在职，返回 user_id；离职用户的会话随即撤销
+func (s *Sessions) Authenticate(ctx context.Context, accessToken string) (string, error) {
+	uid, sid, err := Validate(s.secret, accessToken)
+	if err != nil {
+		return "", err
+	}
+	id, err := uuid.Parse(sid)
+	if err != nil {
+		return "", ErrSessionInvalid
+	}
+	var row struct {
+		UserID     string `db:"user_id"`
+		Active     bool   `db:"active"`
+		UserStatus int    `db:"user_status"`
+	}
+	query := `SELECT s.user_id, (s.revoked_at IS NULL AND s.expires_at > NOW()) AS active, COALESCE(u.status, 1) AS user_status
+		FROM auth_sessions s LEFT JOIN users u ON u.id = s.user_id WHERE s.id = $1`
+	if err := s.db.GetContext(ctx, &row, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrSessionInvalid
+		}
+		return "", fmt.Errorf("get auth session id=%s: %w", id, err)
+	}
+	if row.UserID != uid || !row.Active {
+		return "", ErrSessionInvalid
+	}
+	if row.UserStatus != 0 {
+		if _, err := s.RevokeUser(ctx, uid, RevokeResigned); err != nil {
+			return "", err
+		}
+		return "", ErrSessionInvalid
+	}
+	return uid, nil
+}
+
+// SessionID 从访问令牌解析会话 ID（不校验会话状态），用于退出登录
+func (s *Sessions) SessionID(accessToken string) (uuid.UUID, error) {
+	_, sid, err := Validate(s.secret, accessToken)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(sid)
+}
+
+// Revoke 撤销单个会话，已撤销的返回 false
+func (s *Sessions) Revoke(ctx context.Context, id uuid.UUID, reason string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE auth_sessions SET revoked_at = NOW(), revoked_reason = $2 WHERE id = $1 AND revoked_at IS NULL`, id, reason)
+	if err != nil {
+		return false, fmt.Errorf("revoke auth session id=%s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeByRefresh 按当前刷新令牌撤销会话（访问令牌已过期时退出登录）
+func (s *Sessions) RevokeByRefresh(ctx context.Context, refreshToken, reason string) (bool, error) {
+	if refreshToken == "" {
+		return false, nil
+	}
+	res, err := s.db.ExecContext(ctx, `UPDATE auth_sessions SET revoked_at = NOW(), revoked_reason = $2 WHERE refresh_hash = $1 AND revoked_at IS NULL`, hashToken(refreshToken), reason)
+	if err != nil {
+		return false, fmt.Errorf("revoke auth session by refresh token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeUser 撤销用户全部未撤销会话，返回撤销数量
+func (s *Sessions) RevokeUser(ctx context.Context, userID, reason string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE auth_sessions SET revoked_at = NOW(), revoked_reason = $2 WHERE user_id = $1 AND revoked_at IS NULL`, userID, reason)
+	if err != nil {
+		return 0, fmt.Errorf("revoke auth sessions user=%s: %w", userID, err)
+	}
+	return res.RowsAffected()
+}
+
+// RevokeResigned 撤销离职（users.status <> 0）用户的全部会话，返回撤销数量
+func (s *Sessions) RevokeResigned(ctx context.Context) (int64, error) {
+	query := `UPDATE auth_sessions s SET revoked_at = NOW(), revoked_reason = $1
+		FROM users u WHERE u.id = s.user_id AND u.status <> 0 AND s.revoked_at IS NULL`
+	res, err := s.db.ExecContext(ctx, query, RevokeResigned)
+	if err != nil {
+		return 0, fmt.Errorf("revoke resigned auth sessions: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Cleanup 删除过期或撤销超过 retain 的会话，返回删除数量
+func (s *Sessions) Cleanup(ctx context.Context, retain time.Duration) (int64, error) {
+	before := time.Now().Add(-retain)
+	res, err := s.db.ExecContext(ctx, `DELETE FROM auth_sessions WHERE expires_at < $1 OR revoked_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup auth sessions: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// tokens 为会话签发访问令牌并组装下发结果
+func (s *Sessions) tokens(userID string, id uuid.UUID, refresh string) (*Tokens, error) {
+	access, err := Issue(s.secret, userID, id.String(), s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{AccessToken: access, RefreshToken: refresh, ExpiresIn: int(s.accessTTL / time.Second), SessionID: id.String()}, nil
+}
+
+// revokeTx 在事务内撤销会话
+func revokeTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, reason string) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE auth_sessions SET revoked_at = NOW(), revoked_reason = $2 WHERE id = $1`, id, reason); err != nil {
+		return fmt.Errorf("revoke auth session id=%s: %w", id, err)
+	}
+	return nil
+}
+
+// newRefreshToken 生成随机刷新令牌及其哈希（库中只存哈希）
+func newRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("generate refresh token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// hashToken 刷新令牌的 SHA-256（十六进制）
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// shareAudience 分享令牌的 aud，与会话令牌区分，避免两者混用
+const shareAudience = "share"
+
+// ShareClaims 分享令牌声明，sid 为分享记录 ID（撤销、访问计数以数据库为准）
+type ShareClaims struct {
+	ShareID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// IssueShare 签发分享令牌，到期时间与分享记录一致
+func IssueShare(secret, shareID string, expiresAt time.Time) (string, error) {
+	if secret == "" {
+		return "", errors.New("jwt secret is empty")
+	}
+	claims := &ShareClaims{
+		ShareID: shareID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{shareAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// ErrShareExpired 分享令牌签名有效但已过期（仍返回分享记录 ID，便于审计）
+var ErrShareExpired = errors.New("share token expired")
+
+// ValidateShare 验证分享令牌并返回分享记录 ID；已过期时返回 ID 与 ErrShareExpired
+func ValidateShare(secret, tokenString string) (shareID string, err error) {
+	if secret == "" || tokenString == "" {
+		return "", errors.New("invalid token")
+	}
+	claims := &ShareClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithAudience(shareAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		// 签名校验先于声明校验，过期错误时 claims 已可信
+		if errors.Is(err, jwt.ErrTokenExpired) && !errors.Is(err, jwt.ErrTokenInvalidAudience) && claims.ShareID != "" {
+			return claims.ShareID, ErrShareExpired
+		}
+		return "", err
+	}
+	if !token.Valid || claims.ShareID == "" {
+		return "", errors.New("token invalid")
+	}
+	return claims.ShareID, nil
+}
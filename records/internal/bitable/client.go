@@ -0,0 +1,270 @@
+package bitable
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 飞书多维表格开放接口（/open-apis/bitable/v1）的最小封装。
+// 直接走 HTTP 而不经 SDK，BaseURL 可替换为 httptest 服务端，便于在无飞书环境下验证同步逻辑
+
+// DefaultBaseURL 飞书开放平台地址
+const DefaultBaseURL = "https://open.feishu.cn"
+
+// maxBatch 批量接口单次记录数上限
+const maxBatch = 500
+
+// Record 多维表格中的一条记录
+type Record struct {
+	RecordID         string                 `json:"record_id,omitempty"`
+	Fields           map[string]interface{} `json:"fields"`
+	LastModifiedTime int64                  `json:"last_modified_time,omitempty"` // 毫秒，需 automatic_fields
+}
+
+// API 同步用到的多维表格接口；Client 为飞书实现
+type API interface {
+	BatchCreate(ctx context.Context, tableID string, records []Record) ([]Record, error)
+	BatchUpdate(ctx context.Context, tableID string, records []Record) ([]Record, error)
+	BatchGet(ctx context.Context, tableID string, recordIDs []string) ([]Record, error)
+	BatchDelete(ctx context.Context, tableID string, recordIDs []string) error
+	// SearchByField 返回 field 取值在 values 中的记录（文本字段精确匹配）
+	SearchByField(ctx context.Context, tableID, field string, values []string) ([]Record, error)
+}
+
+// APIError 飞书接口返回的业务错误（code 非 0）
+type APIError struct {
+	Code int
+	Msg  string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("feishu api code=%d: %s", e.Code, e.Msg)
+}
+
+// Client 飞书多维表格客户端；以应用身份（tenant_access_token）调用，多维表格需将该应用添加为协作者
+type Client struct {
+	baseURL   string
+	appID     string
+	appSecret string
+	appToken  string // 多维表格 app_token
+	http      *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewClient 创建客户端；baseURL 为空时使用 DefaultBaseURL，httpClient 为空时使用 30s 超时的默认客户端
+func NewClient(baseURL, appID, appSecret, appToken string, httpClient *http.Client) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		appID:     appID,
+		appSecret: appSecret,
+		appToken:  appToken,
+		http:      httpClient,
+	}
+}
+
+// tenantToken 返回缓存的 tenant_access_token，过期前 5 分钟刷新
+func (c *Client) tenantToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+	var resp struct {
+		Code   int    `json:"code"`
+		Msg    string `json:"msg"`
+		Token  string `json:"tenant_access_token"`
+		Expire int    `json:"expire"` // 秒
+	}
+	body := map[string]string{"app_id": c.appID, "app_secret": c.appSecret}
+	if err := c.do(ctx, "", "/open-apis/auth/v3/tenant_access_token/internal", body, &resp); err != nil {
+		return "", fmt.Errorf("get tenant access token: %w", err)
+	}
+	if resp.Code != 0 {
+		return "", fmt.Errorf("get tenant access token: %w", &APIError{Code: resp.Code, Msg: resp.Msg})
+	}
+	c.token = resp.Token
+	c.tokenExpiry = time.Now().Add(time.Duration(resp.Expire)*time.Second - 5*time.Minute)
+	return c.token, nil
+}
+
+// do 发送 POST JSON 请求并解析响应；token 为空表示不带 Authorization
+func (c *Client) do(ctx context.Context, token, path string, body, out interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("http %d: decode response: %w", resp.StatusCode, err)
+	}
+	return nil
+}
+
+// call 以应用身份调用多维表格接口，data 解析到 out
+func (c *Client) call(ctx context.Context, tableID, action string, body, out interface{}) error {
+	token, err := c.tenantToken(ctx)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/open-apis/bitable/v1/apps/%s/tables/%s/records/%s", url.PathEscape(c.appToken), url.PathEscape(tableID), action)
+	var resp struct {
+		Code int             `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := c.do(ctx, token, path, body, &resp); err != nil {
+		return fmt.Errorf("bitable %s table=%s: %w", action, tableID, err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("bitable %s table=%s: %w", action, tableID, &APIError{Code: resp.Code, Msg: resp.Msg})
+	}
+	if out == nil || len(resp.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return fmt.Errorf("bitable %s table=%s: decode data: %w", action, tableID, err)
+	}
+	return nil
+}
+
+// BatchCreate 批量新建记录，返回带 record_id 的记录（顺序与入参一致）
+func (c *Client) BatchCreate(ctx context.Context, tableID string, records []Record) ([]Record, error) {
+	return c.batchWrite(ctx, tableID, "batch_create", records)
+}
+
+// BatchUpdate 批量更新记录（仅更新 fields 中出现的列）
+func (c *Client) BatchUpdate(ctx context.Context, tableID string, records []Record) ([]Record, error) {
+	return c.batchWrite(ctx, tableID, "batch_update", records)
+}
+
+func (c *Client) batchWrite(ctx context.Context, tableID, action string, records []Record) ([]Record, error) {
+	var all []Record
+	for start := 0; start < len(records); start += maxBatch {
+		end := min(start+maxBatch, len(records))
+		var data struct {
+			Records []Record `json:"records"`
+		}
+		if err := c.call(ctx, tableID, action, map[string]interface{}{"records": records[start:end]}, &data); err != nil {
+			return all, err
+		}
+		all = append(all, data.Records...)
+	}
+	return all, nil
+}
+
+// BatchGet 按 record_id 批量读取记录（含最后修改时间）；已被删除的记录不在返回中
+func (c *Client) BatchGet(ctx context.Context, tableID string, recordIDs []string) ([]Record, error) {
+	var all []Record
+	for start := 0; start < len(recordIDs); start += maxBatch {
+		end := min(start+maxBatch, len(recordIDs))
+		var data struct {
+			Records []Record `json:"records"`
+		}
+		body := map[string]interface{}{"record_ids": recordIDs[start:end], "automatic_fields": true}
+		if err := c.call(ctx, tableID, "batch_get", body, &data); err != nil {
+			return all, err
+		}
+		all = append(all, data.Records...)
+	}
+	return all, nil
+}
+
+// BatchDelete 批量删除记录
+func (c *Client) BatchDelete(ctx context.Context, tableID string, recordIDs []string) error {
+	for start := 0; start < len(recordIDs); start += maxBatch {
+		end := min(start+maxBatch, len(recordIDs))
+		if err := c.call(ctx, tableID, "batch_delete", map[string]interface{}{"records": recordIDs[start:end]}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchByField 按字段取值（OR）查询记录，自动翻页
+func (c *Client) SearchByField(ctx context.Context, tableID, field string, values []string) ([]Record, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	conds := make([]map[string]interface{}, 0, len(values))
+	for _, v := range values {
+		conds = append(conds, map[string]interface{}{"field_name": field, "operator": "is", "value": []string{v}})
+	}
+	body := map[string]interface{}{
+		"filter":           map[string]interface{}{"conjunction": "or", "conditions": conds},
+		"automatic_fields": true,
+	}
+	var all []Record
+	pageToken := ""
+	for {
+		action := "search?page_size=500"
+		if pageToken != "" {
+			action += "&page_token=" + url.QueryEscape(pageToken)
+		}
+		var data struct {
+			Items     []Record `json:"items"`
+			HasMore   bool     `json:"has_more"`
+			PageToken string   `json:"page_token"`
+		}
+		if err := c.call(ctx, tableID, action, body, &data); err != nil {
+			return all, err
+		}
+		all = append(all, data.Items...)
+		if !data.HasMore || data.PageToken == "" {
+			return all, nil
+		}
+		pageToken = data.PageToken
+	}
+}
+
+// TextValue 取文本字段的值：多维表格返回字符串或富文本片段数组 [{"type":"text","text":"..."}]
+func TextValue(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []interface{}:
+		var b strings.Builder
+		for _, seg := range x {
+			if m, ok := seg.(map[string]interface{}); ok {
+				if t, ok := m["text"].(string); ok {
+					b.WriteString(t)
+				}
+			}
+		}
+		return b.String()
+	default:
+		return ""
+	}
+}
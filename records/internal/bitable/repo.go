@@ -0,0 +1,188 @@
+package bitable
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Repo 同步映射、游标与冲突日志数据访问
+type Repo struct {
+	db *sqlx.DB
+}
+
+// NewRepo 创建 Repo
+func NewRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+// mapping bitable_sync_map 表一行
+type mapping struct {
+	LocalID          uuid.UUID  `db:"local_id"`
+	RemoteRecordID   string     `db:"remote_record_id"`
+	RemoteModifiedAt int64      `db:"remote_modified_at"`
+	LocalUpdatedAt   *time.Time `db:"local_updated_at"`
+}
+
+// Mappings 返回本地 ID 对应的映射
+func (r *Repo) Mappings(ctx context.Context, kind, tableID string, ids []uuid.UUID) (map[uuid.UUID]*mapping, error) {
+	var rows []*mapping
+	query := `SELECT local_id, remote_record_id, remote_modified_at, local_updated_at FROM bitable_sync_map
+		WHERE kind = $1 AND table_id = $2 AND local_id = ANY($3)`
+	if err := r.db.SelectContext(ctx, &rows, query, kind, tableID, pq.Array(uuidStrings(ids))); err != nil {
+		return nil, fmt.Errorf("get bitable mappings kind=%s: %w", kind, err)
+	}
+	m := make(map[uuid.UUID]*mapping, len(rows))
+	for _, row := range rows {
+		m[row.LocalID] = row
+	}
+	return m, nil
+}
+
+// SaveMapping 新建或更新映射
+func (r *Repo) SaveMapping(ctx context.Context, kind, tableID string, m *mapping) error {
+	query := `INSERT INTO bitable_sync_map (kind, table_id, local_id, remote_record_id, remote_modified_at, local_updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (kind, table_id, local_id) DO UPDATE SET remote_record_id = EXCLUDED.remote_record_id,
+		remote_modified_at = EXCLUDED.remote_modified_at, local_updated_at = EXCLUDED.local_updated_at, synced_at = NOW()`
+	if _, err := r.db.ExecContext(ctx, query, kind, tableID, m.LocalID, m.RemoteRecordID, m.RemoteModifiedAt, m.LocalUpdatedAt); err != nil {
+		return fmt.Errorf("save bitable mapping kind=%s id=%s: %w", kind, m.LocalID, err)
+	}
+	return nil
+}
+
+// DeleteMappings 删除映射
+func (r *Repo) DeleteMappings(ctx context.Context, kind, tableID string, ids []uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM bitable_sync_map WHERE kind = $1 AND table_id = $2 AND local_id = ANY($3)`,
+		kind, tableID, pq.Array(uuidStrings(ids))); err != nil {
+		return fmt.Errorf("delete bitable mappings kind=%s: %w", kind, err)
+	}
+	return nil
+}
+
+// State 同步状态
+type State struct {
+	Kind      string     `db:"kind" json:"kind"`
+	TableID   string     `db:"table_id" json:"table_id"`
+	Cursor    string     `db:"cursor" json:"-"`
+	Synced    int64      `db:"synced" json:"synced"` // 累计同步条数
+	LastRunAt *time.Time `db:"last_run_at" json:"last_run_at"`
+	LastError *string    `db:"last_error" json:"last_error,omitempty"`
+}
+
+// GetState 返回同步状态，尚未同步过返回零值（游标为空，从头同步）
+func (r *Repo) GetState(ctx context.Context, kind, tableID string) (*State, error) {
+	st := State{Kind: kind, TableID: tableID}
+	query := `SELECT kind, table_id, cursor, synced, last_run_at, last_error FROM bitable_sync_state WHERE kind = $1 AND table_id = $2`
+	if err := r.db.GetContext(ctx, &st, query, kind, tableID); err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("get bitable sync state kind=%s: %w", kind, err)
+	}
+	return &st, nil
+}
+
+// SaveCursor 保存游标并累加同步条数
+func (r *Repo) SaveCursor(ctx context.Context, kind, tableID, cursor string, synced int) error {
+	query := `INSERT INTO bitable_sync_state (kind, table_id, cursor, synced, last_run_at) VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (kind, table_id) DO UPDATE SET cursor = EXCLUDED.cursor, synced = bitable_sync_state.synced + EXCLUDED.synced,
+		last_run_at = NOW(), last_error = NULL`
+	if _, err := r.db.ExecContext(ctx, query, kind, tableID, cursor, synced); err != nil {
+		return fmt.Errorf("save bitable cursor kind=%s: %w", kind, err)
+	}
+	return nil
+}
+
+// SaveRun 记录一次运行的结束时间与错误（游标不变）
+func (r *Repo) SaveRun(ctx context.Context, kind, tableID string, runErr error) error {
+	var msg *string
+	if runErr != nil {
+		s := runErr.Error()
+		msg = &s
+	}
+	query := `INSERT INTO bitable_sync_state (kind, table_id, last_run_at, last_error) VALUES ($1, $2, NOW(), $3)
+		ON CONFLICT (kind, table_id) DO UPDATE SET last_run_at = NOW(), last_error = EXCLUDED.last_error`
+	if _, err := r.db.ExecContext(ctx, query, kind, tableID, msg); err != nil {
+		return fmt.Errorf("save bitable run kind=%s: %w", kind, err)
+	}
+	return nil
+}
+
+// ResetCursor 清空游标，下次从头全量同步（已有映射保留，按 record_id 更新）
+func (r *Repo) ResetCursor(ctx context.Context, kind, tableID string) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE bitable_sync_state SET cursor = '' WHERE kind = $1 AND table_id = $2`, kind, tableID); err != nil {
+		return fmt.Errorf("reset bitable cursor kind=%s: %w", kind, err)
+	}
+	return nil
+}
+
+// Conflict bitable_sync_conflicts 表一行
+type Conflict struct {
+	ID             int64           `db:"id" json:"id"`
+	Kind           string          `db:"kind" json:"kind"`
+	TableID        string          `db:"table_id" json:"table_id"`
+	LocalID        uuid.UUID       `db:"local_id" json:"local_id"`
+	RemoteRecordID string          `db:"remote_record_id" json:"remote_record_id"`
+	Reason         string          `db:"reason" json:"reason"`
+	Resolution     string          `db:"resolution" json:"resolution"`
+	LocalFields    json.RawMessage `db:"local_fields" json:"local_fields"`
+	RemoteFields   json.RawMessage `db:"remote_fields" json:"remote_fields"`
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+}
+
+// LogConflict 记录冲突
+func (r *Repo) LogConflict(ctx context.Context, c *Conflict) error {
+	query := `INSERT INTO bitable_sync_conflicts (kind, table_id, local_id, remote_record_id, reason, resolution, local_fields, remote_fields)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if _, err := r.db.ExecContext(ctx, query, c.Kind, c.TableID, c.LocalID, c.RemoteRecordID, c.Reason, c.Resolution,
+		nullJSON(c.LocalFields), nullJSON(c.RemoteFields)); err != nil {
+		return fmt.Errorf("log bitable conflict kind=%s id=%s: %w", c.Kind, c.LocalID, err)
+	}
+	return nil
+}
+
+// ListConflicts 最近的冲突，最新在前
+func (r *Repo) ListConflicts(ctx context.Context, limit int) ([]*Conflict, error) {
+	list := []*Conflict{}
+	query := `SELECT id, kind, table_id, local_id, remote_record_id, reason, resolution, local_fields, remote_fields, created_at
+		FROM bitable_sync_conflicts ORDER BY id DESC LIMIT $1`
+	if err := r.db.SelectContext(ctx, &list, query, limit); err != nil {
+		return nil, fmt.Errorf("list bitable conflicts: %w", err)
+	}
+	return list, nil
+}
+
+// UserNames 返回用户 ID 对应的姓名
+func (r *Repo) UserNames(ctx context.Context, ids []string) (map[string]string, error) {
+	var rows []struct {
+		ID   string `db:"id"`
+		Name string `db:"name"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, `SELECT id, name FROM users WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("get user names: %w", err)
+	}
+	m := make(map[string]string, len(rows))
+	for _, row := range rows {
+		m[row.ID] = row.Name
+	}
+	return m, nil
+}
+
+func nullJSON(b json.RawMessage) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return []byte(b)
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
@@ -0,0 +1,570 @@
+package bitable
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"records/internal/phonecrypt"
+	"records/internal/repository"
+	"records/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// 单向同步：本地客户与跟进记录 → 多维表格。按 (updated_at, id) 游标增量拉取本地变更，
+// 以本地 ID 列为键新建或更新多维表格记录；多维表格中被手工修改或删除的记录记入冲突日志后按策略处理。
+// updated_at 取事务开始时间，长事务（如批量导入）提交时游标可能已越过其中的变更，
+// 因此每次运行从游标回退 Overlap 重新扫描，映射中记录已写入的本地版本，重扫到的已同步版本直接跳过
+
+// 同步对象
+const (
+	KindCustomer = "customer"
+	KindRecord   = "follow_record"
+)
+
+// 冲突处理策略
+const (
+	ConflictOverwrite = "overwrite" // 以本地为准覆盖（默认）
+	ConflictSkip      = "skip"      // 保留多维表格中的修改，不再覆盖该记录的本次变更
+)
+
+// 冲突原因与处理结果
+const (
+	ReasonRemoteModified = "remote_modified"
+	ReasonRemoteDeleted  = "remote_deleted"
+
+	ResolutionOverwritten = "overwritten"
+	ResolutionSkipped     = "skipped"
+	ResolutionRecreated   = "recreated"
+)
+
+const (
+	defaultBatchSize   = 100
+	defaultSettleDelay = 5 * time.Second
+	defaultOverlap     = 10 * time.Minute
+
+	// searchChunk 按本地 ID 查找多维表格记录时单次查询的取值个数
+	searchChunk = 50
+)
+
+// KeyField 本地 ID 对应的字段名，每张表必须映射到多维表格的一个文本列
+const KeyField = "id"
+
+// RecordFields 跟进记录可映射的本地字段
+var RecordFields = []string{
+	"id", "customer_id", "customer_name", "user_id", "user_name", "contact_person", "contact_phone", "contact_role",
+	"follow_time", "follow_method", "follow_content", "follow_goal", "follow_result", "risk_content", "next_plan",
+	"ai", "version", "deleted", "created_at", "updated_at",
+}
+
+// CustomerFields 客户可映射的本地字段
+var CustomerFields = []string{
+	"id", "name", "tier", "contact_person", "contact_phone", "contact_role", "record_count", "last_follow_time",
+	"created_at", "updated_at",
+}
+
+// TableConfig 一张多维表格的配置；Fields 为本地字段 → 多维表格列名，未映射的字段不同步
+type TableConfig struct {
+	TableID string
+	Fields  map[string]string
+}
+
+// Config 同步配置
+type Config struct {
+	Customers     TableConfig   // TableID 为空则不同步客户
+	Records       TableConfig   // TableID 为空则不同步跟进记录
+	BatchSize     int           // 每批同步的本地记录数，默认 100，最大 200
+	Conflict      string        // 冲突处理策略，默认 overwrite
+	DeleteRemoved bool          // 本地删除的跟进记录同时删除多维表格记录；否则仅更新 deleted 列
+	SettleDelay   time.Duration // 只同步早于该时长之前的变更，避开尚未提交的并发写入，默认 5s
+	Overlap       time.Duration // 每次从游标回退该时长重新扫描，补上提交晚于游标推进的变更，默认 10m
+}
+
+// Validate 校验字段映射
+func (c Config) Validate() error {
+	if c.Conflict != "" && c.Conflict != ConflictOverwrite && c.Conflict != ConflictSkip {
+		return fmt.Errorf("bitable conflict policy must be %s or %s", ConflictOverwrite, ConflictSkip)
+	}
+	tables := []struct {
+		kind   string
+		tc     TableConfig
+		fields []string
+	}{
+		{KindCustomer, c.Customers, CustomerFields},
+		{KindRecord, c.Records, RecordFields},
+	}
+	for _, t := range tables {
+		if t.tc.TableID == "" {
+			continue
+		}
+		if t.tc.Fields[KeyField] == "" {
+			return fmt.Errorf("bitable %s table: field %q must be mapped", t.kind, KeyField)
+		}
+		for local := range t.tc.Fields {
+			if !contains(t.fields, local) {
+				return fmt.Errorf("bitable %s table: unknown field %q", t.kind, local)
+			}
+		}
+	}
+	return nil
+}
+
+// Result 一次同步的结果
+type Result struct {
+	Customers int `json:"customers"`
+	Records   int `json:"records"`
+}
+
+// Syncer 多维表格同步
+type Syncer struct {
+	repo  *Repo
+	store *repository.Repository
+	api   API
+	cfg   Config
+	log   logger.Logger
+}
+
+// NewSyncer 创建同步器，未配置的项取默认值
+func NewSyncer(db *sqlx.DB, api API, cfg Config, log logger.Logger) *Syncer {
+	if cfg.BatchSize <= 0 || cfg.BatchSize > repository.MaxPageLimit {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.Conflict == "" {
+		cfg.Conflict = ConflictOverwrite
+	}
+	if cfg.SettleDelay <= 0 {
+		cfg.SettleDelay = defaultSettleDelay
+	}
+	if cfg.Overlap <= 0 {
+		cfg.Overlap = defaultOverlap
+	}
+	return &Syncer{repo: NewRepo(db), store: repository.New(db), api: api, cfg: cfg, log: log}
+}
+
+// Repo 返回数据访问（管理 API 查看同步状态与冲突）
+func (s *Syncer) Repo() *Repo {
+	return s.repo
+}
+
+// TableID 返回同步对象对应的多维表格 table_id，未配置返回空
+func (s *Syncer) TableID(kind string) string {
+	switch kind {
+	case KindCustomer:
+		return s.cfg.Customers.TableID
+	case KindRecord:
+		return s.cfg.Records.TableID
+	}
+	return ""
+}
+
+// Run 同步客户与跟进记录自上次游标以来的变更
+func (s *Syncer) Run(ctx context.Context) (*Result, error) {
+	if err := s.cfg.Validate(); err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	before := time.Now().Add(-s.cfg.SettleDelay)
+	if s.cfg.Customers.TableID != "" {
+		n, err := s.syncTable(ctx, KindCustomer, s.cfg.Customers, before, s.fetchCustomers)
+		res.Customers = n
+		if err != nil {
+			return res, err
+		}
+	}
+	if s.cfg.Records.TableID != "" {
+		n, err := s.syncTable(ctx, KindRecord, s.cfg.Records, before, s.fetchRecords)
+		res.Records = n
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// item 待同步的一条本地数据，Fields 已按映射转换为多维表格列名
+type item struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+	Deleted   bool
+	Fields    map[string]interface{}
+}
+
+// fetchFunc 按游标拉取一页本地变更
+type fetchFunc func(ctx context.Context, tc TableConfig, cursor string, before time.Time) ([]*item, error)
+
+// syncTable 从游标回退 Overlap 处逐页同步，每页成功后推进游标；失败时游标停在最后成功的一页。
+// 保存的游标只前进不后退，重扫窗口内的页不会把它拉回
+func (s *Syncer) syncTable(ctx context.Context, kind string, tc TableConfig, before time.Time, fetch fetchFunc) (int, error) {
+	st, err := s.repo.GetState(ctx, kind, tc.TableID)
+	if err != nil {
+		return 0, err
+	}
+	var saved *repository.Cursor
+	cursor := ""
+	if st.Cursor != "" {
+		if saved, err = repository.DecodeCursor(st.Cursor, repository.SortUpdatedAt, false); err != nil {
+			return 0, fmt.Errorf("decode bitable cursor kind=%s: %w", kind, err)
+		}
+		cursor = repository.Cursor{Sort: repository.SortUpdatedAt, At: saved.At.Add(-s.cfg.Overlap), ID: uuid.Nil.String()}.Encode()
+	}
+	total := 0
+	for {
+		items, err := fetch(ctx, tc, cursor, before)
+		synced := 0
+		if err == nil && len(items) > 0 {
+			synced, err = s.syncBatch(ctx, kind, tc, items)
+		}
+		if err != nil {
+			if rerr := s.repo.SaveRun(ctx, kind, tc.TableID, err); rerr != nil {
+				s.log.WithContext(ctx).Error("Save bitable sync run failed", "error", rerr, "kind", kind)
+			}
+			return total, err
+		}
+		if len(items) > 0 {
+			last := items[len(items)-1]
+			next := repository.Cursor{Sort: repository.SortUpdatedAt, At: last.UpdatedAt, ID: last.ID.String()}
+			cursor = next.Encode()
+			if saved == nil || cursorAfter(next, *saved) {
+				saved = &next
+			}
+		}
+		stored := ""
+		if saved != nil {
+			stored = saved.Encode()
+		}
+		if err := s.repo.SaveCursor(ctx, kind, tc.TableID, stored, synced); err != nil {
+			return total, err
+		}
+		total += synced
+		if len(items) < s.cfg.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+// cursorAfter 游标 a 是否位于 b 之后，与列表的 (updated_at, id) 排序一致
+func cursorAfter(a, b repository.Cursor) bool {
+	if !a.At.Equal(b.At) {
+		return a.At.After(b.At)
+	}
+	return a.ID > b.ID
+}
+
+// syncBatch 同步一页，返回实际写入的条数：跳过已同步过的版本，未建立映射的先按 ID 列在多维表格中查找
+// （补建丢失的映射），再新建、更新或删除
+func (s *Syncer) syncBatch(ctx context.Context, kind string, tc TableConfig, page []*item) (int, error) {
+	ids := make([]uuid.UUID, len(page))
+	for i, it := range page {
+		ids[i] = it.ID
+	}
+	maps, err := s.repo.Mappings(ctx, kind, tc.TableID, ids)
+	if err != nil {
+		return 0, err
+	}
+	items := make([]*item, 0, len(page))
+	for _, it := range page {
+		if m := maps[it.ID]; m != nil && m.LocalUpdatedAt != nil && !it.UpdatedAt.After(*m.LocalUpdatedAt) {
+			continue
+		}
+		items = append(items, it)
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+	if err := s.adoptExisting(ctx, tc, items, maps); err != nil {
+		return 0, err
+	}
+
+	var remoteIDs []string
+	for _, m := range maps {
+		remoteIDs = append(remoteIDs, m.RemoteRecordID)
+	}
+	remote, err := s.getRemote(ctx, tc.TableID, remoteIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	var creates, updates []Record
+	var createItems, updateItems []*item
+	var deletes []string
+	var deleteIDs []uuid.UUID
+	for _, it := range items {
+		m := maps[it.ID]
+		if it.Deleted && s.cfg.DeleteRemoved {
+			if m != nil {
+				if _, ok := remote[m.RemoteRecordID]; ok {
+					deletes = append(deletes, m.RemoteRecordID)
+				}
+				deleteIDs = append(deleteIDs, it.ID)
+			}
+			continue
+		}
+		if m == nil {
+			if it.Deleted {
+				continue // 从未同步过的已删除记录无需新建
+			}
+			creates = append(creates, Record{Fields: it.Fields})
+			createItems = append(createItems, it)
+			continue
+		}
+		r, ok := remote[m.RemoteRecordID]
+		switch {
+		case !ok:
+			s.logConflict(ctx, kind, tc.TableID, it, m.RemoteRecordID, ReasonRemoteDeleted, ResolutionRecreated, nil)
+			creates = append(creates, Record{Fields: it.Fields})
+			createItems = append(createItems, it)
+			continue
+		case m.RemoteModifiedAt > 0 && r.LastModifiedTime > m.RemoteModifiedAt:
+			if s.cfg.Conflict == ConflictSkip {
+				s.logConflict(ctx, kind, tc.TableID, it, m.RemoteRecordID, ReasonRemoteModified, ResolutionSkipped, r.Fields)
+				// 记下已处理的本地版本，重扫时不再重复记录；修改时间不变，之后的本地变更仍按冲突处理
+				skipped := *m
+				skipped.LocalUpdatedAt = &it.UpdatedAt
+				if err := s.repo.SaveMapping(ctx, kind, tc.TableID, &skipped); err != nil {
+					return 0, err
+				}
+				continue
+			}
+			s.logConflict(ctx, kind, tc.TableID, it, m.RemoteRecordID, ReasonRemoteModified, ResolutionOverwritten, r.Fields)
+		}
+		updates = append(updates, Record{RecordID: m.RemoteRecordID, Fields: it.Fields})
+		updateItems = append(updateItems, it)
+	}
+
+	written := make(map[*item]string, len(creates)+len(updates))
+	if len(creates) > 0 {
+		created, err := s.api.BatchCreate(ctx, tc.TableID, creates)
+		if err != nil {
+			return 0, err
+		}
+		if len(created) != len(createItems) {
+			return 0, fmt.Errorf("bitable batch_create table=%s: got %d records, want %d", tc.TableID, len(created), len(createItems))
+		}
+		for i, r := range created {
+			written[createItems[i]] = r.RecordID
+		}
+	}
+	if len(updates) > 0 {
+		if _, err := s.api.BatchUpdate(ctx, tc.TableID, updates); err != nil {
+			return 0, err
+		}
+		for _, it := range updateItems {
+			written[it] = maps[it.ID].RemoteRecordID
+		}
+	}
+	if err := s.saveMappings(ctx, kind, tc.TableID, written); err != nil {
+		return 0, err
+	}
+
+	if len(deletes) > 0 {
+		if err := s.api.BatchDelete(ctx, tc.TableID, deletes); err != nil {
+			return 0, err
+		}
+	}
+	if len(deleteIDs) > 0 {
+		if err := s.repo.DeleteMappings(ctx, kind, tc.TableID, deleteIDs); err != nil {
+			return 0, err
+		}
+	}
+	return len(items), nil
+}
+
+// adoptExisting 为尚无映射的记录按 ID 列查找多维表格中已有的记录（如映射表被清空或曾手工导入），找到则复用以免重复新建
+func (s *Syncer) adoptExisting(ctx context.Context, tc TableConfig, items []*item, maps map[uuid.UUID]*mapping) error {
+	var missing []string
+	for _, it := range items {
+		if maps[it.ID] == nil && !(it.Deleted && s.cfg.DeleteRemoved) {
+			missing = append(missing, it.ID.String())
+		}
+	}
+	keyCol := tc.Fields[KeyField]
+	for start := 0; start < len(missing); start += searchChunk {
+		end := min(start+searchChunk, len(missing))
+		found, err := s.api.SearchByField(ctx, tc.TableID, keyCol, missing[start:end])
+		if err != nil {
+			return err
+		}
+		for _, r := range found {
+			id, err := uuid.Parse(TextValue(r.Fields[keyCol]))
+			if err != nil || maps[id] != nil {
+				continue
+			}
+			// 修改时间记为 0：本次直接覆盖，不视为冲突
+			maps[id] = &mapping{LocalID: id, RemoteRecordID: r.RecordID}
+		}
+	}
+	return nil
+}
+
+// getRemote 批量读取多维表格记录，按 record_id 索引
+func (s *Syncer) getRemote(ctx context.Context, tableID string, recordIDs []string) (map[string]Record, error) {
+	m := make(map[string]Record, len(recordIDs))
+	if len(recordIDs) == 0 {
+		return m, nil
+	}
+	list, err := s.api.BatchGet(ctx, tableID, recordIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range list {
+		m[r.RecordID] = r
+	}
+	return m, nil
+}
+
+// saveMappings 写入后重新读取修改时间并保存映射，下次据此判断多维表格中是否有手工修改；同时记录已写入的本地版本
+func (s *Syncer) saveMappings(ctx context.Context, kind, tableID string, written map[*item]string) error {
+	if len(written) == 0 {
+		return nil
+	}
+	recordIDs := make([]string, 0, len(written))
+	for _, rid := range written {
+		recordIDs = append(recordIDs, rid)
+	}
+	remote, err := s.getRemote(ctx, tableID, recordIDs)
+	if err != nil {
+		return err
+	}
+	for it, rid := range written {
+		updatedAt := it.UpdatedAt
+		m := &mapping{LocalID: it.ID, RemoteRecordID: rid, RemoteModifiedAt: remote[rid].LastModifiedTime, LocalUpdatedAt: &updatedAt}
+		if err := s.repo.SaveMapping(ctx, kind, tableID, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logConflict 记录冲突；记录失败只打日志，不中断同步
+func (s *Syncer) logConflict(ctx context.Context, kind, tableID string, it *item, remoteID, reason, resolution string, remoteFields map[string]interface{}) {
+	c := &Conflict{Kind: kind, TableID: tableID, LocalID: it.ID, RemoteRecordID: remoteID, Reason: reason, Resolution: resolution}
+	c.LocalFields, _ = json.Marshal(it.Fields)
+	if remoteFields != nil {
+		c.RemoteFields, _ = json.Marshal(remoteFields)
+	}
+	if err := s.repo.LogConflict(ctx, c); err != nil {
+		s.log.WithContext(ctx).Error("Log bitable conflict failed", "error", err, "kind", kind, "local_id", it.ID)
+		return
+	}
+	s.log.WithContext(ctx).Warn("Bitable sync conflict", "kind", kind, "local_id", it.ID, "remote_record_id", remoteID,
+		"reason", reason, "resolution", resolution)
+}
+
+func (s *Syncer) fetchCustomers(ctx context.Context, tc TableConfig, cursor string, before time.Time) ([]*item, error) {
+	page, err := s.store.ListCustomersPage(ctx, repository.CustomerListOptions{
+		Limit: s.cfg.BatchSize, Cursor: cursor, UpdatedBefore: &before,
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*item, 0, len(page.Items))
+	for _, c := range page.Items {
+		items = append(items, &item{ID: c.ID, UpdatedAt: c.UpdatedAt, Fields: mapFields(tc.Fields, customerValues(c))})
+	}
+	return items, nil
+}
+
+func (s *Syncer) fetchRecords(ctx context.Context, tc TableConfig, cursor string, before time.Time) ([]*item, error) {
+	page, err := s.store.ListAPIRecordsPage(ctx, repository.APIRecordListOptions{
+		Limit: s.cfg.BatchSize, Cursor: cursor, UpdatedBefore: &before, IncludeDeleted: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var names map[string]string
+	if tc.Fields["user_name"] != "" && len(page.Items) > 0 {
+		userIDs := make([]string, 0, len(page.Items))
+		for _, r := range page.Items {
+			userIDs = append(userIDs, r.UserID)
+		}
+		if names, err = s.repo.UserNames(ctx, userIDs); err != nil {
+			return nil, err
+		}
+	}
+	items := make([]*item, 0, len(page.Items))
+	for _, r := range page.Items {
+		items = append(items, &item{
+			ID:        r.ID,
+			UpdatedAt: r.UpdatedAt,
+			Deleted:   r.DeletedAt != nil,
+			Fields:    mapFields(tc.Fields, recordValues(r, names[r.UserID])),
+		})
+	}
+	return items, nil
+}
+
+// recordValues 跟进记录的本地字段值；时间为毫秒时间戳（对应多维表格日期列），空值为 nil（清空该列）
+func recordValues(r *repository.APIRecord, userName string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             r.ID.String(),
+		"customer_id":    r.CustomerID.String(),
+		"customer_name":  r.CustomerName,
+		"user_id":        r.UserID,
+		"user_name":      userName,
+		"contact_person": text(r.ContactPerson),
+		"contact_phone":  text(phonecrypt.RevealPtr(r.ContactPhone, false)), // 多维表格对协作者可见，同步脱敏号码
+		"contact_role":   text(r.ContactRole),
+		"follow_time":    r.FollowTime.UnixMilli(),
+		"follow_method":  text(r.FollowMethod),
+		"follow_content": text(r.FollowContent),
+		"follow_goal":    text(r.FollowGoal),
+		"follow_result":  text(r.FollowResult),
+		"risk_content":   text(r.RiskContent),
+		"next_plan":      text(r.NextPlan),
+		"ai":             r.AI,
+		"version":        r.Version,
+		"deleted":        r.DeletedAt != nil,
+		"created_at":     r.CreatedAt.UnixMilli(),
+		"updated_at":     r.UpdatedAt.UnixMilli(),
+	}
+}
+
+// customerValues 客户的本地字段值
+func customerValues(c *repository.APICustomer) map[string]interface{} {
+	var lastFollow interface{}
+	if c.LastFollowTime != nil {
+		lastFollow = c.LastFollowTime.UnixMilli()
+	}
+	return map[string]interface{}{
+		"id":               c.ID.String(),
+		"name":             c.Name,
+		"tier":             text(c.Tier),
+		"contact_person":   text(c.ContactPerson),
+		"contact_phone":    text(phonecrypt.RevealPtr(c.ContactPhone, false)),
+		"contact_role":     text(c.ContactRole),
+		"record_count":     c.RecordCount,
+		"last_follow_time": lastFollow,
+		"created_at":       c.CreatedAt.UnixMilli(),
+		"updated_at":       c.UpdatedAt.UnixMilli(),
+	}
+}
+
+// mapFields 按映射把本地字段转换为多维表格列
+func mapFields(mapping map[string]string, values map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(mapping))
+	for local, column := range mapping {
+		if column != "" {
+			out[column] = values[local]
+		}
+	}
+	return out
+}
+
+func text(s *string) interface{} {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return *s
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,443 @@
+package bitable
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"records/internal/repository"
+	"records/pkg/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const testTable = "tbl_records"
+
+// fakeBitable 内存中的多维表格，实现同步用到的开放接口
+type fakeBitable struct {
+	mu      sync.Mutex
+	records map[string]Record
+	seq     int
+	clock   int64
+	calls   []string
+}
+
+func newFakeBitable() *fakeBitable {
+	return &fakeBitable{records: map[string]Record{}, clock: 1000}
+}
+
+// seed 预置一条记录，返回 record_id
+func (f *fakeBitable) seed(fields map[string]interface{}, modifiedAt int64) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	id := fmt.Sprintf("rec%d", f.seq)
+	f.records[id] = Record{RecordID: id, Fields: fields, LastModifiedTime: modifiedAt}
+	return id
+}
+
+func (f *fakeBitable) get(id string) (Record, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, ok := f.records[id]
+	return r, ok
+}
+
+func (f *fakeBitable) count(action string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		if c == action {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeBitable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/open-apis/auth/v3/tenant_access_token/internal" {
+		writeJSON(w, map[string]interface{}{"code": 0, "tenant_access_token": "t-test", "expire": 7200})
+		return
+	}
+	prefix := "/open-apis/bitable/v1/apps/app_test/tables/" + testTable + "/records/"
+	action, ok := strings.CutPrefix(r.URL.Path, prefix)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	f.calls = append(f.calls, action)
+	var body struct {
+		Records   json.RawMessage `json:"records"`
+		RecordIDs []string        `json:"record_ids"`
+		Filter    struct {
+			Conditions []struct {
+				FieldName string   `json:"field_name"`
+				Value     []string `json:"value"`
+			} `json:"conditions"`
+		} `json:"filter"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch action {
+	case "batch_create", "batch_update":
+		var in []Record
+		_ = json.Unmarshal(body.Records, &in)
+		out := make([]Record, 0, len(in))
+		for _, rec := range in {
+			if action == "batch_create" {
+				f.seq++
+				rec.RecordID = fmt.Sprintf("rec%d", f.seq)
+			}
+			f.clock++
+			rec.LastModifiedTime = f.clock
+			f.records[rec.RecordID] = rec
+			out = append(out, rec)
+		}
+		writeJSON(w, map[string]interface{}{"code": 0, "data": map[string]interface{}{"records": out}})
+	case "batch_get":
+		out := []Record{}
+		for _, id := range body.RecordIDs {
+			if rec, ok := f.records[id]; ok {
+				out = append(out, rec)
+			}
+		}
+		writeJSON(w, map[string]interface{}{"code": 0, "data": map[string]interface{}{"records": out}})
+	case "batch_delete":
+		var ids []string
+		_ = json.Unmarshal(body.Records, &ids)
+		for _, id := range ids {
+			delete(f.records, id)
+		}
+		writeJSON(w, map[string]interface{}{"code": 0})
+	default: // search
+		items := []Record{}
+		for _, rec := range f.records {
+			for _, c := range body.Filter.Conditions {
+				if len(c.Value) > 0 && TextValue(rec.Fields[c.FieldName]) == c.Value[0] {
+					items = append(items, rec)
+					break
+				}
+			}
+		}
+		writeJSON(w, map[string]interface{}{"code": 0, "data": map[string]interface{}{"items": items}})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{})                {}
+func (nopLogger) Info(string, ...interface{})                 {}
+func (nopLogger) Warn(string, ...interface{})                 {}
+func (nopLogger) Error(string, ...interface{})                {}
+func (nopLogger) Fatal(string, ...interface{})                {}
+func (l nopLogger) WithContext(context.Context) logger.Logger { return l }
+
+// newTestSyncer 创建连接 sqlmock 与 httptest 多维表格的同步器，只同步跟进记录
+func newTestSyncer(t *testing.T, fake *fakeBitable, cfg Config) (*Syncer, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	cfg.Records = TableConfig{TableID: testTable, Fields: map[string]string{
+		"id": "记录ID", "customer_name": "客户名称", "follow_content": "跟进内容",
+	}}
+	client := NewClient(srv.URL, "cli_test", "secret", "app_test", srv.Client())
+	return NewSyncer(sqlx.NewDb(db, "postgres"), client, cfg, nopLogger{}), mock
+}
+
+// localRecord 一条本地跟进记录
+type localRecord struct {
+	ID        uuid.UUID
+	Customer  string
+	Content   string
+	UpdatedAt time.Time
+}
+
+func newLocalRecord(customer, content string, updatedAt time.Time) localRecord {
+	return localRecord{ID: uuid.New(), Customer: customer, Content: content, UpdatedAt: updatedAt}
+}
+
+func (r localRecord) cursor() string {
+	return repository.Cursor{Sort: repository.SortUpdatedAt, At: r.UpdatedAt, ID: r.ID.String()}.Encode()
+}
+
+var (
+	stateQuery    = regexp.QuoteMeta(`FROM bitable_sync_state WHERE kind = $1 AND table_id = $2`)
+	recordsQuery  = regexp.QuoteMeta(`FROM follow_records fr WHERE`)
+	mappingsQuery = regexp.QuoteMeta(`FROM bitable_sync_map`)
+	saveMapExec   = regexp.QuoteMeta(`INSERT INTO bitable_sync_map`)
+	saveCursor    = regexp.QuoteMeta(`INSERT INTO bitable_sync_state (kind, table_id, cursor, synced`)
+	saveRunExec   = regexp.QuoteMeta(`INSERT INTO bitable_sync_state (kind, table_id, last_run_at, last_error)`)
+	conflictExec  = regexp.QuoteMeta(`INSERT INTO bitable_sync_conflicts`)
+)
+
+func expectState(mock sqlmock.Sqlmock, cursor string) {
+	rows := sqlmock.NewRows([]string{"kind", "table_id", "cursor", "synced", "last_run_at", "last_error"})
+	if cursor != "" {
+		rows.AddRow(KindRecord, testTable, cursor, 0, nil, nil)
+	}
+	mock.ExpectQuery(stateQuery).WithArgs(KindRecord, testTable).WillReturnRows(rows)
+}
+
+func expectRecords(mock sqlmock.Sqlmock, recs ...localRecord) *sqlmock.ExpectedQuery {
+	rows := sqlmock.NewRows([]string{
+		"id", "user_id", "customer_id", "customer_name", "contact_person", "contact_phone", "contact_role",
+		"follow_time", "follow_method", "follow_content", "follow_goal", "follow_result", "risk_content", "next_plan",
+		"ai", "version", "deleted_at", "created_at", "updated_at",
+	})
+	for _, r := range recs {
+		rows.AddRow(r.ID.String(), "ou_sales", uuid.NewString(), r.Customer, nil, nil, nil,
+			r.UpdatedAt, nil, r.Content, nil, nil, nil, nil, false, 1, nil, r.UpdatedAt, r.UpdatedAt)
+	}
+	return mock.ExpectQuery(recordsQuery).WillReturnRows(rows)
+}
+
+// mappingRow 已有映射；localUpdatedAt 为零值表示尚未记录已同步的本地版本
+type mappingRow struct {
+	LocalID          uuid.UUID
+	RemoteRecordID   string
+	RemoteModifiedAt int64
+	LocalUpdatedAt   time.Time
+}
+
+func expectMappings(mock sqlmock.Sqlmock, maps ...mappingRow) {
+	rows := sqlmock.NewRows([]string{"local_id", "remote_record_id", "remote_modified_at", "local_updated_at"})
+	for _, m := range maps {
+		var at interface{}
+		if !m.LocalUpdatedAt.IsZero() {
+			at = m.LocalUpdatedAt
+		}
+		rows.AddRow(m.LocalID.String(), m.RemoteRecordID, m.RemoteModifiedAt, at)
+	}
+	mock.ExpectQuery(mappingsQuery).WillReturnRows(rows)
+}
+
+func expectSaveMappings(mock sqlmock.Sqlmock, n int) {
+	for i := 0; i < n; i++ {
+		mock.ExpectExec(saveMapExec).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+func expectConflict(mock sqlmock.Sqlmock, localID uuid.UUID, remoteID, reason, resolution string) {
+	mock.ExpectExec(conflictExec).
+		WithArgs(KindRecord, testTable, localID, remoteID, reason, resolution, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectSaveCursor(mock sqlmock.Sqlmock, cursor string, synced int) {
+	mock.ExpectExec(saveCursor).WithArgs(KindRecord, testTable, cursor, synced).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestSyncerRunCreatesAndUpdates(t *testing.T) {
+	fake := newFakeBitable()
+	s, mock := newTestSyncer(t, fake, Config{})
+
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond)
+	fresh := newLocalRecord("甲公司", "首次拜访", base)
+	changed := newLocalRecord("乙公司", "报价已发送", base.Add(time.Second))
+	remoteID := fake.seed(map[string]interface{}{"记录ID": changed.ID.String(), "客户名称": "乙公司", "跟进内容": "初次沟通"}, 500)
+
+	expectState(mock, "")
+	expectRecords(mock, fresh, changed)
+	expectMappings(mock, mappingRow{LocalID: changed.ID, RemoteRecordID: remoteID, RemoteModifiedAt: 500, LocalUpdatedAt: base.Add(-time.Hour)})
+	expectSaveMappings(mock, 2)
+	expectSaveCursor(mock, changed.cursor(), 2)
+
+	res, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Records != 2 {
+		t.Errorf("synced records = %d, want 2", res.Records)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if got, _ := fake.get(remoteID); TextValue(got.Fields["跟进内容"]) != "报价已发送" {
+		t.Errorf("updated record content = %v, want 报价已发送", got.Fields["跟进内容"])
+	}
+	created, ok := fake.get("rec2")
+	if !ok || TextValue(created.Fields["记录ID"]) != fresh.ID.String() {
+		t.Errorf("created record = %+v, want 记录ID %s", created, fresh.ID)
+	}
+	if n := fake.count("search"); n != 1 {
+		t.Errorf("search calls = %d, want 1 (only the unmapped record)", n)
+	}
+}
+
+func TestSyncerRunConflicts(t *testing.T) {
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond)
+
+	t.Run("remote deleted is recreated, remote modified is overwritten", func(t *testing.T) {
+		fake := newFakeBitable()
+		s, mock := newTestSyncer(t, fake, Config{})
+
+		deleted := newLocalRecord("甲公司", "本地内容", base)
+		modified := newLocalRecord("乙公司", "本地修改", base.Add(time.Second))
+		modifiedID := fake.seed(map[string]interface{}{"记录ID": modified.ID.String(), "跟进内容": "手工修改"}, 800)
+
+		expectState(mock, "")
+		expectRecords(mock, deleted, modified)
+		expectMappings(mock,
+			mappingRow{LocalID: deleted.ID, RemoteRecordID: "rec_gone", RemoteModifiedAt: 500},
+			mappingRow{LocalID: modified.ID, RemoteRecordID: modifiedID, RemoteModifiedAt: 700},
+		)
+		expectConflict(mock, deleted.ID, "rec_gone", ReasonRemoteDeleted, ResolutionRecreated)
+		expectConflict(mock, modified.ID, modifiedID, ReasonRemoteModified, ResolutionOverwritten)
+		expectSaveMappings(mock, 2)
+		expectSaveCursor(mock, modified.cursor(), 2)
+
+		if _, err := s.Run(context.Background()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		if got, _ := fake.get(modifiedID); TextValue(got.Fields["跟进内容"]) != "本地修改" {
+			t.Errorf("overwritten content = %v, want 本地修改", got.Fields["跟进内容"])
+		}
+		if fake.count("batch_create") != 1 {
+			t.Errorf("batch_create calls = %d, want 1 (recreate the deleted record)", fake.count("batch_create"))
+		}
+	})
+
+	t.Run("remote modified is kept with skip policy", func(t *testing.T) {
+		fake := newFakeBitable()
+		s, mock := newTestSyncer(t, fake, Config{Conflict: ConflictSkip})
+
+		modified := newLocalRecord("乙公司", "本地修改", base)
+		modifiedID := fake.seed(map[string]interface{}{"记录ID": modified.ID.String(), "跟进内容": "手工修改"}, 800)
+
+		expectState(mock, "")
+		expectRecords(mock, modified)
+		expectMappings(mock, mappingRow{LocalID: modified.ID, RemoteRecordID: modifiedID, RemoteModifiedAt: 700})
+		expectConflict(mock, modified.ID, modifiedID, ReasonRemoteModified, ResolutionSkipped)
+		mock.ExpectExec(saveMapExec).
+			WithArgs(KindRecord, testTable, modified.ID, modifiedID, int64(700), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectSaveCursor(mock, modified.cursor(), 1)
+
+		if _, err := s.Run(context.Background()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		if got, _ := fake.get(modifiedID); TextValue(got.Fields["跟进内容"]) != "手工修改" {
+			t.Errorf("skipped record content = %v, want 手工修改", got.Fields["跟进内容"])
+		}
+		if fake.count("batch_update") != 0 {
+			t.Errorf("batch_update calls = %d, want 0", fake.count("batch_update"))
+		}
+	})
+}
+
+// timeArg 按时间点匹配 SQL 参数
+type timeArg time.Time
+
+func (a timeArg) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && t.Equal(time.Time(a))
+}
+
+func TestSyncerRunCursorAfterPartialFailure(t *testing.T) {
+	fake := newFakeBitable()
+	s, mock := newTestSyncer(t, fake, Config{BatchSize: 2, Overlap: time.Minute})
+
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond)
+	a := newLocalRecord("甲公司", "a", base)
+	b := newLocalRecord("乙公司", "b", base.Add(time.Second))
+	c := newLocalRecord("丙公司", "c", base.Add(2*time.Second))
+
+	// 第一次运行：第一页成功并推进游标，第二页写入失败，游标停在第一页末尾
+	expectState(mock, "")
+	expectRecords(mock, a, b)
+	expectMappings(mock)
+	expectSaveMappings(mock, 2)
+	expectSaveCursor(mock, b.cursor(), 2)
+	expectRecords(mock, c)
+	expectMappings(mock)
+	mock.ExpectExec(saveRunExec).WithArgs(KindRecord, testTable, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// 第二页的 batch_create 失败
+	creates := 0
+	s.api = &failNthCreate{API: s.api, fail: 2, calls: &creates}
+
+	res, err := s.Run(context.Background())
+	if err == nil {
+		t.Fatal("Run: want error from second page")
+	}
+	if res.Records != 2 {
+		t.Errorf("synced before failure = %d, want 2", res.Records)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第二次运行：从保存的游标回退 Overlap 重扫，已同步的 b 跳过，c 补同步，游标推进到 c
+	expectState(mock, b.cursor())
+	expectRecords(mock, b, c).WithArgs(sqlmock.AnyArg(), timeArg(b.UpdatedAt.Add(-time.Minute)), uuid.Nil, 3)
+	expectMappings(mock, mappingRow{LocalID: b.ID, RemoteRecordID: "rec2", RemoteModifiedAt: 1002, LocalUpdatedAt: b.UpdatedAt})
+	expectSaveMappings(mock, 1)
+	expectSaveCursor(mock, c.cursor(), 1)
+	expectRecords(mock) // 上一页已满，再取一页为空
+	expectSaveCursor(mock, c.cursor(), 0)
+
+	res, err = s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if res.Records != 1 {
+		t.Errorf("synced on retry = %d, want 1", res.Records)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if fake.count("batch_update") != 0 {
+		t.Errorf("batch_update calls = %d, want 0 (already synced record must be skipped)", fake.count("batch_update"))
+	}
+	if n := len(fake.records); n != 3 {
+		t.Errorf("remote records = %d, want 3", n)
+	}
+}
+
+// failNthCreate 第 fail 次 BatchCreate 返回错误，其余转发
+type failNthCreate struct {
+	API
+	fail  int
+	calls *int
+}
+
+func (f *failNthCreate) BatchCreate(ctx context.Context, tableID string, records []Record) ([]Record, error) {
+	*f.calls++
+	if *f.calls == f.fail {
+		return nil, &APIError{Code: 1254291, Msg: "write conflict"}
+	}
+	return f.API.BatchCreate(ctx, tableID, records)
+}
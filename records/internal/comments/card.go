@@ -0,0 +1,61 @@
+package comments
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// commentCard 构建评论通知卡片；用户直接回复该消息即回复话题
+func commentCard(record *RecordInfo, c *Comment, reason string) map[string]interface{} {
+	verb := "评论了"
+	if c.ParentID != nil {
+		verb = "回复了"
+	}
+	var title string
+	switch reason {
+	case ReasonMention:
+		title = fmt.Sprintf("%s 在评论中 @ 了你", c.AuthorName)
+	case ReasonOwner:
+		title = fmt.Sprintf("%s %s你的跟进记录", c.AuthorName, verb)
+	default:
+		title = fmt.Sprintf("%s %s你参与的评论", c.AuthorName, verb)
+	}
+
+	summary := fmt.Sprintf("**客户**：%s\n**跟进时间**：%s\n**销售**：%s",
+		record.CustomerName, record.FollowTime.In(time.Local).Format("2006-01-02 15:04"), record.UserName)
+	if record.FollowContent != nil && *record.FollowContent != "" {
+		summary += "\n**跟进内容**：" + truncate(*record.FollowContent, 120)
+	}
+
+	return map[string]interface{}{
+		"config": map[string]interface{}{"wide_screen_mode": true},
+		"header": map[string]interface{}{
+			"template": "orange",
+			"title":    map[string]interface{}{"tag": "plain_text", "content": title},
+		},
+		"elements": []interface{}{
+			map[string]interface{}{
+				"tag":  "div",
+				"text": map[string]interface{}{"tag": "lark_md", "content": summary},
+			},
+			map[string]interface{}{"tag": "hr"},
+			map[string]interface{}{
+				"tag":  "div",
+				"text": map[string]interface{}{"tag": "plain_text", "content": c.Content},
+			},
+			map[string]interface{}{
+				"tag":      "note",
+				"elements": []interface{}{map[string]interface{}{"tag": "plain_text", "content": "直接回复本消息即可回复该评论"}},
+			},
+		},
+	}
+}
+
+func truncate(s string, n int) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) <= n {
+		return string(r)
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,196 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// commentColumns 评论查询列（c 为 record_comments，u 为作者）
+const commentColumns = `c.id, c.follow_record_id, c.parent_id, c.author_id, COALESCE(u.name, c.author_id) AS author_name,
+	c.content, c.mentions, c.source, c.created_at`
+
+// Repo 评论数据访问
+type Repo struct {
+	db *sqlx.DB
+}
+
+// NewRepo 创建 Repo
+func NewRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+// GetRecordInfo 返回未删除记录的概要，不存在返回 nil
+func (r *Repo) GetRecordInfo(ctx context.Context, id uuid.UUID) (*RecordInfo, error) {
+	var info RecordInfo
+	query := `SELECT fr.id, fr.user_id, COALESCE(u.name, fr.user_id) AS user_name, fr.customer_name, fr.follow_time, fr.follow_content
+		FROM follow_records fr LEFT JOIN users u ON u.id = fr.user_id
+		WHERE fr.id = $1 AND fr.deleted_at IS NULL`
+	if err := r.db.GetContext(ctx, &info, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get record info id=%s: %w", id, err)
+	}
+	return &info, nil
+}
+
+// GetComment 按 ID 查询评论，不存在返回 nil
+func (r *Repo) GetComment(ctx context.Context, id uuid.UUID) (*Comment, error) {
+	var c Comment
+	query := `SELECT ` + commentColumns + ` FROM record_comments c LEFT JOIN users u ON u.id = c.author_id WHERE c.id = $1`
+	if err := r.db.GetContext(ctx, &c, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get comment id=%s: %w", id, err)
+	}
+	return &c, nil
+}
+
+// ThreadParticipants 返回话题中发表过评论或被 @ 过的用户
+func (r *Repo) ThreadParticipants(ctx context.Context, rootID uuid.UUID) ([]string, error) {
+	var ids []string
+	query := `SELECT DISTINCT p FROM record_comments c, unnest(array_append(c.mentions, c.author_id)) p
+		WHERE c.id = $1 OR c.parent_id = $1`
+	if err := r.db.SelectContext(ctx, &ids, query, rootID); err != nil {
+		return nil, fmt.Errorf("list thread participants root=%s: %w", rootID, err)
+	}
+	return ids, nil
+}
+
+// Create 写入评论及其接收人；作者本人在该记录上的未读一并标记已读（回复即视为已读）
+func (r *Repo) Create(ctx context.Context, c *Comment, recipients []Recipient) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin create comment: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO record_comments (id, follow_record_id, parent_id, author_id, content, mentions, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`
+	if err := tx.GetContext(ctx, &c.CreatedAt, query, c.ID, c.FollowRecordID, c.ParentID, c.AuthorID, c.Content, pq.Array([]string(c.Mentions)), c.Source); err != nil {
+		return fmt.Errorf("create comment record=%s: %w", c.FollowRecordID, err)
+	}
+	for _, rc := range recipients {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO record_comment_recipients (comment_id, user_id, reason) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+			c.ID, rc.UserID, rc.Reason); err != nil {
+			return fmt.Errorf("create comment recipient user=%s: %w", rc.UserID, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, markReadQuery, c.AuthorID, c.FollowRecordID); err != nil {
+		return fmt.Errorf("mark comments read user=%s: %w", c.AuthorID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit create comment: %w", err)
+	}
+	return nil
+}
+
+// ListThreads 返回记录上的全部话题（按首条时间正序），Unread 为对 viewerID 未读
+func (r *Repo) ListThreads(ctx context.Context, recordID uuid.UUID, viewerID string) ([]*Thread, error) {
+	var list []*Comment
+	query := `SELECT ` + commentColumns + `,
+		EXISTS (SELECT 1 FROM record_comment_recipients rc WHERE rc.comment_id = c.id AND rc.user_id = $2 AND rc.read_at IS NULL) AS unread
+		FROM record_comments c LEFT JOIN users u ON u.id = c.author_id
+		WHERE c.follow_record_id = $1 ORDER BY c.created_at, c.id`
+	if err := r.db.SelectContext(ctx, &list, query, recordID, viewerID); err != nil {
+		return nil, fmt.Errorf("list comments record=%s: %w", recordID, err)
+	}
+	threads := []*Thread{}
+	byID := make(map[uuid.UUID]*Thread)
+	for _, c := range list {
+		if c.ParentID == nil {
+			t := &Thread{Comment: c, Replies: []*Comment{}}
+			byID[c.ID] = t
+			threads = append(threads, t)
+			continue
+		}
+		if t := byID[*c.ParentID]; t != nil {
+			t.Replies = append(t.Replies, c)
+		}
+	}
+	return threads, nil
+}
+
+// markReadQuery 将用户在某记录上的评论全部标记已读（$1 用户，$2 记录）
+const markReadQuery = `UPDATE record_comment_recipients rc SET read_at = NOW()
+	FROM record_comments c WHERE c.id = rc.comment_id AND rc.user_id = $1 AND c.follow_record_id = $2 AND rc.read_at IS NULL`
+
+// MarkRead 将用户在某记录上的评论全部标记已读，返回标记数
+func (r *Repo) MarkRead(ctx context.Context, userID string, recordID uuid.UUID) (int64, error) {
+	res, err := r.db.ExecContext(ctx, markReadQuery, userID, recordID)
+	if err != nil {
+		return 0, fmt.Errorf("mark comments read user=%s record=%s: %w", userID, recordID, err)
+	}
+	return res.RowsAffected()
+}
+
+// SetNotified 记录发给接收人的机器人通知消息 ID
+func (r *Repo) SetNotified(ctx context.Context, commentID uuid.UUID, userID, messageID string) error {
+	query := `UPDATE record_comment_recipients SET message_id = NULLIF($3, ''), notified_at = NOW() WHERE comment_id = $1 AND user_id = $2`
+	if _, err := r.db.ExecContext(ctx, query, commentID, userID, messageID); err != nil {
+		return fmt.Errorf("set comment notified comment=%s user=%s: %w", commentID, userID, err)
+	}
+	return nil
+}
+
+// FindByMessage 按发给 userID 的通知消息 ID 查找评论，未找到返回 nil
+func (r *Repo) FindByMessage(ctx context.Context, userID string, messageIDs []string) (*Comment, error) {
+	var c Comment
+	query := `SELECT ` + commentColumns + ` FROM record_comment_recipients rc
+		JOIN record_comments c ON c.id = rc.comment_id LEFT JOIN users u ON u.id = c.author_id
+		WHERE rc.user_id = $1 AND rc.message_id = ANY($2) LIMIT 1`
+	if err := r.db.GetContext(ctx, &c, query, userID, pq.Array(messageIDs)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("find comment by message user=%s: %w", userID, err)
+	}
+	return &c, nil
+}
+
+// UnreadCounts 返回用户在指定记录上的未读评论数，仅含有未读的记录
+func (r *Repo) UnreadCounts(ctx context.Context, userID string, recordIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	counts := make(map[uuid.UUID]int)
+	if len(recordIDs) == 0 {
+		return counts, nil
+	}
+	ids := make([]string, len(recordIDs))
+	for i, id := range recordIDs {
+		ids[i] = id.String()
+	}
+	var rows []struct {
+		RecordID uuid.UUID `db:"record_id"`
+		Unread   int       `db:"unread"`
+	}
+	query := `SELECT c.follow_record_id AS record_id, COUNT(*) AS unread
+		FROM record_comment_recipients rc JOIN record_comments c ON c.id = rc.comment_id
+		WHERE rc.user_id = $1 AND rc.read_at IS NULL AND c.follow_record_id = ANY($2::uuid[])
+		GROUP BY c.follow_record_id`
+	if err := r.db.SelectContext(ctx, &rows, query, userID, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("count unread comments user=%s: %w", userID, err)
+	}
+	for _, row := range rows {
+		counts[row.RecordID] = row.Unread
+	}
+	return counts, nil
+}
+
+// ListUnread 按记录汇总用户的未读评论，最新评论在前；不含已删除记录
+func (r *Repo) ListUnread(ctx context.Context, userID string) ([]*UnreadRecord, error) {
+	list := []*UnreadRecord{}
+	query := `SELECT fr.id AS record_id, fr.user_id AS owner_id, fr.customer_name, COUNT(*) AS unread, MAX(c.created_at) AS latest_at
+		FROM record_comment_recipients rc JOIN record_comments c ON c.id = rc.comment_id
+		JOIN follow_records fr ON fr.id = c.follow_record_id AND fr.deleted_at IS NULL
+		WHERE rc.user_id = $1 AND rc.read_at IS NULL
+		GROUP BY fr.id, fr.user_id, fr.customer_name ORDER BY latest_at DESC`
+	if err := r.db.SelectContext(ctx, &list, query, userID); err != nil {
+		return nil, fmt.Errorf("list unread comments user=%s: %w", userID, err)
+	}
+	return list, nil
+}
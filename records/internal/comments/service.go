@@ -0,0 +1,201 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"records/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// maxContentLength 单条评论最大字数
+	maxContentLength = 2000
+	// maxMentions 单条评论最多 @ 人数
+	maxMentions = 20
+)
+
+var (
+	ErrEmptyContent   = errors.New("评论内容不能为空")
+	ErrContentTooLong = errors.New("评论内容过长")
+	ErrTooManyMention = errors.New("@ 的人数过多")
+	ErrRecordNotFound = errors.New("记录不存在")
+	ErrParentNotFound = errors.New("回复的评论不存在")
+	ErrForbidden      = errors.New("无权限评论此记录")
+	ErrMentionDenied  = errors.New("被 @ 的用户无权查看此记录")
+)
+
+// Sender 卡片发送方（feishu.Client 实现），需返回消息 ID 以便用户回复通知时定位话题
+type Sender interface {
+	SendCardToUserWithID(ctx context.Context, userID string, card map[string]interface{}) (string, error)
+}
+
+// AccessFunc 判断 userID 能否查看 ownerID 的记录（本人或 view 权限范围内）
+type AccessFunc func(ctx context.Context, userID, ownerID string) (bool, error)
+
+// Service 跟进记录评论：发表、话题、未读与机器人通知
+type Service struct {
+	repo    *Repo
+	sender  Sender
+	canView AccessFunc
+	log     logger.Logger
+}
+
+// NewService 创建评论服务
+func NewService(db *sqlx.DB, sender Sender, canView AccessFunc, log logger.Logger) *Service {
+	return &Service{repo: NewRepo(db), sender: sender, canView: canView, log: log}
+}
+
+// Repo 返回数据访问
+func (s *Service) Repo() *Repo {
+	return s.repo
+}
+
+// Post 发表评论：校验作者与被 @ 者均可查看该记录，写入后异步通知接收人（记录所属销售、被 @ 者与话题参与者，不含作者）
+func (s *Service) Post(ctx context.Context, in *NewComment) (*Comment, error) {
+	content := strings.TrimSpace(in.Content)
+	if content == "" {
+		return nil, ErrEmptyContent
+	}
+	if utf8.RuneCountInString(content) > maxContentLength {
+		return nil, ErrContentTooLong
+	}
+	record, err := s.repo.GetRecordInfo(ctx, in.RecordID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrRecordNotFound
+	}
+	if ok, err := s.canView(ctx, in.AuthorID, record.UserID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrForbidden
+	}
+
+	c := &Comment{
+		ID:             uuid.New(),
+		FollowRecordID: record.ID,
+		AuthorID:       in.AuthorID,
+		Content:        content,
+		Mentions:       []string{},
+		Source:         in.Source,
+	}
+	var participants []string
+	if in.ParentID != nil {
+		parent, err := s.repo.GetComment(ctx, *in.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil || parent.FollowRecordID != record.ID {
+			return nil, ErrParentNotFound
+		}
+		root := parent.ID
+		if parent.ParentID != nil {
+			root = *parent.ParentID
+		}
+		c.ParentID = &root
+		if participants, err = s.repo.ThreadParticipants(ctx, root); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range in.Mentions {
+		if m = strings.TrimSpace(m); m != "" && m != in.AuthorID && !contains(c.Mentions, m) {
+			c.Mentions = append(c.Mentions, m)
+		}
+	}
+	if len(c.Mentions) > maxMentions {
+		return nil, ErrTooManyMention
+	}
+	for _, m := range c.Mentions {
+		ok, err := s.canView(ctx, m, record.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrMentionDenied
+		}
+	}
+
+	// 接收人：记录所属销售 > 被 @ > 话题参与者；话题参与者权限可能已变更，需再次校验
+	var recipients []Recipient
+	seen := map[string]bool{in.AuthorID: true}
+	add := func(userID, reason string) {
+		if !seen[userID] {
+			seen[userID] = true
+			recipients = append(recipients, Recipient{UserID: userID, Reason: reason})
+		}
+	}
+	add(record.UserID, ReasonOwner)
+	for _, m := range c.Mentions {
+		add(m, ReasonMention)
+	}
+	for _, p := range participants {
+		if seen[p] {
+			continue
+		}
+		if ok, err := s.canView(ctx, p, record.UserID); err != nil {
+			return nil, err
+		} else if ok {
+			add(p, ReasonThread)
+		}
+	}
+
+	if err := s.repo.Create(ctx, c, recipients); err != nil {
+		return nil, err
+	}
+	if saved, err := s.repo.GetComment(ctx, c.ID); err == nil && saved != nil {
+		c = saved
+	}
+	go s.notify(record, c, recipients)
+	return c, nil
+}
+
+// ReplyByMessage 用户在飞书中回复评论通知消息：按被回复的消息定位话题并以机器人来源发表回复；
+// 消息不是评论通知时返回 nil, nil
+func (s *Service) ReplyByMessage(ctx context.Context, userID string, messageIDs []string, content string) (*Comment, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+	target, err := s.repo.FindByMessage(ctx, userID, messageIDs)
+	if err != nil || target == nil {
+		return nil, err
+	}
+	return s.Post(ctx, &NewComment{
+		RecordID: target.FollowRecordID,
+		ParentID: &target.ID,
+		AuthorID: userID,
+		Content:  content,
+		Source:   SourceBot,
+	})
+}
+
+// notify 向接收人逐一发送通知卡片并记录消息 ID；失败仅记日志，不影响评论本身（页面仍显示未读）
+func (s *Service) notify(record *RecordInfo, c *Comment, recipients []Recipient) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, rc := range recipients {
+		messageID, err := s.sender.SendCardToUserWithID(ctx, rc.UserID, commentCard(record, c, rc.Reason))
+		if err != nil {
+			s.log.Error("Failed to send comment notification", "comment_id", c.ID, "user_id", rc.UserID, "error", err)
+			continue
+		}
+		if err := s.repo.SetNotified(ctx, c.ID, rc.UserID, messageID); err != nil {
+			s.log.Error("Failed to record comment notification", "comment_id", c.ID, "user_id", rc.UserID, "error", err)
+		}
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package comments
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// 评论来源
+const (
+	SourcePage = "page" // 网页
+	SourceBot  = "bot"  // 回复机器人通知消息
+)
+
+// 接收原因
+const (
+	ReasonOwner   = "owner"   // 记录所属销售
+	ReasonMention = "mention" // 被 @
+	ReasonThread  = "thread"  // 话题中发表过评论或被 @ 过
+)
+
+// Comment record_comments 表一行
+type Comment struct {
+	ID             uuid.UUID      `db:"id" json:"id"`
+	FollowRecordID uuid.UUID      `db:"follow_record_id" json:"follow_record_id"`
+	ParentID       *uuid.UUID     `db:"parent_id" json:"parent_id,omitempty"`
+	AuthorID       string         `db:"author_id" json:"author_id"`
+	AuthorName     string         `db:"author_name" json:"author_name"`
+	Content        string         `db:"content" json:"content"`
+	Mentions       pq.StringArray `db:"mentions" json:"mentions"`
+	Source         string         `db:"source" json:"source"`
+	CreatedAt      time.Time      `db:"created_at" json:"created_at"`
+	Unread         bool           `db:"unread" json:"unread"` // 对当前查看者未读
+}
+
+// Thread 话题：首条评论及其回复（按时间正序）
+type Thread struct {
+	*Comment
+	Replies []*Comment `json:"replies"`
+}
+
+// NewComment 发表评论的输入；ParentID 为话题内任一评论，回复统一挂到话题首条
+type NewComment struct {
+	RecordID uuid.UUID
+	ParentID *uuid.UUID
+	AuthorID string
+	Content  string
+	Mentions []string
+	Source   string
+}
+
+// Recipient 评论接收人
+type Recipient struct {
+	UserID string
+	Reason string
+}
+
+// RecordInfo 评论所属记录的概要（通知卡片与权限判断用）
+type RecordInfo struct {
+	ID            uuid.UUID `db:"id"`
+	UserID        string    `db:"user_id"`
+	UserName      string    `db:"user_name"`
+	CustomerName  string    `db:"customer_name"`
+	FollowTime    time.Time `db:"follow_time"`
+	FollowContent *string   `db:"follow_content"`
+}
+
+// UnreadRecord 某条记录上的未读评论数
+type UnreadRecord struct {
+	RecordID     uuid.UUID `db:"record_id" json:"record_id"`
+	OwnerID      string    `db:"owner_id" json:"owner_id"`
+	CustomerName string    `db:"customer_name" json:"customer_name"`
+	Unread       int       `db:"unread" json:"unread"`
+	LatestAt     time.Time `db:"latest_at" json:"latest_at"`
+}
@@ -9,14 +9,26 @@ import (
 
 // Config 系统配置结构
 type Config struct {
-	Database Database `yaml:"database"`
-	Feishu   Feishu   `yaml:"feishu"`
-	AI       AI       `yaml:"ai"`
-	Server   Server   `yaml:"server"`
-	Logging  Logging  `yaml:"logging"`
-	System   System   `yaml:"system"`
-	Prompts  Prompts  `yaml:"prompts"`
-	Messages Messages `yaml:"messages"`
+	Database        Database        `yaml:"database"`
+	Feishu          Feishu          `yaml:"feishu"`
+	AI              AI              `yaml:"ai"`
+	Server          Server          `yaml:"server"`
+	Logging         Logging         `yaml:"logging"`
+	Tracing         Tracing         `yaml:"tracing"`
+	Redaction       Redaction       `yaml:"redaction"`
+	PhoneEncryption PhoneEncryption `yaml:"phone_encryption"`
+	System          System          `yaml:"system"`
+	Scheduler       Scheduler       `yaml:"scheduler"`
+	FollowTask      FollowTask      `yaml:"follow_task"`
+	Digest          Digest          `yaml:"digest"`
+	Stale           Stale           `yaml:"stale_customer"`
+	Directory       Directory       `yaml:"directory"`
+	Share           Share           `yaml:"share"`
+	Webhook         Webhook         `yaml:"webhook"`
+	Bitable         Bitable         `yaml:"bitable"`
+	CRM             CRM             `yaml:"crm"`
+	Prompts         Prompts         `yaml:"prompts"`
+	Messages        Messages        `yaml:"messages"`
 }
 
 // Feishu 飞书配置
@@ -88,8 +100,13 @@ type Server struct {
 	StaticDir            string        `yaml:"static_dir"`               // 静态页面目录，相对于工作目录
 	APIPrefix            string        `yaml:"api_prefix"`               // API 接口路径前缀，如 /api
 	WebPrefix            string        `yaml:"web_prefix"`               // Web 页面路径前缀，如 / 或 /page
-	JWTSecret            string        `yaml:"jwt_secret"`               // JWT 签名密钥，用于 page API 会话；空则回退到 x-user-id（不安全）
-	AllowXUserIDFallback bool          `yaml:"allow_x_user_id_fallback"` // JWT 缺失或失效时，是否允许使用 x-user-id 回退（开发/调试用）
+	JWTSecret            string        `yaml:"jwt_secret"`               // JWT 签名密钥，用于 page API 会话；空则仅 dev 构建或回环监听可用 x-user-id
+	AccessTokenTTL       time.Duration `yaml:"access_token_ttl"`         // 访问令牌有效期，默认 15m
+	RefreshTokenTTL      time.Duration `yaml:"refresh_token_ttl"`        // 刷新令牌（登录会话）有效期，默认 720h，每次刷新轮换
+	AllowXUserIDFallback bool          `yaml:"allow_x_user_id_fallback"` // JWT 缺失或失效时允许 x-user-id 回退；仅 dev 构建（-tags dev）生效
+	LoopbackListen       string        `yaml:"loopback_listen"`          // 可选回环监听地址（如 127.0.0.1:8001），经此监听的请求可用 x-user-id，供本机调试/运维
+	MetricsToken         string        `yaml:"metrics_token"`            // /metrics 抓取令牌（Authorization: Bearer），空则不校验
+	TrustedProxies       []string      `yaml:"trusted_proxies"`          // 可信反向代理的 IP 或 CIDR，仅来自这些地址的请求采信 X-Forwarded-For
 }
 
 // Logging 日志配置
@@ -104,12 +121,150 @@ type Logging struct {
 	MaxAge     int    `yaml:"max_age"`
 }
 
+// Tracing 链路追踪（OpenTelemetry）配置；未启用时仍生成 trace_id 写入日志，但不采样、不导出
+type Tracing struct {
+	Enabled     bool              `yaml:"enabled"`
+	Exporter    string            `yaml:"exporter"`     // otlp（默认，OTLP/HTTP）、stdout 或 file
+	Endpoint    string            `yaml:"endpoint"`     // otlp：采集端地址 host:port，默认 localhost:4318
+	URLPath     string            `yaml:"url_path"`     // otlp：默认 /v1/traces
+	Insecure    bool              `yaml:"insecure"`     // otlp：使用 http 而非 https
+	Headers     map[string]string `yaml:"headers"`      // otlp：附加请求头，如鉴权
+	FilePath    string            `yaml:"file_path"`    // file：span 以 JSON 追加写入的文件，默认 logs/traces.json
+	ServiceName string            `yaml:"service_name"` // 默认 records
+	SampleRatio float64           `yaml:"sample_ratio"` // 采样比例 (0,1]，默认 1；上游已采样的链路始终采样
+}
+
+// Redaction 敏感信息脱敏配置：发送大模型前替换为可还原的占位符，写日志前替换为类型标记
+type Redaction struct {
+	Enabled bool            `yaml:"enabled"`
+	Builtin []string        `yaml:"builtin"` // 启用的内置规则：mobile、landline、id_card、email，空则全部启用
+	Rules   []RedactionRule `yaml:"rules"`   // 自定义规则，在内置规则之后匹配
+}
+
+// RedactionRule 自定义脱敏规则
+type RedactionRule struct {
+	Name    string `yaml:"name"`    // 规则名（字母、数字、下划线），大写后作为占位符类型，如 bank_card -> [BANK_CARD_1]
+	Pattern string `yaml:"pattern"` // 正则表达式（RE2 语法）
+}
+
+// PhoneEncryption 联系电话加密存储配置：信封加密，主密钥加密每个值的随机数据密钥；检索使用盲索引
+type PhoneEncryption struct {
+	Enabled   bool              `yaml:"enabled"`
+	ActiveKey string            `yaml:"active_key"` // 加密新值使用的主密钥 ID；轮换时新增密钥并改为新 ID，旧密钥保留至 phone_encrypt 任务重新加密完成
+	Keys      map[string]string `yaml:"keys"`       // 主密钥 ID -> base64 编码的 32 字节密钥，或 env:变量名 从环境变量读取
+	IndexKey  string            `yaml:"index_key"`  // 盲索引（HMAC）密钥，格式同上；不随主密钥轮换，更换后已有记录无法按号码检索
+}
+
 // System 系统配置
 type System struct {
 	SessionTimeout        int           `yaml:"session_timeout"`
 	MaxConcurrentSessions int           `yaml:"max_concurrent_sessions"`
 	MaxRetries            int           `yaml:"max_retries"`
 	RetryInterval         time.Duration `yaml:"retry_interval"`
+	// ProcessedEventRetention 飞书事件去重记录（processed_events）保留时长，需覆盖飞书约 7.1 小时的重推窗口；默认 8h
+	ProcessedEventRetention time.Duration `yaml:"processed_event_retention"`
+}
+
+// Scheduler 定时任务配置；各实例通过数据库锁选举主节点，仅主节点按 cron 触发
+type Scheduler struct {
+	Jobs map[string]SchedulerJob `yaml:"jobs"` // key 为任务名，未配置的任务使用代码中的默认计划
+}
+
+// SchedulerJob 单个定时任务配置
+type SchedulerJob struct {
+	Cron    string `yaml:"cron"`    // 5 段 cron 表达式：分 时 日 月 周，空则使用默认计划
+	Enabled *bool  `yaml:"enabled"` // false 则停用定时触发（仍可通过管理 API 手动触发），未设置视为启用
+}
+
+// FollowTask 跟进待办提醒配置
+type FollowTask struct {
+	RemindTime string        `yaml:"remind_time"` // 计划仅含日期时的提醒时刻（HH:MM），默认 09:00
+	Snooze     time.Duration `yaml:"snooze"`      // “稍后提醒”延后时长，默认 24h
+	MaxOverdue time.Duration `yaml:"max_overdue"` // 超过到期时间多久后不再提醒（避免停机后补发大量过期提醒），仍未提醒的待办标记为已过期，默认 72h
+}
+
+// Digest 跟进摘要推送配置；用户在设置页自行订阅（日报/周报），推送由定时任务 digest 触发
+type Digest struct {
+	QuietDays     int `yaml:"quiet_days"`     // 超过多少天未跟进的客户列入个人摘要，默认 14
+	WeeklyWeekday int `yaml:"weekly_weekday"` // 周报推送日：1-7 表示周一至周日，默认 5（周五）
+}
+
+// Stale 久未跟进客户检测配置；按（客户, 销售）计算最近跟进时间，超过客户分级对应天数即提醒销售
+type Stale struct {
+	DefaultDays  int            `yaml:"default_days"`  // 未分级客户的判定天数，默认 30
+	TierDays     map[string]int `yaml:"tier_days"`     // 客户分级（customers.tier）对应的判定天数
+	LookbackDays int            `yaml:"lookback_days"` // 仅统计该天数内跟进过的客户，默认 180
+	Renotify     time.Duration  `yaml:"renotify"`      // 仍未跟进时再次提醒的间隔，默认 168h
+}
+
+// Directory 飞书通讯录同步配置：部门树、部门负责人与成员归属，由定时任务 directory_sync 执行
+type Directory struct {
+	Enabled          bool   `yaml:"enabled"`            // 是否启用同步，需应用开通通讯录读取权限
+	RootDepartmentID string `yaml:"root_department_id"` // 同步的根部门 open_department_id，默认 "0"（全公司）
+	LeaderRole       string `yaml:"leader_role"`        // 自动授予部门负责人的角色（范围为其负责部门及下级），为空不授予
+}
+
+// Share 客户跟进时间线分享链接配置；令牌使用 server.jwt_secret 签名
+type Share struct {
+	DefaultTTL time.Duration `yaml:"default_ttl"` // 未指定有效期时的默认有效期，默认 72h
+	MaxTTL     time.Duration `yaml:"max_ttl"`     // 允许的最长有效期，默认 720h
+}
+
+// Webhook 出站 Webhook 投递配置
+type Webhook struct {
+	PollInterval time.Duration `yaml:"poll_interval"` // 轮询发件箱的间隔，默认 5s
+	Timeout      time.Duration `yaml:"timeout"`       // 单次投递超时，默认 10s
+	MaxAttempts  int           `yaml:"max_attempts"`  // 最多尝试次数（含首次），默认 8
+	BackoffBase  time.Duration `yaml:"backoff_base"`  // 首次重试等待，之后每次翻倍，默认 30s
+	BackoffMax   time.Duration `yaml:"backoff_max"`   // 重试等待上限，默认 6h
+	Retention    time.Duration `yaml:"retention"`     // 已投递事件与投递记录的保留时长，默认 720h
+}
+
+// Bitable 飞书多维表格同步配置
+type Bitable struct {
+	Enabled       bool          `yaml:"enabled"`
+	BaseURL       string        `yaml:"base_url"`       // 为空使用 https://open.feishu.cn
+	AppID         string        `yaml:"app_id"`         // 为空使用 feishu.sale_agent
+	AppSecret     string        `yaml:"app_secret"`     // 为空使用 feishu.sale_agent
+	AppToken      string        `yaml:"app_token"`      // 多维表格 app_token（链接中 /base/ 后的一段）
+	BatchSize     int           `yaml:"batch_size"`     // 每批同步的本地记录数，默认 100，最大 200
+	Conflict      string        `yaml:"conflict"`       // 多维表格中被手工修改时的处理：overwrite（默认）/ skip
+	DeleteRemoved bool          `yaml:"delete_removed"` // 本地删除的跟进记录同时删除多维表格记录
+	Overlap       time.Duration `yaml:"overlap"`        // 每次从游标回退该时长重新扫描，补上提交晚于游标推进的长事务变更，默认 10m
+	Customers     BitableTable  `yaml:"customers"`
+	FollowRecords BitableTable  `yaml:"follow_records"`
+}
+
+// BitableTable 同步到的数据表；fields 为本地字段 → 多维表格列名，必须包含 id
+type BitableTable struct {
+	TableID string            `yaml:"table_id"` // 为空则不同步
+	Fields  map[string]string `yaml:"fields"`
+}
+
+// CRM 公司 CRM 双向同步配置，由定时任务 crm_sync 执行
+type CRM struct {
+	Enabled      bool          `yaml:"enabled"`
+	Connector    string        `yaml:"connector"`     // 连接器类型，目前支持 rest（默认）
+	BatchSize    int           `yaml:"batch_size"`    // 每次拉取/推送的条数，默认 100
+	MatchTimeout time.Duration `yaml:"match_timeout"` // 对话中匹配客户时查询 CRM 的超时，超时按本地匹配，默认 3s
+	REST         CRMREST       `yaml:"rest"`
+}
+
+// CRMREST 通用 REST/JSON 连接器配置；字段名支持点号路径，未配置的项取默认值
+type CRMREST struct {
+	BaseURL        string            `yaml:"base_url"`
+	Headers        map[string]string `yaml:"headers"`          // 每个请求附带的请求头，如 Authorization
+	Timeout        time.Duration     `yaml:"timeout"`          // 默认 15s
+	SearchPath     string            `yaml:"search_path"`      // GET ?q=&limit=，默认 /accounts
+	AccountsPath   string            `yaml:"accounts_path"`    // POST 新建，PUT {path}/{id} 更新，默认 /accounts
+	ActivitiesPath string            `yaml:"activities_path"`  // POST 新建，PUT {path}/{id} 更新，默认 /activities
+	ChangesPath    string            `yaml:"changes_path"`     // GET ?cursor=&limit=，默认 /accounts/changes
+	ItemsPath      string            `yaml:"items_path"`       // 列表响应中数组的位置，默认 items
+	NextCursorPath string            `yaml:"next_cursor_path"` // 默认 next_cursor
+	HasMorePath    string            `yaml:"has_more_path"`    // 默认 has_more
+	IDPath         string            `yaml:"id_path"`          // 写入响应中 ID 的位置，默认 id
+	AccountFields  map[string]string `yaml:"account_fields"`   // 本地字段 → CRM 字段，默认同名（external_id 默认 id），映射为空不同步
+	ActivityFields map[string]string `yaml:"activity_fields"`  // 本地字段 → CRM 字段，默认同名
 }
 
 // Prompts 提示词配置
@@ -125,6 +280,8 @@ type Prompts struct {
 	CustomerSummary         string `yaml:"customer_summary"`
 	EntityNormalization     string `yaml:"entity_normalization"`
 	HotwordsExtractor       string `yaml:"hotwords_extractor"` // 热词抽取 LLM 的 system prompt
+	NextPlanTask            string `yaml:"next_plan_task"`     // 从下一步计划中抽取带日期待办的 system prompt；空则不生成待办
+	WeeklyReport            string `yaml:"weekly_report"`      // 团队周报中按客户总结进展/风险/下一步的 system prompt
 }
 
 type Messages struct {
@@ -137,6 +294,7 @@ type Messages struct {
 	OutputtingEnded       string `yaml:"outputting_ended"`       // OUTPUTTING 阶段用户继续发消息且非跟进信息时的友好提示
 	CollectingAbortConfirm string `yaml:"collecting_abort_confirm"` // 用户表达中断意图时发出的确认文案
 	CollectingAborted     string `yaml:"collecting_aborted"`        // 用户确认中断后发出的结束语
+	FollowTaskSessionBusy string `yaml:"follow_task_session_busy"` // 完成跟进待办时用户仍有未结束的记录会话
 	SystemError           string `yaml:"system_error"`
 	ProcessError          string `yaml:"process_error"`
 }
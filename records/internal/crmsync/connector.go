@@ -0,0 +1,55 @@
+package crmsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Account CRM 账户
+type Account struct {
+	ExternalID    string // CRM 账户 ID；UpsertAccount 时为空表示新建
+	Name          string
+	ContactPerson string
+	ContactPhone  string
+	ContactRole   string
+	Master        bool      // CRM 主数据账户（经审核的正式客户）
+	Deleted       bool      // PullChanges 中表示账户已在 CRM 删除或合并
+	UpdatedAt     time.Time // CRM 中的更新时间，可能为零值
+}
+
+// Activity 推送到 CRM 的跟进活动
+type Activity struct {
+	ExternalID    string // CRM 活动 ID；为空表示新建
+	AccountID     string // CRM 账户 ID
+	RecordID      uuid.UUID
+	UserID        string
+	UserName      string
+	FollowTime    time.Time
+	FollowMethod  string
+	FollowContent string
+	FollowGoal    string
+	FollowResult  string
+	RiskContent   string
+	NextPlan      string
+}
+
+// Changes 一页账户变更
+type Changes struct {
+	Accounts []*Account
+	Cursor   string // 下一次拉取的游标
+	HasMore  bool
+}
+
+// Connector CRM 连接器；不同 CRM 各自实现，REST 为可配置的通用 REST/JSON 实现
+type Connector interface {
+	// SearchAccounts 按名称查找账户（由 CRM 决定匹配方式），最多返回 limit 条
+	SearchAccounts(ctx context.Context, query string, limit int) ([]*Account, error)
+	// UpsertAccount 新建或更新账户，返回 CRM 账户 ID
+	UpsertAccount(ctx context.Context, a *Account) (string, error)
+	// PushActivity 新建或更新跟进活动，返回 CRM 活动 ID
+	PushActivity(ctx context.Context, a *Activity) (string, error)
+	// PullChanges 拉取游标之后变更的账户；cursor 为空表示从头拉取
+	PullChanges(ctx context.Context, cursor string, limit int) (*Changes, error)
+}
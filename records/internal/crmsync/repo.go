@@ -0,0 +1,248 @@
+package crmsync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"records/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Repo CRM 外部 ID 映射与同步状态数据访问；ctx 中有 repository.WithTx 开启的事务时加入该事务
+// （对话中匹配客户时与新建客户在同一事务内写入映射）
+type Repo struct {
+	store *repository.Repository
+}
+
+// NewRepo 创建 Repo
+func NewRepo(db *sqlx.DB) *Repo {
+	return &Repo{store: repository.New(db)}
+}
+
+func (r *Repo) get(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return sqlx.GetContext(ctx, r.store.Execer(ctx), dest, query, args...)
+}
+
+func (r *Repo) sel(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return sqlx.SelectContext(ctx, r.store.Execer(ctx), dest, query, args...)
+}
+
+func (r *Repo) exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := r.store.Execer(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// AccountLink crm_account_map 表一行
+type AccountLink struct {
+	CustomerID      uuid.UUID  `db:"customer_id" json:"customer_id"`
+	ExternalID      string     `db:"external_id" json:"external_id"`
+	Master          bool       `db:"master" json:"master"`
+	RemoteUpdatedAt *time.Time `db:"remote_updated_at" json:"remote_updated_at,omitempty"`
+	LocalSyncedAt   time.Time  `db:"local_synced_at" json:"local_synced_at"`
+	SyncedAt        time.Time  `db:"synced_at" json:"synced_at"`
+}
+
+const accountLinkColumns = `customer_id, external_id, master, remote_updated_at, local_synced_at, synced_at`
+
+// GetLinkByExternalID 按 CRM 账户 ID 查映射，不存在返回 nil
+func (r *Repo) GetLinkByExternalID(ctx context.Context, externalID string) (*AccountLink, error) {
+	var l AccountLink
+	err := r.get(ctx, &l, `SELECT `+accountLinkColumns+` FROM crm_account_map WHERE external_id = $1`, externalID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get crm link external_id=%s: %w", externalID, err)
+	}
+	return &l, nil
+}
+
+// GetLink 按客户 ID 查映射，不存在返回 nil
+func (r *Repo) GetLink(ctx context.Context, customerID uuid.UUID) (*AccountLink, error) {
+	var l AccountLink
+	err := r.get(ctx, &l, `SELECT `+accountLinkColumns+` FROM crm_account_map WHERE customer_id = $1`, customerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get crm link customer=%s: %w", customerID, err)
+	}
+	return &l, nil
+}
+
+// SaveLink 新建或更新映射；同一 CRM 账户改挂到另一客户时先解除原映射（外部 ID 唯一）
+func (r *Repo) SaveLink(ctx context.Context, l *AccountLink) error {
+	if err := r.exec(ctx, `DELETE FROM crm_account_map WHERE external_id = $1 AND customer_id <> $2`, l.ExternalID, l.CustomerID); err != nil {
+		return fmt.Errorf("unlink crm account external_id=%s: %w", l.ExternalID, err)
+	}
+	query := `INSERT INTO crm_account_map (customer_id, external_id, master, remote_updated_at, local_synced_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (customer_id) DO UPDATE SET external_id = EXCLUDED.external_id, master = EXCLUDED.master,
+		remote_updated_at = COALESCE(EXCLUDED.remote_updated_at, crm_account_map.remote_updated_at),
+		local_synced_at = EXCLUDED.local_synced_at, synced_at = NOW()`
+	if err := r.exec(ctx, query, l.CustomerID, l.ExternalID, l.Master, l.RemoteUpdatedAt, l.LocalSyncedAt); err != nil {
+		return fmt.Errorf("save crm link customer=%s: %w", l.CustomerID, err)
+	}
+	return nil
+}
+
+// DeleteLinkByExternalID 解除映射（CRM 账户已删除或合并），本地客户保留
+func (r *Repo) DeleteLinkByExternalID(ctx context.Context, externalID string) error {
+	if err := r.exec(ctx, `DELETE FROM crm_account_map WHERE external_id = $1`, externalID); err != nil {
+		return fmt.Errorf("delete crm link external_id=%s: %w", externalID, err)
+	}
+	return nil
+}
+
+// FindMasterCustomer 按客户名查找已映射到 CRM 主数据账户的客户，不存在返回 uuid.Nil
+func (r *Repo) FindMasterCustomer(ctx context.Context, name string) (uuid.UUID, error) {
+	var id uuid.UUID
+	query := `SELECT c.id FROM customers c JOIN crm_account_map m ON m.customer_id = c.id
+		WHERE m.master AND c.name = $1 ORDER BY m.synced_at DESC LIMIT 1`
+	if err := r.get(ctx, &id, query, name); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, fmt.Errorf("find master customer name=%s: %w", name, err)
+	}
+	return id, nil
+}
+
+// FindUnlinkedCustomer 按客户名查找尚未映射的客户，不存在返回 uuid.Nil
+func (r *Repo) FindUnlinkedCustomer(ctx context.Context, name string) (uuid.UUID, error) {
+	var id uuid.UUID
+	query := `SELECT c.id FROM customers c WHERE c.name = $1
+		AND NOT EXISTS (SELECT 1 FROM crm_account_map m WHERE m.customer_id = c.id)
+		ORDER BY c.created_at LIMIT 1`
+	if err := r.get(ctx, &id, query, name); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, fmt.Errorf("find unlinked customer name=%s: %w", name, err)
+	}
+	return id, nil
+}
+
+// pendingCustomer 待推送的客户：尚未映射，或映射后本地又有修改
+type pendingCustomer struct {
+	ID            uuid.UUID `db:"id"`
+	Name          string    `db:"name"`
+	ContactPerson *string   `db:"contact_person"`
+	ContactPhone  *string   `db:"contact_phone"`
+	ContactRole   *string   `db:"contact_role"`
+	UpdatedAt     time.Time `db:"updated_at"`
+	ExternalID    *string   `db:"external_id"`
+	Master        *bool     `db:"master"`
+}
+
+// pendingCustomers 返回待推送的客户（不含 skip 中本轮已失败的），按更新时间升序
+func (r *Repo) pendingCustomers(ctx context.Context, skip []uuid.UUID, limit int) ([]*pendingCustomer, error) {
+	var list []*pendingCustomer
+	query := `SELECT c.id, c.name, c.contact_person, c.contact_phone, c.contact_role, c.updated_at, m.external_id, m.master
+		FROM customers c LEFT JOIN crm_account_map m ON m.customer_id = c.id
+		WHERE (m.customer_id IS NULL OR c.updated_at > m.local_synced_at) AND c.id <> ALL($1)
+		ORDER BY c.updated_at, c.id LIMIT $2`
+	if err := r.sel(ctx, &list, query, pq.Array(uuidStrings(skip)), limit); err != nil {
+		return nil, fmt.Errorf("list pending crm customers: %w", err)
+	}
+	return list, nil
+}
+
+// pendingActivity 待推送的跟进记录：客户已映射，且尚未推送或推送后又有修改
+type pendingActivity struct {
+	RecordID      uuid.UUID `db:"id"`
+	UserID        string    `db:"user_id"`
+	UserName      *string   `db:"user_name"`
+	AccountID     string    `db:"account_id"`
+	ActivityID    *string   `db:"activity_id"`
+	FollowTime    time.Time `db:"follow_time"`
+	FollowMethod  *string   `db:"follow_method"`
+	FollowContent *string   `db:"follow_content"`
+	FollowGoal    *string   `db:"follow_goal"`
+	FollowResult  *string   `db:"follow_result"`
+	RiskContent   *string   `db:"risk_content"`
+	NextPlan      *string   `db:"next_plan"`
+	Version       int       `db:"version"`
+}
+
+// pendingActivities 返回待推送的跟进记录（不含 skip 中本轮已失败的），按更新时间升序
+func (r *Repo) pendingActivities(ctx context.Context, skip []uuid.UUID, limit int) ([]*pendingActivity, error) {
+	var list []*pendingActivity
+	query := `SELECT fr.id, fr.user_id, u.name AS user_name, m.external_id AS account_id, am.external_id AS activity_id,
+		fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan, fr.version
+		FROM follow_records fr
+		JOIN crm_account_map m ON m.customer_id = fr.customer_id
+		LEFT JOIN crm_activity_map am ON am.record_id = fr.id
+		LEFT JOIN users u ON u.id = fr.user_id
+		WHERE fr.deleted_at IS NULL AND (am.record_id IS NULL OR fr.version > am.version) AND fr.id <> ALL($1)
+		ORDER BY fr.updated_at, fr.id LIMIT $2`
+	if err := r.sel(ctx, &list, query, pq.Array(uuidStrings(skip)), limit); err != nil {
+		return nil, fmt.Errorf("list pending crm activities: %w", err)
+	}
+	return list, nil
+}
+
+// saveActivity 记录已推送的跟进记录版本
+func (r *Repo) saveActivity(ctx context.Context, recordID uuid.UUID, externalID string, version int) error {
+	query := `INSERT INTO crm_activity_map (record_id, external_id, version) VALUES ($1, $2, $3)
+		ON CONFLICT (record_id) DO UPDATE SET external_id = EXCLUDED.external_id, version = EXCLUDED.version, synced_at = NOW()`
+	if err := r.exec(ctx, query, recordID, externalID, version); err != nil {
+		return fmt.Errorf("save crm activity record=%s: %w", recordID, err)
+	}
+	return nil
+}
+
+// State 同步状态
+type State struct {
+	Name      string     `db:"name" json:"name"`
+	Cursor    string     `db:"cursor" json:"cursor"`
+	LastRunAt *time.Time `db:"last_run_at" json:"last_run_at"`
+	LastError *string    `db:"last_error" json:"last_error,omitempty"`
+}
+
+// GetState 返回同步状态，尚未运行过返回零值
+func (r *Repo) GetState(ctx context.Context, name string) (*State, error) {
+	st := State{Name: name}
+	err := r.get(ctx, &st, `SELECT name, cursor, last_run_at, last_error FROM crm_sync_state WHERE name = $1`, name)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("get crm sync state name=%s: %w", name, err)
+	}
+	return &st, nil
+}
+
+// SaveCursor 保存拉取游标
+func (r *Repo) SaveCursor(ctx context.Context, name, cursor string) error {
+	query := `INSERT INTO crm_sync_state (name, cursor) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET cursor = EXCLUDED.cursor`
+	if err := r.exec(ctx, query, name, cursor); err != nil {
+		return fmt.Errorf("save crm cursor name=%s: %w", name, err)
+	}
+	return nil
+}
+
+// SaveRun 记录一次运行的结束时间与错误
+func (r *Repo) SaveRun(ctx context.Context, name string, runErr error) error {
+	var msg *string
+	if runErr != nil {
+		s := runErr.Error()
+		msg = &s
+	}
+	query := `INSERT INTO crm_sync_state (name, last_run_at, last_error) VALUES ($1, NOW(), $2)
+		ON CONFLICT (name) DO UPDATE SET last_run_at = NOW(), last_error = EXCLUDED.last_error`
+	if err := r.exec(ctx, query, name, msg); err != nil {
+		return fmt.Errorf("save crm run name=%s: %w", name, err)
+	}
+	return nil
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
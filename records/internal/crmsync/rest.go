@@ -0,0 +1,346 @@
+package crmsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 通用 REST/JSON 连接器：路径、响应中的列表/游标/ID 位置与字段名均可配置，字段名支持点号路径（如 contact.phone）。
+// 约定：
+//   - 查找账户 GET {search_path}?q=&limit=，响应 {items_path: [账户]}
+//   - 新建账户 POST {accounts_path}，更新账户 PUT {accounts_path}/{id}，请求体为账户，响应 {id_path: ID}
+//   - 推送活动 POST {activities_path} / PUT {activities_path}/{id}
+//   - 拉取变更 GET {changes_path}?cursor=&limit=，响应 {items_path: [账户], next_cursor_path: 游标, has_more_path: 是否还有}
+
+const defaultRESTTimeout = 15 * time.Second
+
+// 可映射的本地字段
+var (
+	AccountFieldNames = []string{"external_id", "name", "contact_person", "contact_phone", "contact_role", "master", "deleted", "updated_at"}
+
+	ActivityFieldNames = []string{"account_id", "record_id", "user_id", "user_name", "follow_time", "follow_method",
+		"follow_content", "follow_goal", "follow_result", "risk_content", "next_plan"}
+)
+
+// RESTConfig 通用 REST 连接器配置，未配置的项取默认值
+type RESTConfig struct {
+	BaseURL        string
+	Headers        map[string]string // 每个请求附带的请求头，如 Authorization
+	Timeout        time.Duration     // 默认 15s
+	SearchPath     string            // 默认 /accounts
+	AccountsPath   string            // 默认 /accounts
+	ActivitiesPath string            // 默认 /activities
+	ChangesPath    string            // 默认 /accounts/changes
+	ItemsPath      string            // 列表响应中数组的位置，默认 items
+	NextCursorPath string            // 默认 next_cursor
+	HasMorePath    string            // 默认 has_more
+	IDPath         string            // 写入响应中 ID 的位置，默认 id
+	AccountFields  map[string]string // 本地字段 → CRM 字段，未配置的字段同名（external_id 默认 id）
+	ActivityFields map[string]string // 本地字段 → CRM 字段，未配置的字段同名
+}
+
+// REST 通用 REST/JSON 连接器
+type REST struct {
+	cfg    RESTConfig
+	client *http.Client
+}
+
+// NewREST 创建通用 REST 连接器
+func NewREST(cfg RESTConfig) *REST {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultRESTTimeout
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	cfg.SearchPath = orDefault(cfg.SearchPath, "/accounts")
+	cfg.AccountsPath = orDefault(cfg.AccountsPath, "/accounts")
+	cfg.ActivitiesPath = orDefault(cfg.ActivitiesPath, "/activities")
+	cfg.ChangesPath = orDefault(cfg.ChangesPath, "/accounts/changes")
+	cfg.ItemsPath = orDefault(cfg.ItemsPath, "items")
+	cfg.NextCursorPath = orDefault(cfg.NextCursorPath, "next_cursor")
+	cfg.HasMorePath = orDefault(cfg.HasMorePath, "has_more")
+	cfg.IDPath = orDefault(cfg.IDPath, "id")
+	cfg.AccountFields = withDefaults(cfg.AccountFields, AccountFieldNames, map[string]string{"external_id": "id"})
+	cfg.ActivityFields = withDefaults(cfg.ActivityFields, ActivityFieldNames, nil)
+	return &REST{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// SearchAccounts 按名称查找账户
+func (c *REST) SearchAccounts(ctx context.Context, query string, limit int) ([]*Account, error) {
+	q := url.Values{"q": {query}, "limit": {strconv.Itoa(limit)}}
+	var resp map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, c.cfg.SearchPath+"?"+q.Encode(), nil, &resp); err != nil {
+		return nil, fmt.Errorf("crm search accounts: %w", err)
+	}
+	return c.decodeAccounts(resp)
+}
+
+// UpsertAccount 新建（POST）或更新（PUT）账户；更新时响应中没有 ID 则沿用原 ID
+func (c *REST) UpsertAccount(ctx context.Context, a *Account) (string, error) {
+	fields := c.cfg.AccountFields
+	body := map[string]interface{}{}
+	setPath(body, fields["name"], a.Name)
+	// 本地为空的联系人字段不发送，避免清空 CRM 中已有的值
+	for local, v := range map[string]string{"contact_person": a.ContactPerson, "contact_phone": a.ContactPhone, "contact_role": a.ContactRole} {
+		if v != "" {
+			setPath(body, fields[local], v)
+		}
+	}
+	id, err := c.write(ctx, c.cfg.AccountsPath, a.ExternalID, body)
+	if err != nil {
+		return "", fmt.Errorf("crm upsert account name=%s: %w", a.Name, err)
+	}
+	return id, nil
+}
+
+// PushActivity 新建（POST）或更新（PUT）跟进活动
+func (c *REST) PushActivity(ctx context.Context, a *Activity) (string, error) {
+	fields := c.cfg.ActivityFields
+	body := map[string]interface{}{}
+	setPath(body, fields["account_id"], a.AccountID)
+	setPath(body, fields["record_id"], a.RecordID.String())
+	setPath(body, fields["user_id"], a.UserID)
+	setPath(body, fields["user_name"], a.UserName)
+	setPath(body, fields["follow_time"], a.FollowTime.Format(time.RFC3339))
+	setPath(body, fields["follow_method"], a.FollowMethod)
+	setPath(body, fields["follow_content"], a.FollowContent)
+	setPath(body, fields["follow_goal"], a.FollowGoal)
+	setPath(body, fields["follow_result"], a.FollowResult)
+	setPath(body, fields["risk_content"], a.RiskContent)
+	setPath(body, fields["next_plan"], a.NextPlan)
+	id, err := c.write(ctx, c.cfg.ActivitiesPath, a.ExternalID, body)
+	if err != nil {
+		return "", fmt.Errorf("crm push activity record=%s: %w", a.RecordID, err)
+	}
+	return id, nil
+}
+
+// PullChanges 拉取游标之后变更的账户
+func (c *REST) PullChanges(ctx context.Context, cursor string, limit int) (*Changes, error) {
+	q := url.Values{"limit": {strconv.Itoa(limit)}}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	var resp map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, c.cfg.ChangesPath+"?"+q.Encode(), nil, &resp); err != nil {
+		return nil, fmt.Errorf("crm pull changes: %w", err)
+	}
+	accounts, err := c.decodeAccounts(resp)
+	if err != nil {
+		return nil, err
+	}
+	ch := &Changes{Accounts: accounts, Cursor: stringValue(getPath(resp, c.cfg.NextCursorPath))}
+	ch.HasMore, _ = getPath(resp, c.cfg.HasMorePath).(bool)
+	if ch.Cursor == "" {
+		ch.Cursor = cursor
+		ch.HasMore = false
+	}
+	return ch, nil
+}
+
+// write 无 ID 时 POST 新建，有 ID 时 PUT 更新，返回响应中的 ID
+func (c *REST) write(ctx context.Context, path, id string, body map[string]interface{}) (string, error) {
+	method := http.MethodPost
+	if id != "" {
+		method = http.MethodPut
+		path += "/" + url.PathEscape(id)
+	}
+	var resp map[string]interface{}
+	if err := c.do(ctx, method, path, body, &resp); err != nil {
+		return "", err
+	}
+	if got := stringValue(getPath(resp, c.cfg.IDPath)); got != "" {
+		return got, nil
+	}
+	if id == "" {
+		return "", fmt.Errorf("response has no %q", c.cfg.IDPath)
+	}
+	return id, nil
+}
+
+// do 发送请求，非 2xx 视为失败；响应体为空时不解析
+func (c *REST) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range c.cfg.Headers {
+		if v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncate(string(data), 200))
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// decodeAccounts 按字段映射解析响应中的账户列表
+func (c *REST) decodeAccounts(resp map[string]interface{}) ([]*Account, error) {
+	raw := getPath(resp, c.cfg.ItemsPath)
+	if raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("crm response %q is not an array", c.cfg.ItemsPath)
+	}
+	fields := c.cfg.AccountFields
+	accounts := make([]*Account, 0, len(items))
+	for _, it := range items {
+		m, ok := it.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		a := &Account{
+			ExternalID:    stringValue(getPath(m, fields["external_id"])),
+			Name:          stringValue(getPath(m, fields["name"])),
+			ContactPerson: stringValue(getPath(m, fields["contact_person"])),
+			ContactPhone:  stringValue(getPath(m, fields["contact_phone"])),
+			ContactRole:   stringValue(getPath(m, fields["contact_role"])),
+			Master:        boolValue(getPath(m, fields["master"])),
+			Deleted:       boolValue(getPath(m, fields["deleted"])),
+			UpdatedAt:     timeValue(getPath(m, fields["updated_at"])),
+		}
+		if a.ExternalID == "" {
+			continue
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, nil
+}
+
+// getPath 按点号路径取值，路径不存在返回 nil
+func getPath(m map[string]interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	var cur interface{} = m
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = obj[key]
+	}
+	return cur
+}
+
+// setPath 按点号路径写值，中间层不存在时创建；路径为空表示不同步该字段
+func setPath(m map[string]interface{}, path string, v interface{}) {
+	if path == "" {
+		return
+	}
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = v
+}
+
+func stringValue(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	default:
+		return ""
+	}
+}
+
+func boolValue(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case string:
+		b, _ := strconv.ParseBool(x)
+		return b
+	case float64:
+		return x != 0
+	default:
+		return false
+	}
+}
+
+// timeValue 解析 RFC3339 字符串或毫秒时间戳
+func timeValue(v interface{}) time.Time {
+	switch x := v.(type) {
+	case string:
+		t, _ := time.Parse(time.RFC3339, x)
+		return t
+	case float64:
+		return time.UnixMilli(int64(x))
+	default:
+		return time.Time{}
+	}
+}
+
+func withDefaults(m map[string]string, names []string, defaults map[string]string) map[string]string {
+	out := make(map[string]string, len(names))
+	for _, n := range names {
+		if v, ok := m[n]; ok {
+			out[n] = v
+		} else if v, ok := defaults[n]; ok {
+			out[n] = v
+		} else {
+			out[n] = n
+		}
+	}
+	return out
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
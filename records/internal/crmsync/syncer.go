@@ -0,0 +1,461 @@
+package crmsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"records/internal/models"
+	"records/internal/phonecrypt"
+	"records/internal/repository"
+	"records/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// 双向同步：先拉取 CRM 账户变更（CRM 为准，更新或新建本地客户），再推送本地新建/修改的客户与跟进记录。
+// 映射表记录外部 ID 与已同步到的位置，同步中断后下次从映射处继续，不会重复新建
+
+const (
+	defaultBatchSize    = 100
+	defaultMatchTimeout = 3 * time.Second
+
+	// maxConsecutiveFailures 连续失败次数上限，超过后中止本轮推送（CRM 不可用时避免逐条超时）
+	maxConsecutiveFailures = 5
+	// searchLimit 按名称查找账户时的返回条数
+	searchLimit = 10
+	// maxCustomerWriteAttempts 以 CRM 账户更新本地客户遇到并发修改时的最大尝试次数
+	maxCustomerWriteAttempts = 3
+)
+
+// PullState 拉取游标在 crm_sync_state 中的名称
+const PullState = "pull"
+
+// Config 同步配置
+type Config struct {
+	BatchSize    int           // 每次拉取/推送的条数，默认 100
+	MatchTimeout time.Duration // 对话中匹配客户时查询 CRM 的超时，超时按本地匹配，默认 3s
+}
+
+// Result 一次同步的统计
+type Result struct {
+	Pulled           int `json:"pulled"`            // 拉取并应用的 CRM 账户变更数
+	Unlinked         int `json:"unlinked"`          // 因 CRM 账户删除而解除的映射数
+	AccountsPushed   int `json:"accounts_pushed"`   // 推送的客户数
+	ActivitiesPushed int `json:"activities_pushed"` // 推送的跟进记录数
+	Failed           int `json:"failed"`            // 推送失败数（下轮重试）
+}
+
+// Syncer CRM 双向同步
+type Syncer struct {
+	repo  *Repo
+	store *repository.Repository
+	conn  Connector
+	cfg   Config
+	log   logger.Logger
+}
+
+// NewSyncer 创建同步器，未配置的项取默认值
+func NewSyncer(db *sqlx.DB, conn Connector, cfg Config, log logger.Logger) *Syncer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.MatchTimeout <= 0 {
+		cfg.MatchTimeout = defaultMatchTimeout
+	}
+	return &Syncer{repo: NewRepo(db), store: repository.New(db), conn: conn, cfg: cfg, log: log}
+}
+
+// Repo 返回数据访问（管理 API 查看同步状态）
+func (s *Syncer) Repo() *Repo {
+	return s.repo
+}
+
+// Run 执行一轮双向同步，结果记入 crm_sync_state
+func (s *Syncer) Run(ctx context.Context) (*Result, error) {
+	res := &Result{}
+	err := s.pull(ctx, res)
+	if err == nil {
+		err = s.pushAccounts(ctx, res)
+	}
+	if err == nil {
+		err = s.pushActivities(ctx, res)
+	}
+	if err == nil && res.Failed > 0 {
+		err = fmt.Errorf("%d crm pushes failed", res.Failed)
+	}
+	if rerr := s.repo.SaveRun(ctx, PullState, err); rerr != nil {
+		s.log.WithContext(ctx).Error("Save crm sync run failed", "error", rerr)
+	}
+	return res, err
+}
+
+// pull 从游标处拉取 CRM 账户变更，每页应用后推进游标
+func (s *Syncer) pull(ctx context.Context, res *Result) error {
+	st, err := s.repo.GetState(ctx, PullState)
+	if err != nil {
+		return err
+	}
+	cursor := st.Cursor
+	for {
+		ch, err := s.conn.PullChanges(ctx, cursor, s.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, a := range ch.Accounts {
+			if err := s.applyAccount(ctx, a, res); err != nil {
+				return err
+			}
+		}
+		// 游标未前进时结束本轮，避免连接器异常时反复拉取同一页
+		if ch.Cursor == cursor {
+			return nil
+		}
+		if err := s.repo.SaveCursor(ctx, PullState, ch.Cursor); err != nil {
+			return err
+		}
+		cursor = ch.Cursor
+		if !ch.HasMore || len(ch.Accounts) == 0 {
+			return nil
+		}
+	}
+}
+
+// applyAccount 应用一个 CRM 账户：已映射的更新本地客户，未映射的关联同名客户或新建客户；已删除的解除映射
+func (s *Syncer) applyAccount(ctx context.Context, a *Account, res *Result) error {
+	link, err := s.repo.GetLinkByExternalID(ctx, a.ExternalID)
+	if err != nil {
+		return err
+	}
+	if a.Deleted {
+		if link != nil {
+			if err := s.repo.DeleteLinkByExternalID(ctx, a.ExternalID); err != nil {
+				return err
+			}
+			res.Unlinked++
+		}
+		return nil
+	}
+	if a.Name == "" {
+		return nil
+	}
+	if link == nil {
+		if _, _, err := s.linkAccount(ctx, a); err != nil {
+			return err
+		}
+		res.Pulled++
+		return nil
+	}
+	found, synced, err := s.updateFromAccount(ctx, link.CustomerID, a, link.LocalSyncedAt)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+	if synced != nil {
+		link.LocalSyncedAt = *synced
+	}
+	link.Master = a.Master
+	link.RemoteUpdatedAt = remoteTime(a.UpdatedAt)
+	if err := s.repo.SaveLink(ctx, link); err != nil {
+		return err
+	}
+	res.Pulled++
+	return nil
+}
+
+// updateFromAccount 以 CRM 账户覆盖本地客户字段；found 为 false 表示客户不存在。
+// 以读取时的 updated_at 为前置条件，期间被其他写入修改时重新读取后再覆盖；改名时同步跟进记录上冗余的客户名。
+// 本地已同步的修改（updated_at 不晚于 syncedAt）被覆盖后视为已同步，synced 返回新的 updated_at；
+// 本地尚有未推送的修改或没有字段变化时 synced 为 nil，保留原 local_synced_at，合并后的结果在本轮推送
+func (s *Syncer) updateFromAccount(ctx context.Context, id uuid.UUID, a *Account, syncedAt time.Time) (found bool, synced *time.Time, err error) {
+	for attempt := 1; ; attempt++ {
+		c, err := s.store.GetCustomer(ctx, id)
+		if err != nil {
+			return false, nil, err
+		}
+		if c == nil {
+			return false, nil, nil
+		}
+		pending := c.UpdatedAt != nil && c.UpdatedAt.After(syncedAt)
+		oldName := c.Name
+		if !applyAccountFields(c, a) {
+			return true, nil, nil
+		}
+		err = s.store.UpdateCustomerRenaming(ctx, c, oldName)
+		if errors.Is(err, repository.ErrVersionConflict) && attempt < maxCustomerWriteAttempts {
+			s.log.WithContext(ctx).Warn("Customer modified concurrently, retrying crm account apply",
+				"customer_id", id, "external_id", a.ExternalID, "attempt", attempt)
+			continue
+		}
+		if err != nil {
+			return false, nil, err
+		}
+		if pending {
+			return true, nil, nil
+		}
+		return true, c.UpdatedAt, nil
+	}
+}
+
+// linkAccount 为未映射的 CRM 账户关联同名且未映射的本地客户，没有则按 CRM 账户新建客户；created 表示是否新建
+func (s *Syncer) linkAccount(ctx context.Context, a *Account) (uuid.UUID, bool, error) {
+	id, err := s.repo.FindUnlinkedCustomer(ctx, a.Name)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	link := &AccountLink{ExternalID: a.ExternalID, Master: a.Master, RemoteUpdatedAt: remoteTime(a.UpdatedAt)}
+	created := id == uuid.Nil
+	if created {
+		c := &models.Customer{ID: uuid.New(), Name: a.Name}
+		applyAccountFields(c, a)
+		if err := s.store.CreateCustomer(ctx, c); err != nil {
+			return uuid.Nil, false, err
+		}
+		saved, err := s.store.GetCustomer(ctx, c.ID)
+		if err != nil {
+			return uuid.Nil, false, err
+		}
+		id = c.ID
+		if saved != nil && saved.UpdatedAt != nil {
+			link.LocalSyncedAt = *saved.UpdatedAt
+		}
+	} else {
+		// local_synced_at 取零值：本地已有的联系人等信息在本轮推送到 CRM
+		if _, _, err := s.updateFromAccount(ctx, id, a, time.Time{}); err != nil {
+			return uuid.Nil, false, err
+		}
+	}
+	link.CustomerID = id
+	if err := s.repo.SaveLink(ctx, link); err != nil {
+		return uuid.Nil, false, err
+	}
+	return id, created, nil
+}
+
+// pushAccounts 推送尚未映射或映射后本地有修改的客户；未映射的先按名称在 CRM 中查找，避免重复建账户
+func (s *Syncer) pushAccounts(ctx context.Context, res *Result) error {
+	var skip []uuid.UUID
+	failures := 0
+	for {
+		list, err := s.repo.pendingCustomers(ctx, skip, s.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, c := range list {
+			if err := s.pushAccount(ctx, c); err != nil {
+				s.log.WithContext(ctx).Warn("Push customer to crm failed", "error", err, "customer_id", c.ID)
+				skip = append(skip, c.ID)
+				res.Failed++
+				if failures++; failures >= maxConsecutiveFailures {
+					return fmt.Errorf("push customers aborted after %d consecutive failures: %w", failures, err)
+				}
+				continue
+			}
+			failures = 0
+			res.AccountsPushed++
+		}
+		if len(list) < s.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+func (s *Syncer) pushAccount(ctx context.Context, c *pendingCustomer) error {
+	link := &AccountLink{CustomerID: c.ID, LocalSyncedAt: c.UpdatedAt}
+	if c.ExternalID != nil {
+		link.ExternalID = *c.ExternalID
+		link.Master = c.Master != nil && *c.Master
+	} else {
+		accounts, err := s.conn.SearchAccounts(ctx, c.Name, searchLimit)
+		if err != nil {
+			return err
+		}
+		if best := pickAccount(accounts, c.Name); best != nil {
+			existing, err := s.repo.GetLinkByExternalID(ctx, best.ExternalID)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				link.ExternalID = best.ExternalID
+				link.Master = best.Master
+			}
+		}
+	}
+	id, err := s.conn.UpsertAccount(ctx, &Account{
+		ExternalID:    link.ExternalID,
+		Name:          c.Name,
+		ContactPerson: deref(c.ContactPerson),
+		ContactPhone:  phonecrypt.Reveal(c.ContactPhone, true), // CRM 为受信任的主数据系统，推送明文
+		ContactRole:   deref(c.ContactRole),
+	})
+	if err != nil {
+		return err
+	}
+	link.ExternalID = id
+	return s.repo.SaveLink(ctx, link)
+}
+
+// pushActivities 推送已映射客户下尚未推送或推送后有修改的跟进记录
+func (s *Syncer) pushActivities(ctx context.Context, res *Result) error {
+	var skip []uuid.UUID
+	failures := 0
+	for {
+		list, err := s.repo.pendingActivities(ctx, skip, s.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, p := range list {
+			id, err := s.conn.PushActivity(ctx, &Activity{
+				ExternalID:    deref(p.ActivityID),
+				AccountID:     p.AccountID,
+				RecordID:      p.RecordID,
+				UserID:        p.UserID,
+				UserName:      deref(p.UserName),
+				FollowTime:    p.FollowTime,
+				FollowMethod:  deref(p.FollowMethod),
+				FollowContent: deref(p.FollowContent),
+				FollowGoal:    deref(p.FollowGoal),
+				FollowResult:  deref(p.FollowResult),
+				RiskContent:   deref(p.RiskContent),
+				NextPlan:      deref(p.NextPlan),
+			})
+			if err == nil {
+				err = s.repo.saveActivity(ctx, p.RecordID, id, p.Version)
+			}
+			if err != nil {
+				s.log.WithContext(ctx).Warn("Push follow record to crm failed", "error", err, "record_id", p.RecordID)
+				skip = append(skip, p.RecordID)
+				res.Failed++
+				if failures++; failures >= maxConsecutiveFailures {
+					return fmt.Errorf("push activities aborted after %d consecutive failures: %w", failures, err)
+				}
+				continue
+			}
+			failures = 0
+			res.ActivitiesPushed++
+		}
+		if len(list) < s.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+// FindOrCreateCustomer 对话中按客户名匹配客户，优先 CRM 主数据账户：
+// 已映射到主数据账户的同名客户 → CRM 中同名账户（主数据优先，必要时关联或新建本地客户）→ 本地同名客户或新建。
+// CRM 查询失败或超时时退回本地匹配，不影响对话
+func (s *Syncer) FindOrCreateCustomer(ctx context.Context, name string) (uuid.UUID, bool, error) {
+	id, err := s.repo.FindMasterCustomer(ctx, name)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	if id != uuid.Nil {
+		return id, false, nil
+	}
+	sctx, cancel := context.WithTimeout(ctx, s.cfg.MatchTimeout)
+	accounts, err := s.conn.SearchAccounts(sctx, name, searchLimit)
+	cancel()
+	if err != nil {
+		s.log.WithContext(ctx).Warn("Search crm accounts failed, falling back to local match", "error", err, "name", name)
+		return s.store.FindOrCreateCustomer(ctx, name)
+	}
+	if best := pickAccount(accounts, name); best != nil {
+		link, err := s.repo.GetLinkByExternalID(ctx, best.ExternalID)
+		if err != nil {
+			return uuid.Nil, false, err
+		}
+		if link != nil {
+			return link.CustomerID, false, nil
+		}
+		return s.linkAccount(ctx, best)
+	}
+	return s.store.FindOrCreateCustomer(ctx, name)
+}
+
+// MatchCustomer 按客户名匹配已有客户（只读，供导入预览与提交前解析）：已映射到主数据账户的同名客户 →
+// CRM 中同名且已关联本地客户的账户 → 本地同名客户。CRM 中有同名账户但尚未关联时按本地匹配，由下一轮推送按名称关联；
+// CRM 查询失败或超时时退回本地匹配
+func (s *Syncer) MatchCustomer(ctx context.Context, name string) (uuid.UUID, bool, error) {
+	id, err := s.repo.FindMasterCustomer(ctx, name)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	if id != uuid.Nil {
+		return id, true, nil
+	}
+	sctx, cancel := context.WithTimeout(ctx, s.cfg.MatchTimeout)
+	accounts, err := s.conn.SearchAccounts(sctx, name, searchLimit)
+	cancel()
+	if err != nil {
+		s.log.WithContext(ctx).Warn("Search crm accounts failed, falling back to local match", "error", err, "name", name)
+	} else if best := pickAccount(accounts, name); best != nil {
+		link, err := s.repo.GetLinkByExternalID(ctx, best.ExternalID)
+		if err != nil {
+			return uuid.Nil, false, err
+		}
+		if link != nil {
+			return link.CustomerID, true, nil
+		}
+	}
+	c, err := s.store.GetCustomerByName(ctx, name)
+	if err != nil || c == nil {
+		return uuid.Nil, false, err
+	}
+	return c.ID, true, nil
+}
+
+// pickAccount 在查找结果中选出与客户名一致的账户，主数据账户优先
+func pickAccount(accounts []*Account, name string) *Account {
+	name = strings.TrimSpace(name)
+	var best *Account
+	for _, a := range accounts {
+		if a.Deleted || !strings.EqualFold(strings.TrimSpace(a.Name), name) {
+			continue
+		}
+		if best == nil || (a.Master && !best.Master) {
+			best = a
+		}
+	}
+	return best
+}
+
+// applyAccountFields 以 CRM 账户覆盖客户的名称与联系人（CRM 中为空的字段保留本地值），返回是否有变化
+func applyAccountFields(c *models.Customer, a *Account) bool {
+	changed := false
+	if a.Name != "" && a.Name != c.Name {
+		c.Name = a.Name
+		changed = true
+	}
+	set := func(dst **string, v string) {
+		if v != "" && (*dst == nil || **dst != v) {
+			*dst = &v
+			changed = true
+		}
+	}
+	set(&c.ContactPerson, a.ContactPerson)
+	// 库中号码为密文，按明文比较
+	if a.ContactPhone != "" && phonecrypt.Reveal(c.ContactPhone, true) != a.ContactPhone {
+		c.ContactPhone = &a.ContactPhone
+		changed = true
+	}
+	set(&c.ContactRole, a.ContactRole)
+	return changed
+}
+
+func remoteTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
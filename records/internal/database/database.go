@@ -50,18 +50,45 @@ func loadSchemaSQL() (string, error) {
 
 // loadHotwordsSQL 加载热词表 DDL
 func loadHotwordsSQL() (string, error) {
+	return loadSQLFile("hotwords.sql")
+}
+
+// loadSQLFile 从 sql 目录加载指定 SQL 文件
+func loadSQLFile(name string) (string, error) {
 	wd, err := os.Getwd()
 	if err != nil {
 		return "", fmt.Errorf("failed to get working directory: %w", err)
 	}
-	p := filepath.Join(wd, "sql", "hotwords.sql")
+	p := filepath.Join(wd, "sql", name)
 	sqlBytes, err := os.ReadFile(p)
 	if err != nil {
-		return "", fmt.Errorf("failed to read hotwords.sql: %w", err)
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
 	}
 	return string(sqlBytes), nil
 }
 
+// incrementalSQLFiles 增量 DDL 脚本：均使用 IF NOT EXISTS，可重复执行，每次启动时按顺序执行以补齐已有库缺失的表/列
+var incrementalSQLFiles = []string{
+	"cluster.sql",
+	"follow_tasks.sql",
+	"digest.sql",
+	"stale_customers.sql",
+	"import.sql",
+	"search.sql",
+	"pagination.sql",
+	"record_versions.sql",
+	"rbac.sql",
+	"directory.sql",
+	"record_comments.sql",
+	"record_shares.sql",
+	"auth_sessions.sql",
+	"api_keys.sql",
+	"webhooks.sql",
+	"bitable_sync.sql",
+	"crm_sync.sql",
+	"phone_encryption.sql",
+}
+
 // 初始化数据库，创建表结构
 func InitDatabase(db *sqlx.DB) error {
 
@@ -103,5 +130,16 @@ func InitDatabase(db *sqlx.DB) error {
 		}
 	}
 
+	// 增量脚本：可重复执行
+	for _, name := range incrementalSQLFiles {
+		sqlText, err := loadSQLFile(name)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", name, err)
+		}
+		if _, err = db.Exec(sqlText); err != nil {
+			return fmt.Errorf("failed to execute %s: %w", name, err)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,129 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// periodTitle 摘要标题中的周期描述
+func periodTitle(p Period) string {
+	if p.Frequency == FrequencyWeekly {
+		return fmt.Sprintf("%s ~ %s", p.From.In(time.Local).Format("01-02"), p.To.In(time.Local).Add(-time.Second).Format("01-02"))
+	}
+	return p.To.In(time.Local).Format("2006-01-02")
+}
+
+func periodName(p Period) string {
+	if p.Frequency == FrequencyWeekly {
+		return "本周"
+	}
+	return "今日"
+}
+
+// RepCard 构建个人摘要卡片
+func RepCard(d *RepDigest) map[string]interface{} {
+	name := periodName(d.Period)
+	sections := []string{
+		fmt.Sprintf("**%s记录**：%d 条　**跟进客户**：%d 个", name, d.RecordCount, d.CustomerCount),
+	}
+	if len(d.Customers) > 0 {
+		sections = append(sections, "**跟进的客户**："+strings.Join(d.Customers, "、"))
+	}
+
+	if len(d.OpenPlans) > 0 {
+		lines := make([]string, 0, len(d.OpenPlans))
+		for _, p := range d.OpenPlans {
+			lines = append(lines, fmt.Sprintf("- %s %s：%s", p.DueAt.In(time.Local).Format("01-02 15:04"), p.CustomerName, p.Action))
+		}
+		sections = append(sections, "**待办的下一步计划**\n"+strings.Join(lines, "\n"))
+	} else {
+		sections = append(sections, "**待办的下一步计划**：暂无")
+	}
+
+	if len(d.QuietCustomers) > 0 {
+		lines := make([]string, 0, len(d.QuietCustomers))
+		for _, c := range d.QuietCustomers {
+			lines = append(lines, fmt.Sprintf("- %s（上次跟进 %s）", c.CustomerName, c.LastFollowAt.In(time.Local).Format("01-02")))
+		}
+		sections = append(sections, fmt.Sprintf("**超过 %d 天未跟进的客户**\n%s", d.QuietDays, strings.Join(lines, "\n")))
+	}
+
+	return buildCard("blue", fmt.Sprintf("%s跟进小结 · %s", name, periodTitle(d.Period)), sections)
+}
+
+// ManagerCard 构建团队摘要卡片
+func ManagerCard(d *ManagerDigest) map[string]interface{} {
+	name := periodName(d.Period)
+	sections := []string{
+		fmt.Sprintf("**团队人数**：%d　**有记录人数**：%d\n**%s记录**：%d 条　**跟进客户**：%d 个",
+			d.TeamSize, d.ActiveReps, name, d.RecordCount, d.CustomerCount),
+	}
+
+	if len(d.TopReps) > 0 {
+		lines := make([]string, 0, len(d.TopReps))
+		for _, r := range d.TopReps {
+			lines = append(lines, fmt.Sprintf("- %s：%d 条", r.Name, r.RecordCount))
+		}
+		sections = append(sections, "**记录最多的成员**\n"+strings.Join(lines, "\n"))
+	}
+
+	if len(d.IdleReps) > 0 {
+		names := make([]string, 0, len(d.IdleReps))
+		for _, r := range d.IdleReps {
+			names = append(names, r.Name)
+		}
+		more := ""
+		if len(names) > MaxListItems {
+			more = fmt.Sprintf(" 等 %d 人", len(names))
+			names = names[:MaxListItems]
+		}
+		sections = append(sections, fmt.Sprintf("**%s无记录的成员**：%s%s", name, strings.Join(names, "、"), more))
+	}
+
+	if len(d.Risks) > 0 {
+		lines := make([]string, 0, len(d.Risks))
+		for _, r := range d.Risks {
+			lines = append(lines, fmt.Sprintf("- %s / %s：%s", r.UserName, r.CustomerName, truncate(r.RiskContent, 80)))
+		}
+		sections = append(sections, "**新增风险**\n"+strings.Join(lines, "\n"))
+	} else {
+		sections = append(sections, "**新增风险**：暂无")
+	}
+
+	template := "indigo"
+	if len(d.Risks) > 0 {
+		template = "orange"
+	}
+	return buildCard(template, fmt.Sprintf("团队%s跟进概览 · %s", name, periodTitle(d.Period)), sections)
+}
+
+// buildCard 构建消息卡片：标题 + 以分割线隔开的若干 lark_md 段落
+func buildCard(template, title string, sections []string) map[string]interface{} {
+	elements := make([]interface{}, 0, len(sections)*2)
+	for i, content := range sections {
+		if i > 0 {
+			elements = append(elements, map[string]interface{}{"tag": "hr"})
+		}
+		elements = append(elements, map[string]interface{}{
+			"tag":  "div",
+			"text": map[string]interface{}{"tag": "lark_md", "content": content},
+		})
+	}
+	return map[string]interface{}{
+		"config": map[string]interface{}{"wide_screen_mode": true},
+		"header": map[string]interface{}{
+			"template": template,
+			"title":    map[string]interface{}{"tag": "plain_text", "content": title},
+		},
+		"elements": elements,
+	}
+}
+
+func truncate(s string, n int) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) <= n {
+		return string(r)
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,192 @@
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// MaxListItems 摘要卡片中各列表的最大条数
+const MaxListItems = 10
+
+// quietLookback 久未跟进客户的回溯范围：仅统计该范围内跟进过的客户，避免早已放弃的客户反复出现
+const quietLookback = 90 * 24 * time.Hour
+
+// Repo 摘要相关数据访问
+type Repo struct {
+	db *sqlx.DB
+}
+
+// NewRepo 创建摘要 Repo
+func NewRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+const subscriptionColumns = `user_id, rep_frequency, manager_frequency, last_rep_sent_at, last_manager_sent_at`
+
+// GetSubscription 获取用户订阅；未订阅过返回默认（全部关闭）
+func (r *Repo) GetSubscription(ctx context.Context, userID string) (*Subscription, error) {
+	var sub Subscription
+	err := r.db.GetContext(ctx, &sub, `SELECT `+subscriptionColumns+` FROM digest_subscriptions WHERE user_id = $1`, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &Subscription{UserID: userID, RepFrequency: FrequencyOff, ManagerFrequency: FrequencyOff}, nil
+		}
+		return nil, fmt.Errorf("get digest subscription user=%s: %w", userID, err)
+	}
+	return &sub, nil
+}
+
+// UpsertSubscription 保存用户订阅频率
+func (r *Repo) UpsertSubscription(ctx context.Context, userID, repFrequency, managerFrequency string) error {
+	query := `INSERT INTO digest_subscriptions (user_id, rep_frequency, manager_frequency) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET rep_frequency = EXCLUDED.rep_frequency, manager_frequency = EXCLUDED.manager_frequency, updated_at = NOW()`
+	if _, err := r.db.ExecContext(ctx, query, userID, repFrequency, managerFrequency); err != nil {
+		return fmt.Errorf("upsert digest subscription user=%s: %w", userID, err)
+	}
+	return nil
+}
+
+// ListActiveSubscriptions 返回在职用户中至少开启一种摘要的订阅
+func (r *Repo) ListActiveSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	var list []*Subscription
+	query := `SELECT s.user_id, s.rep_frequency, s.manager_frequency, s.last_rep_sent_at, s.last_manager_sent_at
+		FROM digest_subscriptions s JOIN users u ON u.id = s.user_id AND u.status = 0
+		WHERE s.rep_frequency <> $1 OR s.manager_frequency <> $1`
+	if err := r.db.SelectContext(ctx, &list, query, FrequencyOff); err != nil {
+		return nil, fmt.Errorf("list digest subscriptions: %w", err)
+	}
+	return list, nil
+}
+
+// MarkSent 记录摘要发送时间，避免同一周期重复推送
+func (r *Repo) MarkSent(ctx context.Context, userID, kind string, sentAt time.Time) error {
+	column := "last_rep_sent_at"
+	if kind == KindManager {
+		column = "last_manager_sent_at"
+	}
+	if _, err := r.db.ExecContext(ctx, `UPDATE digest_subscriptions SET `+column+` = $1 WHERE user_id = $2`, sentAt, userID); err != nil {
+		return fmt.Errorf("mark digest sent user=%s kind=%s: %w", userID, kind, err)
+	}
+	return nil
+}
+
+// GetUserName 获取用户姓名
+func (r *Repo) GetUserName(ctx context.Context, userID string) (string, error) {
+	var name string
+	if err := r.db.GetContext(ctx, &name, `SELECT COALESCE(name, id) FROM users WHERE id = $1`, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return userID, nil
+		}
+		return "", fmt.Errorf("get user name id=%s: %w", userID, err)
+	}
+	return name, nil
+}
+
+// RepActivityCounts 返回个人在区间内的记录数与跟进客户数
+func (r *Repo) RepActivityCounts(ctx context.Context, userID string, p Period) (records, customers int, err error) {
+	var row struct {
+		Records   int `db:"records"`
+		Customers int `db:"customers"`
+	}
+	query := `SELECT COUNT(*) AS records, COUNT(DISTINCT customer_id) AS customers
+		FROM follow_records WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND deleted_at IS NULL`
+	if err := r.db.GetContext(ctx, &row, query, userID, p.From, p.To); err != nil {
+		return 0, 0, fmt.Errorf("rep activity counts user=%s: %w", userID, err)
+	}
+	return row.Records, row.Customers, nil
+}
+
+// RepCustomers 返回个人在区间内跟进的客户名，按最近跟进倒序
+func (r *Repo) RepCustomers(ctx context.Context, userID string, p Period) ([]string, error) {
+	var names []string
+	query := `SELECT customer_name FROM follow_records WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND deleted_at IS NULL
+		GROUP BY customer_name ORDER BY MAX(follow_time) DESC LIMIT $4`
+	if err := r.db.SelectContext(ctx, &names, query, userID, p.From, p.To, MaxListItems); err != nil {
+		return nil, fmt.Errorf("rep customers user=%s: %w", userID, err)
+	}
+	return names, nil
+}
+
+// RepOpenPlans 返回个人未完成的跟进待办，按到期时间升序
+func (r *Repo) RepOpenPlans(ctx context.Context, userID string) ([]OpenPlan, error) {
+	var list []OpenPlan
+	query := `SELECT c.name AS customer_name, t.action, t.due_at
+		FROM follow_tasks t JOIN customers c ON c.id = t.customer_id
+		WHERE t.user_id = $1 AND t.status IN ('pending', 'reminded')
+		ORDER BY t.due_at LIMIT $2`
+	if err := r.db.SelectContext(ctx, &list, query, userID, MaxListItems); err != nil {
+		return nil, fmt.Errorf("rep open plans user=%s: %w", userID, err)
+	}
+	return list, nil
+}
+
+// RepQuietCustomers 返回个人久未跟进的客户：最近一次跟进早于 now-quietDays（回溯 quietLookback 内）
+func (r *Repo) RepQuietCustomers(ctx context.Context, userID string, now time.Time, quietDays int) ([]CustomerItem, error) {
+	var list []CustomerItem
+	query := `SELECT MAX(customer_name) AS customer_name, MAX(follow_time) AS last_follow_at
+		FROM follow_records WHERE user_id = $1 AND follow_time >= $2 AND deleted_at IS NULL
+		GROUP BY customer_id
+		HAVING MAX(follow_time) < $3
+		ORDER BY last_follow_at LIMIT $4`
+	cutoff := now.AddDate(0, 0, -quietDays)
+	if err := r.db.SelectContext(ctx, &list, query, userID, now.Add(-quietLookback), cutoff, MaxListItems); err != nil {
+		return nil, fmt.Errorf("rep quiet customers user=%s: %w", userID, err)
+	}
+	return list, nil
+}
+
+// scopeClause 团队范围条件：userIDs 为 nil 表示全部用户
+func scopeClause(column string, userIDs []string, args []interface{}) (string, []interface{}) {
+	if userIDs == nil {
+		return "", args
+	}
+	args = append(args, pq.Array(userIDs))
+	return fmt.Sprintf(" AND %s = ANY($%d)", column, len(args)), args
+}
+
+// TeamActivity 返回团队（在职）成员在区间内的记录数，按记录数倒序
+func (r *Repo) TeamActivity(ctx context.Context, userIDs []string, p Period) ([]RepActivity, error) {
+	args := []interface{}{p.From, p.To}
+	cond, args := scopeClause("u.id", userIDs, args)
+	query := `SELECT u.id AS user_id, COALESCE(u.name, u.id) AS name,
+		(SELECT COUNT(*) FROM follow_records fr WHERE fr.user_id = u.id AND fr.created_at >= $1 AND fr.created_at < $2 AND fr.deleted_at IS NULL) AS record_count
+		FROM users u WHERE u.status = 0` + cond + `
+		ORDER BY record_count DESC, u.name`
+	var list []RepActivity
+	if err := r.db.SelectContext(ctx, &list, query, args...); err != nil {
+		return nil, fmt.Errorf("team activity: %w", err)
+	}
+	return list, nil
+}
+
+// TeamCustomerCount 返回团队在区间内跟进的客户数
+func (r *Repo) TeamCustomerCount(ctx context.Context, userIDs []string, p Period) (int, error) {
+	args := []interface{}{p.From, p.To}
+	cond, args := scopeClause("user_id", userIDs, args)
+	var n int
+	query := `SELECT COUNT(DISTINCT customer_id) FROM follow_records WHERE created_at >= $1 AND created_at < $2 AND deleted_at IS NULL` + cond
+	if err := r.db.GetContext(ctx, &n, query, args...); err != nil {
+		return 0, fmt.Errorf("team customer count: %w", err)
+	}
+	return n, nil
+}
+
+// TeamRisks 返回团队在区间内新记录的风险（risk_content 非空），按跟进时间倒序
+func (r *Repo) TeamRisks(ctx context.Context, userIDs []string, p Period) ([]RiskItem, error) {
+	args := []interface{}{p.From, p.To, MaxListItems}
+	cond, args := scopeClause("fr.user_id", userIDs, args)
+	query := `SELECT COALESCE(u.name, fr.user_id) AS user_name, fr.customer_name, fr.risk_content, fr.follow_time
+		FROM follow_records fr JOIN users u ON u.id = fr.user_id
+		WHERE fr.created_at >= $1 AND fr.created_at < $2 AND fr.deleted_at IS NULL AND COALESCE(TRIM(fr.risk_content), '') <> ''` + cond + `
+		ORDER BY fr.follow_time DESC LIMIT $3`
+	var list []RiskItem
+	if err := r.db.SelectContext(ctx, &list, query, args...); err != nil {
+		return nil, fmt.Errorf("team risks: %w", err)
+	}
+	return list, nil
+}
@@ -0,0 +1,193 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"records/internal/repository"
+	"records/pkg/logger"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const defaultQuietDays = 14
+
+// Sender 卡片发送方（feishu.Client 实现）
+type Sender interface {
+	SendCardToUser(ctx context.Context, userID string, card map[string]interface{}) error
+}
+
+// Config 摘要配置
+type Config struct {
+	QuietDays     int          // 超过多少天未跟进视为久未跟进，默认 14
+	WeeklyWeekday time.Weekday // 周报推送的星期，默认周五
+}
+
+// Service 摘要生成与推送
+type Service struct {
+	db     *sqlx.DB
+	repo   *Repo
+	sender Sender
+	cfg    Config
+	log    logger.Logger
+}
+
+// NewService 创建摘要服务
+func NewService(db *sqlx.DB, sender Sender, cfg Config, log logger.Logger) *Service {
+	if cfg.QuietDays <= 0 {
+		cfg.QuietDays = defaultQuietDays
+	}
+	return &Service{db: db, repo: NewRepo(db), sender: sender, cfg: cfg, log: log}
+}
+
+// Repo 返回摘要数据访问（设置页读写订阅）
+func (s *Service) Repo() *Repo {
+	return s.repo
+}
+
+// DuePeriod 判断某频率的摘要在 now 时是否应推送，返回统计区间。
+// daily：当天未推送过即推送；weekly：仅在配置的星期推送。统计区间从上次推送时间开始，
+// 保证相邻两次摘要首尾相接、不漏不重；从未推送过或上次推送早于一个周期（如停推后恢复）时，
+// daily 只统计最近 24 小时，weekly 只统计最近 7 天。
+func (s *Service) DuePeriod(frequency string, lastSentAt *time.Time, now time.Time) (Period, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if lastSentAt != nil && !lastSentAt.Before(today) {
+		return Period{}, false
+	}
+	var from time.Time
+	switch frequency {
+	case FrequencyDaily:
+		from = now.Add(-24 * time.Hour)
+	case FrequencyWeekly:
+		if now.Weekday() != s.cfg.WeeklyWeekday {
+			return Period{}, false
+		}
+		from = now.AddDate(0, 0, -7)
+	default:
+		return Period{}, false
+	}
+	if lastSentAt != nil && lastSentAt.After(from) {
+		from = *lastSentAt
+	}
+	return Period{Frequency: frequency, From: from, To: now}, true
+}
+
+// RunDue 推送当前到期的所有摘要，返回发送数；单个用户失败不影响其他用户
+func (s *Service) RunDue(ctx context.Context, now time.Time) (sent int, err error) {
+	subs, err := s.repo.ListActiveSubscriptions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	failed := 0
+	for _, sub := range subs {
+		if p, ok := s.DuePeriod(sub.RepFrequency, sub.LastRepSentAt, now); ok {
+			if err := s.sendRep(ctx, sub.UserID, p); err != nil {
+				s.log.WithContext(ctx).Error("Failed to send rep digest", "user_id", sub.UserID, "error", err)
+				failed++
+			} else {
+				sent++
+			}
+		}
+		if p, ok := s.DuePeriod(sub.ManagerFrequency, sub.LastManagerSentAt, now); ok {
+			if err := s.sendManager(ctx, sub.UserID, p); err != nil {
+				s.log.WithContext(ctx).Error("Failed to send manager digest", "user_id", sub.UserID, "error", err)
+				failed++
+			} else {
+				sent++
+			}
+		}
+	}
+	if failed > 0 {
+		return sent, fmt.Errorf("%d digests failed", failed)
+	}
+	return sent, nil
+}
+
+func (s *Service) sendRep(ctx context.Context, userID string, p Period) error {
+	d, err := s.BuildRepDigest(ctx, userID, p)
+	if err != nil {
+		return err
+	}
+	if err := s.sender.SendCardToUser(ctx, userID, RepCard(d)); err != nil {
+		return err
+	}
+	return s.repo.MarkSent(ctx, userID, KindRep, p.To)
+}
+
+func (s *Service) sendManager(ctx context.Context, userID string, p Period) error {
+	isManager, err := repository.New(s.db).IsManager(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !isManager {
+		// 已不再是主管：跳过但记为已发送，避免每次调度重复查询
+		s.log.WithContext(ctx).Info("Skip manager digest for non-manager", "user_id", userID)
+		return s.repo.MarkSent(ctx, userID, KindManager, p.To)
+	}
+	d, err := s.BuildManagerDigest(ctx, userID, p)
+	if err != nil {
+		return err
+	}
+	if err := s.sender.SendCardToUser(ctx, userID, ManagerCard(d)); err != nil {
+		return err
+	}
+	return s.repo.MarkSent(ctx, userID, KindManager, p.To)
+}
+
+// BuildRepDigest 汇总个人摘要
+func (s *Service) BuildRepDigest(ctx context.Context, userID string, p Period) (*RepDigest, error) {
+	d := &RepDigest{Period: p, QuietDays: s.cfg.QuietDays}
+	var err error
+	if d.UserName, err = s.repo.GetUserName(ctx, userID); err != nil {
+		return nil, err
+	}
+	if d.RecordCount, d.CustomerCount, err = s.repo.RepActivityCounts(ctx, userID, p); err != nil {
+		return nil, err
+	}
+	if d.Customers, err = s.repo.RepCustomers(ctx, userID, p); err != nil {
+		return nil, err
+	}
+	if d.OpenPlans, err = s.repo.RepOpenPlans(ctx, userID); err != nil {
+		return nil, err
+	}
+	if d.QuietCustomers, err = s.repo.RepQuietCustomers(ctx, userID, p.To, s.cfg.QuietDays); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// BuildManagerDigest 汇总主管团队摘要（范围为 view 权限范围）
+func (s *Service) BuildManagerDigest(ctx context.Context, managerID string, p Period) (*ManagerDigest, error) {
+	scope, err := repository.New(s.db).GetManagerScopeUserIDs(ctx, managerID)
+	if err != nil {
+		return nil, err
+	}
+	d := &ManagerDigest{Period: p}
+	if d.ManagerName, err = s.repo.GetUserName(ctx, managerID); err != nil {
+		return nil, err
+	}
+	team, err := s.repo.TeamActivity(ctx, scope, p)
+	if err != nil {
+		return nil, err
+	}
+	d.TeamSize = len(team)
+	for _, r := range team {
+		if r.RecordCount > 0 {
+			d.ActiveReps++
+			d.RecordCount += r.RecordCount
+			if len(d.TopReps) < 5 {
+				d.TopReps = append(d.TopReps, r)
+			}
+		} else {
+			d.IdleReps = append(d.IdleReps, r)
+		}
+	}
+	if d.CustomerCount, err = s.repo.TeamCustomerCount(ctx, scope, p); err != nil {
+		return nil, err
+	}
+	if d.Risks, err = s.repo.TeamRisks(ctx, scope, p); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
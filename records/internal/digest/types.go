@@ -0,0 +1,90 @@
+package digest
+
+import "time"
+
+// 推送频率
+const (
+	FrequencyOff    = "off"
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+)
+
+// 摘要类型
+const (
+	KindRep     = "rep"     // 个人摘要
+	KindManager = "manager" // 团队摘要
+)
+
+// ValidFrequency 判断频率取值是否合法
+func ValidFrequency(f string) bool {
+	return f == FrequencyOff || f == FrequencyDaily || f == FrequencyWeekly
+}
+
+// Subscription digest_subscriptions 表一行
+type Subscription struct {
+	UserID            string     `db:"user_id" json:"user_id"`
+	RepFrequency      string     `db:"rep_frequency" json:"rep_frequency"`
+	ManagerFrequency  string     `db:"manager_frequency" json:"manager_frequency"`
+	LastRepSentAt     *time.Time `db:"last_rep_sent_at" json:"last_rep_sent_at,omitempty"`
+	LastManagerSentAt *time.Time `db:"last_manager_sent_at" json:"last_manager_sent_at,omitempty"`
+}
+
+// Period 摘要统计区间 [From, To)
+type Period struct {
+	Frequency string
+	From      time.Time
+	To        time.Time
+}
+
+// CustomerItem 客户条目（名称 + 最近跟进时间）
+type CustomerItem struct {
+	CustomerName string    `db:"customer_name"`
+	LastFollowAt time.Time `db:"last_follow_at"`
+}
+
+// OpenPlan 未完成的跟进待办
+type OpenPlan struct {
+	CustomerName string    `db:"customer_name"`
+	Action       string    `db:"action"`
+	DueAt        time.Time `db:"due_at"`
+}
+
+// RepDigest 个人摘要内容
+type RepDigest struct {
+	Period         Period
+	UserName       string
+	RecordCount    int
+	CustomerCount  int
+	Customers      []string       // 本期跟进的客户（最多 MaxListItems 个）
+	OpenPlans      []OpenPlan     // 未完成的下一步计划
+	QuietCustomers []CustomerItem // 久未跟进的客户
+	QuietDays      int
+}
+
+// RepActivity 团队成员在区间内的记录数
+type RepActivity struct {
+	UserID      string `db:"user_id"`
+	Name        string `db:"name"`
+	RecordCount int    `db:"record_count"`
+}
+
+// RiskItem 区间内新出现的风险
+type RiskItem struct {
+	UserName     string    `db:"user_name"`
+	CustomerName string    `db:"customer_name"`
+	RiskContent  string    `db:"risk_content"`
+	FollowTime   time.Time `db:"follow_time"`
+}
+
+// ManagerDigest 团队摘要内容
+type ManagerDigest struct {
+	Period        Period
+	ManagerName   string
+	TeamSize      int
+	RecordCount   int
+	CustomerCount int
+	ActiveReps    int
+	TopReps       []RepActivity // 记录数最多的成员
+	IdleReps      []RepActivity // 本期无记录的成员
+	Risks         []RiskItem
+}
@@ -0,0 +1,165 @@
+package directory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Repo 通讯录数据访问
+type Repo struct {
+	db *sqlx.DB
+}
+
+// NewRepo 创建 Repo
+func NewRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+// Apply 在一个事务内写入部门树、已有用户的部门归属与在职状态，并重建部门负责人授权
+func (r *Repo) Apply(ctx context.Context, nodes []*Node, members map[string]*Member, opts ApplyOptions) (*Result, error) {
+	res := &Result{Departments: len(nodes)}
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin directory sync: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+		query := `INSERT INTO departments (id, name, parent_id, leader_ids, ancestors, deleted_at, synced_at)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULL, NOW())
+			ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, parent_id = EXCLUDED.parent_id, leader_ids = EXCLUDED.leader_ids,
+				ancestors = EXCLUDED.ancestors, deleted_at = NULL, synced_at = NOW()`
+		if _, err := tx.ExecContext(ctx, query, n.ID, n.Name, n.ParentID, pq.Array(n.LeaderIDs), pq.Array(n.Ancestors)); err != nil {
+			return nil, fmt.Errorf("upsert department id=%s: %w", n.ID, err)
+		}
+	}
+	// 同步范围内已不存在的部门标记删除（非全公司同步时仅处理根部门之下的部门）
+	query := `UPDATE departments SET deleted_at = NOW() WHERE deleted_at IS NULL AND NOT (id = ANY($1))
+		AND ($2 = '0' OR $2 = ANY(ancestors))`
+	if _, err := tx.ExecContext(ctx, query, pq.Array(ids), opts.RootID); err != nil {
+		return nil, fmt.Errorf("mark deleted departments: %w", err)
+	}
+
+	var users []struct {
+		ID          string         `db:"id"`
+		Name        string         `db:"name"`
+		Status      int            `db:"status"`
+		Departments pq.StringArray `db:"departments"`
+	}
+	query = `SELECT u.id, u.name, u.status,
+		COALESCE(array_agg(d.department_id ORDER BY d.department_id) FILTER (WHERE d.department_id IS NOT NULL), '{}') AS departments
+		FROM users u LEFT JOIN user_departments d ON d.user_id = u.id GROUP BY u.id`
+	if err := tx.SelectContext(ctx, &users, query); err != nil {
+		return nil, fmt.Errorf("list users for directory sync: %w", err)
+	}
+	active := make(map[string]bool, len(users))
+	for _, u := range users {
+		m := members[u.ID]
+		if m == nil {
+			if opts.RootID != RootDepartmentID || u.Status != 0 {
+				continue
+			}
+			// 全公司通讯录中已不存在：视为离职
+			m = &Member{UserID: u.ID, Name: u.Name, Status: 1, DepartmentIDs: []string{}}
+		}
+		depts := append([]string{}, m.DepartmentIDs...)
+		sort.Strings(depts)
+		if m.Status == 0 {
+			active[u.ID] = true
+		}
+		if m.Status == u.Status && m.Name == u.Name && strings.Join(depts, ",") == strings.Join(u.Departments, ",") {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET status = $2, name = COALESCE(NULLIF($3, ''), name), updated_at = NOW() WHERE id = $1`,
+			u.ID, m.Status, m.Name); err != nil {
+			return nil, fmt.Errorf("update user id=%s: %w", u.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_departments WHERE user_id = $1`, u.ID); err != nil {
+			return nil, fmt.Errorf("clear user departments id=%s: %w", u.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO user_departments (user_id, department_id) SELECT $1, unnest($2::text[]) ON CONFLICT DO NOTHING`,
+			u.ID, pq.Array(depts)); err != nil {
+			return nil, fmt.Errorf("set user departments id=%s: %w", u.ID, err)
+		}
+		res.Updated++
+		if m.Status != 0 && u.Status == 0 {
+			res.Resigned++
+		}
+	}
+
+	if opts.LeaderRole != "" {
+		if res.Leaders, err = r.rebuildLeaderAssignments(ctx, tx, nodes, active, opts.LeaderRole); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit directory sync: %w", err)
+	}
+	return res, nil
+}
+
+// rebuildLeaderAssignments 重建负责人授权：每位在职负责人一条，范围为其负责的部门及下级（department_tree）
+func (r *Repo) rebuildLeaderAssignments(ctx context.Context, tx *sqlx.Tx, nodes []*Node, active map[string]bool, role string) (int, error) {
+	var exists int
+	if err := tx.GetContext(ctx, &exists, `SELECT 1 FROM rbac_roles WHERE name = $1`, role); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("leader role %q not found", role)
+		}
+		return 0, fmt.Errorf("get leader role: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM rbac_user_roles WHERE created_by = $1`, LeaderAssignmentCreator); err != nil {
+		return 0, fmt.Errorf("clear leader assignments: %w", err)
+	}
+	led := make(map[string][]string)
+	var leaders []string
+	for _, n := range nodes {
+		for _, l := range n.LeaderIDs {
+			if !active[l] {
+				continue // 未登录过（users 中不存在）或已离职
+			}
+			if led[l] == nil {
+				leaders = append(leaders, l)
+			}
+			led[l] = append(led[l], n.ID)
+		}
+	}
+	for _, l := range leaders {
+		query := `INSERT INTO rbac_user_roles (user_id, role, scope_type, scope_values, created_by) VALUES ($1, $2, 'department_tree', $3, $4)`
+		if _, err := tx.ExecContext(ctx, query, l, role, pq.Array(led[l]), LeaderAssignmentCreator); err != nil {
+			return 0, fmt.Errorf("create leader assignment user=%s: %w", l, err)
+		}
+	}
+	return len(leaders), nil
+}
+
+// ListDepartments 返回未删除的部门及直属成员数，按部门链排序（父部门在前）
+func (r *Repo) ListDepartments(ctx context.Context) ([]*Department, error) {
+	var rows []struct {
+		Department
+		LeaderIDs pq.StringArray `db:"leader_ids"`
+		Ancestors pq.StringArray `db:"ancestors"`
+	}
+	query := `SELECT dp.id, dp.name, dp.parent_id, dp.leader_ids, dp.ancestors, dp.deleted_at, dp.synced_at,
+		(SELECT COUNT(*) FROM user_departments ud JOIN users u ON u.id = ud.user_id AND u.status = 0 WHERE ud.department_id = dp.id) AS member_count
+		FROM departments dp WHERE dp.deleted_at IS NULL ORDER BY dp.ancestors`
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("list departments: %w", err)
+	}
+	list := make([]*Department, 0, len(rows))
+	for i := range rows {
+		d := rows[i].Department
+		d.LeaderIDs = []string(rows[i].LeaderIDs)
+		d.Ancestors = []string(rows[i].Ancestors)
+		list = append(list, &d)
+	}
+	return list, nil
+}
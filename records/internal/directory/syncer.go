@@ -0,0 +1,143 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+
+	"records/internal/feishu"
+	"records/pkg/logger"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RootDepartmentID 飞书根部门（全公司）
+const RootDepartmentID = "0"
+
+// Source 通讯录数据源（feishu.Client 实现）
+type Source interface {
+	ListDepartments(ctx context.Context, rootID string) ([]*feishu.Department, error)
+	ListDepartmentUsers(ctx context.Context, departmentID string) ([]*feishu.UserInfo, error)
+}
+
+// Config 同步配置
+type Config struct {
+	RootDepartmentID string // 默认 "0"
+	LeaderRole       string // 自动授予部门负责人的角色，为空不授予
+}
+
+// Result 一次同步的统计
+type Result struct {
+	Departments int `json:"departments"` // 同步的部门数
+	Members     int `json:"members"`     // 通讯录中的成员数
+	Updated     int `json:"updated"`     // 已有用户中更新了归属或状态的人数
+	Resigned    int `json:"resigned"`    // 标记为离职的人数
+	Leaders     int `json:"leaders"`     // 自动授权的部门负责人数
+}
+
+// Syncer 通讯录同步
+type Syncer struct {
+	repo *Repo
+	src  Source
+	cfg  Config
+	log  logger.Logger
+}
+
+// NewSyncer 创建通讯录同步
+func NewSyncer(db *sqlx.DB, src Source, cfg Config, log logger.Logger) *Syncer {
+	if cfg.RootDepartmentID == "" {
+		cfg.RootDepartmentID = RootDepartmentID
+	}
+	return &Syncer{repo: NewRepo(db), src: src, cfg: cfg, log: log}
+}
+
+// Repo 返回数据访问（管理 API 查询部门）
+func (s *Syncer) Repo() *Repo {
+	return s.repo
+}
+
+// Run 拉取部门树与各部门成员后一次性写入；拉取阶段任一请求失败则不写入，避免以不完整的通讯录误判离职
+func (s *Syncer) Run(ctx context.Context) (*Result, error) {
+	depts, err := s.src.ListDepartments(ctx, s.cfg.RootDepartmentID)
+	if err != nil {
+		return nil, err
+	}
+	nodes := buildTree(depts, s.cfg.RootDepartmentID)
+
+	members := make(map[string]*Member)
+	deptIDs := make([]string, 0, len(nodes)+1)
+	deptIDs = append(deptIDs, s.cfg.RootDepartmentID) // 根部门直属成员
+	for _, n := range nodes {
+		deptIDs = append(deptIDs, n.ID)
+	}
+	for _, id := range deptIDs {
+		users, err := s.src.ListDepartmentUsers(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("department %s: %w", id, err)
+		}
+		for _, u := range users {
+			m := members[u.UserID]
+			if m == nil {
+				m = &Member{UserID: u.UserID, Name: u.Name, Status: u.Status, DepartmentIDs: []string{}}
+				members[u.UserID] = m
+			}
+			for _, d := range u.DepartmentIDs {
+				if !contains(m.DepartmentIDs, d) {
+					m.DepartmentIDs = append(m.DepartmentIDs, d)
+				}
+			}
+		}
+	}
+
+	res, err := s.repo.Apply(ctx, nodes, members, ApplyOptions{
+		RootID:     s.cfg.RootDepartmentID,
+		LeaderRole: s.cfg.LeaderRole,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Members = len(members)
+	s.log.WithContext(ctx).Info("Directory synced", "departments", res.Departments, "members", res.Members,
+		"updated", res.Updated, "resigned", res.Resigned, "leaders", res.Leaders)
+	return res, nil
+}
+
+// buildTree 计算每个部门自根向下的部门链（含自身）；父部门不在同步范围内的视为顶层
+func buildTree(depts []*feishu.Department, rootID string) []*Node {
+	byID := make(map[string]*feishu.Department, len(depts))
+	for _, d := range depts {
+		byID[d.ID] = d
+	}
+	nodes := make([]*Node, 0, len(depts))
+	for _, d := range depts {
+		chain := []string{d.ID}
+		seen := map[string]bool{d.ID: true}
+		for p := byID[d.ParentID]; p != nil && !seen[p.ID]; p = byID[p.ParentID] {
+			chain = append(chain, p.ID)
+			seen[p.ID] = true
+		}
+		if rootID != RootDepartmentID {
+			chain = append(chain, rootID)
+		}
+		ancestors := make([]string, len(chain))
+		for i, id := range chain {
+			ancestors[len(chain)-1-i] = id
+		}
+		nodes = append(nodes, &Node{
+			ID:        d.ID,
+			Name:      d.Name,
+			ParentID:  d.ParentID,
+			LeaderIDs: append([]string{}, d.LeaderIDs...),
+			Ancestors: ancestors,
+		})
+	}
+	return nodes
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package directory
+
+import "time"
+
+// LeaderAssignmentCreator 同步自动创建的负责人授权的 created_by，下次同步时整体重建
+const LeaderAssignmentCreator = "directory_sync"
+
+// Node 待写入的部门
+type Node struct {
+	ID        string
+	Name      string
+	ParentID  string
+	LeaderIDs []string
+	Ancestors []string // 自根向下的部门链（含自身）
+}
+
+// Member 通讯录成员（UserID 为 union_id，与 users.id 一致）
+type Member struct {
+	UserID        string
+	Name          string
+	Status        int // 0 在职 / 1 离职
+	DepartmentIDs []string
+}
+
+// ApplyOptions 写入选项
+type ApplyOptions struct {
+	RootID     string // 同步的根部门；为 "0" 时通讯录中已不存在的用户标记为离职
+	LeaderRole string // 自动授予部门负责人的角色，为空不授予
+}
+
+// Department departments 表一行（管理 API 返回）
+type Department struct {
+	ID          string     `db:"id" json:"id"`
+	Name        string     `db:"name" json:"name"`
+	ParentID    *string    `db:"parent_id" json:"parent_id,omitempty"`
+	LeaderIDs   []string   `db:"-" json:"leader_ids"`
+	Ancestors   []string   `db:"-" json:"ancestors"`
+	MemberCount int        `db:"member_count" json:"member_count"`
+	DeletedAt   *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	SyncedAt    time.Time  `db:"synced_at" json:"synced_at"`
+}
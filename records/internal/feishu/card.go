@@ -0,0 +1,110 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"records/internal/metrics"
+
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// CardAction 消息卡片按钮回调
+type CardAction struct {
+	UserID    string                 `json:"user_id"`    // 点击者 union_id
+	ChatID    string                 `json:"chat_id"`    // 卡片所在会话
+	MessageID string                 `json:"message_id"` // 卡片消息 ID
+	Value     map[string]interface{} `json:"value"`      // 按钮 value
+}
+
+// CardActionResult 卡片回调的响应：Toast 为弹出提示；Card 非 nil 时替换原卡片内容
+type CardActionResult struct {
+	Toast     string
+	ToastType string // info/success/error/warning，空则为 info
+	Card      map[string]interface{}
+}
+
+// SendCardToUser 以机器人身份向用户（union_id）私聊发送消息卡片
+func (c *FeishuClient) SendCardToUser(ctx context.Context, userID string, card map[string]interface{}) error {
+	_, err := c.SendCardToUserWithID(ctx, userID, card)
+	return err
+}
+
+// SendCardToUserWithID 同 SendCardToUser，返回消息 ID（用于识别用户对该消息的回复）
+func (c *FeishuClient) SendCardToUserWithID(ctx context.Context, userID string, card map[string]interface{}) (string, error) {
+	content, err := json.Marshal(card)
+	if err != nil {
+		return "", fmt.Errorf("marshal card: %w", err)
+	}
+
+	resp, err := c.client.Im.Message.Create(ctx, larkim.NewCreateMessageReqBuilder().
+		ReceiveIdType(larkim.ReceiveIdTypeUnionId).
+		Body(larkim.NewCreateMessageReqBodyBuilder().
+			MsgType(larkim.MsgTypeInteractive).
+			ReceiveId(userID).
+			Content(string(content)).
+			Build()).
+		Build())
+	if err != nil {
+		metrics.FeishuSendFailures.WithLabelValues("card").Inc()
+		c.logger.WithContext(ctx).Error("Failed to send card", "error", err, "user_id", userID)
+		return "", fmt.Errorf("failed to send card: %w", err)
+	}
+	if !resp.Success() {
+		metrics.FeishuSendFailures.WithLabelValues("card").Inc()
+		c.logger.WithContext(ctx).Error("Send card failed", "code", resp.Code, "msg", resp.Msg, "user_id", userID)
+		return "", fmt.Errorf("send card failed: %d %s", resp.Code, resp.Msg)
+	}
+
+	c.logger.WithContext(ctx).Debug("Card sent successfully", "user_id", userID)
+	if resp.Data != nil && resp.Data.MessageId != nil {
+		return *resp.Data.MessageId, nil
+	}
+	return "", nil
+}
+
+// handleCardActionEvent 解析卡片回调（点击者仅含 open_id，需解析为 union_id）并交由 MessageHandler 处理
+func (c *FeishuClient) handleCardActionEvent(ctx context.Context, event *callback.CardActionTriggerEvent, messageHandler MessageHandler) (*callback.CardActionTriggerResponse, error) {
+	if event.Event == nil || event.Event.Operator == nil || event.Event.Action == nil {
+		return nil, nil
+	}
+	userID, err := c.ResolveToUnionID(ctx, event.Event.Operator.OpenID)
+	if err != nil {
+		c.logger.WithContext(ctx).Error("Failed to resolve card operator", "error", err, "open_id", event.Event.Operator.OpenID)
+		return &callback.CardActionTriggerResponse{Toast: &callback.Toast{Type: "error", Content: "操作失败，请稍后重试"}}, nil
+	}
+
+	action := &CardAction{
+		UserID: userID,
+		Value:  event.Event.Action.Value,
+	}
+	if event.Event.Context != nil {
+		action.ChatID = event.Event.Context.OpenChatID
+		action.MessageID = event.Event.Context.OpenMessageID
+	}
+	c.logger.WithContext(ctx).Info("Received card action", "user_id", userID, "message_id", action.MessageID)
+
+	result, err := messageHandler.HandleCardAction(ctx, action)
+	if err != nil {
+		c.logger.WithContext(ctx).Error("Failed to handle card action", "error", err, "user_id", userID)
+		return &callback.CardActionTriggerResponse{Toast: &callback.Toast{Type: "error", Content: "操作失败，请稍后重试"}}, nil
+	}
+	if result == nil {
+		return nil, nil
+	}
+
+	resp := &callback.CardActionTriggerResponse{}
+	if result.Toast != "" {
+		toastType := result.ToastType
+		if toastType == "" {
+			toastType = "info"
+		}
+		resp.Toast = &callback.Toast{Type: toastType, Content: result.Toast}
+	}
+	if result.Card != nil {
+		resp.Card = &callback.Card{Type: "raw", Data: result.Card}
+	}
+	return resp, nil
+}
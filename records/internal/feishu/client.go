@@ -8,29 +8,44 @@ import (
 	"time"
 
 	"records/internal/config"
+	"records/internal/metrics"
+	"records/internal/tracing"
 	"records/pkg/logger"
 
 	lark "github.com/larksuite/oapi-sdk-go/v3"
 	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
 	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher"
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
 	larkcontact "github.com/larksuite/oapi-sdk-go/v3/service/contact/v3"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 	larkws "github.com/larksuite/oapi-sdk-go/v3/ws"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Client 飞书客户端接口
 type Client interface {
 	Start(ctx context.Context, messageHandler MessageHandler) error
 	SendMessage(ctx context.Context, chatID, content string) error
+	// SendCardToUser 以机器人身份向用户（union_id）私聊发送消息卡片
+	SendCardToUser(ctx context.Context, userID string, card map[string]interface{}) error
+	// SendCardToUserWithID 同 SendCardToUser，返回消息 ID（用于识别用户对该消息的回复）
+	SendCardToUserWithID(ctx context.Context, userID string, card map[string]interface{}) (string, error)
+	// SendFileToUser 上传文件并以机器人身份私聊发送给用户（union_id）
+	SendFileToUser(ctx context.Context, userID, fileName string, data []byte) error
 	GetUserInfo(ctx context.Context, userID, userIDType string) (*UserInfo, error)
 	// ResolveToUnionID 当飞书事件仅含 open_id 时，通过 contact API 解析为 union_id（内部使用）
 	ResolveToUnionID(ctx context.Context, openID string) (unionID string, err error)
+	// ListDepartments / ListDepartmentUsers 通讯录同步：部门树与部门直属成员
+	ListDepartments(ctx context.Context, rootID string) ([]*Department, error)
+	ListDepartmentUsers(ctx context.Context, departmentID string) ([]*UserInfo, error)
 }
 
 // MessageHandler 消息处理器接口
 type MessageHandler interface {
 	HandleMessage(ctx context.Context, msg *Message) error
 	HandleUserEnter(ctx context.Context, userID, chatID string) error
+	// HandleCardAction 处理用户点击消息卡片按钮的回调
+	HandleCardAction(ctx context.Context, action *CardAction) (*CardActionResult, error)
 }
 
 // Message 消息结构
@@ -40,6 +55,9 @@ type Message struct {
 	Content   string `json:"content"`
 	MessageID string `json:"message_id"`
 	ChatType  string `json:"chat_type"`
+	// ParentID / RootID 用户回复某条消息时为被回复消息及其话题根消息的 ID，否则为空
+	ParentID string `json:"parent_id,omitempty"`
+	RootID   string `json:"root_id,omitempty"`
 }
 
 // UserInfo 用户信息
@@ -50,19 +68,40 @@ type UserInfo struct {
 	Mobile  string `json:"mobile"`
 	OrgName string `json:"org_name"`
 	Status  int    `json:"status"`
+	// DepartmentIDs 所属部门（open_department_id）
+	DepartmentIDs []string `json:"department_ids"`
 }
 
-// processedEventsCache 已处理事件的去重缓存，用于忽略飞书超时重推的重复消息
+// EventDeduper 飞书事件去重：用于忽略飞书超时重推的重复消息。
+// TryClaim 原子占用事件，返回 false 表示该事件已处理完成或正在处理；占用超过 staleAfter 仍未完成的视为处理方已崩溃，可被重新占用。
+// 处理成功后调用 Complete 标记完成，处理失败时调用 Release 以便重推后可再次处理。
+// 多实例部署时应使用数据库实现（repository.Repository），默认的内存实现仅在单实例内有效。
+type EventDeduper interface {
+	TryClaim(ctx context.Context, eventID string, staleAfter time.Duration) (bool, error)
+	Complete(ctx context.Context, eventID string) error
+	Release(ctx context.Context, eventID string) error
+}
+
+// eventHandleTimeout 单条消息的处理时限；超过该时长仍未完成的占用可被飞书重推重新处理
+const eventHandleTimeout = 10 * time.Minute
+
+// processedEvent 内存去重记录：占用或完成时间，以及是否已处理完成
+type processedEvent struct {
+	at   time.Time
+	done bool
+}
+
+// processedEventsCache 已处理事件的内存去重缓存（单实例默认实现）
 type processedEventsCache struct {
-	mu         sync.RWMutex
-	cache      map[string]time.Time
+	mu         sync.Mutex
+	cache      map[string]processedEvent
 	retention  time.Duration
 	maxEntries int
 }
 
 func newProcessedEventsCache() *processedEventsCache {
 	c := &processedEventsCache{
-		cache:      make(map[string]time.Time),
+		cache:      make(map[string]processedEvent),
 		retention:  8 * time.Hour, // 覆盖飞书约 7.1 小时的重推窗口
 		maxEntries: 10000,
 	}
@@ -70,33 +109,35 @@ func newProcessedEventsCache() *processedEventsCache {
 	return c
 }
 
-func (c *processedEventsCache) isProcessed(id string) bool {
-	c.mu.RLock()
-	t, ok := c.cache[id]
-	c.mu.RUnlock()
-	if !ok {
-		return false
-	}
-	if time.Since(t) > c.retention {
-		c.mu.Lock()
-		delete(c.cache, id)
-		c.mu.Unlock()
-		return false
-	}
-	return true
-}
-
-func (c *processedEventsCache) markProcessed(id string) {
+func (c *processedEventsCache) TryClaim(ctx context.Context, id string, staleAfter time.Duration) (bool, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.cache[id] = time.Now()
+	if e, ok := c.cache[id]; ok && time.Since(e.at) <= c.retention && (e.done || time.Since(e.at) <= staleAfter) {
+		return false, nil
+	}
+	c.cache[id] = processedEvent{at: time.Now()}
 	if len(c.cache) > c.maxEntries {
 		for k, v := range c.cache {
-			if time.Since(v) > c.retention {
+			if time.Since(v.at) > c.retention {
 				delete(c.cache, k)
 			}
 		}
 	}
+	return true, nil
+}
+
+func (c *processedEventsCache) Complete(ctx context.Context, id string) error {
+	c.mu.Lock()
+	c.cache[id] = processedEvent{at: time.Now(), done: true}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *processedEventsCache) Release(ctx context.Context, id string) error {
+	c.mu.Lock()
+	delete(c.cache, id)
+	c.mu.Unlock()
+	return nil
 }
 
 func (c *processedEventsCache) cleanupLoop() {
@@ -106,7 +147,7 @@ func (c *processedEventsCache) cleanupLoop() {
 		c.mu.Lock()
 		now := time.Now()
 		for k, v := range c.cache {
-			if now.Sub(v) > c.retention {
+			if now.Sub(v.at) > c.retention {
 				delete(c.cache, k)
 			}
 		}
@@ -120,7 +161,7 @@ type FeishuClient struct {
 	config          config.FeishuApp
 	logger          logger.Logger
 	wsClient        *larkws.Client
-	processedEvents *processedEventsCache
+	processedEvents EventDeduper
 }
 
 // NewClient 创建飞书客户端
@@ -135,6 +176,46 @@ func NewClient(cfg config.FeishuApp, logger logger.Logger) *FeishuClient {
 	}
 }
 
+// SetEventDeduper 替换事件去重实现（多实例部署时注入数据库实现），需在 Start 之前调用
+func (c *FeishuClient) SetEventDeduper(d EventDeduper) {
+	if d != nil {
+		c.processedEvents = d
+	}
+}
+
+// claimEvent 占用事件；去重存储异常时放行（宁可重复处理也不丢消息）
+func (c *FeishuClient) claimEvent(ctx context.Context, eventID string) bool {
+	if eventID == "" {
+		return true
+	}
+	claimed, err := c.processedEvents.TryClaim(ctx, eventID, eventHandleTimeout)
+	if err != nil {
+		c.logger.WithContext(ctx).Error("Failed to claim event, processing anyway", "error", err, "message_id", eventID)
+		return true
+	}
+	return claimed
+}
+
+// completeEvent 处理成功后标记事件已完成，此后的重推一律忽略
+func (c *FeishuClient) completeEvent(ctx context.Context, eventID string) {
+	if eventID == "" {
+		return
+	}
+	if err := c.processedEvents.Complete(ctx, eventID); err != nil {
+		c.logger.WithContext(ctx).Error("Failed to complete event", "error", err, "message_id", eventID)
+	}
+}
+
+// releaseEvent 处理失败时释放事件，使飞书重推可被再次处理
+func (c *FeishuClient) releaseEvent(ctx context.Context, eventID string) {
+	if eventID == "" {
+		return
+	}
+	if err := c.processedEvents.Release(ctx, eventID); err != nil {
+		c.logger.WithContext(ctx).Error("Failed to release event", "error", err, "message_id", eventID)
+	}
+}
+
 // extractUnionID 从事件 UserId 提取 union_id，若无则用 open_id 解析（飞书事件有时仅含 open_id）
 func (c *FeishuClient) extractUnionID(ctx context.Context, uid *larkim.UserId) (string, error) {
 	if uid != nil && uid.UnionId != nil && *uid.UnionId != "" {
@@ -151,74 +232,57 @@ func (c *FeishuClient) Start(ctx context.Context, messageHandler MessageHandler)
 	// 创建事件处理器
 	eventHandler := dispatcher.NewEventDispatcher("", "").
 		// 用户进入与机器人的会话
-		OnP2ChatAccessEventBotP2pChatEnteredV1(func(ctx context.Context, event *larkim.P2ChatAccessEventBotP2pChatEnteredV1) error {
+		OnP2ChatAccessEventBotP2pChatEnteredV1(func(ctx context.Context, event *larkim.P2ChatAccessEventBotP2pChatEnteredV1) (err error) {
+			ctx, span := tracing.Start(ctx, "feishu.chat_entered")
+			defer func() { tracing.End(span, err) }()
+
 			userID, err := c.extractUnionID(ctx, event.Event.OperatorId)
 			if err != nil {
-				c.logger.Error("Failed to extract union_id", "error", err)
+				c.logger.WithContext(ctx).Error("Failed to extract union_id", "error", err)
 				return err
 			}
-			c.logger.Info("User entered chat", "user_id", userID, "chat_id", *event.Event.ChatId)
+			c.logger.WithContext(ctx).Info("User entered chat", "user_id", userID, "chat_id", *event.Event.ChatId)
 			return messageHandler.HandleUserEnter(ctx, userID, *event.Event.ChatId)
 		}).
 		// 接收消息事件
-		OnP2MessageReceiveV1(func(ctx context.Context, event *larkim.P2MessageReceiveV1) error {
-			// 去重：飞书超时重推会导致同一消息多次推送，使用 message_id 忽略重复
+		OnP2MessageReceiveV1(func(ctx context.Context, event *larkim.P2MessageReceiveV1) (err error) {
+			// 去重：飞书超时重推会导致同一消息多次推送（可能落到其他实例），使用 message_id 原子占用，忽略重复
 			dedupKey := ""
 			if event.Event != nil && event.Event.Message != nil && event.Event.Message.MessageId != nil {
 				dedupKey = *event.Event.Message.MessageId
 			}
-			if dedupKey != "" && c.processedEvents.isProcessed(dedupKey) {
-				c.logger.Debug("Duplicate message ignored", "message_id", dedupKey)
+			// 每条消息一条链路的根 span，后续处理、大模型调用与回复均挂在其下
+			ctx, span := tracing.Start(ctx, "feishu.message_receive", attribute.String("feishu.message_id", dedupKey))
+			defer func() { tracing.End(span, err) }()
+
+			if !c.claimEvent(ctx, dedupKey) {
+				c.logger.WithContext(ctx).Debug("Duplicate message ignored", "message_id", dedupKey)
 				return nil
 			}
 
-			userID, err := c.extractUnionID(ctx, event.Event.Sender.SenderId)
+			// 处理时限与占用超时一致：超时未完成的占用可被重推重新占用，此时本次处理也应已放弃
+			handleCtx, cancel := context.WithTimeout(ctx, eventHandleTimeout)
+			err = c.handleMessageEvent(handleCtx, event, messageHandler)
+			cancel()
 			if err != nil {
-				c.logger.Error("Failed to extract union_id", "error", err)
+				c.releaseEvent(ctx, dedupKey)
 				return err
 			}
-			c.logger.Info("Received message", "user_id", userID, "chat_id", *event.Event.Message.ChatId)
-
-			// 检查消息类型
-			if *event.Event.Message.MessageType != "text" {
-				c.logger.Warn("Unsupported message type", "type", *event.Event.Message.MessageType)
-				err := c.SendMessage(ctx, *event.Event.Message.ChatId, "抱歉，我只能处理文本消息")
-				if err == nil && dedupKey != "" {
-					c.processedEvents.markProcessed(dedupKey)
-				}
-				return err
-			}
-
-			// 解析消息内容
-			var content map[string]string
-			if err := json.Unmarshal([]byte(*event.Event.Message.Content), &content); err != nil {
-				c.logger.Error("Failed to parse message content", "error", err)
-				sendErr := c.SendMessage(ctx, *event.Event.Message.ChatId, "消息解析失败，请重新发送")
-				if sendErr == nil && dedupKey != "" {
-					c.processedEvents.markProcessed(dedupKey)
-				}
-				return sendErr
-			}
-
-			msg := &Message{
-				UserID:    userID,
-				ChatID:    *event.Event.Message.ChatId,
-				Content:   content["text"],
-				MessageID: *event.Event.Message.MessageId,
-				ChatType:  *event.Event.Message.ChatType,
-			}
-
-			err = messageHandler.HandleMessage(ctx, msg)
-			if err == nil && dedupKey != "" {
-				c.processedEvents.markProcessed(dedupKey)
-			}
-			return err
+			c.completeEvent(ctx, dedupKey)
+			return nil
+		}).
+		// 消息卡片按钮回调
+		OnP2CardActionTrigger(func(ctx context.Context, event *callback.CardActionTriggerEvent) (resp *callback.CardActionTriggerResponse, err error) {
+			ctx, span := tracing.Start(ctx, "feishu.card_action")
+			defer func() { tracing.End(span, err) }()
+			return c.handleCardActionEvent(ctx, event, messageHandler)
 		})
 
 	// 创建WebSocket客户端
 	c.wsClient = larkws.NewClient(c.config.AppID, c.config.AppSecret,
 		larkws.WithEventHandler(eventHandler),
 		larkws.WithLogLevel(larkcore.LogLevelInfo),
+		larkws.WithLogger(newWSLogger(larkcore.LogLevelInfo)),
 	)
 
 	// 启动WebSocket连接
@@ -226,12 +290,54 @@ func (c *FeishuClient) Start(ctx context.Context, messageHandler MessageHandler)
 		return fmt.Errorf("failed to start feishu websocket client: %w", err)
 	}
 
-	c.logger.Info("Feishu client started successfully")
+	c.logger.WithContext(ctx).Info("Feishu client started successfully")
 	return nil
 }
 
+// handleMessageEvent 处理单条消息事件（已完成去重占用）
+func (c *FeishuClient) handleMessageEvent(ctx context.Context, event *larkim.P2MessageReceiveV1, messageHandler MessageHandler) error {
+	userID, err := c.extractUnionID(ctx, event.Event.Sender.SenderId)
+	if err != nil {
+		c.logger.WithContext(ctx).Error("Failed to extract union_id", "error", err)
+		return err
+	}
+	c.logger.WithContext(ctx).Info("Received message", "user_id", userID, "chat_id", *event.Event.Message.ChatId)
+
+	// 检查消息类型
+	if *event.Event.Message.MessageType != "text" {
+		c.logger.WithContext(ctx).Warn("Unsupported message type", "type", *event.Event.Message.MessageType)
+		return c.SendMessage(ctx, *event.Event.Message.ChatId, "抱歉，我只能处理文本消息")
+	}
+
+	// 解析消息内容
+	var content map[string]string
+	if err := json.Unmarshal([]byte(*event.Event.Message.Content), &content); err != nil {
+		c.logger.WithContext(ctx).Error("Failed to parse message content", "error", err)
+		return c.SendMessage(ctx, *event.Event.Message.ChatId, "消息解析失败，请重新发送")
+	}
+
+	msg := &Message{
+		UserID:    userID,
+		ChatID:    *event.Event.Message.ChatId,
+		Content:   content["text"],
+		MessageID: *event.Event.Message.MessageId,
+		ChatType:  *event.Event.Message.ChatType,
+	}
+	if event.Event.Message.ParentId != nil {
+		msg.ParentID = *event.Event.Message.ParentId
+	}
+	if event.Event.Message.RootId != nil {
+		msg.RootID = *event.Event.Message.RootId
+	}
+
+	return messageHandler.HandleMessage(ctx, msg)
+}
+
 // SendMessage 发送文本消息
-func (c *FeishuClient) SendMessage(ctx context.Context, chatID, content string) error {
+func (c *FeishuClient) SendMessage(ctx context.Context, chatID, content string) (err error) {
+	ctx, span := tracing.Start(ctx, "feishu.send_message")
+	defer func() { tracing.End(span, err) }()
+
 	// 构建消息内容，使用 json.Marshal 处理文本内容的转义，防止下面构造 json 结构体时报错
 	escapedContent, _ := json.Marshal(content)
 
@@ -250,16 +356,18 @@ func (c *FeishuClient) SendMessage(ctx context.Context, chatID, content string)
 		Build())
 
 	if err != nil {
-		c.logger.Error("Failed to send message", "error", err, "chat_id", chatID)
+		metrics.FeishuSendFailures.WithLabelValues("text").Inc()
+		c.logger.WithContext(ctx).Error("Failed to send message", "error", err, "chat_id", chatID)
 		return fmt.Errorf("failed to send message: %w", err)
 	}
 
 	if !resp.Success() {
-		c.logger.Error("Send message failed", "code", resp.Code, "msg", resp.Msg, "chat_id", chatID)
+		metrics.FeishuSendFailures.WithLabelValues("text").Inc()
+		c.logger.WithContext(ctx).Error("Send message failed", "code", resp.Code, "msg", resp.Msg, "chat_id", chatID)
 		return fmt.Errorf("send message failed: %d %s", resp.Code, resp.Msg)
 	}
 
-	c.logger.Debug("Message sent successfully", "chat_id", chatID)
+	c.logger.WithContext(ctx).Debug("Message sent successfully", "chat_id", chatID)
 	return nil
 }
 
@@ -322,12 +430,12 @@ func (c *FeishuClient) GetUserInfo(ctx context.Context, userID, userIDType strin
 
 	resp, err := c.client.Contact.V3.User.Get(ctx, req)
 	if err != nil {
-		c.logger.Error("Failed to get user info", "error", err, "user_id", userID)
+		c.logger.WithContext(ctx).Error("Failed to get user info", "error", err, "user_id", userID)
 		return nil, fmt.Errorf("failed to get user info: %w", err)
 	}
 
 	if !resp.Success() {
-		c.logger.Error("Get user info failed", "code", resp.CodeError.Code, "user_id", userID)
+		c.logger.WithContext(ctx).Error("Get user info failed", "code", resp.CodeError.Code, "user_id", userID)
 		return nil, fmt.Errorf("get user info failed: %d", resp.CodeError.Code)
 	}
 
@@ -348,6 +456,12 @@ func (c *FeishuClient) GetUserInfo(ctx context.Context, userID, userIDType strin
 		userInfo.Status = 1
 	}
 
+	for _, depId := range user.DepartmentIds {
+		if depId != "0" {
+			userInfo.DepartmentIDs = append(userInfo.DepartmentIDs, depId)
+		}
+	}
+
 	// 获取组织名称
 	departmentId := ""
 	for _, depId := range user.DepartmentIds {
@@ -358,12 +472,12 @@ func (c *FeishuClient) GetUserInfo(ctx context.Context, userID, userIDType strin
 	}
 	orgName, err := c.getOrgnameByOrgId(ctx, departmentId)
 	if err != nil {
-		c.logger.Error("Failed to get org name", "error", err, "department_id", departmentId)
+		c.logger.WithContext(ctx).Error("Failed to get org name", "error", err, "department_id", departmentId)
 	}
 
 	parentOrgName, err := c.getParentOrgNameByOrgId(ctx, departmentId)
 	if err != nil {
-		c.logger.Error("Failed to get parent org name", "error", err, "department_id", departmentId)
+		c.logger.WithContext(ctx).Error("Failed to get parent org name", "error", err, "department_id", departmentId)
 	}
 
 	if parentOrgName != "" {
@@ -399,12 +513,12 @@ func (c *FeishuClient) GetUserByMobile(ctx context.Context, mobile string) (stri
 
 	resp, err := c.client.Contact.V3.User.BatchGetId(ctx, req)
 	if err != nil {
-		c.logger.Error("Failed to get user by mobile", "error", err, "mobile", mobile)
+		c.logger.WithContext(ctx).Error("Failed to get user by mobile", "error", err, "mobile", mobile)
 		return "", fmt.Errorf("failed to get user by mobile: %w", err)
 	}
 
 	if !resp.Success() {
-		c.logger.Error("Get user by mobile failed", "code", resp.CodeError.Code, "mobile", mobile)
+		c.logger.WithContext(ctx).Error("Get user by mobile failed", "code", resp.CodeError.Code, "mobile", mobile)
 		return "", fmt.Errorf("get user by mobile failed: %d", resp.CodeError.Code)
 	}
 
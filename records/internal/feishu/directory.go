@@ -0,0 +1,126 @@
+package feishu
+
+import (
+	"context"
+	"fmt"
+
+	larkcontact "github.com/larksuite/oapi-sdk-go/v3/service/contact/v3"
+)
+
+// directoryPageSize 通讯录分页大小（接口上限 50）
+const directoryPageSize = 50
+
+// Department 通讯录部门；ID 为 open_department_id，负责人为 union_id
+type Department struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	ParentID  string   `json:"parent_id"` // 根部门下为 "0"
+	LeaderIDs []string `json:"leader_ids"`
+}
+
+// ListDepartments 递归列出 rootID（"0" 为根部门）下的全部子部门，不含已删除部门
+func (c *FeishuClient) ListDepartments(ctx context.Context, rootID string) ([]*Department, error) {
+	var list []*Department
+	pageToken := ""
+	for {
+		builder := larkcontact.NewChildrenDepartmentReqBuilder().
+			DepartmentId(rootID).
+			UserIdType("union_id").
+			DepartmentIdType("open_department_id").
+			FetchChild(true).
+			PageSize(directoryPageSize)
+		if pageToken != "" {
+			builder = builder.PageToken(pageToken)
+		}
+		resp, err := c.client.Contact.V3.Department.Children(ctx, builder.Build())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list departments: %w", err)
+		}
+		if !resp.Success() {
+			return nil, fmt.Errorf("list departments failed: %d %s", resp.Code, resp.Msg)
+		}
+		for _, item := range resp.Data.Items {
+			if item.OpenDepartmentId == nil || (item.Status != nil && item.Status.IsDeleted != nil && *item.Status.IsDeleted) {
+				continue
+			}
+			d := &Department{ID: *item.OpenDepartmentId}
+			if item.Name != nil {
+				d.Name = *item.Name
+			}
+			if item.ParentDepartmentId != nil {
+				d.ParentID = *item.ParentDepartmentId
+			}
+			if item.LeaderUserId != nil && *item.LeaderUserId != "" {
+				d.LeaderIDs = append(d.LeaderIDs, *item.LeaderUserId)
+			}
+			for _, l := range item.Leaders {
+				if l != nil && l.LeaderID != nil && *l.LeaderID != "" && !containsID(d.LeaderIDs, *l.LeaderID) {
+					d.LeaderIDs = append(d.LeaderIDs, *l.LeaderID)
+				}
+			}
+			list = append(list, d)
+		}
+		if resp.Data.HasMore == nil || !*resp.Data.HasMore || resp.Data.PageToken == nil {
+			return list, nil
+		}
+		pageToken = *resp.Data.PageToken
+	}
+}
+
+// ListDepartmentUsers 列出部门的直属成员（UserID 为 union_id）
+func (c *FeishuClient) ListDepartmentUsers(ctx context.Context, departmentID string) ([]*UserInfo, error) {
+	var list []*UserInfo
+	pageToken := ""
+	for {
+		builder := larkcontact.NewFindByDepartmentUserReqBuilder().
+			DepartmentId(departmentID).
+			UserIdType("union_id").
+			DepartmentIdType("open_department_id").
+			PageSize(directoryPageSize)
+		if pageToken != "" {
+			builder = builder.PageToken(pageToken)
+		}
+		resp, err := c.client.Contact.V3.User.FindByDepartment(ctx, builder.Build())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list department users: %w", err)
+		}
+		if !resp.Success() {
+			return nil, fmt.Errorf("list department users failed: %d %s", resp.Code, resp.Msg)
+		}
+		for _, u := range resp.Data.Items {
+			if u.UnionId == nil || *u.UnionId == "" {
+				continue
+			}
+			info := &UserInfo{UserID: *u.UnionId, UnionID: *u.UnionId}
+			if u.Name != nil {
+				info.Name = *u.Name
+			}
+			if u.Mobile != nil {
+				info.Mobile = *u.Mobile
+			}
+			// 0 在职 / 1 离职（已离职或主动退出）
+			if u.Status != nil && ((u.Status.IsResigned != nil && *u.Status.IsResigned) || (u.Status.IsExited != nil && *u.Status.IsExited)) {
+				info.Status = 1
+			}
+			for _, id := range u.DepartmentIds {
+				if id != "0" {
+					info.DepartmentIDs = append(info.DepartmentIDs, id)
+				}
+			}
+			list = append(list, info)
+		}
+		if resp.Data.HasMore == nil || !*resp.Data.HasMore || resp.Data.PageToken == nil {
+			return list, nil
+		}
+		pageToken = *resp.Data.PageToken
+	}
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package feishu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"records/internal/metrics"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// SendFileToUser 上传文件并以机器人身份私聊发送给用户（union_id）
+func (c *FeishuClient) SendFileToUser(ctx context.Context, userID, fileName string, data []byte) error {
+	fileType := larkim.FileTypeStream
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".doc", ".docx":
+		fileType = larkim.FileTypeDoc
+	case ".pdf":
+		fileType = larkim.FileTypePdf
+	}
+
+	upload, err := c.client.Im.File.Create(ctx, larkim.NewCreateFileReqBuilder().
+		Body(larkim.NewCreateFileReqBodyBuilder().
+			FileType(fileType).
+			FileName(fileName).
+			File(bytes.NewReader(data)).
+			Build()).
+		Build())
+	if err != nil {
+		metrics.FeishuSendFailures.WithLabelValues("file").Inc()
+		c.logger.WithContext(ctx).Error("Failed to upload file", "error", err, "file_name", fileName)
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if !upload.Success() || upload.Data == nil || upload.Data.FileKey == nil {
+		metrics.FeishuSendFailures.WithLabelValues("file").Inc()
+		c.logger.WithContext(ctx).Error("Upload file failed", "code", upload.Code, "msg", upload.Msg, "file_name", fileName)
+		return fmt.Errorf("upload file failed: %d %s", upload.Code, upload.Msg)
+	}
+
+	content, _ := json.Marshal(map[string]string{"file_key": *upload.Data.FileKey})
+	resp, err := c.client.Im.Message.Create(ctx, larkim.NewCreateMessageReqBuilder().
+		ReceiveIdType(larkim.ReceiveIdTypeUnionId).
+		Body(larkim.NewCreateMessageReqBodyBuilder().
+			MsgType(larkim.MsgTypeFile).
+			ReceiveId(userID).
+			Content(string(content)).
+			Build()).
+		Build())
+	if err != nil {
+		metrics.FeishuSendFailures.WithLabelValues("file").Inc()
+		c.logger.WithContext(ctx).Error("Failed to send file", "error", err, "user_id", userID)
+		return fmt.Errorf("failed to send file: %w", err)
+	}
+	if !resp.Success() {
+		metrics.FeishuSendFailures.WithLabelValues("file").Inc()
+		c.logger.WithContext(ctx).Error("Send file failed", "code", resp.Code, "msg", resp.Msg, "user_id", userID)
+		return fmt.Errorf("send file failed: %d %s", resp.Code, resp.Msg)
+	}
+
+	c.logger.WithContext(ctx).Debug("File sent successfully", "user_id", userID, "file_name", fileName)
+	return nil
+}
@@ -0,0 +1,30 @@
+package feishu
+
+import (
+	"context"
+	"strings"
+
+	"records/internal/metrics"
+
+	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+)
+
+// wsLogger 长连接客户端日志：转交 SDK 默认日志，并从重连日志统计重连次数（SDK 未提供重连回调）
+type wsLogger struct {
+	larkcore.Logger
+}
+
+// newWSLogger 创建长连接日志；自定义日志不经 SDK 的级别过滤，故包装按 level 过滤的默认日志
+func newWSLogger(level larkcore.LogLevel) *wsLogger {
+	return &wsLogger{Logger: larkcore.NewDefaultLogger(level)}
+}
+
+// Info 重连日志为 "trying to reconnect: N"
+func (l *wsLogger) Info(ctx context.Context, args ...interface{}) {
+	if len(args) > 0 {
+		if msg, ok := args[0].(string); ok && strings.HasPrefix(msg, "trying to reconnect") {
+			metrics.FeishuReconnects.Inc()
+		}
+	}
+	l.Logger.Info(ctx, args...)
+}
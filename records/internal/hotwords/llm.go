@@ -4,13 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"records/internal/metrics"
+	"records/internal/redact"
+	"records/internal/tracing"
 	"records/pkg/logger"
 	"regexp"
 	"strings"
+	"time"
 
 	jsonrepair "github.com/RealAlexandreAI/json-repair"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ExtractorConfig LLM 调用配置
@@ -20,7 +25,8 @@ type ExtractorConfig struct {
 	ModelName           string
 	Temperature         float64
 	MaxCompletionTokens int64
-	SystemPrompt        string // 热词抽取的 system prompt，空则使用 defaultSystemPrompt
+	SystemPrompt        string           // 热词抽取的 system prompt，空则使用 defaultSystemPrompt
+	Redactor            *redact.Redactor // 非空时日志文本发送前脱敏（热词不需要手机号等原值，不还原）
 }
 
 // Extractor 热词抽取 LLM 调用
@@ -58,10 +64,12 @@ func extractFinalContent(raw string) string {
 // Extract 从日志文本中抽取四类关键词，返回结构化结果
 func (e *Extractor) Extract(ctx context.Context, logsText string) (*ExtractedPayload, error) {
 	systemPrompt := e.cfg.SystemPrompt
-	userPrompt := "销售日志：\n\n" + logsText
+	userPrompt := "销售日志：\n\n" + e.cfg.Redactor.Mask(logsText)
 
-	e.log.Debug("hotwords extract: user prompt", "prompt", userPrompt)
+	e.log.WithContext(ctx).Debug("hotwords extract: user prompt", "prompt", userPrompt)
 
+	ctx, span := tracing.Start(ctx, "llm.hotwords.Extract", attribute.String("llm.model", e.cfg.ModelName))
+	start := time.Now()
 	resp, err := e.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
 		Model: openai.ChatModel(e.cfg.ModelName),
 		Messages: []openai.ChatCompletionMessageParamUnion{
@@ -71,12 +79,18 @@ func (e *Extractor) Extract(ctx context.Context, logsText string) (*ExtractedPay
 		Temperature:         openai.Float(e.cfg.Temperature),
 		MaxCompletionTokens: openai.Int(int64(e.cfg.MaxCompletionTokens)),
 	})
+	var promptTokens, completionTokens int64
+	if resp != nil {
+		promptTokens, completionTokens = resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+	}
+	metrics.ObserveLLM("hotwords.Extract", e.cfg.ModelName, start, promptTokens, completionTokens, err)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, fmt.Errorf("hotwords extract completion: %w", err)
 	}
 	content := extractFinalContent(resp.Choices[0].Message.Content)
 
-	e.log.Debug("hotwords extract: response", "response", content)
+	e.log.WithContext(ctx).Debug("hotwords extract: response", "response", content)
 
 	var payload ExtractedPayload
 	if err := json.Unmarshal([]byte(content), &payload); err != nil {
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"records/internal/metrics"
 	"records/pkg/logger"
 
 	"github.com/jmoiron/sqlx"
@@ -40,18 +41,25 @@ func NewPipeline(db *sqlx.DB, extractor *Extractor, cfg PipelineConfig, log logg
 
 // Run 执行全流程：抽取日志 -> LLM 抽取 -> 同义词归一 -> 写关键词表 -> 统计 -> 写统计表 -> 生成 H5
 func (p *Pipeline) Run(ctx context.Context) error {
+	start := time.Now()
+	err := p.run(ctx)
+	metrics.HotwordsDuration.WithLabelValues(metrics.Result(err)).Observe(metrics.Since(start))
+	return err
+}
+
+func (p *Pipeline) run(ctx context.Context) error {
 	repo := NewRepo(p.db)
 	synonyms, err := repo.LoadSynonyms(ctx)
 	if err != nil {
 		return fmt.Errorf("load synonyms: %w", err)
 	}
-	p.log.Info("hotwords pipeline: loaded synonyms", "count", len(synonyms))
+	p.log.WithContext(ctx).Info("hotwords pipeline: loaded synonyms", "count", len(synonyms))
 
 	logs, err := repo.ListFollowLogsForExtract(ctx, p.cfg.IncrementalSince)
 	if err != nil {
 		return fmt.Errorf("list follow logs: %w", err)
 	}
-	p.log.Info("hotwords pipeline: follow logs to process", "total", len(logs))
+	p.log.WithContext(ctx).Info("hotwords pipeline: follow logs to process", "total", len(logs))
 
 	now := time.Now()
 	var totalInserted int
@@ -71,13 +79,13 @@ func (p *Pipeline) Run(ctx context.Context) error {
 		}
 		totalInserted += len(records)
 	}
-	p.log.Info("hotwords pipeline: keyword records inserted", "count", totalInserted)
+	p.log.WithContext(ctx).Info("hotwords pipeline: keyword records inserted", "count", totalInserted)
 
 	runTime := time.Now()
 	if err := BuildAndPersistStats(ctx, p.db, runTime, p.cfg.LimitPerCategory); err != nil {
 		return fmt.Errorf("build and persist stats: %w", err)
 	}
-	p.log.Info("hotwords pipeline: stats persisted", "run_time", runTime)
+	p.log.WithContext(ctx).Info("hotwords pipeline: stats persisted", "run_time", runTime)
 	// 热词展示由 records/pages/hot_words.html 动态页通过 API 拉取每日统计
 	return nil
 }
@@ -51,7 +51,7 @@ SELECT id,
   E'\n风险：' || COALESCE(risk_content,'')
   AS log_text
 FROM follow_records
-WHERE created_at > $1
+WHERE created_at > $1 AND deleted_at IS NULL
 ORDER BY created_at ASC`
 	var rows []FollowLogForExtract
 	if err := r.db.SelectContext(ctx, &rows, query, cutoff); err != nil {
@@ -0,0 +1,441 @@
+// Package importer 从 CSV / Excel 批量导入历史跟进记录：列映射、逐行校验、预览（dry-run）与按批次提交/回滚
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"records/internal/models"
+	"records/internal/repository"
+	"records/internal/spreadsheet"
+	"records/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxRows 单次导入的最大数据行数
+const MaxRows = 5000
+
+// ErrHasInvalidRows 存在校验失败的行且未允许跳过
+var ErrHasInvalidRows = errors.New("import has invalid rows")
+
+// Options 导入选项
+type Options struct {
+	FileName   string
+	Mapping    map[string]string // 自定义列映射：表头 -> 字段（FieldIgnore 表示忽略），未指定的列按别名自动识别
+	ImporterID string            // 执行导入的用户，无销售列时记录归属于该用户
+	// AllowedUserIDs 可作为记录归属的销售；nil 表示不限（管理员/CLI）
+	AllowedUserIDs []string
+	// SkipInvalid 提交时跳过校验失败的行；为 false 时存在失败行则整体不导入
+	SkipInvalid bool
+}
+
+// RowResult 单行校验/导入结果；Row 为表格中的行号（表头为第 1 行）
+type RowResult struct {
+	Row           int      `json:"row"`
+	CustomerName  string   `json:"customer_name,omitempty"`
+	CustomerMatch string   `json:"customer_match,omitempty"` // existing/new
+	UserID        string   `json:"user_id,omitempty"`
+	UserName      string   `json:"user_name,omitempty"`
+	FollowTime    string   `json:"follow_time,omitempty"`
+	Errors        []string `json:"errors,omitempty"`
+
+	record *models.FollowRecord
+}
+
+// Result 导入结果
+type Result struct {
+	DryRun       bool              `json:"dry_run"`
+	BatchID      *uuid.UUID        `json:"batch_id,omitempty"`
+	Columns      map[string]string `json:"columns"` // 表头 -> 识别的字段
+	TotalRows    int               `json:"total_rows"`
+	ValidRows    int               `json:"valid_rows"`
+	InvalidRows  int               `json:"invalid_rows"`
+	ImportedRows int               `json:"imported_rows"`
+	NewCustomers int               `json:"new_customers"`
+	Rows         []*RowResult      `json:"rows"`
+
+	customerIDs map[string]uuid.UUID // 校验时匹配到的已有客户：客户名 -> id
+}
+
+// CustomerMatcher 按客户名匹配已有客户（只读，可能查询外部 CRM）；found 为 false 表示提交时将新建本地客户。
+// 预览与提交共用同一次匹配结果，匹配在事务外完成，事务内只做本地写入
+type CustomerMatcher interface {
+	MatchCustomer(ctx context.Context, name string) (id uuid.UUID, found bool, err error)
+}
+
+// Importer 跟进记录导入器
+type Importer struct {
+	db        *sqlx.DB
+	log       logger.Logger
+	customers CustomerMatcher // 按客户名匹配已有客户，默认本地精确匹配
+}
+
+// New 创建导入器
+func New(db *sqlx.DB, log logger.Logger) *Importer {
+	return &Importer{db: db, log: log, customers: repository.New(db)}
+}
+
+// SetCustomerMatcher 替换客户匹配实现（启用 CRM 同步时注入，与对话中的客户匹配一致）
+func (im *Importer) SetCustomerMatcher(m CustomerMatcher) {
+	if m != nil {
+		im.customers = m
+	}
+}
+
+// Preview 解析并校验表格，不写入数据库
+func (im *Importer) Preview(ctx context.Context, r io.Reader, opts Options) (*Result, error) {
+	res, err := im.validate(ctx, r, opts)
+	if err != nil {
+		return nil, err
+	}
+	res.DryRun = true
+	return res, nil
+}
+
+// Commit 校验后在一个事务内写入全部有效行并生成导入批次；存在失败行且未设置 SkipInvalid 时返回 ErrHasInvalidRows 与校验结果
+func (im *Importer) Commit(ctx context.Context, r io.Reader, opts Options) (*Result, error) {
+	res, err := im.validate(ctx, r, opts)
+	if err != nil {
+		return nil, err
+	}
+	if res.InvalidRows > 0 && !opts.SkipInvalid {
+		return res, ErrHasInvalidRows
+	}
+	if res.ValidRows == 0 {
+		return res, fmt.Errorf("no valid rows to import")
+	}
+
+	// 导入的记录以导入人身份写入版本历史
+	ctx = repository.WithAudit(ctx, opts.ImporterID, models.RecordSourceImport)
+	batchID := uuid.New()
+	repo := repository.New(im.db)
+	err = repo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := repo.CreateImportBatch(txCtx, &models.ImportBatch{
+			ID:        batchID,
+			UserID:    opts.ImporterID,
+			FileName:  opts.FileName,
+			Status:    models.ImportBatchCommitted,
+			TotalRows: res.TotalRows,
+		}); err != nil {
+			return err
+		}
+		// 已匹配的客户在校验时（事务外）解析；未匹配的在事务内新建本地客户并记入批次，回滚时一并删除
+		customers := make(map[string]uuid.UUID, len(res.customerIDs))
+		for name, id := range res.customerIDs {
+			customers[name] = id
+		}
+		imported, newCustomers := 0, 0
+		for _, row := range res.Rows {
+			if len(row.Errors) > 0 {
+				continue
+			}
+			rec := row.record
+			customerID, ok := customers[rec.CustomerName]
+			if !ok {
+				id, created, err := repo.FindOrCreateCustomer(txCtx, rec.CustomerName)
+				if err != nil {
+					return fmt.Errorf("row %d: %w", row.Row, err)
+				}
+				if created {
+					if err := repo.TagCustomerImportBatch(txCtx, id, batchID); err != nil {
+						return err
+					}
+					newCustomers++
+				}
+				customers[rec.CustomerName] = id
+				customerID = id
+			}
+			rec.CustomerID = customerID
+			if err := repo.CreateImportedFollowRecord(txCtx, rec, batchID); err != nil {
+				return fmt.Errorf("row %d: %w", row.Row, err)
+			}
+			imported++
+		}
+		res.ImportedRows = imported
+		res.NewCustomers = newCustomers
+		return repo.UpdateImportBatchCounts(txCtx, batchID, imported, newCustomers)
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.BatchID = &batchID
+	// 提交结果只保留失败行，避免响应过大
+	var failed []*RowResult
+	for _, row := range res.Rows {
+		if len(row.Errors) > 0 {
+			failed = append(failed, row)
+		}
+	}
+	res.Rows = failed
+	im.log.WithContext(ctx).Info("Follow records imported", "batch_id", batchID, "user_id", opts.ImporterID, "rows", res.ImportedRows, "new_customers", res.NewCustomers)
+	return res, nil
+}
+
+// Rollback 回滚导入批次；已回滚的批次返回错误。被删除记录的版本历史操作人取自 ctx（repository.WithAudit）
+func (im *Importer) Rollback(ctx context.Context, batchID uuid.UUID) (records, customers int64, err error) {
+	repo := repository.New(im.db)
+	err = repo.WithTx(ctx, func(txCtx context.Context) error {
+		batch, err := repo.GetImportBatch(txCtx, batchID)
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			return fmt.Errorf("import batch %s not found", batchID)
+		}
+		if batch.Status == models.ImportBatchRolledBack {
+			return fmt.Errorf("import batch %s already rolled back", batchID)
+		}
+		records, customers, err = repo.RollbackImportBatch(txCtx, batchID)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	im.log.WithContext(ctx).Info("Import batch rolled back", "batch_id", batchID, "records", records, "customers", customers)
+	return records, customers, nil
+}
+
+// validate 读取表格并逐行校验，解析销售与客户匹配情况
+func (im *Importer) validate(ctx context.Context, r io.Reader, opts Options) (*Result, error) {
+	format := spreadsheet.FormatFromFileName(opts.FileName)
+	if format == "" {
+		return nil, fmt.Errorf("unsupported file type: %s (csv or xlsx)", opts.FileName)
+	}
+	rows, err := spreadsheet.ReadAll(r, format, MaxRows+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty spreadsheet")
+	}
+	header := rows[0]
+	cols := resolveColumns(header, opts.Mapping)
+	res := &Result{Columns: make(map[string]string, len(header)), customerIDs: make(map[string]uuid.UUID)}
+	hasField := make(map[string]bool)
+	for i, h := range header {
+		res.Columns[strings.TrimSpace(h)] = cols[i]
+		if cols[i] != "" {
+			hasField[cols[i]] = true
+		}
+	}
+	for _, required := range []string{FieldCustomerName, FieldFollowTime} {
+		if !hasField[required] {
+			return nil, fmt.Errorf("missing required column: %s", required)
+		}
+	}
+	if !hasField[FieldFollowContent] && !hasField[FieldFollowResult] {
+		return nil, fmt.Errorf("missing required column: %s or %s", FieldFollowContent, FieldFollowResult)
+	}
+
+	repo := repository.New(im.db)
+	users := make(map[string]*models.User)  // 销售列取值 -> 用户
+	userErrs := make(map[string]string)     // 销售列取值 -> 错误
+	customerExists := make(map[string]bool) // 客户名 -> 是否已存在
+	newCustomers := make(map[string]bool)
+	seen := make(map[string]int) // 文件内去重：销售|客户|时间|内容 -> 行号
+
+	for i, cells := range rows[1:] {
+		rowNum := i + 2
+		if isBlankRow(cells) {
+			continue
+		}
+		res.TotalRows++
+		if res.TotalRows > MaxRows {
+			return nil, fmt.Errorf("too many rows: max %d", MaxRows)
+		}
+
+		values := make(map[string]string)
+		for j, f := range cols {
+			if f != "" && j < len(cells) {
+				values[f] = strings.TrimSpace(cells[j])
+			}
+		}
+		row := &RowResult{Row: rowNum, CustomerName: values[FieldCustomerName]}
+		res.Rows = append(res.Rows, row)
+		addErr := func(format string, args ...interface{}) {
+			row.Errors = append(row.Errors, fmt.Sprintf(format, args...))
+		}
+
+		if values[FieldCustomerName] == "" {
+			addErr("客户名称为空")
+		}
+		followTime, ok := parseFollowTime(values[FieldFollowTime])
+		if values[FieldFollowTime] == "" {
+			addErr("跟进时间为空")
+		} else if !ok {
+			addErr("无法识别的跟进时间：%s", values[FieldFollowTime])
+		} else {
+			row.FollowTime = followTime.Format(time.RFC3339)
+		}
+		if values[FieldFollowContent] == "" && values[FieldFollowResult] == "" {
+			addErr("跟进内容与跟进结果均为空")
+		}
+		for f, max := range fieldMaxRunes {
+			if n := utf8.RuneCountInString(values[f]); n > max {
+				addErr("%s 超过 %d 字", f, max)
+			}
+		}
+
+		// 销售：未提供时归属导入人
+		userKey := values[FieldUser]
+		if userKey == "" {
+			userKey = opts.ImporterID
+		}
+		u, cached := users[userKey]
+		if !cached && userErrs[userKey] == "" {
+			found, err := repo.FindActiveUsersByIDOrName(ctx, userKey)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case len(found) == 0:
+				userErrs[userKey] = fmt.Sprintf("找不到在职销售：%s", userKey)
+			case len(found) > 1:
+				userErrs[userKey] = fmt.Sprintf("销售姓名重复，请改用用户 ID：%s", userKey)
+			case opts.AllowedUserIDs != nil && !contains(opts.AllowedUserIDs, found[0].ID):
+				userErrs[userKey] = fmt.Sprintf("无权为该销售导入记录：%s", userKey)
+			default:
+				u = found[0]
+				users[userKey] = u
+			}
+		}
+		if msg := userErrs[userKey]; msg != "" {
+			addErr("%s", msg)
+		} else if u != nil {
+			row.UserID, row.UserName = u.ID, u.Name
+		}
+
+		if name := values[FieldCustomerName]; name != "" {
+			exists, ok := customerExists[name]
+			if !ok {
+				id, found, err := im.customers.MatchCustomer(ctx, name)
+				if err != nil {
+					return nil, err
+				}
+				if found {
+					res.customerIDs[name] = id
+				}
+				exists = found
+				customerExists[name] = exists
+			}
+			if exists {
+				row.CustomerMatch = "existing"
+			} else {
+				row.CustomerMatch = "new"
+			}
+		}
+
+		if len(row.Errors) == 0 {
+			key := strings.Join([]string{row.UserID, values[FieldCustomerName], row.FollowTime, values[FieldFollowContent], values[FieldFollowResult]}, "|")
+			if prev, dup := seen[key]; dup {
+				addErr("与第 %d 行重复", prev)
+			} else {
+				seen[key] = rowNum
+				dupInDB, err := repo.FollowRecordExists(ctx, row.UserID, values[FieldCustomerName], followTime)
+				if err != nil {
+					return nil, err
+				}
+				if dupInDB {
+					addErr("已存在相同销售、客户与跟进时间的记录")
+				}
+			}
+		}
+
+		if len(row.Errors) > 0 {
+			res.InvalidRows++
+			continue
+		}
+		res.ValidRows++
+		if row.CustomerMatch == "new" {
+			newCustomers[values[FieldCustomerName]] = true
+		}
+		row.record = &models.FollowRecord{
+			ID:            uuid.New(),
+			UserID:        row.UserID,
+			CustomerName:  values[FieldCustomerName],
+			FollowTime:    followTime,
+			ContactPerson: optional(values[FieldContactPerson]),
+			ContactPhone:  optional(values[FieldContactPhone]),
+			ContactRole:   optional(values[FieldContactRole]),
+			FollowMethod:  optional(values[FieldFollowMethod]),
+			FollowContent: optional(values[FieldFollowContent]),
+			FollowGoal:    optional(values[FieldFollowGoal]),
+			FollowResult:  optional(values[FieldFollowResult]),
+			RiskContent:   optional(values[FieldRiskContent]),
+			NextPlan:      optional(values[FieldNextPlan]),
+		}
+	}
+	res.NewCustomers = len(newCustomers)
+	return res, nil
+}
+
+// timeLayouts 支持的跟进时间格式（本地时间）
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006/1/2",
+	"2006.1.2",
+	"2006年1月2日 15:04",
+	"2006年1月2日",
+	"20060102",
+}
+
+// parseFollowTime 解析跟进时间：常见日期格式、RFC3339 或 Excel 日期序列号
+func parseFollowTime(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	// Excel 序列号：1900 日期系统，以 1899-12-30 为 0；限制在 1955~2173 年间避免误判普通数字
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f > 20000 && f < 100000 {
+		days := math.Floor(f)
+		secs := math.Round((f - days) * 86400)
+		base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.Local)
+		return base.AddDate(0, 0, int(days)).Add(time.Duration(secs) * time.Second), true
+	}
+	return time.Time{}, false
+}
+
+func isBlankRow(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
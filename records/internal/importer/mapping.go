@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"strings"
+)
+
+// 可导入的字段（与 follow_records 列同名）；FieldUser 为销售（用户 ID 或姓名）
+const (
+	FieldCustomerName  = "customer_name"
+	FieldUser          = "user"
+	FieldFollowTime    = "follow_time"
+	FieldFollowMethod  = "follow_method"
+	FieldContactPerson = "contact_person"
+	FieldContactPhone  = "contact_phone"
+	FieldContactRole   = "contact_role"
+	FieldFollowGoal    = "follow_goal"
+	FieldFollowContent = "follow_content"
+	FieldFollowResult  = "follow_result"
+	FieldRiskContent   = "risk_content"
+	FieldNextPlan      = "next_plan"
+
+	// FieldIgnore 在自定义映射中表示忽略该列
+	FieldIgnore = "-"
+)
+
+// headerAliases 表头别名（含导出文件的表头），用于自动识别列
+var headerAliases = map[string][]string{
+	FieldCustomerName:  {"客户名称", "客户", "客户名", "customer_name", "customer"},
+	FieldUser:          {"销售", "跟进人", "负责人", "user", "user_id", "user_name", "sales"},
+	FieldFollowTime:    {"跟进时间", "时间", "日期", "跟进日期", "follow_time"},
+	FieldFollowMethod:  {"跟进方式", "方式", "follow_method"},
+	FieldContactPerson: {"联系人", "contact_person"},
+	FieldContactPhone:  {"联系电话", "电话", "手机", "手机号", "contact_phone"},
+	FieldContactRole:   {"联系人角色", "职位", "角色", "contact_role"},
+	FieldFollowGoal:    {"跟进目标", "目标", "follow_goal"},
+	FieldFollowContent: {"跟进内容", "内容", "follow_content"},
+	FieldFollowResult:  {"跟进结果", "结果", "follow_result"},
+	FieldRiskContent:   {"风险", "风险内容", "risk_content", "risk"},
+	FieldNextPlan:      {"下一步计划", "下一步", "计划", "next_plan"},
+}
+
+// fieldMaxRunes 各字段长度上限（与表结构一致）
+var fieldMaxRunes = map[string]int{
+	FieldCustomerName:  255,
+	FieldFollowMethod:  255,
+	FieldContactPerson: 255,
+	FieldContactPhone:  255,
+	FieldContactRole:   255,
+	FieldFollowGoal:    2000,
+	FieldFollowContent: 2000,
+	FieldFollowResult:  2000,
+	FieldRiskContent:   2000,
+	FieldNextPlan:      2000,
+}
+
+// ValidField 字段名是否可用于映射
+func ValidField(f string) bool {
+	if f == FieldIgnore {
+		return true
+	}
+	_, ok := headerAliases[f]
+	return ok
+}
+
+// resolveColumns 根据表头与自定义映射（表头 -> 字段）确定每列对应的字段；未识别的列为空
+func resolveColumns(header []string, mapping map[string]string) []string {
+	aliasToField := make(map[string]string)
+	for field, aliases := range headerAliases {
+		for _, a := range aliases {
+			aliasToField[strings.ToLower(a)] = field
+		}
+	}
+	cols := make([]string, len(header))
+	used := make(map[string]bool)
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		if f, ok := mapping[h]; ok {
+			if f != FieldIgnore && !used[f] {
+				cols[i] = f
+				used[f] = true
+			}
+			continue
+		}
+		if f, ok := aliasToField[strings.ToLower(h)]; ok && !used[f] {
+			cols[i] = f
+			used[f] = true
+		}
+	}
+	return cols
+}
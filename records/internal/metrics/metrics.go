@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 本服务的业务指标，注册在 Prometheus 默认注册表；各模块直接引用并在关键路径上记录。
+// 默认注册表自带 Go 运行时（go_*）与进程（process_*）指标
+
+var (
+	// 一轮对话（ProcessTurn）耗时，按结束时的会话状态分组
+	TurnDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "records_turn_duration_seconds",
+		Help:    "Duration of a conversation turn, by resulting session status.",
+		Buckets: []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"status"})
+
+	// 大模型调用，按 ai.Client 方法与模型分组
+	LLMCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "records_llm_calls_total",
+		Help: "LLM completion calls, by ai.Client method and model.",
+	}, []string{"method", "model"})
+	LLMErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "records_llm_errors_total",
+		Help: "Failed LLM completion calls, by ai.Client method and model.",
+	}, []string{"method", "model"})
+	LLMDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "records_llm_call_duration_seconds",
+		Help:    "Duration of LLM completion calls, by ai.Client method and model.",
+		Buckets: []float64{0.25, 0.5, 1, 2, 5, 10, 20, 40, 80},
+	}, []string{"method", "model"})
+	LLMTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "records_llm_tokens_total",
+		Help: "LLM tokens consumed, by ai.Client method, model and type (prompt|completion).",
+	}, []string{"method", "model", "type"})
+
+	// 输出任务（OutputWorker），outcome 为 succeeded|failed|rejected（队列已满）
+	OutputTasks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "records_output_tasks_total",
+		Help: "Output worker tasks, by outcome (succeeded|failed|rejected).",
+	}, []string{"outcome"})
+	OutputTaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "records_output_task_duration_seconds",
+		Help:    "Time from output task submission to completion, by outcome.",
+		Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60},
+	}, []string{"outcome"})
+
+	// 飞书
+	FeishuSendFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "records_feishu_send_failures_total",
+		Help: "Failed Feishu message sends, by message type (text|card|file).",
+	}, []string{"type"})
+	FeishuReconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "records_feishu_ws_reconnects_total",
+		Help: "Feishu websocket reconnect attempts.",
+	})
+
+	// 热词管线耗时，result 为 ok|error
+	HotwordsDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "records_hotwords_pipeline_duration_seconds",
+		Help:    "Duration of a hotwords pipeline run, by result (ok|error).",
+		Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800},
+	}, []string{"result"})
+)
+
+// Since 返回自 start 起经过的秒数
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// Result 按 err 返回 ok 或 error，用作 result 标签
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// Register 注册抓取时取值的采集器（如队列长度、连接池状态）；已注册过同一指标时先注销旧的，服务重建时可重新注册
+func Register(c prometheus.Collector) {
+	prometheus.Unregister(c)
+	prometheus.MustRegister(c)
+}
+
+// ObserveLLM 记录一次大模型调用；出错时 token 数为 0 不计入
+func ObserveLLM(method, model string, start time.Time, promptTokens, completionTokens int64, err error) {
+	LLMCalls.WithLabelValues(method, model).Inc()
+	LLMDuration.WithLabelValues(method, model).Observe(Since(start))
+	if err != nil {
+		LLMErrors.WithLabelValues(method, model).Inc()
+		return
+	}
+	LLMTokens.WithLabelValues(method, model, "prompt").Add(float64(promptTokens))
+	LLMTokens.WithLabelValues(method, model, "completion").Add(float64(completionTokens))
+}
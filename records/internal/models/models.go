@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // User 用户模型
@@ -23,8 +24,12 @@ type Customer struct {
 	ID            uuid.UUID `db:"id" json:"id"`
 	Name          string    `db:"name" json:"name"`
 	ContactPerson *string   `db:"contact_person" json:"contact_person,omitempty"`
-	ContactPhone  *string   `db:"contact_phone" json:"contact_phone,omitempty"`
+	ContactPhone  *string   `db:"contact_phone" json:"contact_phone,omitempty"` // 库中为密文（启用加密时），展示前经 phonecrypt 解密或脱敏
 	ContactRole   *string   `db:"contact_role" json:"contact_role,omitempty"`
+	// ContactPhoneBidx 联系电话盲索引，仓储写入时由 ContactPhone 计算
+	ContactPhoneBidx *string `db:"contact_phone_bidx" json:"-"`
+	// UpdatedAt 读取时的更新时间，非空时 UpdateCustomer 以其为前置条件（乐观并发）
+	UpdatedAt *time.Time `db:"updated_at" json:"-"`
 }
 
 // CustomerContact 客户联系人模型
@@ -64,22 +69,159 @@ type Dialog struct {
 
 // FollowRecord 跟进记录模型
 type FollowRecord struct {
-	ID            uuid.UUID `db:"id" json:"id"`
-	UserID        string    `db:"user_id" json:"user_id"`
-	CustomerID    uuid.UUID `db:"customer_id" json:"customer_id"`
-	CustomerName  string    `db:"customer_name" json:"customer_name"`
-	ContactPerson *string   `db:"contact_person" json:"contact_person,omitempty"`
-	ContactPhone  *string   `db:"contact_phone" json:"contact_phone,omitempty"`
-	ContactRole   *string   `db:"contact_role" json:"contact_role,omitempty"`
-	FollowTime    time.Time `db:"follow_time" json:"follow_time"`
-	FollowMethod  *string   `db:"follow_method" json:"follow_method,omitempty"`
-	FollowContent *string   `db:"follow_content" json:"follow_content,omitempty"`
-	FollowGoal    *string   `db:"follow_goal" json:"follow_goal,omitempty"`
-	FollowResult  *string   `db:"follow_result" json:"follow_result,omitempty"`
-	RiskContent   *string   `db:"risk_content" json:"risk_content,omitempty"`
-	NextPlan      *string   `db:"next_plan" json:"next_plan,omitempty"`
-	AI            bool      `db:"ai" json:"ai"`
-	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	ID            uuid.UUID  `db:"id" json:"id"`
+	UserID        string     `db:"user_id" json:"user_id"`
+	CustomerID    uuid.UUID  `db:"customer_id" json:"customer_id"`
+	CustomerName  string     `db:"customer_name" json:"customer_name"`
+	ContactPerson *string    `db:"contact_person" json:"contact_person,omitempty"`
+	ContactPhone  *string    `db:"contact_phone" json:"contact_phone,omitempty"` // 库中为密文（启用加密时），展示前经 phonecrypt 解密或脱敏
+	ContactRole   *string    `db:"contact_role" json:"contact_role,omitempty"`
+	FollowTime    time.Time  `db:"follow_time" json:"follow_time"`
+	FollowMethod  *string    `db:"follow_method" json:"follow_method,omitempty"`
+	FollowContent *string    `db:"follow_content" json:"follow_content,omitempty"`
+	FollowGoal    *string    `db:"follow_goal" json:"follow_goal,omitempty"`
+	FollowResult  *string    `db:"follow_result" json:"follow_result,omitempty"`
+	RiskContent   *string    `db:"risk_content" json:"risk_content,omitempty"`
+	NextPlan      *string    `db:"next_plan" json:"next_plan,omitempty"`
+	AI            bool       `db:"ai" json:"ai"`
+	Version       int        `db:"version" json:"version"`                 // 每次写入递增，对应 follow_record_versions.version
+	DeletedAt     *time.Time `db:"deleted_at" json:"deleted_at,omitempty"` // 软删除时间，仅查询已删除记录时填充
+	// ContactPhoneBidx 联系电话盲索引，仓储写入时由 ContactPhone 计算
+	ContactPhoneBidx *string   `db:"contact_phone_bidx" json:"-"`
+	CreatedAt        time.Time `db:"created_at" json:"created_at"`
+}
+
+// 跟进记录版本操作
+const (
+	RecordOpCreate  = "create"
+	RecordOpUpdate  = "update"
+	RecordOpDelete  = "delete"
+	RecordOpRestore = "restore"
+)
+
+// 跟进记录写入来源
+const (
+	RecordSourceBot    = "bot"    // 机器人对话录入
+	RecordSourcePage   = "page"   // 网页端
+	RecordSourceImport = "import" // 批量导入
+	RecordSourceSystem = "system" // 系统任务（如客户合并）
+	RecordSourceAPI    = "api"    // 对外 REST API（actor 为 api_key:{前缀}）
+)
+
+// 出站 Webhook 事件类型
+const (
+	WebhookRecordCreated  = "follow_record.created"
+	WebhookRecordUpdated  = "follow_record.updated"
+	WebhookRecordDeleted  = "follow_record.deleted"
+	WebhookRecordRestored = "follow_record.restored"
+	WebhookCustomerMerged = "customer.merged"
+)
+
+// WebhookEvents 可订阅的事件类型
+var WebhookEvents = []string{WebhookRecordCreated, WebhookRecordUpdated, WebhookRecordDeleted, WebhookRecordRestored, WebhookCustomerMerged}
+
+// FollowRecordVersion 跟进记录版本快照（写入后的整行）
+type FollowRecordVersion struct {
+	ID        int64           `db:"id" json:"id"`
+	RecordID  uuid.UUID       `db:"record_id" json:"record_id"`
+	Version   int             `db:"version" json:"version"`
+	Op        string          `db:"op" json:"op"`
+	Source    string          `db:"source" json:"source"`
+	ActorID   *string         `db:"actor_id" json:"actor_id,omitempty"`
+	ActorName *string         `db:"actor_name" json:"actor_name,omitempty"`
+	Snapshot  json.RawMessage `db:"snapshot" json:"snapshot"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// 权限点
+const (
+	PermView      = "view"       // 查看范围内销售的跟进记录
+	PermExport    = "export"     // 导出范围内的跟进记录
+	PermEdit      = "edit"       // 修改、删除、恢复范围内的跟进记录，代其导入
+	PermViewPhone = "view_phone" // 查看范围内记录的明文联系电话
+	PermAdmin     = "admin"      // 管理角色授权与定时任务
+)
+
+// Permissions 全部权限点
+var Permissions = []string{PermView, PermExport, PermEdit, PermViewPhone, PermAdmin}
+
+// 授权数据范围类型
+const (
+	ScopeAll        = "all"        // 全部用户
+	ScopeUsers      = "users"      // 指定用户 id
+	ScopeDepartment = "department" // 飞书部门（open_department_id）
+	ScopeOrg        = "org"        // orgname 及其下级（以 . 分隔）
+
+	ScopeDepartmentTree = "department_tree" // 飞书部门及其全部下级部门（依赖通讯录同步的部门树）
+)
+
+// Role 角色及其权限点
+type Role struct {
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description"`
+	Permissions []string  `db:"-" json:"permissions"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// RoleAssignment 用户授权：角色 + 数据范围
+type RoleAssignment struct {
+	ID          int64          `db:"id" json:"id"`
+	UserID      string         `db:"user_id" json:"user_id"`
+	UserName    *string        `db:"user_name" json:"user_name,omitempty"`
+	Role        string         `db:"role" json:"role"`
+	ScopeType   string         `db:"scope_type" json:"scope_type"`
+	ScopeValues pq.StringArray `db:"scope_values" json:"scope_values"`
+	CreatedBy   *string        `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+}
+
+// FollowTask 跟进待办（从跟进记录的下一步计划中抽取的带日期行动）
+type FollowTask struct {
+	ID             uuid.UUID  `db:"id" json:"id"`
+	UserID         string     `db:"user_id" json:"user_id"`
+	CustomerID     uuid.UUID  `db:"customer_id" json:"customer_id"`
+	FollowRecordID *uuid.UUID `db:"follow_record_id" json:"follow_record_id,omitempty"`
+	Action         string     `db:"action" json:"action"`
+	DueAt          time.Time  `db:"due_at" json:"due_at"`
+	Status         string     `db:"status" json:"status"`
+	SnoozeCount    int        `db:"snooze_count" json:"snooze_count"`
+	RemindedAt     *time.Time `db:"reminded_at" json:"reminded_at,omitempty"`
+	CompletedAt    *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
+
+// 导入批次状态
+const (
+	ImportBatchCommitted  = "committed"
+	ImportBatchRolledBack = "rolled_back"
+)
+
+// ImportBatch 历史跟进记录导入批次
+type ImportBatch struct {
+	ID           uuid.UUID  `db:"id" json:"id"`
+	UserID       string     `db:"user_id" json:"user_id"`
+	FileName     string     `db:"file_name" json:"file_name"`
+	Status       string     `db:"status" json:"status"`
+	TotalRows    int        `db:"total_rows" json:"total_rows"`
+	ImportedRows int        `db:"imported_rows" json:"imported_rows"`
+	NewCustomers int        `db:"new_customers" json:"new_customers"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	RolledBackAt *time.Time `db:"rolled_back_at" json:"rolled_back_at,omitempty"`
+}
+
+// NextPlanTask 大模型从下一步计划中抽取的待办；HasDate 为 false 表示计划中没有可确定的日期
+type NextPlanTask struct {
+	HasDate bool   `json:"has_date"`
+	DueDate string `json:"due_date,omitempty"` // YYYY-MM-DD
+	DueTime string `json:"due_time,omitempty"` // HH:MM，计划未提及具体时刻时为空
+	Action  string `json:"action,omitempty"`
+}
+
+// CustomerReportSummary 大模型对单个客户一段时间内跟进记录的周报总结
+type CustomerReportSummary struct {
+	Progress  string `json:"progress"`   // 进展
+	Risks     string `json:"risks"`      // 风险，无则为空
+	NextSteps string `json:"next_steps"` // 下一步
 }
 
 // RuntimeState 运行态状态
@@ -187,6 +329,13 @@ const (
 	// 语义相关性
 	SemanticStrong = "STRONG"
 	SemanticNone   = "NONE"
+
+	// 跟进待办状态
+	FollowTaskPending    = "pending"    // 未到期或延后后待提醒
+	FollowTaskReminded   = "reminded"   // 已推送提醒，等待处理
+	FollowTaskDone       = "done"       // 已完成
+	FollowTaskSuperseded = "superseded" // 该客户已有新的跟进记录，待办被新计划取代
+	FollowTaskExpired    = "expired"    // 超过到期时间 max_overdue 仍未提醒（如长时间停机），不再提醒
 )
 
 // StateFieldMap 状态到字段的映射
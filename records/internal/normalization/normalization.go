@@ -6,6 +6,7 @@ import (
 
 	"records/internal/ai"
 	"records/internal/models"
+	"records/internal/phonecrypt"
 	"records/internal/repository"
 	"records/pkg/logger"
 
@@ -37,7 +38,7 @@ func (n *Normalizer) NormalizeEntities(ctx context.Context, sessionID uuid.UUID)
 	}
 
 	if len(mentions) == 0 {
-		n.logger.Info("No mentions found in session", "session_id", sessionID)
+		n.logger.WithContext(ctx).Info("No mentions found in session", "session_id", sessionID)
 		return make(map[uuid.UUID]uuid.UUID), nil
 	}
 
@@ -96,7 +97,7 @@ func (n *Normalizer) extractMentions(ctx context.Context, sessionID uuid.UUID) (
 	for customerID := range customerIDs {
 		customer, err := n.repo.GetCustomer(ctx, customerID)
 		if err != nil {
-			n.logger.Error("Failed to get customer", "customer_id", customerID, "error", err)
+			n.logger.WithContext(ctx).Error("Failed to get customer", "customer_id", customerID, "error", err)
 			continue
 		}
 
@@ -150,7 +151,7 @@ func (n *Normalizer) getCandidateEntities(ctx context.Context, mentions []models
 				Name:         *customer.ContactPerson,
 				CustomerName: customer.Name,
 				ContactRole:  customer.ContactRole,
-				ContactPhone: customer.ContactPhone,
+				ContactPhone: phonecrypt.RevealPtr(customer.ContactPhone, false), // 候选发送大模型，仅需尾号辅助区分
 			})
 		}
 	}
@@ -213,14 +214,14 @@ func (n *Normalizer) processMergeResults(
 			// 解析目标客户ID
 			targetCustomerID, err := uuid.Parse(*bestResult.EntityID)
 			if err != nil {
-				n.logger.Error("Failed to parse target customer ID", "entity_id", *bestResult.EntityID, "error", err)
+				n.logger.WithContext(ctx).Error("Failed to parse target customer ID", "entity_id", *bestResult.EntityID, "error", err)
 				continue
 			}
 
 			// 如果源和目标不同，添加到合并映射
 			if sourceCustomerID != targetCustomerID {
 				mergeMap[sourceCustomerID] = targetCustomerID
-				n.logger.Info("Customer merge identified",
+				n.logger.WithContext(ctx).Info("Customer merge identified",
 					"source", sourceCustomerID,
 					"target", targetCustomerID,
 					"score", bestResult.NormalizationScore)
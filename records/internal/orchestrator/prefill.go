@@ -0,0 +1,79 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"records/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionInProgress 用户有尚未结束的记录会话，不能开启新的预填会话
+var ErrSessionInProgress = errors.New("session in progress")
+
+// StartPrefilledSession 为指定客户开启一个预填的 COLLECTING 会话（如完成跟进待办后记录本次跟进），返回机器人首句回复。
+// prefill 为预填字段（与 pending_updates 同名，如 follow_goal）；userInput 作为本轮用户输入写入对话上下文，供大模型理解来意。
+// 用户已有进行中的会话（OUTPUTTING 除外）时返回 ErrSessionInProgress，避免覆盖未确认的内容。
+func (o *TurnOrchestrator) StartPrefilledSession(ctx context.Context, userID string, customerID uuid.UUID, prefill map[string]interface{}, userInput string) (string, error) {
+	var reply string
+
+	err := o.repo.WithTx(ctx, func(txCtx context.Context) error {
+		session, err := o.repo.GetActiveSession(txCtx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to load session: %w", err)
+		}
+		if session != nil {
+			if session.Status != models.StatusOutputting {
+				return ErrSessionInProgress
+			}
+			// OUTPUTTING 会话已提交异步落库，结束后开启新会话（与 ProcessTurn 一致）
+			endTime := time.Now()
+			if err := o.repo.UpdateSession(txCtx, &models.Session{ID: session.ID, Status: models.StatusExit, EndedAt: &endTime}); err != nil {
+				return fmt.Errorf("end OUTPUTTING session: %w", err)
+			}
+		}
+
+		session = &models.Session{
+			ID:     uuid.New(),
+			UserID: userID,
+			Status: models.StatusCollecting,
+		}
+		if err := o.repo.CreateSession(txCtx, session); err != nil {
+			return fmt.Errorf("create session: %w", err)
+		}
+
+		runtime, err := o.loadLatestRuntime(txCtx, session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load runtime: %w", err)
+		}
+		runtime.TurnIndex++
+		data := make(map[string]interface{}, len(prefill))
+		for k, v := range prefill {
+			data[k] = v
+		}
+		runtime.PendingUpdates[customerID.String()] = data
+		runtime.MentionedCustomerID = &customerID
+
+		if err := o.recalculateStates(txCtx, runtime); err != nil {
+			return fmt.Errorf("recalculate states: %w", err)
+		}
+
+		reply, err = o.generateReply(txCtx, runtime, userInput)
+		if err != nil {
+			o.logger.WithContext(ctx).Error("Failed to generate reply for prefilled session", "error", err)
+			reply = o.systemError
+		}
+
+		if err := o.saveRuntimeSnapshot(txCtx, session.ID, runtime, userInput, reply); err != nil {
+			return fmt.Errorf("failed to save runtime snapshot: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return reply, nil
+}
@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -10,13 +11,17 @@ import (
 
 	"records/internal/ai"
 	"records/internal/engine"
+	"records/internal/metrics"
 	"records/internal/models"
+	"records/internal/phonecrypt"
 	"records/internal/repository"
+	"records/internal/tracing"
 	"records/internal/worker"
 	"records/pkg/logger"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // TurnOrchestrator 对话轮次编排器
@@ -33,6 +38,12 @@ type TurnOrchestrator struct {
 	collectingAbortConfirm string // 用户表达中断意图时发出的确认文案
 	collectingAborted      string // 用户确认中断后发出的结束语
 	systemError            string
+	customers              CustomerMatcher // 按客户名匹配或新建客户，默认本地精确匹配
+}
+
+// CustomerMatcher 对话中按客户名匹配客户，不存在则新建；created 表示是否新建
+type CustomerMatcher interface {
+	FindOrCreateCustomer(ctx context.Context, name string) (id uuid.UUID, created bool, err error)
 }
 
 // NewTurnOrchestrator 创建对话轮次编排器
@@ -64,6 +75,7 @@ func NewTurnOrchestrator(
 		collectingAbortConfirm: collectingAbortConfirm,
 		collectingAborted:      collectingAborted,
 		systemError:            systemError,
+		customers:              repo,
 	}
 }
 
@@ -71,6 +83,11 @@ func NewTurnOrchestrator(
 func (o *TurnOrchestrator) ProcessTurn(ctx context.Context, userID, userInput string) (string, error) {
 	var reply string
 
+	// 本轮耗时按结束时的会话状态记录，失败记为 ERROR
+	start := time.Now()
+	status := "UNKNOWN"
+	ctx, span := tracing.Start(ctx, "orchestrator.ProcessTurn", attribute.String("user_id", userID))
+
 	// 使用事务确保数据一致性
 	err := o.repo.WithTx(ctx, func(txCtx context.Context) error {
 		// 1. 加载或创建会话
@@ -78,12 +95,13 @@ func (o *TurnOrchestrator) ProcessTurn(ctx context.Context, userID, userInput st
 		if err != nil {
 			return fmt.Errorf("failed to load session: %w", err)
 		}
+		status = session.Status
 
 		// 1.5 OUTPUTTING 阶段用户继续发消息：判断是否为新的客户跟进信息
 		if session.Status == models.StatusOutputting {
 			isFollowUp, err := o.aiClient.IsCustomerFollowRelated(ctx, userInput)
 			if err != nil {
-				o.logger.Error("IsCustomerFollowRelated failed in OUTPUTTING", "error", err)
+				o.logger.WithContext(ctx).Error("IsCustomerFollowRelated failed in OUTPUTTING", "error", err)
 			}
 			if !isFollowUp {
 				reply = o.outputtingEnded
@@ -119,11 +137,11 @@ func (o *TurnOrchestrator) ProcessTurn(ctx context.Context, userID, userInput st
 		if !session.UpdatedAt.IsZero() {
 			claimed, err := o.repo.ClaimSessionForTurn(txCtx, session.ID, session.UpdatedAt)
 			if err != nil {
-				o.logger.Error("ClaimSessionForTurn failed", "error", err)
+				o.logger.WithContext(ctx).Error("ClaimSessionForTurn failed", "error", err)
 				return err
 			}
 			if !claimed {
-				o.logger.Info("Duplicate request detected: claim failed, skipping processing", "session_id", session.ID)
+				o.logger.WithContext(ctx).Info("Duplicate request detected: claim failed, skipping processing", "session_id", session.ID)
 				reply = ""
 				return nil
 			}
@@ -133,7 +151,7 @@ func (o *TurnOrchestrator) ProcessTurn(ctx context.Context, userID, userInput st
 		if runtime.PendingAbortConfirm {
 			confirmed, err := o.aiClient.IsUserAbortConfirmation(ctx, userInput)
 			if err != nil {
-				o.logger.Error("IsUserAbortConfirmation failed", "error", err)
+				o.logger.WithContext(ctx).Error("IsUserAbortConfirmation failed", "error", err)
 			} else if confirmed {
 				if err := o.repo.DeleteDialogsBySession(txCtx, session.ID); err != nil {
 					return fmt.Errorf("delete dialogs on abort: %w", err)
@@ -141,6 +159,7 @@ func (o *TurnOrchestrator) ProcessTurn(ctx context.Context, userID, userInput st
 				if err := o.repo.DeleteSession(txCtx, session.ID); err != nil {
 					return fmt.Errorf("delete session on abort: %w", err)
 				}
+				status = models.StatusExit
 				reply = o.collectingAborted
 				if reply == "" {
 					reply = "好的，本次记录已结束。之后有新的客户跟进要整理，再找我即可。"
@@ -154,7 +173,7 @@ func (o *TurnOrchestrator) ProcessTurn(ctx context.Context, userID, userInput st
 		if !runtime.PendingAbortConfirm && (session.Status == models.StatusCollecting || session.Status == models.StatusConfirming || session.Status == models.StatusAskingOtherCustomers) {
 			abort, err := o.aiClient.IsUserAbortCollecting(ctx, userInput)
 			if err != nil {
-				o.logger.Error("IsUserAbortCollecting failed", "error", err)
+				o.logger.WithContext(ctx).Error("IsUserAbortCollecting failed", "error", err)
 			} else if abort {
 				runtime.PendingAbortConfirm = true
 				reply = o.collectingAbortConfirm
@@ -163,7 +182,7 @@ func (o *TurnOrchestrator) ProcessTurn(ctx context.Context, userID, userInput st
 				}
 				runtime.TurnIndex++
 				if err := o.saveRuntimeSnapshot(txCtx, session.ID, runtime, userInput, reply); err != nil {
-					o.logger.Error("Failed to save runtime snapshot on abort confirm", "error", err)
+					o.logger.WithContext(ctx).Error("Failed to save runtime snapshot on abort confirm", "error", err)
 					return fmt.Errorf("failed to save runtime snapshot: %w", err)
 				}
 				return nil
@@ -189,7 +208,7 @@ func (o *TurnOrchestrator) ProcessTurn(ctx context.Context, userID, userInput st
 			expectedFieldDescription := o.getExpectedFieldDescription(runtime.State)
 			semanticResult, err = o.aiClient.SemanticAnalysis(ctx, userInput, runtime.Status, focusCustomerName, expectedFieldDescription, convHistory)
 			if err != nil {
-				o.logger.Error("Semantic analysis failed", "error", err)
+				o.logger.WithContext(ctx).Error("Semantic analysis failed", "error", err)
 				// 语义分析失败不影响对话继续
 			}
 		}
@@ -199,15 +218,16 @@ func (o *TurnOrchestrator) ProcessTurn(ctx context.Context, userID, userInput st
 		if err != nil {
 			return fmt.Errorf("rule engine processing failed: %w", err)
 		}
+		status = newRuntime.Status
 
 		// 6. 处理 OUTPUTTING 阶段（异步）
 		if newRuntime.Status == models.StatusOutputting {
 			// 提交异步任务，不阻塞用户交互
-			if err := o.outputWorker.SubmitTask(session.ID, userID); err != nil {
-				o.logger.Error("Failed to submit output task", "error", err)
+			if err := o.outputWorker.SubmitTask(ctx, session.ID, userID); err != nil {
+				o.logger.WithContext(ctx).Error("Failed to submit output task", "error", err)
 				// 任务提交失败不影响对话回复
 			} else {
-				o.logger.Info("Output task submitted successfully", "session_id", session.ID)
+				o.logger.WithContext(ctx).Info("Output task submitted successfully", "session_id", session.ID)
 			}
 
 			// 立即更新会话状态为 OUTPUTTING（实际的 EXIT 状态由 worker 异步更新）
@@ -220,19 +240,26 @@ func (o *TurnOrchestrator) ProcessTurn(ctx context.Context, userID, userInput st
 		// 7. 生成对话回复
 		reply, err = o.generateReply(txCtx, newRuntime, userInput)
 		if err != nil {
-			o.logger.Error("Failed to generate reply", "error", err)
+			o.logger.WithContext(ctx).Error("Failed to generate reply", "error", err)
 			reply = o.systemError
 		}
 
 		// 8. 持久化运行态快照（含原始对话内容，供后续大模型理解上下文）
 		if err := o.saveRuntimeSnapshot(txCtx, session.ID, newRuntime, userInput, reply); err != nil {
-			o.logger.Error("Failed to save runtime snapshot", "error", err)
+			o.logger.WithContext(ctx).Error("Failed to save runtime snapshot", "error", err)
 			return fmt.Errorf("failed to save runtime snapshot: %w", err)
 		}
 
 		return nil
 	})
 
+	if err != nil {
+		status = "ERROR"
+	}
+	metrics.TurnDuration.WithLabelValues(status).Observe(metrics.Since(start))
+	span.SetAttributes(attribute.String("session.status", status))
+	tracing.End(span, err)
+
 	if err != nil {
 		return "", err
 	}
@@ -360,7 +387,7 @@ func (o *TurnOrchestrator) ensureFocusWhenPending(ctx context.Context, runtime *
 	if latest, err := o.repo.GetLatestFocusCustomerIDFromDialogs(ctx, runtime.SessionID); err == nil && latest != nil {
 		if _, exists := runtime.PendingUpdates[latest.String()]; exists {
 			runtime.FocusCustomerID = latest
-			o.logger.Info("Recovered focus from dialogs", "customer_id", latest, "status", status)
+			o.logger.WithContext(ctx).Info("Recovered focus from dialogs", "customer_id", latest, "status", status)
 		}
 	}
 }
@@ -384,13 +411,13 @@ func (o *TurnOrchestrator) processWithRuleEngine(
 	if runtime.Status == models.StatusConfirming {
 		confirmed, err := o.aiClient.IsUserConfirmation(ctx, userInput)
 		if err != nil {
-			o.logger.Error("IsUserConfirmation failed", "error", err)
+			o.logger.WithContext(ctx).Error("IsUserConfirmation failed", "error", err)
 		} else if confirmed {
 			if err := o.saveConfirmedCustomerAndTransition(ctx, &newRuntime, userID); err != nil {
-				o.logger.Error("Failed to save confirmed customer", "error", err)
+				o.logger.WithContext(ctx).Error("Failed to save confirmed customer", "error", err)
 				return nil, err
 			}
-			o.logger.Info("User confirmed, saved customer, transitioning to ASKING_OTHER_CUSTOMERS")
+			o.logger.WithContext(ctx).Info("User confirmed, saved customer, transitioning to ASKING_OTHER_CUSTOMERS")
 			return &newRuntime, nil
 		}
 	}
@@ -402,16 +429,16 @@ func (o *TurnOrchestrator) processWithRuleEngine(
 		if firstEntered, err := o.getFirstAskingOtherCustomersTime(ctx, runtime.SessionID); err == nil && !firstEntered.IsZero() {
 			if time.Since(firstEntered) >= 30*time.Minute {
 				newRuntime.Status = models.StatusOutputting
-				o.logger.Info("ASKING_OTHER_CUSTOMERS timeout (>=30min), auto-transitioning to OUTPUTTING")
+				o.logger.WithContext(ctx).Info("ASKING_OTHER_CUSTOMERS timeout (>=30min), auto-transitioning to OUTPUTTING")
 				return &newRuntime, nil
 			}
 		}
 		noMore, err := o.aiClient.IsUserNoMoreCustomers(ctx, userInput)
 		if err != nil {
-			o.logger.Error("IsUserNoMoreCustomers failed", "error", err)
+			o.logger.WithContext(ctx).Error("IsUserNoMoreCustomers failed", "error", err)
 		} else if noMore {
 			newRuntime.Status = models.StatusOutputting
-			o.logger.Info("User has no more customers, transitioning to OUTPUTTING")
+			o.logger.WithContext(ctx).Info("User has no more customers, transitioning to OUTPUTTING")
 			return &newRuntime, nil
 		}
 		// 用户说了其他内容（不管说什么），后续应进入 COLLECTING
@@ -439,12 +466,12 @@ func (o *TurnOrchestrator) processWithRuleEngine(
 						if item.CustomerName != "" && item.CustomerName != focusCustomerName {
 							nameUpdates := map[string]interface{}{"customer_name": item.CustomerName}
 							if err := o.processFieldUpdates(ctx, &newRuntime, nameUpdates); err != nil {
-								o.logger.Error("Failed to process CONFIRMING customer name change", "error", err)
+								o.logger.WithContext(ctx).Error("Failed to process CONFIRMING customer name change", "error", err)
 							}
 						}
 						if len(item.FieldUpdates) > 0 {
 							if err := o.processFieldUpdates(ctx, &newRuntime, item.FieldUpdates); err != nil {
-								o.logger.Error("Failed to process CONFIRMING correction", "error", err)
+								o.logger.WithContext(ctx).Error("Failed to process CONFIRMING correction", "error", err)
 							}
 						}
 					}
@@ -454,7 +481,7 @@ func (o *TurnOrchestrator) processWithRuleEngine(
 				if item.CustomerName == "" {
 					if newRuntime.FocusCustomerID != nil && len(item.FieldUpdates) > 0 {
 						if err := o.processFieldUpdates(ctx, &newRuntime, item.FieldUpdates); err != nil {
-							o.logger.Error("Failed to process field updates for focus", "error", err)
+							o.logger.WithContext(ctx).Error("Failed to process field updates for focus", "error", err)
 						}
 					}
 					continue
@@ -462,19 +489,19 @@ func (o *TurnOrchestrator) processWithRuleEngine(
 				// COLLECTING/ASKING：若客户名与 focus 一致，应用到 focus
 				if item.CustomerName == focusCustomerName {
 					if err := o.processFieldUpdates(ctx, &newRuntime, item.FieldUpdates); err != nil {
-						o.logger.Error("Failed to process field updates for focus", "customer", item.CustomerName, "error", err)
+						o.logger.WithContext(ctx).Error("Failed to process field updates for focus", "customer", item.CustomerName, "error", err)
 					}
 					continue
 				}
 				customerID, err := o.findOrCreateCustomer(ctx, item.CustomerName)
 				if err != nil {
-					o.logger.Error("Failed to find or create customer", "name", item.CustomerName, "error", err)
+					o.logger.WithContext(ctx).Error("Failed to find or create customer", "name", item.CustomerName, "error", err)
 					continue
 				}
 				newRuntime.MentionedCustomerID = &customerID
 				newRuntime.FocusCustomerID = &customerID
 				if err := o.processFieldUpdates(ctx, &newRuntime, item.FieldUpdates); err != nil {
-					o.logger.Error("Failed to process field updates", "customer", item.CustomerName, "error", err)
+					o.logger.WithContext(ctx).Error("Failed to process field updates", "customer", item.CustomerName, "error", err)
 				}
 			}
 		}
@@ -488,7 +515,7 @@ func (o *TurnOrchestrator) processWithRuleEngine(
 	// ASKING_OTHER_CUSTOMERS 阶段用户说了任何话（非超时、非“没有其他客户”）后，无论 recalculateStates 结果如何，都进入 COLLECTING
 	if fromAskingOtherCustomersUserResponded && newRuntime.Status == models.StatusAskingOtherCustomers {
 		newRuntime.Status = models.StatusCollecting
-		o.logger.Info("User responded in ASKING_OTHER_CUSTOMERS, transitioning to COLLECTING")
+		o.logger.WithContext(ctx).Info("User responded in ASKING_OTHER_CUSTOMERS, transitioning to COLLECTING")
 	}
 
 	return &newRuntime, nil
@@ -556,7 +583,7 @@ func (o *TurnOrchestrator) saveConfirmedCustomerAndTransition(ctx context.Contex
 	followRecord.UserID = userID
 	followRecord.ID = uuid.New()
 
-	if err := o.repo.CreateFollowRecord(ctx, followRecord); err != nil {
+	if err := o.repo.CreateFollowRecord(repository.WithAudit(ctx, userID, models.RecordSourceBot), followRecord); err != nil {
 		return fmt.Errorf("create follow record customer=%s: %w", customerID, err)
 	}
 
@@ -570,7 +597,7 @@ func (o *TurnOrchestrator) saveConfirmedCustomerAndTransition(ctx context.Contex
 		return err
 	}
 
-	o.logger.Info("Saved confirmed customer to DB", "customer_id", customerID, "customer_name", customer.Name)
+	o.logger.WithContext(ctx).Info("Saved confirmed customer to DB", "customer_id", customerID, "customer_name", customer.Name)
 	return nil
 }
 
@@ -625,7 +652,7 @@ func (o *TurnOrchestrator) handleConfirmingStageModifications(
 	}
 	if hasContact && runtime.FocusCustomerID != nil {
 		if err := o.writeContactPerson(ctx, *runtime.FocusCustomerID, contactInfo); err != nil {
-			o.logger.Error("Failed to write contact person in CONFIRMING", "error", err)
+			o.logger.WithContext(ctx).Error("Failed to write contact person in CONFIRMING", "error", err)
 		}
 	}
 
@@ -722,7 +749,7 @@ func (o *TurnOrchestrator) handleSpecialFields(
 	}
 	if hasContact {
 		if err := o.writeContactPerson(ctx, *runtime.FocusCustomerID, contactInfo); err != nil {
-			o.logger.Error("Failed to write contact person", "error", err)
+			o.logger.WithContext(ctx).Error("Failed to write contact person", "error", err)
 		}
 	}
 
@@ -731,7 +758,7 @@ func (o *TurnOrchestrator) handleSpecialFields(
 
 // recalculateStates 重新计算状态
 func (o *TurnOrchestrator) recalculateStates(ctx context.Context, runtime *RuntimeContext) error {
-	o.logger.Debug("Recalculating states", "runtime", runtime)
+	o.logger.WithContext(ctx).Debug("Recalculating states", "runtime", runtime)
 
 	// 获取所有客户的状态（COLLECTING/CONFIRMING 使用 collected_follow_data，OUTPUTTING 使用 DB）
 	customerStates, err := o.getAllCustomerStates(ctx, runtime)
@@ -789,7 +816,7 @@ func (o *TurnOrchestrator) recalculateStates(ctx context.Context, runtime *Runti
 			// 检查该客户是否之前被聚焦过
 			isFirstFocus, err := o.isFirstFocusForCustomer(ctx, runtime.SessionID, *runtime.FocusCustomerID)
 			if err != nil {
-				o.logger.Error("Failed to check first focus", "error", err)
+				o.logger.WithContext(ctx).Error("Failed to check first focus", "error", err)
 			} else {
 				runtime.IsFirstFocus = isFirstFocus
 			}
@@ -816,7 +843,7 @@ func (o *TurnOrchestrator) recalculateStates(ctx context.Context, runtime *Runti
 	if newStatus == models.StatusAskingOtherCustomers && runtime.PendingReconfirm && len(customerStates) > 0 {
 		runtime.Status = models.StatusConfirming
 		runtime.PendingReconfirm = false
-		o.logger.Info("Returning to CONFIRMING after modification completion")
+		o.logger.WithContext(ctx).Info("Returning to CONFIRMING after modification completion")
 	} else {
 		runtime.Status = newStatus
 		if newStatus == models.StatusAskingOtherCustomers {
@@ -961,27 +988,16 @@ func (o *TurnOrchestrator) getExpectedFieldDescription(state string) string {
 	return models.GetExpectedFieldDescription(state)
 }
 
-func (o *TurnOrchestrator) findOrCreateCustomer(ctx context.Context, name string) (uuid.UUID, error) {
-	customer, err := o.repo.GetCustomerByName(ctx, name)
-	if err != nil {
-		return uuid.Nil, err
-	}
-
-	if customer != nil {
-		return customer.ID, nil
-	}
-
-	// 创建新客户
-	newCustomer := &models.Customer{
-		ID:   uuid.New(),
-		Name: name,
-	}
-
-	if err := o.repo.CreateCustomer(ctx, newCustomer); err != nil {
-		return uuid.Nil, err
+// SetCustomerMatcher 替换客户匹配实现（启用 CRM 同步时注入，优先匹配 CRM 主数据账户）
+func (o *TurnOrchestrator) SetCustomerMatcher(m CustomerMatcher) {
+	if m != nil {
+		o.customers = m
 	}
+}
 
-	return newCustomer.ID, nil
+func (o *TurnOrchestrator) findOrCreateCustomer(ctx context.Context, name string) (uuid.UUID, error) {
+	id, _, err := o.customers.FindOrCreateCustomer(ctx, name)
+	return id, err
 }
 
 // writeFieldToRuntime 将字段写入 pending_updates（COLLECTING/CONFIRMING 阶段，OUTPUTTING 时由 output_worker 写入 follow_records）
@@ -1011,7 +1027,7 @@ func (o *TurnOrchestrator) writeFieldToRuntime(ctx context.Context, runtime *Run
 		runtime.PendingUpdates[customerKey][dbKey] = valueStr
 		return nil
 	default:
-		o.logger.Warn("Unknown field name", "field", fieldName)
+		o.logger.WithContext(ctx).Warn("Unknown field name", "field", fieldName)
 		return nil
 	}
 }
@@ -1057,13 +1073,13 @@ func (o *TurnOrchestrator) getAllCustomerStates(ctx context.Context, runtime *Ru
 
 		state := o.ruleEngine.DetermineState(customer, followRecord)
 
-		o.logger.Info("Determining state", "state", state, "customer_name", customer.Name, "follow_record", followRecord)
+		o.logger.WithContext(ctx).Info("Determining state", "state", state, "customer_name", customer.Name, "follow_record", followRecord)
 
 		customerStates[customerID] = state
 	}
 
 	if len(batchErrors) > 0 {
-		o.logger.Warn("Batch processing completed with errors",
+		o.logger.WithContext(ctx).Warn("Batch processing completed with errors",
 			"session_id", runtime.SessionID,
 			"total_customers", len(customerIDs),
 			"success_count", len(customerStates),
@@ -1161,8 +1177,9 @@ func (o *TurnOrchestrator) buildConfirmationDisplayData(ctx context.Context, run
 	if customer.ContactPerson != nil && *customer.ContactPerson != "" {
 		out["contact_person"] = *customer.ContactPerson
 	}
-	if customer.ContactPhone != nil && *customer.ContactPhone != "" {
-		out["contact_phone"] = *customer.ContactPhone
+	// 复述对象为记录所属销售本人，显示明文
+	if phone := phonecrypt.Reveal(customer.ContactPhone, true); phone != "" {
+		out["contact_phone"] = phone
 	}
 	if customer.ContactRole != nil && *customer.ContactRole != "" {
 		out["contact_role"] = *customer.ContactRole
@@ -1247,25 +1264,37 @@ func (o *TurnOrchestrator) writeContactPerson(ctx context.Context, customerID uu
 		return nil
 	}
 
-	// 更新客户表中的联系人信息
-	customer, err := o.repo.GetCustomer(ctx, customerID)
-	if err != nil {
-		return err
-	}
+	// 更新客户表中的联系人信息：以读取时的 updated_at 为前置条件，期间被其他写入修改时重新读取后再覆盖本次字段
+	for attempt := 1; ; attempt++ {
+		customer, err := o.repo.GetCustomer(ctx, customerID)
+		if err != nil {
+			return err
+		}
+		if customer == nil {
+			return fmt.Errorf("customer %s not found", customerID)
+		}
 
-	if name != nil {
-		customer.ContactPerson = name
-	}
-	if role != nil {
-		customer.ContactRole = role
-	}
-	if phone != nil {
-		customer.ContactPhone = phone
-	}
+		if name != nil {
+			customer.ContactPerson = name
+		}
+		if role != nil {
+			customer.ContactRole = role
+		}
+		if phone != nil {
+			customer.ContactPhone = phone
+		}
 
-	return o.repo.UpdateCustomer(ctx, customer)
+		err = o.repo.UpdateCustomer(ctx, customer)
+		if !errors.Is(err, repository.ErrVersionConflict) || attempt >= maxContactWriteAttempts {
+			return err
+		}
+		o.logger.WithContext(ctx).Warn("Customer modified concurrently, retrying contact write", "customer_id", customerID, "attempt", attempt)
+	}
 }
 
+// maxContactWriteAttempts 写入客户联系人遇到并发修改时的最大尝试次数
+const maxContactWriteAttempts = 3
+
 // handleFieldModificationInConfirming 处理 CONFIRMING 阶段的字段修改（修改 pending_updates，不写 DB）
 func (o *TurnOrchestrator) handleFieldModificationInConfirming(
 	ctx context.Context,
@@ -1316,7 +1345,7 @@ func (o *TurnOrchestrator) handleFieldModificationInConfirming(
 		runtime.MentionedCustomerID = &newCustomerID
 		runtime.State = models.StateCustomerName
 		runtime.PendingReconfirm = true
-		o.logger.Info("Customer name changed in CONFIRMING: migrated pending to new customer", "new_name", newName, "new_customer_id", newCustomerID)
+		o.logger.WithContext(ctx).Info("Customer name changed in CONFIRMING: migrated pending to new customer", "new_name", newName, "new_customer_id", newCustomerID)
 		return nil
 	}
 
@@ -1360,7 +1389,7 @@ func (o *TurnOrchestrator) handleFieldModificationInConfirming(
 	// 用户提出修改后，仅更新 pending_updates，不落库；流程回到 COLLECTING，待用户再次确认后才落库
 	runtime.PendingReconfirm = true
 
-	o.logger.Info("Field modified in CONFIRMING stage",
+	o.logger.WithContext(ctx).Info("Field modified in CONFIRMING stage",
 		"modified_field", modifiedField,
 		"new_state", newState,
 		"fields_cleared", fieldsToClear)
@@ -0,0 +1,327 @@
+package phonecrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"records/internal/config"
+)
+
+// 联系电话加密存储（customers / follow_records 的 contact_phone）：信封加密，每个值随机生成数据密钥并以 AES-256-GCM 加密，
+// 数据密钥再由主密钥加密后随密文保存。密文格式 enc:v1:<主密钥ID>:<base64 加密的数据密钥>:<base64 密文>；
+// 主密钥可轮换，旧密钥保留用于解密，由 phone_encrypt 任务改用当前主密钥重新加密。
+// 检索使用盲索引（规范化号码的 HMAC-SHA256），存于 contact_phone_bidx 列
+
+const (
+	prefix    = "enc:v1:"
+	keySize   = 32
+	bidxBytes = 16 // 盲索引截取的字节数，十六进制后 32 字符
+)
+
+// ErrNoKey 密文的主密钥未配置（或未启用加密），无法解密
+var ErrNoKey = errors.New("phone encryption key not configured")
+
+// Keyring 主密钥与盲索引密钥；nil 表示未启用加密，写入时保留明文
+type Keyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+	index  []byte
+}
+
+// New 按配置创建密钥环；未启用返回 nil。密钥为 base64 编码的 32 字节，或 env:变量名 从环境变量读取
+func New(cfg config.PhoneEncryption) (*Keyring, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	k := &Keyring{keys: map[string]cipher.AEAD{}, active: cfg.ActiveKey}
+	for id, value := range cfg.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid phone encryption key id %q", id)
+		}
+		raw, err := loadKey(value)
+		if err != nil {
+			return nil, fmt.Errorf("load phone encryption key %s: %w", id, err)
+		}
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return nil, fmt.Errorf("init phone encryption key %s: %w", id, err)
+		}
+		k.keys[id] = aead
+	}
+	if _, ok := k.keys[k.active]; !ok {
+		return nil, fmt.Errorf("phone encryption active_key %q not found in keys", k.active)
+	}
+	index, err := loadKey(cfg.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("load phone blind index key: %w", err)
+	}
+	k.index = index
+	return k, nil
+}
+
+// loadKey 解析密钥配置值：env:VAR 读取环境变量，值均为 base64 编码的 32 字节
+func loadKey(value string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(value, "env:"); ok {
+		v, found := os.LookupEnv(name)
+		if !found {
+			return nil, fmt.Errorf("env %s not set", name)
+		}
+		value = v
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, errors.New("empty key")
+	}
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("decode base64: %w", err)
+	}
+	if len(raw) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(raw))
+	}
+	return raw, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal 以随机 nonce 加密，输出 nonce+密文
+func seal(aead cipher.AEAD, plain []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
+
+// IsEncrypted 判断值是否为本包生成的密文
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, prefix)
+}
+
+// Encrypt 加密明文；未启用、空值或已是密文时原样返回
+func (k *Keyring) Encrypt(plain string) (string, error) {
+	if k == nil || plain == "" || IsEncrypted(plain) {
+		return plain, nil
+	}
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", fmt.Errorf("generate data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	ct, err := seal(dataAEAD, []byte(plain))
+	if err != nil {
+		return "", fmt.Errorf("encrypt phone: %w", err)
+	}
+	wrapped, err := seal(k.keys[k.active], dek)
+	if err != nil {
+		return "", fmt.Errorf("wrap data key: %w", err)
+	}
+	enc := base64.RawStdEncoding
+	return prefix + k.active + ":" + enc.EncodeToString(wrapped) + ":" + enc.EncodeToString(ct), nil
+}
+
+// Decrypt 解密密文；非密文（未加密的历史数据）原样返回
+func (k *Keyring) Decrypt(v string) (string, error) {
+	if !IsEncrypted(v) {
+		return v, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(v, prefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed phone ciphertext")
+	}
+	if k == nil {
+		return "", ErrNoKey
+	}
+	kek, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("phone encryption key %s: %w", parts[0], ErrNoKey)
+	}
+	enc := base64.RawStdEncoding
+	wrapped, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("decode data key: %w", err)
+	}
+	ct, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("decode phone ciphertext: %w", err)
+	}
+	dek, err := open(kek, wrapped)
+	if err != nil {
+		return "", fmt.Errorf("unwrap data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	plain, err := open(dataAEAD, ct)
+	if err != nil {
+		return "", fmt.Errorf("decrypt phone: %w", err)
+	}
+	return string(plain), nil
+}
+
+// ActivePrefix 当前主密钥密文的前缀；不以此开头的非空存储值（明文或旧主密钥密文）需重新加密
+func (k *Keyring) ActivePrefix() string {
+	return prefix + k.active + ":"
+}
+
+// Reseal 以当前主密钥重新加密存储值（明文或旧主密钥密文），并计算盲索引
+func (k *Keyring) Reseal(v string) (stored string, bidx *string, err error) {
+	plain, err := k.Decrypt(v)
+	if err != nil {
+		return "", nil, err
+	}
+	if stored, err = k.Encrypt(plain); err != nil {
+		return "", nil, err
+	}
+	if idx := k.BlindIndex(plain); idx != "" {
+		bidx = &idx
+	}
+	return stored, bidx, nil
+}
+
+// BlindIndex 计算号码的盲索引；未启用或号码中没有数字时返回空串
+func (k *Keyring) BlindIndex(phone string) string {
+	n := Normalize(phone)
+	if k == nil || n == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, k.index)
+	mac.Write([]byte(n))
+	return hex.EncodeToString(mac.Sum(nil)[:bidxBytes])
+}
+
+// Normalize 规范化号码用于盲索引：只保留数字，去掉 +86 / 0086 国家码
+func Normalize(phone string) string {
+	var b strings.Builder
+	for _, c := range phone {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	n := b.String()
+	switch {
+	case len(n) == 15 && strings.HasPrefix(n, "0086"):
+		n = n[4:]
+	case len(n) == 13 && strings.HasPrefix(n, "86"):
+		n = n[2:]
+	}
+	return n
+}
+
+// LooksLikePhone 判断检索词是否为号码（仅含数字与常见分隔符，且至少 7 位数字），用于改走盲索引
+func LooksLikePhone(q string) bool {
+	q = strings.TrimSpace(q)
+	if q == "" || strings.Trim(q, "0123456789+-() ") != "" {
+		return false
+	}
+	return len(Normalize(q)) >= 7
+}
+
+// Mask 号码脱敏：11 位及以上保留前 3 后 4 位，较短的保留后 4 位
+func Mask(phone string) string {
+	r := []rune(strings.TrimSpace(phone))
+	switch {
+	case len(r) == 0:
+		return ""
+	case len(r) >= 11:
+		return string(r[:3]) + strings.Repeat("*", len(r)-7) + string(r[len(r)-4:])
+	case len(r) > 4:
+		return strings.Repeat("*", len(r)-4) + string(r[len(r)-4:])
+	default:
+		return strings.Repeat("*", len(r))
+	}
+}
+
+// defaultKeyring 进程内使用的密钥环，由 main 在启动时设置
+var defaultKeyring atomic.Pointer[Keyring]
+
+// SetDefault 设置进程内使用的密钥环；nil 表示未启用加密
+func SetDefault(k *Keyring) {
+	defaultKeyring.Store(k)
+}
+
+// Default 返回进程内使用的密钥环
+func Default() *Keyring {
+	return defaultKeyring.Load()
+}
+
+// Seal 将待写入的号码转为存储值与盲索引：明文加密，已是密文则保留；空值原样返回
+func Seal(phone *string) (stored, bidx *string, err error) {
+	if phone == nil || *phone == "" {
+		return phone, nil, nil
+	}
+	k := Default()
+	plain, err := k.Decrypt(*phone)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc, err := k.Encrypt(*phone)
+	if err != nil {
+		return nil, nil, err
+	}
+	if idx := k.BlindIndex(plain); idx != "" {
+		bidx = &idx
+	}
+	return &enc, bidx, nil
+}
+
+// Open 解密存储值；未启用加密时明文原样返回
+func Open(v string) (string, error) {
+	return Default().Decrypt(v)
+}
+
+// Reveal 返回调用方可见的号码：allowed 为 true 时解密为明文，否则脱敏；无法解密时返回空串
+func Reveal(phone *string, allowed bool) string {
+	if phone == nil || *phone == "" {
+		return ""
+	}
+	plain, err := Open(*phone)
+	if err != nil {
+		return ""
+	}
+	if !allowed {
+		return Mask(plain)
+	}
+	return plain
+}
+
+// RevealPtr 同 Reveal，空值返回 nil
+func RevealPtr(phone *string, allowed bool) *string {
+	v := Reveal(phone, allowed)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// BlindIndex 按进程内密钥环计算号码的盲索引；未启用时返回空串
+func BlindIndex(phone string) string {
+	return Default().BlindIndex(phone)
+}
@@ -0,0 +1,160 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"records/internal/config"
+)
+
+// 敏感信息脱敏：发送大模型前替换为可还原的占位符（如 [MOBILE_1]），返回结果中的占位符再还原为原值；
+// 写日志前替换为类型标记（如 [MOBILE]），不可还原
+
+// 内置规则；按顺序匹配，先匹配的优先（邮箱可能含手机号，身份证号含日期数字）
+var builtinRules = []struct {
+	name    string
+	pattern string
+}{
+	{"email", `[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`},
+	{"id_card", `[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]`},
+	{"mobile", `(?:\+?86[- ]?)?1[3-9]\d[- ]?\d{4}[- ]?\d{4}`},
+	{"landline", `(?:\(0\d{2,3}\)\s?|0\d{2,3}[- ]?)[2-9]\d{6,7}(?:-\d{1,6})?`},
+}
+
+var ruleNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// rule 一条脱敏规则，label 为占位符中的类型名
+type rule struct {
+	label string
+	re    *regexp.Regexp
+}
+
+// Redactor 脱敏器；nil 表示未启用，各方法原样返回
+type Redactor struct {
+	rules []rule
+}
+
+// New 按配置创建脱敏器；未启用返回 nil。builtin 为空时启用全部内置规则，自定义规则排在内置规则之后
+func New(cfg config.Redaction) (*Redactor, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	r := &Redactor{}
+	enabled := map[string]bool{}
+	for _, name := range cfg.Builtin {
+		enabled[strings.ToLower(name)] = true
+	}
+	for _, b := range builtinRules {
+		if len(enabled) == 0 || enabled[b.name] {
+			r.rules = append(r.rules, rule{label: strings.ToUpper(b.name), re: regexp.MustCompile(b.pattern)})
+			delete(enabled, b.name)
+		}
+	}
+	for name := range enabled {
+		return nil, fmt.Errorf("unknown builtin redaction rule %q", name)
+	}
+	for _, c := range cfg.Rules {
+		if !ruleNameRe.MatchString(c.Name) {
+			return nil, fmt.Errorf("invalid redaction rule name %q", c.Name)
+		}
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compile redaction rule %s: %w", c.Name, err)
+		}
+		r.rules = append(r.rules, rule{label: strings.ToUpper(c.Name), re: re})
+	}
+	return r, nil
+}
+
+// replace 依次按规则替换匹配项；与数字相邻的匹配视为更长数字串的一部分，不替换
+func (r *Redactor) replace(text string, fn func(label, value string) string) string {
+	for _, ru := range r.rules {
+		locs := ru.re.FindAllStringIndex(text, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, loc := range locs {
+			start, end := loc[0], loc[1]
+			if start > 0 && isDigit(text[start-1]) && isDigit(text[start]) ||
+				end < len(text) && isDigit(text[end]) && isDigit(text[end-1]) {
+				continue
+			}
+			b.WriteString(text[last:start])
+			b.WriteString(fn(ru.label, text[start:end]))
+			last = end
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return text
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// Mask 不可还原的脱敏，用于日志：匹配项替换为 [类型]
+func (r *Redactor) Mask(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+	return r.replace(text, func(label, _ string) string { return "[" + label + "]" })
+}
+
+// NewSession 创建一次可还原的脱敏会话（通常对应一次大模型调用）；同一原值在会话内对应同一占位符
+func (r *Redactor) NewSession() *Session {
+	return &Session{r: r, tokens: map[string]string{}, values: map[string]string{}, counts: map[string]int{}}
+}
+
+// Session 可还原的脱敏会话
+type Session struct {
+	r      *Redactor
+	mu     sync.Mutex
+	tokens map[string]string // 原值 -> 占位符名（不含括号）
+	values map[string]string // 占位符名 -> 原值
+	counts map[string]int    // 类型 -> 已分配序号
+}
+
+// Redact 将匹配项替换为占位符 [类型_序号]
+func (s *Session) Redact(text string) string {
+	if s.r == nil || text == "" {
+		return text
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.replace(text, func(label, value string) string {
+		token, ok := s.tokens[value]
+		if !ok {
+			s.counts[label]++
+			token = label + "_" + strconv.Itoa(s.counts[label])
+			s.tokens[value] = token
+			s.values[token] = value
+		}
+		return "[" + token + "]"
+	})
+}
+
+// tokenRe 占位符；大模型偶尔丢掉方括号，故括号可选
+var tokenRe = regexp.MustCompile(`\[?([A-Z][A-Z0-9_]*_\d+)\]?`)
+
+// Restore 将本会话分配过的占位符还原为原值，其他内容不变
+func (s *Session) Restore(text string) string {
+	if s.r == nil || text == "" {
+		return text
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.values) == 0 {
+		return text
+	}
+	return tokenRe.ReplaceAllStringFunc(text, func(m string) string {
+		name := strings.Trim(m, "[]")
+		if v, ok := s.values[name]; ok {
+			return v
+		}
+		return m
+	})
+}
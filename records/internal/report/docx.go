@@ -0,0 +1,125 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// 最小化的 DOCX（Office Open XML）输出：仅包含 document.xml 与必要的关系文件，样式直接写在段落/文字属性上
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+</Types>`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+
+// 标题字号（半磅）：一级 16pt、二级 14pt、三级 12pt；正文 10.5pt
+var docxHeadingSizes = map[int]int{1: 32, 2: 28, 3: 24}
+
+const docxBodySize = 21
+
+// RenderDOCX 输出 DOCX 格式周报
+func RenderDOCX(r *Report) ([]byte, error) {
+	var body strings.Builder
+	for _, b := range r.blocks() {
+		switch b.kind {
+		case blockHeading:
+			align := ""
+			if b.level == 1 {
+				align = `<w:jc w:val="center"/>`
+			}
+			body.WriteString(`<w:p><w:pPr>` + align + `<w:spacing w:before="240" w:after="120"/></w:pPr>`)
+			body.WriteString(docxRun(b.text, true, docxHeadingSizes[b.level]))
+			body.WriteString(`</w:p>`)
+		case blockParagraph:
+			body.WriteString(`<w:p>` + docxRun(b.text, false, docxBodySize) + `</w:p>`)
+		case blockField:
+			body.WriteString(`<w:p><w:pPr><w:ind w:left="420"/></w:pPr>`)
+			body.WriteString(docxRun(b.label+"：", true, docxBodySize))
+			body.WriteString(docxRun(b.text, false, docxBodySize))
+			body.WriteString(`</w:p>`)
+		case blockTable:
+			body.WriteString(docxTable(b.rows))
+			body.WriteString(`<w:p/>`)
+		}
+	}
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		body.String() +
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="720" w:footer="720" w:gutter="0"/></w:sectPr>` +
+		`</w:body></w:document>`
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+		{"word/document.xml", document},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, fmt.Errorf("create docx part %s: %w", f.name, err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			return nil, fmt.Errorf("write docx part %s: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close docx: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// docxRun 输出一段文字；换行转为 <w:br/>
+func docxRun(text string, bold bool, size int) string {
+	var sb strings.Builder
+	sb.WriteString(`<w:r><w:rPr><w:rFonts w:eastAsia="微软雅黑"/>`)
+	if bold {
+		sb.WriteString(`<w:b/>`)
+	}
+	sb.WriteString(fmt.Sprintf(`<w:sz w:val="%d"/><w:szCs w:val="%d"/></w:rPr>`, size, size))
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		if i > 0 {
+			sb.WriteString(`<w:br/>`)
+		}
+		sb.WriteString(`<w:t xml:space="preserve">` + xmlEscape(line) + `</w:t>`)
+	}
+	sb.WriteString(`</w:r>`)
+	return sb.String()
+}
+
+// docxTable 输出带边框的表格，首行为加粗表头
+func docxTable(rows [][]string) string {
+	var sb strings.Builder
+	sb.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="5000" w:type="pct"/><w:tblBorders>`)
+	for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+		sb.WriteString(`<w:` + side + ` w:val="single" w:sz="4" w:space="0" w:color="999999"/>`)
+	}
+	sb.WriteString(`</w:tblBorders></w:tblPr>`)
+	for i, row := range rows {
+		sb.WriteString(`<w:tr>`)
+		for _, cell := range row {
+			sb.WriteString(`<w:tc><w:p>` + docxRun(cell, i == 0, docxBodySize) + `</w:p></w:tc>`)
+		}
+		sb.WriteString(`</w:tr>`)
+	}
+	sb.WriteString(`</w:tbl>`)
+	return sb.String()
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,173 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"records/internal/models"
+	"records/internal/repository"
+	"records/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// summarizeConcurrency 同时进行的大模型总结请求数
+const summarizeConcurrency = 4
+
+// Summarizer 按客户总结跟进记录（ai.Client 实现）
+type Summarizer interface {
+	SummarizeCustomerReport(ctx context.Context, customerName, followRecords string) (*models.CustomerReportSummary, error)
+}
+
+// Generator 团队周报生成器
+type Generator struct {
+	db         *sqlx.DB
+	summarizer Summarizer
+	log        logger.Logger
+}
+
+// NewGenerator 创建周报生成器
+func NewGenerator(db *sqlx.DB, summarizer Summarizer, log logger.Logger) *Generator {
+	return &Generator{db: db, summarizer: summarizer, log: log}
+}
+
+// Generate 生成主管 view 权限范围内 [from, to] 日期区间的团队周报；from/to 为本地日期，均包含
+func (g *Generator) Generate(ctx context.Context, managerID string, from, to time.Time) (*Report, error) {
+	repo := repository.New(g.db)
+	scope, err := repo.GetManagerScopeUserIDs(ctx, managerID)
+	if err != nil {
+		return nil, err
+	}
+	managerName := managerID
+	if u, err := repo.GetUser(ctx, managerID); err == nil && u != nil && u.Name != "" {
+		managerName = u.Name
+	}
+
+	records, err := listRecords(ctx, g.db, scope, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &Report{ManagerName: managerName, From: from, To: to, GeneratedAt: time.Now(), RecordCount: len(records)}
+	byCustomer := make(map[uuid.UUID][]Record)
+	var customerOrder []uuid.UUID
+	repsByID := make(map[string]*RepSection)
+	repCustomers := make(map[string]map[uuid.UUID]bool)
+	for _, r := range records {
+		if _, ok := byCustomer[r.CustomerID]; !ok {
+			customerOrder = append(customerOrder, r.CustomerID)
+		}
+		byCustomer[r.CustomerID] = append(byCustomer[r.CustomerID], r)
+
+		rs, ok := repsByID[r.UserID]
+		if !ok {
+			rs = &RepSection{UserID: r.UserID, Name: r.UserName}
+			repsByID[r.UserID] = rs
+			repCustomers[r.UserID] = make(map[uuid.UUID]bool)
+			rep.Reps = append(rep.Reps, rs)
+		}
+		rs.RecordCount++
+		if !repCustomers[r.UserID][r.CustomerID] {
+			repCustomers[r.UserID][r.CustomerID] = true
+			rs.CustomerCount++
+			rs.Customers = append(rs.Customers, r.CustomerName)
+		}
+	}
+	sort.SliceStable(rep.Reps, func(i, j int) bool { return rep.Reps[i].RecordCount > rep.Reps[j].RecordCount })
+
+	for _, id := range customerOrder {
+		list := byCustomer[id]
+		last := list[len(list)-1]
+		sec := &CustomerSection{
+			CustomerID:   id,
+			CustomerName: last.CustomerName,
+			RecordCount:  len(list),
+			LastFollowAt: last.FollowTime,
+		}
+		seen := make(map[string]bool)
+		for _, r := range list {
+			if !seen[r.UserName] {
+				seen[r.UserName] = true
+				sec.Reps = append(sec.Reps, r.UserName)
+			}
+		}
+		rep.Customers = append(rep.Customers, sec)
+	}
+	sort.SliceStable(rep.Customers, func(i, j int) bool { return rep.Customers[i].LastFollowAt.After(rep.Customers[j].LastFollowAt) })
+
+	g.summarize(ctx, rep.Customers, byCustomer)
+	return rep, nil
+}
+
+// summarize 并发调用大模型总结各客户；单个客户失败时回退为最近一条记录的内容，不影响整份报告
+func (g *Generator) summarize(ctx context.Context, sections []*CustomerSection, byCustomer map[uuid.UUID][]Record) {
+	sem := make(chan struct{}, summarizeConcurrency)
+	var wg sync.WaitGroup
+	for _, sec := range sections {
+		sec := sec
+		records := byCustomer[sec.CustomerID]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			summary, err := g.summarizer.SummarizeCustomerReport(ctx, sec.CustomerName, recordsJSON(records))
+			if err != nil || summary == nil {
+				g.log.WithContext(ctx).Warn("Customer report summary failed, fallback to latest record", "customer", sec.CustomerName, "error", err)
+				sec.Summary = fallbackSummary(records)
+				return
+			}
+			sec.Summary = *summary
+			sec.AIGenerated = true
+		}()
+	}
+	wg.Wait()
+}
+
+// recordsJSON 将客户的跟进记录转为大模型输入（仅保留业务字段，不含联系电话）
+func recordsJSON(records []Record) string {
+	items := make([]map[string]interface{}, 0, len(records))
+	for _, r := range records {
+		items = append(items, map[string]interface{}{
+			"follow_time":    r.FollowTime.Format("2006-01-02 15:04"),
+			"sales":          r.UserName,
+			"contact_person": deref(r.ContactPerson),
+			"contact_role":   deref(r.ContactRole),
+			"follow_method":  deref(r.FollowMethod),
+			"follow_content": deref(r.FollowContent),
+			"follow_goal":    deref(r.FollowGoal),
+			"follow_result":  deref(r.FollowResult),
+			"risk_content":   deref(r.RiskContent),
+			"next_plan":      deref(r.NextPlan),
+		})
+	}
+	b, _ := json.Marshal(items)
+	return string(b)
+}
+
+// fallbackSummary 大模型不可用时取最近一条记录的结果、风险与下一步
+func fallbackSummary(records []Record) models.CustomerReportSummary {
+	last := records[len(records)-1]
+	progress := deref(last.FollowResult)
+	if progress == "" {
+		progress = deref(last.FollowContent)
+	}
+	return models.CustomerReportSummary{
+		Progress:  progress,
+		Risks:     deref(last.RiskContent),
+		NextSteps: deref(last.NextPlan),
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
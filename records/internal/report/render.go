@@ -0,0 +1,105 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 报告先转为与格式无关的块序列，再分别输出 Markdown 与 DOCX，保证两种格式内容一致
+
+type blockKind int
+
+const (
+	blockHeading blockKind = iota
+	blockParagraph
+	blockField // 「标签：内容」段落，标签加粗
+	blockTable
+)
+
+type block struct {
+	kind  blockKind
+	level int // 标题级别 1-3
+	label string
+	text  string
+	rows  [][]string // 表格，首行为表头
+}
+
+func (r *Report) blocks() []block {
+	bs := []block{
+		{kind: blockHeading, level: 1, text: r.Title()},
+		{kind: blockParagraph, text: fmt.Sprintf("主管：%s　生成时间：%s", r.ManagerName, r.GeneratedAt.Format("2006-01-02 15:04"))},
+		{kind: blockHeading, level: 2, text: "一、概览"},
+		{kind: blockParagraph, text: fmt.Sprintf("本期共 %d 名销售提交跟进记录 %d 条，涉及客户 %d 个。", len(r.Reps), r.RecordCount, len(r.Customers))},
+	}
+	if len(r.Reps) > 0 {
+		rows := [][]string{{"销售", "记录数", "客户数", "跟进客户"}}
+		for _, rep := range r.Reps {
+			rows = append(rows, []string{rep.Name, strconv.Itoa(rep.RecordCount), strconv.Itoa(rep.CustomerCount), strings.Join(rep.Customers, "、")})
+		}
+		bs = append(bs, block{kind: blockTable, rows: rows})
+	}
+
+	bs = append(bs, block{kind: blockHeading, level: 2, text: "二、客户进展"})
+	if len(r.Customers) == 0 {
+		bs = append(bs, block{kind: blockParagraph, text: "本期无跟进记录。"})
+	}
+	for i, c := range r.Customers {
+		bs = append(bs,
+			block{kind: blockHeading, level: 3, text: fmt.Sprintf("%d. %s", i+1, c.CustomerName)},
+			block{kind: blockField, label: "跟进人", text: fmt.Sprintf("%s（%d 条，最近 %s）", strings.Join(c.Reps, "、"), c.RecordCount, c.LastFollowAt.Format("01-02"))},
+			block{kind: blockField, label: "进展", text: orNone(c.Summary.Progress)},
+			block{kind: blockField, label: "风险", text: orNone(c.Summary.Risks)},
+			block{kind: blockField, label: "下一步", text: orNone(c.Summary.NextSteps)},
+		)
+		if !c.AIGenerated {
+			bs = append(bs, block{kind: blockParagraph, text: "（自动总结失败，以上内容取自最近一次跟进记录）"})
+		}
+	}
+	return bs
+}
+
+func orNone(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "无"
+	}
+	return s
+}
+
+// RenderMarkdown 输出 Markdown 格式周报
+func RenderMarkdown(r *Report) []byte {
+	var sb strings.Builder
+	for _, b := range r.blocks() {
+		switch b.kind {
+		case blockHeading:
+			sb.WriteString("\n" + strings.Repeat("#", b.level) + " " + b.text + "\n\n")
+		case blockParagraph:
+			sb.WriteString("\n" + b.text + "\n\n")
+		case blockField:
+			sb.WriteString("- **" + b.label + "**：" + mdInline(b.text) + "\n")
+		case blockTable:
+			for i, row := range b.rows {
+				cells := make([]string, len(row))
+				for j, c := range row {
+					cells[j] = strings.ReplaceAll(mdInline(c), "|", "\\|")
+				}
+				sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+				if i == 0 {
+					sb.WriteString("|" + strings.Repeat(" --- |", len(row)) + "\n")
+				}
+			}
+			sb.WriteString("\n")
+		}
+	}
+	// 段落前统一补空行以隔开列表，这里合并多余空行
+	out := sb.String()
+	for strings.Contains(out, "\n\n\n") {
+		out = strings.ReplaceAll(out, "\n\n\n", "\n\n")
+	}
+	return []byte(strings.TrimLeft(out, "\n"))
+}
+
+// mdInline 将多行内容压成一行，避免破坏列表/表格结构
+func mdInline(s string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, "\r", "")), " ")
+}
@@ -0,0 +1,31 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// listRecords 返回范围内销售在 [from, to) 的跟进记录，按客户、跟进时间升序；userIDs 为 nil 表示全部用户
+func listRecords(ctx context.Context, db *sqlx.DB, userIDs []string, from, to time.Time) ([]Record, error) {
+	query := `SELECT fr.id, fr.user_id, fr.customer_id, fr.customer_name, fr.contact_person, fr.contact_phone, fr.contact_role,
+		fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan,
+		fr.ai, fr.created_at, COALESCE(u.name, fr.user_id) AS user_name
+		FROM follow_records fr LEFT JOIN users u ON u.id = fr.user_id
+		WHERE fr.follow_time >= $1 AND fr.follow_time < $2 AND fr.deleted_at IS NULL`
+	args := []interface{}{from, to}
+	if userIDs != nil {
+		query += ` AND fr.user_id = ANY($3)`
+		args = append(args, pq.Array(userIDs))
+	}
+	query += ` ORDER BY fr.customer_id, fr.follow_time`
+
+	var list []Record
+	if err := db.SelectContext(ctx, &list, query, args...); err != nil {
+		return nil, fmt.Errorf("list report records: %w", err)
+	}
+	return list, nil
+}
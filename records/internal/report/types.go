@@ -0,0 +1,56 @@
+package report
+
+import (
+	"time"
+
+	"records/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Record 报告区间内的一条跟进记录（附销售姓名）
+type Record struct {
+	models.FollowRecord
+	UserName string `db:"user_name"`
+}
+
+// CustomerSection 周报中单个客户的段落
+type CustomerSection struct {
+	CustomerID   uuid.UUID
+	CustomerName string
+	Reps         []string // 本期跟进该客户的销售
+	RecordCount  int
+	LastFollowAt time.Time
+	Summary      models.CustomerReportSummary
+	AIGenerated  bool // false 表示大模型总结失败，Summary 取自最近一条记录
+}
+
+// RepSection 周报中单个销售的统计
+type RepSection struct {
+	UserID        string
+	Name          string
+	RecordCount   int
+	CustomerCount int
+	Customers     []string
+}
+
+// Report 团队周报
+type Report struct {
+	ManagerName string
+	From        time.Time // 起始日期（含）
+	To          time.Time // 结束日期（含）
+	GeneratedAt time.Time
+	RecordCount int
+	Customers   []*CustomerSection // 按最近跟进时间倒序
+	Reps        []*RepSection      // 按记录数倒序
+}
+
+// Title 报告标题
+func (r *Report) Title() string {
+	return "销售团队周报（" + r.From.Format("2006-01-02") + " ~ " + r.To.Format("2006-01-02") + "）"
+}
+
+// FileName 下载/发送时使用的文件名（不含扩展名）
+func (r *Report) FileName() string {
+	return "销售周报_" + r.From.Format("20060102") + "-" + r.To.Format("20060102")
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"records/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// 对外 REST API（/api/v1）查询：客户、联系人与跟进记录，按 (updated_at, id) 游标增量拉取
+
+// SortUpdatedAt 对外 API 列表的排序字段
+const SortUpdatedAt = "updated_at"
+
+// APICustomer 对外 API 的客户
+type APICustomer struct {
+	ID             uuid.UUID  `db:"id"`
+	Name           string     `db:"name"`
+	Tier           *string    `db:"tier"`
+	ContactPerson  *string    `db:"contact_person"`
+	ContactPhone   *string    `db:"contact_phone"`
+	ContactRole    *string    `db:"contact_role"`
+	RecordCount    int        `db:"record_count"`
+	LastFollowTime *time.Time `db:"last_follow_time"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+}
+
+// CustomerListOptions 客户分页查询条件；零值字段表示不限
+type CustomerListOptions struct {
+	Limit         int
+	Cursor        string     // 上一页返回的 next_cursor
+	Name          string     // 客户名模糊匹配
+	UpdatedSince  *time.Time // 更新时间下限（含）
+	UpdatedBefore *time.Time // 更新时间上限（不含），增量同步时避开尚未提交的并发写入
+}
+
+// CustomerPage 客户分页结果
+type CustomerPage struct {
+	Items      []*APICustomer
+	NextCursor string // 为空表示没有下一页
+}
+
+const apiCustomerColumns = `c.id, c.name, c.tier, c.contact_person, c.contact_phone, c.contact_role, c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM follow_records fr WHERE fr.customer_id = c.id AND fr.deleted_at IS NULL) AS record_count,
+	(SELECT MAX(fr.follow_time) FROM follow_records fr WHERE fr.customer_id = c.id AND fr.deleted_at IS NULL) AS last_follow_time`
+
+// ListCustomersPage 按 (updated_at, id) 升序分页返回客户，供外部系统增量同步
+func (r *Repository) ListCustomersPage(ctx context.Context, opts CustomerListOptions) (*CustomerPage, error) {
+	page := &CustomerPage{Items: []*APICustomer{}}
+	limit := clampLimit(opts.Limit)
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conds := []string{"TRUE"}
+	if opts.Name != "" {
+		conds = append(conds, "c.name ILIKE '%' || "+arg(opts.Name)+" || '%'")
+	}
+	if opts.UpdatedSince != nil {
+		conds = append(conds, "c.updated_at >= "+arg(*opts.UpdatedSince))
+	}
+	if opts.UpdatedBefore != nil {
+		conds = append(conds, "c.updated_at < "+arg(*opts.UpdatedBefore))
+	}
+	if opts.Cursor != "" {
+		c, err := DecodeCursor(opts.Cursor, SortUpdatedAt, false)
+		if err != nil {
+			return nil, err
+		}
+		id, err := uuid.Parse(c.ID)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		conds = append(conds, keysetCond("c.updated_at", "c.id", false, arg(c.At), arg(id)))
+	}
+	query := `SELECT ` + apiCustomerColumns + ` FROM customers c WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY c.updated_at, c.id LIMIT ` + arg(limit+1)
+	if err := r.getExecer(ctx).SelectContext(ctx, &page.Items, query, args...); err != nil {
+		return nil, fmt.Errorf("list customers page: %w", err)
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = Cursor{Sort: SortUpdatedAt, At: last.UpdatedAt, ID: last.ID.String()}.Encode()
+	}
+	return page, nil
+}
+
+// GetAPICustomer 按 ID 查询客户，不存在返回 nil
+func (r *Repository) GetAPICustomer(ctx context.Context, id uuid.UUID) (*APICustomer, error) {
+	var c APICustomer
+	if err := r.getExecer(ctx).GetContext(ctx, &c, `SELECT `+apiCustomerColumns+` FROM customers c WHERE c.id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get api customer id=%s: %w", id, err)
+	}
+	return &c, nil
+}
+
+// RenameCustomer 修改客户名，并同步跟进记录上冗余的客户名
+func (r *Repository) RenameCustomer(ctx context.Context, id uuid.UUID, name string) error {
+	executor := r.getExecer(ctx)
+	if _, err := executor.ExecContext(ctx, `UPDATE customers SET name = $2, updated_at = NOW() WHERE id = $1`, id, name); err != nil {
+		return fmt.Errorf("rename customer id=%s: %w", id, err)
+	}
+	if _, err := executor.ExecContext(ctx, `UPDATE follow_records SET customer_name = $2, updated_at = NOW() WHERE customer_id = $1 AND customer_name <> $2`, id, name); err != nil {
+		return fmt.Errorf("rename customer records id=%s: %w", id, err)
+	}
+	return nil
+}
+
+// UpdateCustomerRenaming 更新客户（同 UpdateCustomer，UpdatedAt 非空时作为前置条件），
+// 客户名与 oldName 不同时在同一事务内同步跟进记录上冗余的客户名
+func (r *Repository) UpdateCustomerRenaming(ctx context.Context, customer *models.Customer, oldName string) error {
+	return r.inTx(ctx, func(ctx context.Context) error {
+		if err := r.UpdateCustomer(ctx, customer); err != nil {
+			return err
+		}
+		if customer.Name == oldName {
+			return nil
+		}
+		return r.RenameCustomer(ctx, customer.ID, customer.Name)
+	})
+}
+
+// APIContact 客户联系人：客户主联系人与跟进记录中出现过的联系人（按姓名合并，角色/电话取最近一次）
+type APIContact struct {
+	ContactPerson  string     `db:"contact_person"`
+	ContactRole    *string    `db:"contact_role"`
+	ContactPhone   *string    `db:"contact_phone"`
+	RecordCount    int        `db:"record_count"`
+	LastFollowTime *time.Time `db:"last_follow_time"`
+}
+
+// ListCustomerContacts 返回客户跟进记录中出现过的联系人，最近跟进在前
+func (r *Repository) ListCustomerContacts(ctx context.Context, customerID uuid.UUID) ([]*APIContact, error) {
+	list := []*APIContact{}
+	query := `SELECT contact_person,
+		(ARRAY_AGG(contact_role ORDER BY follow_time DESC) FILTER (WHERE COALESCE(contact_role, '') <> ''))[1] AS contact_role,
+		(ARRAY_AGG(contact_phone ORDER BY follow_time DESC) FILTER (WHERE COALESCE(contact_phone, '') <> ''))[1] AS contact_phone,
+		COUNT(*) AS record_count, MAX(follow_time) AS last_follow_time
+		FROM follow_records
+		WHERE customer_id = $1 AND deleted_at IS NULL AND COALESCE(contact_person, '') <> ''
+		GROUP BY contact_person
+		ORDER BY MAX(follow_time) DESC`
+	if err := r.getExecer(ctx).SelectContext(ctx, &list, query, customerID); err != nil {
+		return nil, fmt.Errorf("list customer contacts id=%s: %w", customerID, err)
+	}
+	return list, nil
+}
+
+// APIRecord 对外 API 的跟进记录（含更新时间；软删除的记录带 deleted_at）
+type APIRecord struct {
+	models.FollowRecord
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// APIRecordListOptions 跟进记录增量分页查询条件；零值字段表示不限
+type APIRecordListOptions struct {
+	Limit          int
+	Cursor         string     // 上一页返回的 next_cursor
+	CustomerID     *uuid.UUID // 指定客户
+	UserID         string     // 指定销售
+	UpdatedSince   *time.Time // 更新时间下限（含）
+	UpdatedBefore  *time.Time // 更新时间上限（不含），增量同步时避开尚未提交的并发写入
+	IncludeDeleted bool       // 包含软删除的记录（同步删除）
+}
+
+// APIRecordPage 跟进记录增量分页结果
+type APIRecordPage struct {
+	Items      []*APIRecord
+	NextCursor string // 为空表示没有下一页
+}
+
+const apiRecordColumns = `fr.id, fr.user_id, fr.customer_id, fr.customer_name, fr.contact_person, fr.contact_phone, fr.contact_role,
+	fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan,
+	fr.ai, fr.version, fr.deleted_at, fr.created_at, fr.updated_at`
+
+// ListAPIRecordsPage 按 (updated_at, id) 升序分页返回跟进记录，供外部系统增量同步
+func (r *Repository) ListAPIRecordsPage(ctx context.Context, opts APIRecordListOptions) (*APIRecordPage, error) {
+	page := &APIRecordPage{Items: []*APIRecord{}}
+	limit := clampLimit(opts.Limit)
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conds := []string{"TRUE"}
+	if !opts.IncludeDeleted {
+		conds = append(conds, "fr.deleted_at IS NULL")
+	}
+	if opts.CustomerID != nil {
+		conds = append(conds, "fr.customer_id = "+arg(*opts.CustomerID))
+	}
+	if opts.UserID != "" {
+		conds = append(conds, "fr.user_id = "+arg(opts.UserID))
+	}
+	if opts.UpdatedSince != nil {
+		conds = append(conds, "fr.updated_at >= "+arg(*opts.UpdatedSince))
+	}
+	if opts.UpdatedBefore != nil {
+		conds = append(conds, "fr.updated_at < "+arg(*opts.UpdatedBefore))
+	}
+	if opts.Cursor != "" {
+		c, err := DecodeCursor(opts.Cursor, SortUpdatedAt, false)
+		if err != nil {
+			return nil, err
+		}
+		id, err := uuid.Parse(c.ID)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		conds = append(conds, keysetCond("fr.updated_at", "fr.id", false, arg(c.At), arg(id)))
+	}
+	query := `SELECT ` + apiRecordColumns + ` FROM follow_records fr WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY fr.updated_at, fr.id LIMIT ` + arg(limit+1)
+	if err := r.getExecer(ctx).SelectContext(ctx, &page.Items, query, args...); err != nil {
+		return nil, fmt.Errorf("list api records page: %w", err)
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = Cursor{Sort: SortUpdatedAt, At: last.UpdatedAt, ID: last.ID.String()}.Encode()
+	}
+	return page, nil
+}
+
+// GetAPIRecord 按 ID 查询未删除的跟进记录，不存在返回 nil
+func (r *Repository) GetAPIRecord(ctx context.Context, id uuid.UUID) (*APIRecord, error) {
+	var rec APIRecord
+	query := `SELECT ` + apiRecordColumns + ` FROM follow_records fr WHERE fr.id = $1 AND fr.deleted_at IS NULL`
+	if err := r.getExecer(ctx).GetContext(ctx, &rec, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get api record id=%s: %w", id, err)
+	}
+	return &rec, nil
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// 多实例部署：租约锁（leases，用户级串行与定时任务调度共用）与飞书事件去重（processed_events）
+
+// 租约参数：持有期间每 leaseRenew 续约一次，续约失败即视为失去租约；获取用户锁失败时按退避重试，重试间不占用数据库连接
+const (
+	leaseTTL           = 30 * time.Second
+	leaseRenew         = 10 * time.Second
+	userLockMinBackoff = 50 * time.Millisecond
+	userLockMaxBackoff = time.Second
+)
+
+// ErrLeaseLost 持有期间续约失败或租约已被其他实例获取；此时应停止处理，避免两个实例同时持有同一租约
+var ErrLeaseLost = errors.New("lease lost")
+
+// Lease 已持有的租约：后台定期续约，续约失败时取消 Context()
+type Lease struct {
+	repo    *Repository
+	name    string
+	owner   string
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// TryAcquireLease 尝试获取名为 name 的租约（单次尝试，只执行一条语句，不占用连接）；ok 为 false 表示已被其他持有方占用。
+// 返回的租约 Context() 派生自 ctx，续约失败时以 ErrLeaseLost 取消，持有期间的处理须使用它
+func (r *Repository) TryAcquireLease(ctx context.Context, name string) (lease *Lease, ok bool, err error) {
+	owner := uuid.NewString()
+	if ok, err = r.claimLease(ctx, name, owner); err != nil || !ok {
+		return nil, false, err
+	}
+	leaseCtx, cancel := context.WithCancelCause(ctx)
+	lease = &Lease{
+		repo:    r,
+		name:    name,
+		owner:   owner,
+		ctx:     leaseCtx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go lease.renewLoop()
+	return lease, true, nil
+}
+
+// Context 租约有效期间的 context：续约失败（租约可能已被其他实例获取）或释放后取消
+func (l *Lease) Context() context.Context {
+	return l.ctx
+}
+
+// Release 停止续约并释放租约；可重复调用
+func (l *Lease) Release() {
+	l.once.Do(func() {
+		close(l.done)
+		<-l.stopped
+		l.cancel(nil)
+		// 使用独立 context：调用方 ctx 可能已取消，但租约必须释放；释放失败时租约到期后自动失效
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, _ = l.repo.db.ExecContext(ctx, `DELETE FROM leases WHERE name = $1 AND owner = $2`, l.name, l.owner)
+	})
+}
+
+// renewLoop 定期续约；续约失败或租约已不属于本方时立即取消 Context()，由持有方停止处理（防护：不在失去租约后继续写入）
+func (l *Lease) renewLoop() {
+	defer close(l.stopped)
+	ticker := time.NewTicker(leaseRenew)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), leaseRenew/2)
+			ok, err := l.repo.claimLease(ctx, l.name, l.owner)
+			cancel()
+			if err != nil {
+				l.cancel(fmt.Errorf("%w: renew %s: %v", ErrLeaseLost, l.name, err))
+				return
+			}
+			if !ok {
+				l.cancel(fmt.Errorf("%w: %s", ErrLeaseLost, l.name))
+				return
+			}
+		}
+	}
+}
+
+// claimLease 以一条语句获取或续约租约：无人持有、已过期或本人持有时成功
+func (r *Repository) claimLease(ctx context.Context, name, owner string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `INSERT INTO leases (name, owner, expires_at) VALUES ($1, $2, NOW() + $3 * INTERVAL '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+		WHERE leases.expires_at < NOW() OR leases.owner = EXCLUDED.owner`, name, owner, leaseTTL.Milliseconds())
+	if err != nil {
+		return false, fmt.Errorf("claim lease %s: %w", name, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("get rows affected: %w", err)
+	}
+	return n == 1, nil
+}
+
+// AcquireUserLock 获取用户级租约锁（租约名 user:{userID}），阻塞直至获取成功或 ctx 取消；跨实例同一用户的消息由此串行处理。
+// 返回的 lockCtx 在失去租约时以 ErrLeaseLost 取消，持锁期间的处理须使用 lockCtx；返回的 unlock 停止续约并释放租约
+func (r *Repository) AcquireUserLock(ctx context.Context, userID string) (lockCtx context.Context, unlock func(), err error) {
+	backoff := userLockMinBackoff
+	for {
+		lease, ok, err := r.TryAcquireLease(ctx, "user:"+userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			return lease.Context(), lease.Release, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("acquire user lock user=%s: %w", userID, ctx.Err())
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > userLockMaxBackoff {
+			backoff = userLockMaxBackoff
+		}
+	}
+}
+
+// TryClaimEvent 原子性地占用一个飞书事件（message_id）；返回 true 表示首次处理或上次占用已超过 staleAfter 仍未完成（处理方崩溃），
+// false 表示已被本实例或其他实例处理完成/处理中
+func (r *Repository) TryClaimEvent(ctx context.Context, eventID string, staleAfter time.Duration) (bool, error) {
+	executor := r.getExecer(ctx)
+	result, err := executor.ExecContext(ctx, `INSERT INTO processed_events (event_id, status) VALUES ($1, 'processing')
+		ON CONFLICT (event_id) DO UPDATE SET processed_at = NOW()
+		WHERE processed_events.status = 'processing' AND processed_events.processed_at < NOW() - $2 * INTERVAL '1 millisecond'`,
+		eventID, staleAfter.Milliseconds())
+	if err != nil {
+		return false, fmt.Errorf("claim event id=%s: %w", eventID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("get rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// CompleteEvent 标记事件已处理完成（处理成功后调用），此后不再被重新占用
+func (r *Repository) CompleteEvent(ctx context.Context, eventID string) error {
+	executor := r.getExecer(ctx)
+	if _, err := executor.ExecContext(ctx, `UPDATE processed_events SET status = 'done', processed_at = NOW() WHERE event_id = $1`, eventID); err != nil {
+		return fmt.Errorf("complete event id=%s: %w", eventID, err)
+	}
+	return nil
+}
+
+// ReleaseEvent 释放已占用的事件（处理失败时调用），以便飞书重推时可被再次处理
+func (r *Repository) ReleaseEvent(ctx context.Context, eventID string) error {
+	executor := r.getExecer(ctx)
+	if _, err := executor.ExecContext(ctx, `DELETE FROM processed_events WHERE event_id = $1`, eventID); err != nil {
+		return fmt.Errorf("release event id=%s: %w", eventID, err)
+	}
+	return nil
+}
+
+// CleanupProcessedEvents 删除早于 retention 的事件记录，返回删除条数
+func (r *Repository) CleanupProcessedEvents(ctx context.Context, retention time.Duration) (int64, error) {
+	executor := r.getExecer(ctx)
+	result, err := executor.ExecContext(ctx, `DELETE FROM processed_events WHERE processed_at < $1`, time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("cleanup processed events: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// EventDeduper 基于 processed_events 表的飞书事件去重实现（满足 feishu.EventDeduper），多实例共享
+type EventDeduper struct {
+	repo *Repository
+}
+
+// NewEventDeduper 创建数据库事件去重实现
+func NewEventDeduper(db *sqlx.DB) *EventDeduper {
+	return &EventDeduper{repo: New(db)}
+}
+
+// TryClaim 见 Repository.TryClaimEvent
+func (d *EventDeduper) TryClaim(ctx context.Context, eventID string, staleAfter time.Duration) (bool, error) {
+	return d.repo.TryClaimEvent(ctx, eventID, staleAfter)
+}
+
+// Complete 见 Repository.CompleteEvent
+func (d *EventDeduper) Complete(ctx context.Context, eventID string) error {
+	return d.repo.CompleteEvent(ctx, eventID)
+}
+
+// Release 见 Repository.ReleaseEvent
+func (d *EventDeduper) Release(ctx context.Context, eventID string) error {
+	return d.repo.ReleaseEvent(ctx, eventID)
+}
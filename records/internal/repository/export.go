@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ExportFilter 导出跟进记录的筛选条件；零值字段表示不限
+type ExportFilter struct {
+	UserIDs      []string   // 销售范围；nil 表示全部用户，空切片表示无数据
+	CustomerID   *uuid.UUID // 指定客户
+	CustomerName string     // 客户名模糊匹配
+	From         *time.Time // 跟进时间下限（含）
+	To           *time.Time // 跟进时间上限（不含）
+}
+
+// FollowRecordForExport 导出行：跟进记录 + 销售姓名
+type FollowRecordForExport struct {
+	FollowRecordWithCustomerID
+	UserName string `db:"user_name"`
+}
+
+// IterateFollowRecordsForExport 按跟进时间倒序逐行读取符合条件的跟进记录并回调 fn，避免大量数据一次性载入内存；fn 返回错误时中止
+func (r *Repository) IterateFollowRecordsForExport(ctx context.Context, f ExportFilter, fn func(*FollowRecordForExport) error) error {
+	if f.UserIDs != nil && len(f.UserIDs) == 0 {
+		return nil
+	}
+	conds := []string{"fr.deleted_at IS NULL"}
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.UserIDs != nil {
+		add("fr.user_id = ANY($%d)", pq.Array(f.UserIDs))
+	}
+	if f.CustomerID != nil {
+		add("fr.customer_id = $%d", *f.CustomerID)
+	}
+	if f.CustomerName != "" {
+		add("fr.customer_name ILIKE '%%' || $%d || '%%'", f.CustomerName)
+	}
+	if f.From != nil {
+		add("fr.follow_time >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("fr.follow_time < $%d", *f.To)
+	}
+	where := "WHERE " + strings.Join(conds, " AND ")
+
+	query := `SELECT fr.id, fr.user_id, fr.customer_id, fr.customer_name, fr.contact_person, fr.contact_phone, fr.contact_role,
+		fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan, fr.ai, fr.version, fr.created_at,
+		fr.customer_id::text AS customer_id_str, COALESCE(u.name, fr.user_id) AS user_name
+		FROM follow_records fr
+		LEFT JOIN users u ON u.id = fr.user_id
+		` + where + `
+		ORDER BY fr.follow_time DESC`
+	rows, err := r.getExecer(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query follow records for export: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec FollowRecordForExport
+		if err := rows.StructScan(&rec); err != nil {
+			return fmt.Errorf("scan follow record for export: %w", err)
+		}
+		if err := fn(&rec); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate follow records for export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"records/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// 跟进待办（follow_tasks）：由输出阶段从下一步计划抽取，到期由机器人提醒
+
+// FollowTaskWithCustomer 待办及客户名称（用于提醒卡片）
+type FollowTaskWithCustomer struct {
+	models.FollowTask
+	CustomerName string `db:"customer_name"`
+}
+
+const followTaskColumns = `t.id, t.user_id, t.customer_id, t.follow_record_id, t.action, t.due_at, t.status, t.snooze_count, t.reminded_at, t.completed_at, t.created_at`
+
+func (r *Repository) CreateFollowTask(ctx context.Context, task *models.FollowTask) error {
+	query := `INSERT INTO follow_tasks (id, user_id, customer_id, follow_record_id, action, due_at, status) VALUES (:id, :user_id, :customer_id, :follow_record_id, :action, :due_at, :status)`
+	executor := r.getExecer(ctx)
+	if _, err := executor.NamedExecContext(ctx, query, task); err != nil {
+		return fmt.Errorf("create follow task customer=%s: %w", task.CustomerID, err)
+	}
+	return nil
+}
+
+// GetFollowTask 按 id 获取待办及客户名称；不存在返回 nil
+func (r *Repository) GetFollowTask(ctx context.Context, id uuid.UUID) (*FollowTaskWithCustomer, error) {
+	var task FollowTaskWithCustomer
+	query := `SELECT ` + followTaskColumns + `, c.name AS customer_name FROM follow_tasks t JOIN customers c ON c.id = t.customer_id WHERE t.id = $1`
+	executor := r.getExecer(ctx)
+	if err := executor.GetContext(ctx, &task, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get follow task id=%s: %w", id, err)
+	}
+	return &task, nil
+}
+
+// SupersedeOpenFollowTasks 该用户对该客户写入新跟进记录后，将其未完成的待办标记为已被取代（以最新的下一步计划为准）
+func (r *Repository) SupersedeOpenFollowTasks(ctx context.Context, userID string, customerID uuid.UUID) (int64, error) {
+	query := `UPDATE follow_tasks SET status = $1, updated_at = NOW() WHERE user_id = $2 AND customer_id = $3 AND status IN ($4, $5)`
+	executor := r.getExecer(ctx)
+	result, err := executor.ExecContext(ctx, query, models.FollowTaskSuperseded, userID, customerID, models.FollowTaskPending, models.FollowTaskReminded)
+	if err != nil {
+		return 0, fmt.Errorf("supersede follow tasks user=%s customer=%s: %w", userID, customerID, err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// ExpireOverdueFollowTasks 将到期时间早于 before 仍未提醒的待办标记为已过期，返回过期条数
+func (r *Repository) ExpireOverdueFollowTasks(ctx context.Context, before time.Time) (int64, error) {
+	query := `UPDATE follow_tasks SET status = $1, updated_at = NOW() WHERE status = $2 AND due_at <= $3`
+	executor := r.getExecer(ctx)
+	result, err := executor.ExecContext(ctx, query, models.FollowTaskExpired, models.FollowTaskPending, before)
+	if err != nil {
+		return 0, fmt.Errorf("expire overdue follow tasks: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// ListDueFollowTasks 返回到期待提醒的待办：状态为 pending、due_at 在 (since, now] 内且用户在职，按到期时间升序
+func (r *Repository) ListDueFollowTasks(ctx context.Context, now, since time.Time, limit int) ([]*FollowTaskWithCustomer, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var tasks []*FollowTaskWithCustomer
+	query := `SELECT ` + followTaskColumns + `, c.name AS customer_name
+		FROM follow_tasks t
+		JOIN customers c ON c.id = t.customer_id
+		JOIN users u ON u.id = t.user_id AND u.status = 0
+		WHERE t.status = $1 AND t.due_at <= $2 AND t.due_at > $3
+		ORDER BY t.due_at
+		LIMIT $4`
+	executor := r.getExecer(ctx)
+	if err := executor.SelectContext(ctx, &tasks, query, models.FollowTaskPending, now, since, limit); err != nil {
+		return nil, fmt.Errorf("list due follow tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// MarkFollowTaskReminded 标记待办已推送提醒
+func (r *Repository) MarkFollowTaskReminded(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE follow_tasks SET status = $1, reminded_at = NOW(), updated_at = NOW() WHERE id = $2 AND status = $3`
+	executor := r.getExecer(ctx)
+	if _, err := executor.ExecContext(ctx, query, models.FollowTaskReminded, id, models.FollowTaskPending); err != nil {
+		return fmt.Errorf("mark follow task reminded id=%s: %w", id, err)
+	}
+	return nil
+}
+
+// CompleteFollowTask 完成待办；仅本人且待办未结束时生效，返回是否更新
+func (r *Repository) CompleteFollowTask(ctx context.Context, id uuid.UUID, userID string) (bool, error) {
+	query := `UPDATE follow_tasks SET status = $1, completed_at = NOW(), updated_at = NOW() WHERE id = $2 AND user_id = $3 AND status IN ($4, $5)`
+	executor := r.getExecer(ctx)
+	result, err := executor.ExecContext(ctx, query, models.FollowTaskDone, id, userID, models.FollowTaskPending, models.FollowTaskReminded)
+	if err != nil {
+		return false, fmt.Errorf("complete follow task id=%s: %w", id, err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// SnoozeFollowTask 延后待办到 dueAt 重新提醒；仅本人且待办未结束时生效，返回是否更新
+func (r *Repository) SnoozeFollowTask(ctx context.Context, id uuid.UUID, userID string, dueAt time.Time) (bool, error) {
+	query := `UPDATE follow_tasks SET status = $1, due_at = $2, snooze_count = snooze_count + 1, updated_at = NOW() WHERE id = $3 AND user_id = $4 AND status IN ($5, $6)`
+	executor := r.getExecer(ctx)
+	result, err := executor.ExecContext(ctx, query, models.FollowTaskPending, dueAt, id, userID, models.FollowTaskPending, models.FollowTaskReminded)
+	if err != nil {
+		return false, fmt.Errorf("snooze follow task id=%s: %w", id, err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// ReassignFollowTasks 客户合并时将源客户的待办转移到目标客户
+func (r *Repository) ReassignFollowTasks(ctx context.Context, sourceCustomerID, targetCustomerID uuid.UUID) error {
+	query := `UPDATE follow_tasks SET customer_id = $1, updated_at = NOW() WHERE customer_id = $2`
+	executor := r.getExecer(ctx)
+	if _, err := executor.ExecContext(ctx, query, targetCustomerID, sourceCustomerID); err != nil {
+		return fmt.Errorf("reassign follow tasks %s->%s: %w", sourceCustomerID, targetCustomerID, err)
+	}
+	return nil
+}
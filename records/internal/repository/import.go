@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"records/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// FindOrCreateCustomer 按客户名精确匹配已有客户，不存在则新建；created 表示是否新建
+func (r *Repository) FindOrCreateCustomer(ctx context.Context, name string) (id uuid.UUID, created bool, err error) {
+	customer, err := r.GetCustomerByName(ctx, name)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	if customer != nil {
+		return customer.ID, false, nil
+	}
+	newCustomer := &models.Customer{ID: uuid.New(), Name: name}
+	if err := r.CreateCustomer(ctx, newCustomer); err != nil {
+		return uuid.Nil, false, err
+	}
+	return newCustomer.ID, true, nil
+}
+
+// MatchCustomer 按客户名精确匹配已有客户（只读）；found 为 false 表示不存在
+func (r *Repository) MatchCustomer(ctx context.Context, name string) (id uuid.UUID, found bool, err error) {
+	customer, err := r.GetCustomerByName(ctx, name)
+	if err != nil || customer == nil {
+		return uuid.Nil, false, err
+	}
+	return customer.ID, true, nil
+}
+
+// FindActiveUsersByIDOrName 按用户 ID 或姓名查找在职用户（用于导入时解析销售列）
+func (r *Repository) FindActiveUsersByIDOrName(ctx context.Context, key string) ([]*models.User, error) {
+	var users []*models.User
+	query := `SELECT id, name, phone, status, orgname, avatar_url, start_lark FROM users WHERE status = 0 AND (id = $1 OR name = $1)`
+	if err := r.getExecer(ctx).SelectContext(ctx, &users, query, key); err != nil {
+		return nil, fmt.Errorf("find users by id or name=%s: %w", key, err)
+	}
+	return users, nil
+}
+
+// CreateImportBatch 创建导入批次
+func (r *Repository) CreateImportBatch(ctx context.Context, b *models.ImportBatch) error {
+	query := `INSERT INTO import_batches (id, user_id, file_name, status, total_rows, imported_rows, new_customers)
+		VALUES (:id, :user_id, :file_name, :status, :total_rows, :imported_rows, :new_customers)`
+	if _, err := r.getExecer(ctx).NamedExecContext(ctx, query, b); err != nil {
+		return fmt.Errorf("create import batch id=%s: %w", b.ID, err)
+	}
+	return nil
+}
+
+// UpdateImportBatchCounts 更新批次的导入条数与新建客户数
+func (r *Repository) UpdateImportBatchCounts(ctx context.Context, id uuid.UUID, importedRows, newCustomers int) error {
+	query := `UPDATE import_batches SET imported_rows = $1, new_customers = $2 WHERE id = $3`
+	if _, err := r.getExecer(ctx).ExecContext(ctx, query, importedRows, newCustomers, id); err != nil {
+		return fmt.Errorf("update import batch id=%s: %w", id, err)
+	}
+	return nil
+}
+
+// GetImportBatch 获取导入批次
+func (r *Repository) GetImportBatch(ctx context.Context, id uuid.UUID) (*models.ImportBatch, error) {
+	var b models.ImportBatch
+	query := `SELECT id, user_id, file_name, status, total_rows, imported_rows, new_customers, created_at, rolled_back_at FROM import_batches WHERE id = $1`
+	if err := r.getExecer(ctx).GetContext(ctx, &b, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get import batch id=%s: %w", id, err)
+	}
+	return &b, nil
+}
+
+// ListImportBatches 返回用户的导入批次，按时间倒序
+func (r *Repository) ListImportBatches(ctx context.Context, userID string, limit int) ([]*models.ImportBatch, error) {
+	var list []*models.ImportBatch
+	query := `SELECT id, user_id, file_name, status, total_rows, imported_rows, new_customers, created_at, rolled_back_at
+		FROM import_batches WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`
+	if err := r.getExecer(ctx).SelectContext(ctx, &list, query, userID, limit); err != nil {
+		return nil, fmt.Errorf("list import batches user=%s: %w", userID, err)
+	}
+	return list, nil
+}
+
+// TagCustomerImportBatch 标记客户由某导入批次新建
+func (r *Repository) TagCustomerImportBatch(ctx context.Context, customerID, batchID uuid.UUID) error {
+	if _, err := r.getExecer(ctx).ExecContext(ctx, `UPDATE customers SET import_batch_id = $1 WHERE id = $2`, batchID, customerID); err != nil {
+		return fmt.Errorf("tag customer import batch id=%s: %w", customerID, err)
+	}
+	return nil
+}
+
+// CreateImportedFollowRecord 写入一条导入的跟进记录（ai=false，带批次号）并写入首个版本与 follow_record.created 事件
+func (r *Repository) CreateImportedFollowRecord(ctx context.Context, record *models.FollowRecord, batchID uuid.UUID) error {
+	a := auditFromContext(ctx)
+	sealed, err := sealFollowRecord(record)
+	if err != nil {
+		return err
+	}
+	query := `WITH w AS (INSERT INTO follow_records (id, user_id, customer_id, customer_name, contact_person, contact_phone, contact_role,
+		follow_time, follow_method, follow_content, follow_goal, follow_result, risk_content, next_plan, ai, import_batch_id, contact_phone_bidx)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, false, $15, $18) RETURNING *) ` +
+		fmt.Sprintf(insertVersionFrom, "'"+models.RecordOpCreate+"'", "$16", "$17")
+	return r.inTx(ctx, func(ctx context.Context) error {
+		_, err := r.getExecer(ctx).ExecContext(ctx, query, record.ID, record.UserID, record.CustomerID, record.CustomerName,
+			record.ContactPerson, sealed.ContactPhone, record.ContactRole, record.FollowTime, record.FollowMethod,
+			record.FollowContent, record.FollowGoal, record.FollowResult, record.RiskContent, record.NextPlan, batchID,
+			a.Source, a.actorArg(), sealed.ContactPhoneBidx)
+		if err != nil {
+			return fmt.Errorf("create imported follow record customer=%s: %w", record.CustomerID, err)
+		}
+		record.Version = 1
+		return r.enqueueRecordEvent(ctx, models.WebhookRecordCreated, record)
+	})
+}
+
+// RollbackImportBatch 物理删除批次导入的跟进记录（删除前的快照记入版本历史并写入 follow_record.deleted 事件），
+// 以及该批次新建且已无其他引用的客户，并将批次标记为已回滚
+func (r *Repository) RollbackImportBatch(ctx context.Context, id uuid.UUID) (deletedRecords, deletedCustomers int64, err error) {
+	exec := r.getExecer(ctx)
+	a := auditFromContext(ctx)
+	var deleted []*models.FollowRecord
+	err = exec.SelectContext(ctx, &deleted, `WITH w AS (DELETE FROM follow_records WHERE import_batch_id = $1 RETURNING *),
+		v AS (INSERT INTO follow_record_versions (record_id, version, op, source, actor_id, snapshot)
+		SELECT w.id, w.version + 1, '`+models.RecordOpDelete+`', $2, $3, to_jsonb(w) FROM w)
+		SELECT `+followRecordColumns+`, NOW() AS deleted_at FROM w`, id, a.Source, a.actorArg())
+	if err != nil {
+		return 0, 0, fmt.Errorf("delete imported follow records batch=%s: %w", id, err)
+	}
+	deletedRecords = int64(len(deleted))
+	for _, record := range deleted {
+		record.Version++
+		if err := r.enqueueRecordEvent(ctx, models.WebhookRecordDeleted, record); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	res, err := exec.ExecContext(ctx, `DELETE FROM customers c WHERE c.import_batch_id = $1
+		AND NOT EXISTS (SELECT 1 FROM follow_records fr WHERE fr.customer_id = c.id)
+		AND NOT EXISTS (SELECT 1 FROM follow_tasks t WHERE t.customer_id = c.id)
+		AND NOT EXISTS (SELECT 1 FROM dialogs d WHERE d.focus_customer_id = c.id)`, id)
+	if err != nil {
+		return 0, 0, fmt.Errorf("delete imported customers batch=%s: %w", id, err)
+	}
+	deletedCustomers, _ = res.RowsAffected()
+
+	if _, err := exec.ExecContext(ctx, `UPDATE import_batches SET status = $1, rolled_back_at = NOW() WHERE id = $2`, models.ImportBatchRolledBack, id); err != nil {
+		return 0, 0, fmt.Errorf("mark import batch rolled back id=%s: %w", id, err)
+	}
+	return deletedRecords, deletedCustomers, nil
+}
+
+// FollowRecordExists 是否已存在相同销售、客户名与跟进时间的记录（导入去重）
+func (r *Repository) FollowRecordExists(ctx context.Context, userID, customerName string, followTime time.Time) (bool, error) {
+	var n int
+	query := `SELECT COUNT(*) FROM follow_records WHERE user_id = $1 AND customer_name = $2 AND follow_time = $3 AND deleted_at IS NULL`
+	if err := r.getExecer(ctx).GetContext(ctx, &n, query, userID, customerName, followTime); err != nil {
+		return false, fmt.Errorf("check follow record exists user=%s customer=%s: %w", userID, customerName, err)
+	}
+	return n > 0, nil
+}
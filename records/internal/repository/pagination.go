@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// 分页参数
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// ErrInvalidCursor 游标无法解析或与当前排序不一致
+var ErrInvalidCursor = errors.New("无效的 cursor")
+
+// 跟进记录列表排序字段
+const (
+	SortFollowTime = "follow_time"
+	SortCreatedAt  = "created_at"
+)
+
+// Cursor 游标：上一页最后一条的排序键（时间 + 主键），Sort/Desc 用于校验游标与当前排序一致
+type Cursor struct {
+	Sort string
+	Desc bool
+	At   time.Time
+	ID   string
+}
+
+// Encode 编码为 URL 安全的不透明字符串
+func (c Cursor) Encode() string {
+	dir := "asc"
+	if c.Desc {
+		dir = "desc"
+	}
+	raw := strings.Join([]string{c.Sort, dir, c.At.UTC().Format(time.RFC3339Nano), c.ID}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor 解析游标，须与当前排序字段及方向一致
+func DecodeCursor(s, sort string, desc bool) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 4)
+	if len(parts) != 4 {
+		return nil, ErrInvalidCursor
+	}
+	at, err := time.Parse(time.RFC3339Nano, parts[2])
+	if err != nil || parts[3] == "" {
+		return nil, ErrInvalidCursor
+	}
+	c := &Cursor{Sort: parts[0], Desc: parts[1] == "desc", At: at, ID: parts[3]}
+	if c.Sort != sort || c.Desc != desc {
+		return nil, fmt.Errorf("%w：与排序方式不一致", ErrInvalidCursor)
+	}
+	return c, nil
+}
+
+// clampLimit 规范每页条数
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return limit
+}
+
+// keysetCond 游标条件：(sortColumn, idColumn) 严格位于游标之后
+func keysetCond(sortColumn, idColumn string, desc bool, atArg, idArg string) string {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s (%s, %s)", sortColumn, idColumn, op, atArg, idArg)
+}
+
+// RecordListOptions 跟进记录分页查询条件；零值字段表示不限
+type RecordListOptions struct {
+	Limit        int
+	Cursor       string     // 上一页返回的 next_cursor
+	Sort         string     // follow_time（默认）或 created_at
+	Desc         bool       // 倒序
+	CustomerID   *uuid.UUID // 指定客户
+	CustomerName string     // 客户名模糊匹配
+	FollowMethod string     // 跟进方式（精确）
+	From         *time.Time // 跟进时间下限（含）
+	To           *time.Time // 跟进时间上限（不含）
+	AI           *bool      // 是否 AI 录入
+}
+
+// RecordPage 跟进记录分页结果
+type RecordPage struct {
+	Items      []*FollowRecordWithCustomerID
+	NextCursor string // 为空表示没有下一页
+	Total      int    // 符合筛选条件的总数（与游标无关）
+}
+
+// ListFollowRecordsPageForUser 按游标分页返回某用户的跟进记录（page 列表使用）。
+// 排序键为 (排序字段, id)，由 idx_follow_records_user_follow_time / idx_follow_records_user_id 支撑
+func (r *Repository) ListFollowRecordsPageForUser(ctx context.Context, userID string, opts RecordListOptions) (*RecordPage, error) {
+	page := &RecordPage{Items: []*FollowRecordWithCustomerID{}}
+	if userID == "" {
+		return page, nil
+	}
+	sort := opts.Sort
+	if sort == "" {
+		sort = SortFollowTime
+	}
+	if sort != SortFollowTime && sort != SortCreatedAt {
+		return nil, fmt.Errorf("不支持的排序字段：%s", sort)
+	}
+	limit := clampLimit(opts.Limit)
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conds := []string{"fr.user_id = " + arg(userID), "fr.deleted_at IS NULL"}
+	if opts.CustomerID != nil {
+		conds = append(conds, "fr.customer_id = "+arg(*opts.CustomerID))
+	}
+	if opts.CustomerName != "" {
+		conds = append(conds, "fr.customer_name ILIKE '%' || "+arg(opts.CustomerName)+" || '%'")
+	}
+	if opts.FollowMethod != "" {
+		conds = append(conds, "fr.follow_method = "+arg(opts.FollowMethod))
+	}
+	if opts.From != nil {
+		conds = append(conds, "fr.follow_time >= "+arg(*opts.From))
+	}
+	if opts.To != nil {
+		conds = append(conds, "fr.follow_time < "+arg(*opts.To))
+	}
+	if opts.AI != nil {
+		conds = append(conds, "fr.ai = "+arg(*opts.AI))
+	}
+
+	executor := r.getExecer(ctx)
+	countQuery := `SELECT COUNT(*) FROM follow_records fr WHERE ` + strings.Join(conds, " AND ")
+	if err := executor.GetContext(ctx, &page.Total, countQuery, args...); err != nil {
+		return nil, fmt.Errorf("count follow records page user=%s: %w", userID, err)
+	}
+
+	if opts.Cursor != "" {
+		c, err := DecodeCursor(opts.Cursor, sort, opts.Desc)
+		if err != nil {
+			return nil, err
+		}
+		id, err := uuid.Parse(c.ID)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		conds = append(conds, keysetCond("fr."+sort, "fr.id", opts.Desc, arg(c.At), arg(id)))
+	}
+	dir := "ASC"
+	if opts.Desc {
+		dir = "DESC"
+	}
+	query := `SELECT fr.id, fr.user_id, fr.customer_id, fr.customer_name, fr.contact_person, fr.contact_phone, fr.contact_role,
+		fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan, fr.ai, fr.version, fr.created_at,
+		fr.customer_id::text AS customer_id_str
+		FROM follow_records fr
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY fr.` + sort + ` ` + dir + `, fr.id ` + dir + `
+		LIMIT ` + arg(limit+1)
+	if err := executor.SelectContext(ctx, &page.Items, query, args...); err != nil {
+		return nil, fmt.Errorf("list follow records page user=%s: %w", userID, err)
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		at := last.FollowTime
+		if sort == SortCreatedAt {
+			at = last.CreatedAt
+		}
+		page.NextCursor = Cursor{Sort: sort, Desc: opts.Desc, At: at, ID: last.ID.String()}.Encode()
+	}
+	return page, nil
+}
+
+// UserListOptions 主管用户列表分页查询条件
+type UserListOptions struct {
+	Limit  int
+	Cursor string // 上一页返回的 next_cursor
+	Desc   bool   // 按最近记录时间倒序（无记录的用户排在最后）
+	Name   string // 姓名模糊匹配
+}
+
+// ManagerUserPage 主管用户列表分页结果
+type ManagerUserPage struct {
+	Items      []*ManagerUser
+	NextCursor string
+	Total      int
+}
+
+// noRecordAt 无记录用户的排序时间，使其在倒序时排在最后
+var noRecordAt = time.Unix(0, 0).UTC()
+
+// ListUsersPageForManager 按游标分页返回该管理员可查看的用户，排序键为 (最近记录时间, user_id)
+func (r *Repository) ListUsersPageForManager(ctx context.Context, managerID string, opts UserListOptions) (*ManagerUserPage, error) {
+	page := &ManagerUserPage{Items: []*ManagerUser{}}
+	scopeIDs, err := r.GetManagerScopeUserIDs(ctx, managerID)
+	if err != nil {
+		return nil, err
+	}
+	if scopeIDs != nil && len(scopeIDs) == 0 {
+		return page, nil
+	}
+	limit := clampLimit(opts.Limit)
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conds := []string{"TRUE"}
+	if scopeIDs != nil {
+		conds = append(conds, "u.id = ANY("+arg(pq.Array(scopeIDs))+")")
+	}
+	if opts.Name != "" {
+		conds = append(conds, "u.name ILIKE '%' || "+arg(opts.Name)+" || '%'")
+	}
+
+	executor := r.getExecer(ctx)
+	countQuery := `SELECT COUNT(*) FROM users u WHERE ` + strings.Join(conds, " AND ")
+	if err := executor.GetContext(ctx, &page.Total, countQuery, args...); err != nil {
+		return nil, fmt.Errorf("count users for manager: %w", err)
+	}
+
+	sortExpr := "COALESCE(s.last_record_at, " + arg(noRecordAt) + ")"
+	if opts.Cursor != "" {
+		c, err := DecodeCursor(opts.Cursor, "last_record_at", opts.Desc)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, keysetCond(sortExpr, "u.id", opts.Desc, arg(c.At), arg(c.ID)))
+	}
+	dir := "ASC"
+	if opts.Desc {
+		dir = "DESC"
+	}
+	// 记录统计走 idx_follow_records_user_id(user_id, created_at) 索引扫描
+	query := `SELECT u.id AS user_id, COALESCE(u.name, u.id) AS name,
+		COALESCE(s.record_count, 0) AS record_count, s.last_record_at
+		FROM users u
+		LEFT JOIN (
+			SELECT user_id, COUNT(*) AS record_count, MAX(created_at) AS last_record_at
+			FROM follow_records WHERE deleted_at IS NULL GROUP BY user_id
+		) s ON s.user_id = u.id
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY ` + sortExpr + ` ` + dir + `, u.id ` + dir + `
+		LIMIT ` + arg(limit+1)
+	if err := executor.SelectContext(ctx, &page.Items, query, args...); err != nil {
+		return nil, fmt.Errorf("list users page for manager: %w", err)
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		at := noRecordAt
+		if last.LastRecordAt != nil {
+			at = *last.LastRecordAt
+		}
+		page.NextCursor = Cursor{Sort: "last_record_at", Desc: opts.Desc, At: at, ID: last.UserID}.Encode()
+	}
+	return page, nil
+}
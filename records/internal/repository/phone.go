@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"records/internal/models"
+	"records/internal/phonecrypt"
+)
+
+// 联系电话加密存储：写入前加密 contact_phone 并计算盲索引 contact_phone_bidx；读取返回存储值（可能为密文），
+// 由展示方按权限经 phonecrypt.Reveal 解密或脱敏
+
+// sealCustomer 返回联系电话已加密并带盲索引的副本，调用方的 customer 不变
+func sealCustomer(customer *models.Customer) (*models.Customer, error) {
+	sealed := *customer
+	var err error
+	if sealed.ContactPhone, sealed.ContactPhoneBidx, err = phonecrypt.Seal(customer.ContactPhone); err != nil {
+		return nil, fmt.Errorf("encrypt customer phone id=%s: %w", customer.ID, err)
+	}
+	return &sealed, nil
+}
+
+// sealFollowRecord 返回联系电话已加密并带盲索引的副本，调用方的 record 不变
+func sealFollowRecord(record *models.FollowRecord) (*models.FollowRecord, error) {
+	sealed := *record
+	var err error
+	if sealed.ContactPhone, sealed.ContactPhoneBidx, err = phonecrypt.Seal(record.ContactPhone); err != nil {
+		return nil, fmt.Errorf("encrypt follow record phone id=%s: %w", record.ID, err)
+	}
+	return &sealed, nil
+}
+
+// 存有联系电话的表；follow_record_versions 的号码在快照 JSON 中
+const (
+	PhoneTableCustomers     = "customers"
+	PhoneTableFollowRecords = "follow_records"
+	PhoneTableVersions      = "follow_record_versions"
+)
+
+// phoneTables 各表联系电话的取值表达式与主键类型
+var phoneTables = map[string]struct {
+	column string
+	idType string
+}{
+	PhoneTableCustomers:     {"contact_phone", "uuid"},
+	PhoneTableFollowRecords: {"contact_phone", "uuid"},
+	PhoneTableVersions:      {"snapshot->>'contact_phone'", "bigint"},
+}
+
+// StoredPhone 一条存储的联系电话（明文或密文）
+type StoredPhone struct {
+	ID    string `db:"id"`
+	Phone string `db:"contact_phone"`
+}
+
+// ListPhonesToReseal 按主键顺序返回 afterID（空表示从头）之后联系电话不以 keepPrefix 开头的行，即明文或旧主密钥加密的号码
+func (r *Repository) ListPhonesToReseal(ctx context.Context, table, keepPrefix, afterID string, limit int) ([]*StoredPhone, error) {
+	t, ok := phoneTables[table]
+	if !ok {
+		return nil, fmt.Errorf("unknown phone table %s", table)
+	}
+	after := "TRUE"
+	args := []interface{}{keepPrefix, limit}
+	if afterID != "" {
+		after = "t.id > $3::" + t.idType
+		args = append(args, afterID)
+	}
+	// 按 t.id 排序：输出列 id 为文本，按其排序时 bigint 主键顺序不对
+	query := fmt.Sprintf(`SELECT t.id::text AS id, t.%[1]s AS contact_phone FROM %[2]s t
+		WHERE COALESCE(t.%[1]s, '') <> '' AND left(t.%[1]s, length($1::text)) <> $1::text AND %[3]s
+		ORDER BY t.id LIMIT $2`, t.column, table, after)
+	var list []*StoredPhone
+	if err := r.getExecer(ctx).SelectContext(ctx, &list, query, args...); err != nil {
+		return nil, fmt.Errorf("list phones to reseal table=%s: %w", table, err)
+	}
+	return list, nil
+}
+
+// ResealPhone 将一行的联系电话替换为新的存储值与盲索引；仅在存储值仍为 old 时更新，避免覆盖并发写入。
+// 不修改 updated_at / version，不触发 CRM、多维表格同步与新版本
+func (r *Repository) ResealPhone(ctx context.Context, table, id, old, stored string, bidx *string) (bool, error) {
+	var query string
+	switch table {
+	case PhoneTableCustomers, PhoneTableFollowRecords:
+		query = `UPDATE ` + table + ` SET contact_phone = $2, contact_phone_bidx = $3 WHERE id = $1::uuid AND contact_phone = $4`
+	case PhoneTableVersions:
+		query = `UPDATE follow_record_versions
+			SET snapshot = jsonb_set(jsonb_set(snapshot, '{contact_phone}', to_jsonb($2::text)), '{contact_phone_bidx}', COALESCE(to_jsonb($3::text), 'null'::jsonb))
+			WHERE id = $1::bigint AND snapshot->>'contact_phone' = $4`
+	default:
+		return false, fmt.Errorf("unknown phone table %s", table)
+	}
+	res, err := r.getExecer(ctx).ExecContext(ctx, query, id, stored, bidx, old)
+	if err != nil {
+		return false, fmt.Errorf("reseal phone table=%s id=%s: %w", table, id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("reseal phone table=%s id=%s: %w", table, id, err)
+	}
+	return n > 0, nil
+}
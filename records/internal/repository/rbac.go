@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"records/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// 角色权限：rbac_roles / rbac_role_permissions / rbac_user_roles，数据范围按授权的 scope_type 解析为 user_id
+
+// scopeUsersQuery 解析用户在某权限下各授权范围覆盖的用户（不含 all 范围）
+const scopeUsersQuery = `SELECT DISTINCT u.id FROM rbac_user_roles a
+	JOIN rbac_role_permissions p ON p.role = a.role AND p.permission = $2
+	JOIN users u ON
+		(a.scope_type = 'users' AND u.id = ANY(a.scope_values))
+		OR (a.scope_type = 'department' AND EXISTS (
+			SELECT 1 FROM user_departments d WHERE d.user_id = u.id AND d.department_id = ANY(a.scope_values)))
+		OR (a.scope_type = 'department_tree' AND EXISTS (
+			SELECT 1 FROM user_departments d LEFT JOIN departments dp ON dp.id = d.department_id AND dp.deleted_at IS NULL
+			WHERE d.user_id = u.id AND (d.department_id = ANY(a.scope_values) OR dp.ancestors && a.scope_values)))
+		OR (a.scope_type = 'org' AND EXISTS (
+			SELECT 1 FROM unnest(a.scope_values) o WHERE u.orgname = o OR left(u.orgname, length(o) + 1) = o || '.'))
+	WHERE a.user_id = $1
+	ORDER BY u.id`
+
+// HasPermission 查询用户是否被授予某权限（任意范围）
+func (r *Repository) HasPermission(ctx context.Context, userID, perm string) (bool, error) {
+	var n int
+	query := `SELECT 1 FROM rbac_user_roles a JOIN rbac_role_permissions p ON p.role = a.role AND p.permission = $2
+		WHERE a.user_id = $1 LIMIT 1`
+	if err := r.getExecer(ctx).GetContext(ctx, &n, query, userID, perm); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("has permission user=%s perm=%s: %w", userID, perm, err)
+	}
+	return true, nil
+}
+
+// PermissionScope 返回用户在某权限下可访问的 user_id：nil 表示全部，空切片表示无。
+// 本人数据不依赖授权，由调用方单独放行
+func (r *Repository) PermissionScope(ctx context.Context, userID, perm string) ([]string, error) {
+	executor := r.getExecer(ctx)
+	var n int
+	query := `SELECT 1 FROM rbac_user_roles a JOIN rbac_role_permissions p ON p.role = a.role AND p.permission = $2
+		WHERE a.user_id = $1 AND a.scope_type = 'all' LIMIT 1`
+	err := executor.GetContext(ctx, &n, query, userID, perm)
+	if err == nil {
+		return nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("get permission scope user=%s perm=%s: %w", userID, perm, err)
+	}
+	ids := []string{}
+	if err := executor.SelectContext(ctx, &ids, scopeUsersQuery, userID, perm); err != nil {
+		return nil, fmt.Errorf("resolve permission scope user=%s perm=%s: %w", userID, perm, err)
+	}
+	return ids, nil
+}
+
+// UserPermissions 返回用户被授予的权限点
+func (r *Repository) UserPermissions(ctx context.Context, userID string) ([]string, error) {
+	perms := []string{}
+	query := `SELECT DISTINCT p.permission FROM rbac_user_roles a JOIN rbac_role_permissions p ON p.role = a.role
+		WHERE a.user_id = $1 ORDER BY p.permission`
+	if err := r.getExecer(ctx).SelectContext(ctx, &perms, query, userID); err != nil {
+		return nil, fmt.Errorf("list user permissions user=%s: %w", userID, err)
+	}
+	return perms, nil
+}
+
+// ListRoles 返回全部角色及其权限点
+func (r *Repository) ListRoles(ctx context.Context) ([]*models.Role, error) {
+	executor := r.getExecer(ctx)
+	var roles []*models.Role
+	if err := executor.SelectContext(ctx, &roles, `SELECT name, description, created_at FROM rbac_roles ORDER BY name`); err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+	var rows []struct {
+		Role       string `db:"role"`
+		Permission string `db:"permission"`
+	}
+	if err := executor.SelectContext(ctx, &rows, `SELECT role, permission FROM rbac_role_permissions ORDER BY role, permission`); err != nil {
+		return nil, fmt.Errorf("list role permissions: %w", err)
+	}
+	byName := make(map[string]*models.Role, len(roles))
+	for _, role := range roles {
+		role.Permissions = []string{}
+		byName[role.Name] = role
+	}
+	for _, row := range rows {
+		if role := byName[row.Role]; role != nil {
+			role.Permissions = append(role.Permissions, row.Permission)
+		}
+	}
+	return roles, nil
+}
+
+// SaveRole 创建或更新角色，权限点整体替换
+func (r *Repository) SaveRole(ctx context.Context, role *models.Role) error {
+	return r.WithTx(ctx, func(ctx context.Context) error {
+		executor := r.getExecer(ctx)
+		query := `INSERT INTO rbac_roles (name, description) VALUES ($1, $2)
+			ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description, updated_at = NOW()`
+		if _, err := executor.ExecContext(ctx, query, role.Name, role.Description); err != nil {
+			return fmt.Errorf("save role name=%s: %w", role.Name, err)
+		}
+		if _, err := executor.ExecContext(ctx, `DELETE FROM rbac_role_permissions WHERE role = $1`, role.Name); err != nil {
+			return fmt.Errorf("clear role permissions name=%s: %w", role.Name, err)
+		}
+		query = `INSERT INTO rbac_role_permissions (role, permission) SELECT $1, unnest($2::text[]) ON CONFLICT DO NOTHING`
+		if _, err := executor.ExecContext(ctx, query, role.Name, pq.Array(role.Permissions)); err != nil {
+			return fmt.Errorf("save role permissions name=%s: %w", role.Name, err)
+		}
+		return nil
+	})
+}
+
+// DeleteRole 删除角色，其授权一并删除；角色不存在返回 false
+func (r *Repository) DeleteRole(ctx context.Context, name string) (bool, error) {
+	result, err := r.getExecer(ctx).ExecContext(ctx, `DELETE FROM rbac_roles WHERE name = $1`, name)
+	if err != nil {
+		return false, fmt.Errorf("delete role name=%s: %w", name, err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ListRoleAssignments 返回用户授权；userID 为空时返回全部
+func (r *Repository) ListRoleAssignments(ctx context.Context, userID string) ([]*models.RoleAssignment, error) {
+	list := []*models.RoleAssignment{}
+	query := `SELECT a.id, a.user_id, u.name AS user_name, a.role, a.scope_type, a.scope_values, a.created_by, a.created_at
+		FROM rbac_user_roles a LEFT JOIN users u ON u.id = a.user_id
+		WHERE ($1 = '' OR a.user_id = $1)
+		ORDER BY a.user_id, a.id`
+	if err := r.getExecer(ctx).SelectContext(ctx, &list, query, userID); err != nil {
+		return nil, fmt.Errorf("list role assignments: %w", err)
+	}
+	return list, nil
+}
+
+// CreateRoleAssignment 新增用户授权，回填 ID 与创建时间
+func (r *Repository) CreateRoleAssignment(ctx context.Context, a *models.RoleAssignment) error {
+	if a.ScopeValues == nil {
+		a.ScopeValues = pq.StringArray{}
+	}
+	query := `INSERT INTO rbac_user_roles (user_id, role, scope_type, scope_values, created_by)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	var row struct {
+		ID        int64     `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	if err := r.getExecer(ctx).GetContext(ctx, &row, query, a.UserID, a.Role, a.ScopeType, a.ScopeValues, a.CreatedBy); err != nil {
+		return fmt.Errorf("create role assignment user=%s role=%s: %w", a.UserID, a.Role, err)
+	}
+	a.ID, a.CreatedAt = row.ID, row.CreatedAt
+	return nil
+}
+
+// DeleteRoleAssignment 删除用户授权；不存在返回 false
+func (r *Repository) DeleteRoleAssignment(ctx context.Context, id int64) (bool, error) {
+	result, err := r.getExecer(ctx).ExecContext(ctx, `DELETE FROM rbac_user_roles WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("delete role assignment id=%d: %w", id, err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// SetUserDepartments 以飞书返回的部门列表整体替换用户所属部门
+func (r *Repository) SetUserDepartments(ctx context.Context, userID string, departmentIDs []string) error {
+	return r.WithTx(ctx, func(ctx context.Context) error {
+		executor := r.getExecer(ctx)
+		if _, err := executor.ExecContext(ctx, `DELETE FROM user_departments WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("clear user departments user=%s: %w", userID, err)
+		}
+		query := `INSERT INTO user_departments (user_id, department_id) SELECT $1, unnest($2::text[]) ON CONFLICT DO NOTHING`
+		if _, err := executor.ExecContext(ctx, query, userID, pq.Array(departmentIDs)); err != nil {
+			return fmt.Errorf("set user departments user=%s: %w", userID, err)
+		}
+		return nil
+	})
+}
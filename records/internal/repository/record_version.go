@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"records/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrVersionConflict 乐观并发冲突：读取后数据已被其他请求修改
+var ErrVersionConflict = errors.New("version conflict")
+
+type auditKey struct{}
+
+// Audit 写入跟进记录时的操作人与来源，记入 follow_record_versions
+type Audit struct {
+	ActorID string // 为空表示系统操作
+	Source  string // models.RecordSource*
+}
+
+// WithAudit 在 ctx 中标注后续跟进记录写入的操作人与来源
+func WithAudit(ctx context.Context, actorID, source string) context.Context {
+	return context.WithValue(ctx, auditKey{}, Audit{ActorID: actorID, Source: source})
+}
+
+// auditFromContext 取 ctx 中的操作人与来源；未标注时视为系统操作
+func auditFromContext(ctx context.Context) Audit {
+	if a, ok := ctx.Value(auditKey{}).(Audit); ok && a.Source != "" {
+		return a
+	}
+	return Audit{Source: models.RecordSourceSystem}
+}
+
+// actorArg 操作人参数，空串写入 NULL
+func (a Audit) actorArg() *string {
+	if a.ActorID == "" {
+		return nil
+	}
+	return &a.ActorID
+}
+
+// auditedRecord 带版本信息的命名参数：跟进记录字段 + 版本操作、来源与操作人
+type auditedRecord struct {
+	*models.FollowRecord
+	AuditOp     string  `db:"audit_op"`
+	AuditSource string  `db:"audit_source"`
+	AuditActor  *string `db:"audit_actor"`
+}
+
+func newAuditedRecord(ctx context.Context, record *models.FollowRecord, op string) auditedRecord {
+	a := auditFromContext(ctx)
+	return auditedRecord{FollowRecord: record, AuditOp: op, AuditSource: a.Source, AuditActor: a.actorArg()}
+}
+
+// followRecordColumns 跟进记录常用列
+const followRecordColumns = `id, user_id, customer_id, customer_name, contact_person, contact_phone, contact_role, follow_time, follow_method, follow_content, follow_goal, follow_result, risk_content, next_plan, ai, version, created_at`
+
+// insertVersionFrom 将 CTE w 中写入后的行记为版本快照；与写入在同一语句内完成，version 由行锁保证递增
+const insertVersionFrom = `INSERT INTO follow_record_versions (record_id, version, op, source, actor_id, snapshot)
+	SELECT w.id, w.version, %s, %s, %s, to_jsonb(w) FROM w`
+
+// RestoreFollowRecord 恢复本人已软删除的跟进记录并记录版本，同一事务内写入 follow_record.restored 事件；返回是否有记录被恢复
+func (r *Repository) RestoreFollowRecord(ctx context.Context, id uuid.UUID, userID string) (bool, error) {
+	a := auditFromContext(ctx)
+	query := `WITH w AS (
+		UPDATE follow_records SET deleted_at = NULL, deleted_by = NULL, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL RETURNING *
+	) ` + fmt.Sprintf(insertVersionFrom, "'"+models.RecordOpRestore+"'", "$4", "$3")
+	restored := false
+	err := r.inTx(ctx, func(ctx context.Context) error {
+		result, err := r.getExecer(ctx).ExecContext(ctx, query, id, userID, a.actorArg(), a.Source)
+		if err != nil {
+			return fmt.Errorf("restore follow record id=%s: %w", id, err)
+		}
+		rows, _ := result.RowsAffected()
+		if restored = rows > 0; !restored {
+			return nil
+		}
+		return r.enqueueRecordEventByID(ctx, models.WebhookRecordRestored, id)
+	})
+	return restored, err
+}
+
+// GetFollowRecordIncludingDeleted 按 ID 获取跟进记录（含已软删除），不存在返回 nil
+func (r *Repository) GetFollowRecordIncludingDeleted(ctx context.Context, id uuid.UUID) (*models.FollowRecord, error) {
+	var record models.FollowRecord
+	query := `SELECT ` + followRecordColumns + `, deleted_at FROM follow_records WHERE id = $1`
+	if err := r.getExecer(ctx).GetContext(ctx, &record, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get follow record including deleted id=%s: %w", id, err)
+	}
+	return &record, nil
+}
+
+// ListDeletedFollowRecords 返回本人已软删除的跟进记录，按删除时间倒序
+func (r *Repository) ListDeletedFollowRecords(ctx context.Context, userID string, limit int) ([]*models.FollowRecord, error) {
+	var list []*models.FollowRecord
+	query := `SELECT ` + followRecordColumns + `, deleted_at FROM follow_records
+		WHERE user_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT $2`
+	if err := r.getExecer(ctx).SelectContext(ctx, &list, query, userID, clampLimit(limit)); err != nil {
+		return nil, fmt.Errorf("list deleted follow records user=%s: %w", userID, err)
+	}
+	return list, nil
+}
+
+// ListFollowRecordVersions 返回跟进记录的全部版本（含操作人姓名），按版本号升序
+func (r *Repository) ListFollowRecordVersions(ctx context.Context, recordID uuid.UUID) ([]*models.FollowRecordVersion, error) {
+	var list []*models.FollowRecordVersion
+	query := `SELECT v.id, v.record_id, v.version, v.op, v.source, v.actor_id, u.name AS actor_name, v.snapshot, v.created_at
+		FROM follow_record_versions v LEFT JOIN users u ON u.id = v.actor_id
+		WHERE v.record_id = $1 ORDER BY v.version`
+	if err := r.getExecer(ctx).SelectContext(ctx, &list, query, recordID); err != nil {
+		return nil, fmt.Errorf("list follow record versions id=%s: %w", recordID, err)
+	}
+	return list, nil
+}
@@ -23,10 +23,11 @@ func New(db *sqlx.DB) *Repository {
 
 type txKey struct{}
 
+// execer 事务或数据库连接
 type execer interface {
+	sqlx.ExtContext
 	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
-	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
 }
 
@@ -37,6 +38,11 @@ func (r *Repository) getExecer(ctx context.Context) execer {
 	return r.db
 }
 
+// Execer 返回 ctx 中由 WithTx 开启的事务，没有则返回数据库连接；供其他包的数据访问加入同一事务
+func (r *Repository) Execer(ctx context.Context) sqlx.ExtContext {
+	return r.getExecer(ctx)
+}
+
 func (r *Repository) GetUser(ctx context.Context, userID string) (*models.User, error) {
 	var user models.User
 	executor := r.getExecer(ctx)
@@ -117,7 +123,7 @@ func (r *Repository) UpdateUserStartLark(ctx context.Context, userID string) err
 func (r *Repository) GetCustomer(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
 	var customer models.Customer
 	executor := r.getExecer(ctx)
-	err := executor.GetContext(ctx, &customer, "SELECT id, name, contact_person, contact_phone, contact_role FROM customers WHERE id = $1", customerID)
+	err := executor.GetContext(ctx, &customer, "SELECT id, name, contact_person, contact_phone, contact_role, updated_at FROM customers WHERE id = $1", customerID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -151,22 +157,46 @@ func (r *Repository) GetAllCustomers(ctx context.Context) ([]*models.Customer, e
 }
 
 func (r *Repository) CreateCustomer(ctx context.Context, customer *models.Customer) error {
-	query := `INSERT INTO customers (id, name, contact_person, contact_phone, contact_role) VALUES (:id, :name, :contact_person, :contact_phone, :contact_role)`
+	sealed, err := sealCustomer(customer)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO customers (id, name, contact_person, contact_phone, contact_phone_bidx, contact_role) VALUES (:id, :name, :contact_person, :contact_phone, :contact_phone_bidx, :contact_role)`
 	executor := r.getExecer(ctx)
-	_, err := executor.NamedExecContext(ctx, query, customer)
+	_, err = executor.NamedExecContext(ctx, query, sealed)
 	if err != nil {
 		return fmt.Errorf("create customer name=%s: %w", customer.Name, err)
 	}
 	return nil
 }
 
+// UpdateCustomer 更新客户；customer.UpdatedAt 非空时仅在库中 updated_at 未变时更新，否则返回 ErrVersionConflict。
+// 成功后 customer.UpdatedAt 更新为新值
 func (r *Repository) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
-	query := `UPDATE customers SET name = :name, contact_person = :contact_person, contact_phone = :contact_phone, contact_role = :contact_role, updated_at = NOW() WHERE id = :id`
-	executor := r.getExecer(ctx)
-	_, err := executor.NamedExecContext(ctx, query, customer)
+	sealed, err := sealCustomer(customer)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE customers SET name = :name, contact_person = :contact_person, contact_phone = :contact_phone, contact_phone_bidx = :contact_phone_bidx, contact_role = :contact_role, updated_at = NOW() WHERE id = :id`
+	if customer.UpdatedAt != nil {
+		query += ` AND updated_at = :updated_at`
+	}
+	query, args, err := sqlx.Named(query+` RETURNING updated_at`, sealed)
 	if err != nil {
+		return fmt.Errorf("bind update customer id=%s: %w", customer.ID, err)
+	}
+	var updatedAt time.Time
+	executor := r.getExecer(ctx)
+	if err := executor.GetContext(ctx, &updatedAt, r.db.Rebind(query), args...); err != nil {
+		if err == sql.ErrNoRows {
+			if customer.UpdatedAt != nil {
+				return ErrVersionConflict
+			}
+			return nil
+		}
 		return fmt.Errorf("update customer id=%s: %w", customer.ID, err)
 	}
+	customer.UpdatedAt = &updatedAt
 	return nil
 }
 
@@ -337,7 +367,7 @@ func (r *Repository) DeleteSession(ctx context.Context, sessionID uuid.UUID) err
 
 func (r *Repository) GetLatestFollowRecord(ctx context.Context, customerID uuid.UUID) (*models.FollowRecord, error) {
 	var record models.FollowRecord
-	query := `SELECT id, user_id, customer_id, customer_name, contact_person, contact_phone, contact_role, follow_time, follow_method, follow_content, follow_goal, follow_result, risk_content, next_plan, ai, created_at FROM follow_records WHERE customer_id = $1 ORDER BY follow_time DESC LIMIT 1`
+	query := `SELECT id, user_id, customer_id, customer_name, contact_person, contact_phone, contact_role, follow_time, follow_method, follow_content, follow_goal, follow_result, risk_content, next_plan, ai, version, created_at FROM follow_records WHERE customer_id = $1 AND deleted_at IS NULL ORDER BY follow_time DESC LIMIT 1`
 	executor := r.getExecer(ctx)
 	err := executor.GetContext(ctx, &record, query, customerID)
 	if err != nil {
@@ -349,29 +379,71 @@ func (r *Repository) GetLatestFollowRecord(ctx context.Context, customerID uuid.
 	return &record, nil
 }
 
+// CreateFollowRecord 新建跟进记录并写入首个版本（操作人与来源取自 WithAudit），同一事务内写入 follow_record.created 事件
 func (r *Repository) CreateFollowRecord(ctx context.Context, record *models.FollowRecord) error {
-	query := `INSERT INTO follow_records (id, user_id, customer_id, customer_name, contact_person, contact_phone, contact_role, follow_time, follow_method, follow_content, follow_goal, follow_result, risk_content, next_plan, ai) VALUES (:id, :user_id, :customer_id, :customer_name, :contact_person, :contact_phone, :contact_role, :follow_time, :follow_method, :follow_content, :follow_goal, :follow_result, :risk_content, :next_plan, :ai)`
-	executor := r.getExecer(ctx)
-	_, err := executor.NamedExecContext(ctx, query, record)
+	sealed, err := sealFollowRecord(record)
 	if err != nil {
-		return fmt.Errorf("create follow record customer=%s: %w", record.CustomerID, err)
+		return err
 	}
-	return nil
+	query := `WITH w AS (INSERT INTO follow_records (id, user_id, customer_id, customer_name, contact_person, contact_phone, contact_phone_bidx, contact_role, follow_time, follow_method, follow_content, follow_goal, follow_result, risk_content, next_plan, ai) VALUES (:id, :user_id, :customer_id, :customer_name, :contact_person, :contact_phone, :contact_phone_bidx, :contact_role, :follow_time, :follow_method, :follow_content, :follow_goal, :follow_result, :risk_content, :next_plan, :ai) RETURNING *) ` +
+		fmt.Sprintf(insertVersionFrom, ":audit_op", ":audit_source", ":audit_actor")
+	return r.inTx(ctx, func(ctx context.Context) error {
+		executor := r.getExecer(ctx)
+		_, err := executor.NamedExecContext(ctx, query, newAuditedRecord(ctx, sealed, models.RecordOpCreate))
+		if err != nil {
+			return fmt.Errorf("create follow record customer=%s: %w", record.CustomerID, err)
+		}
+		record.Version = 1
+		return r.enqueueRecordEvent(ctx, models.WebhookRecordCreated, record)
+	})
 }
 
+// UpdateFollowRecord 更新跟进记录并写入新版本（操作人与来源取自 WithAudit），同一事务内写入 follow_record.updated 事件。
+// record.Version 非零时仅在库中版本一致时更新，否则返回 ErrVersionConflict；成功后 record.Version 更新为新版本
 func (r *Repository) UpdateFollowRecord(ctx context.Context, record *models.FollowRecord) error {
-	query := `UPDATE follow_records SET customer_name = :customer_name, contact_person = :contact_person, contact_phone = :contact_phone, contact_role = :contact_role, follow_time = :follow_time, follow_method = :follow_method, follow_content = :follow_content, follow_goal = :follow_goal, follow_result = :follow_result, risk_content = :risk_content, next_plan = :next_plan, ai = :ai, updated_at = NOW() WHERE id = :id`
-	executor := r.getExecer(ctx)
-	_, err := executor.NamedExecContext(ctx, query, record)
+	return r.inTx(ctx, func(ctx context.Context) error {
+		updated, err := r.updateFollowRecord(ctx, record)
+		if err != nil || !updated {
+			return err
+		}
+		return r.enqueueRecordEvent(ctx, models.WebhookRecordUpdated, record)
+	})
+}
+
+// updateFollowRecord 执行更新，返回是否有记录被更新
+func (r *Repository) updateFollowRecord(ctx context.Context, record *models.FollowRecord) (bool, error) {
+	cond := ""
+	if record.Version > 0 {
+		cond = " AND version = :version"
+	}
+	sealed, err := sealFollowRecord(record)
 	if err != nil {
-		return fmt.Errorf("update follow record id=%s: %w", record.ID, err)
+		return false, err
 	}
-	return nil
+	query := `WITH w AS (UPDATE follow_records SET customer_id = :customer_id, customer_name = :customer_name, contact_person = :contact_person, contact_phone = :contact_phone, contact_phone_bidx = :contact_phone_bidx, contact_role = :contact_role, follow_time = :follow_time, follow_method = :follow_method, follow_content = :follow_content, follow_goal = :follow_goal, follow_result = :follow_result, risk_content = :risk_content, next_plan = :next_plan, ai = :ai, version = version + 1, updated_at = NOW() WHERE id = :id` + cond + ` RETURNING *) ` +
+		fmt.Sprintf(insertVersionFrom, ":audit_op", ":audit_source", ":audit_actor") + ` RETURNING version`
+	query, args, err := sqlx.Named(query, newAuditedRecord(ctx, sealed, models.RecordOpUpdate))
+	if err != nil {
+		return false, fmt.Errorf("bind update follow record id=%s: %w", record.ID, err)
+	}
+	var version int
+	executor := r.getExecer(ctx)
+	if err := executor.GetContext(ctx, &version, r.db.Rebind(query), args...); err != nil {
+		if err == sql.ErrNoRows {
+			if record.Version > 0 {
+				return false, ErrVersionConflict
+			}
+			return false, nil
+		}
+		return false, fmt.Errorf("update follow record id=%s: %w", record.ID, err)
+	}
+	record.Version = version
+	return true, nil
 }
 
 func (r *Repository) GetSessionFollowRecords(ctx context.Context, sessionID uuid.UUID) ([]*models.FollowRecord, error) {
 	var records []*models.FollowRecord
-	query := `SELECT fr.id, fr.user_id, fr.customer_id, fr.customer_name, fr.contact_person, fr.contact_phone, fr.contact_role, fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan, fr.ai, fr.created_at FROM follow_records fr JOIN dialogs d ON d.focus_customer_id = fr.customer_id WHERE d.session_id = $1 ORDER BY fr.follow_time DESC`
+	query := `SELECT fr.id, fr.user_id, fr.customer_id, fr.customer_name, fr.contact_person, fr.contact_phone, fr.contact_role, fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan, fr.ai, fr.version, fr.created_at FROM follow_records fr JOIN dialogs d ON d.focus_customer_id = fr.customer_id WHERE d.session_id = $1 AND fr.deleted_at IS NULL ORDER BY fr.follow_time DESC`
 	executor := r.getExecer(ctx)
 	err := executor.SelectContext(ctx, &records, query, sessionID)
 	if err != nil {
@@ -380,9 +452,10 @@ func (r *Repository) GetSessionFollowRecords(ctx context.Context, sessionID uuid
 	return records, nil
 }
 
+// GetCustomerFollowRecords 返回客户的全部跟进记录（含已软删除，合并客户时一并迁移）
 func (r *Repository) GetCustomerFollowRecords(ctx context.Context, customerID uuid.UUID) ([]*models.FollowRecord, error) {
 	var records []*models.FollowRecord
-	query := `SELECT id, user_id, customer_id, customer_name, contact_person, contact_phone, contact_role, follow_time, follow_method, follow_content, follow_goal, follow_result, risk_content, next_plan, ai, created_at FROM follow_records WHERE customer_id = $1 ORDER BY follow_time DESC`
+	query := `SELECT ` + followRecordColumns + ` FROM follow_records WHERE customer_id = $1 ORDER BY follow_time DESC`
 	executor := r.getExecer(ctx)
 	err := executor.SelectContext(ctx, &records, query, customerID)
 	if err != nil {
@@ -431,7 +504,7 @@ func (r *Repository) GetDistinctUserIDsInFollowRecords(ctx context.Context) ([]s
 	var rows []struct {
 		UserID string `db:"user_id"`
 	}
-	query := `SELECT DISTINCT user_id FROM follow_records ORDER BY user_id`
+	query := `SELECT DISTINCT user_id FROM follow_records WHERE deleted_at IS NULL ORDER BY user_id`
 	executor := r.getExecer(ctx)
 	if err := executor.SelectContext(ctx, &rows, query); err != nil {
 		return nil, fmt.Errorf("get distinct user_ids: %w", err)
@@ -450,11 +523,11 @@ func (r *Repository) ListFollowRecordsForPage(ctx context.Context, userID string
 		return records, nil
 	}
 	query := `SELECT fr.id, fr.user_id, fr.customer_id, fr.customer_name, fr.contact_person, fr.contact_phone, fr.contact_role,
-		fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan, fr.ai, fr.created_at,
+		fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan, fr.ai, fr.version, fr.created_at,
 		c.id::text AS customer_id_str
 		FROM follow_records fr
 		JOIN customers c ON fr.customer_id = c.id
-		WHERE fr.user_id = $1
+		WHERE fr.user_id = $1 AND fr.deleted_at IS NULL
 		ORDER BY fr.follow_time DESC`
 	executor := r.getExecer(ctx)
 	err := executor.SelectContext(ctx, &records, query, userID)
@@ -508,7 +581,7 @@ func (r *Repository) CreateFollowRecordForPage(ctx context.Context, userID, cust
 
 func (r *Repository) GetFollowRecordByID(ctx context.Context, id uuid.UUID) (*models.FollowRecord, error) {
 	var record models.FollowRecord
-	query := `SELECT id, user_id, customer_id, customer_name, contact_person, contact_phone, contact_role, follow_time, follow_method, follow_content, follow_goal, follow_result, risk_content, next_plan, ai, created_at FROM follow_records WHERE id = $1`
+	query := `SELECT ` + followRecordColumns + ` FROM follow_records WHERE id = $1 AND deleted_at IS NULL`
 	executor := r.getExecer(ctx)
 	err := executor.GetContext(ctx, &record, query, id)
 	if err != nil {
@@ -520,62 +593,40 @@ func (r *Repository) GetFollowRecordByID(ctx context.Context, id uuid.UUID) (*mo
 	return &record, nil
 }
 
+// DeleteFollowRecord 软删除本人的跟进记录并写入版本，可通过 RestoreFollowRecord 恢复；同一事务内写入 follow_record.deleted 事件。
+// 返回是否有记录被删除
 func (r *Repository) DeleteFollowRecord(ctx context.Context, id uuid.UUID, userID string) (bool, error) {
-	query := `DELETE FROM follow_records WHERE id = $1 AND user_id = $2`
-	executor := r.getExecer(ctx)
-	result, err := executor.ExecContext(ctx, query, id, userID)
-	if err != nil {
-		return false, fmt.Errorf("delete follow record id=%s: %w", id, err)
-	}
-	rows, _ := result.RowsAffected()
-	return rows > 0, nil
+	a := auditFromContext(ctx)
+	query := `WITH w AS (
+		UPDATE follow_records SET deleted_at = NOW(), deleted_by = $3, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL RETURNING *
+	) ` + fmt.Sprintf(insertVersionFrom, "'"+models.RecordOpDelete+"'", "$4", "$3")
+	deleted := false
+	err := r.inTx(ctx, func(ctx context.Context) error {
+		executor := r.getExecer(ctx)
+		result, err := executor.ExecContext(ctx, query, id, userID, a.actorArg(), a.Source)
+		if err != nil {
+			return fmt.Errorf("delete follow record id=%s: %w", id, err)
+		}
+		rows, _ := result.RowsAffected()
+		if deleted = rows > 0; !deleted {
+			return nil
+		}
+		return r.enqueueRecordEventByID(ctx, models.WebhookRecordDeleted, id)
+	})
+	return deleted, err
 }
 
-// Manager 页面与 records_scope：管理员可查看的日志范围
+// Manager 页面：主管即拥有 view 权限的用户，其范围由角色授权解析（见 rbac.go）
 
-// IsManager 查询该用户是否为管理员（在 records_scope 中作为 manager_id 存在）
+// IsManager 查询该用户是否为主管（被授予 view 权限）
 func (r *Repository) IsManager(ctx context.Context, userID string) (bool, error) {
-	var n int
-	query := `SELECT 1 FROM records_scope WHERE manager_id = $1 LIMIT 1`
-	executor := r.getExecer(ctx)
-	err := executor.GetContext(ctx, &n, query, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return false, nil
-		}
-		return false, fmt.Errorf("is manager: %w", err)
-	}
-	return true, nil
+	return r.HasPermission(ctx, userID, models.PermView)
 }
 
-// GetManagerScopeUserIDs 返回该管理员可查看的 user_id 列表。
-// user_id 列存储逗号分隔的字符串：若为 '0' 表示全部，否则解析为 id 列表。
+// GetManagerScopeUserIDs 返回该主管可查看的 user_id 列表：nil 表示全部，空切片表示无
 func (r *Repository) GetManagerScopeUserIDs(ctx context.Context, managerID string) ([]string, error) {
-	var userIDStr string
-	query := `SELECT user_id FROM records_scope WHERE manager_id = $1 LIMIT 1`
-	executor := r.getExecer(ctx)
-	if err := executor.GetContext(ctx, &userIDStr, query, managerID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("manager scope not found")
-		}
-		return nil, fmt.Errorf("get manager scope: %w", err)
-	}
-	s := strings.TrimSpace(userIDStr)
-	if s == "0" {
-		return nil, nil // 表示“全部”
-	}
-	if s == "" {
-		return []string{}, nil // 未配置则无人可看
-	}
-	parts := strings.Split(s, ",")
-	ids := make([]string, 0, len(parts))
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p != "" && p != "0" {
-			ids = append(ids, p)
-		}
-	}
-	return ids, nil
+	return r.PermissionScope(ctx, managerID, models.PermView)
 }
 
 // ManagerUser 用于 ListUsersForManager 返回
@@ -597,8 +648,8 @@ func (r *Repository) ListUsersForManager(ctx context.Context, managerID string)
 	if scopeIDs == nil {
 		var list []*ManagerUser
 		query := `SELECT u.id AS user_id, COALESCE(u.name, u.id) AS name,
-			(SELECT COUNT(*) FROM follow_records fr WHERE fr.user_id = u.id) AS record_count,
-			(SELECT MAX(fr.created_at) FROM follow_records fr WHERE fr.user_id = u.id) AS last_record_at
+			(SELECT COUNT(*) FROM follow_records fr WHERE fr.user_id = u.id AND fr.deleted_at IS NULL) AS record_count,
+			(SELECT MAX(fr.created_at) FROM follow_records fr WHERE fr.user_id = u.id AND fr.deleted_at IS NULL) AS last_record_at
 			FROM users u ORDER BY last_record_at DESC NULLS LAST, u.name, u.id`
 		if err := executor.SelectContext(ctx, &list, query); err != nil {
 			return nil, fmt.Errorf("list users for manager (all): %w", err)
@@ -609,8 +660,8 @@ func (r *Repository) ListUsersForManager(ctx context.Context, managerID string)
 		return []*ManagerUser{}, nil
 	}
 	query, args, err := sqlx.In(`SELECT u.id AS user_id, COALESCE(u.name, u.id) AS name,
-		(SELECT COUNT(*) FROM follow_records fr WHERE fr.user_id = u.id) AS record_count,
-		(SELECT MAX(fr.created_at) FROM follow_records fr WHERE fr.user_id = u.id) AS last_record_at
+		(SELECT COUNT(*) FROM follow_records fr WHERE fr.user_id = u.id AND fr.deleted_at IS NULL) AS record_count,
+		(SELECT MAX(fr.created_at) FROM follow_records fr WHERE fr.user_id = u.id AND fr.deleted_at IS NULL) AS last_record_at
 		FROM users u WHERE u.id IN (?) ORDER BY last_record_at DESC NULLS LAST, u.name, u.id`, scopeIDs)
 	if err != nil {
 		return nil, fmt.Errorf("build list users query: %w", err)
@@ -634,7 +685,7 @@ type CustomerFollowGroup struct {
 func (r *Repository) ListCustomerFollowGroupsForManager(ctx context.Context, targetUserID string) ([]*CustomerFollowGroup, error) {
 	var list []*CustomerFollowGroup
 	query := `SELECT customer_name, MAX(created_at) AS last_record_at
-		FROM follow_records WHERE user_id = $1
+		FROM follow_records WHERE user_id = $1 AND deleted_at IS NULL
 		GROUP BY customer_name
 		ORDER BY last_record_at DESC NULLS LAST, customer_name`
 	executor := r.getExecer(ctx)
@@ -648,11 +699,11 @@ func (r *Repository) ListCustomerFollowGroupsForManager(ctx context.Context, tar
 func (r *Repository) ListFollowRecordsForManager(ctx context.Context, targetUserID, customerName, followContent string) ([]*FollowRecordWithCustomerID, error) {
 	var list []*FollowRecordWithCustomerID
 	query := `SELECT fr.id, fr.user_id, fr.customer_id, fr.customer_name, fr.contact_person, fr.contact_phone, fr.contact_role,
-		fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan, fr.ai, fr.created_at,
+		fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan, fr.ai, fr.version, fr.created_at,
 		c.id::text AS customer_id_str
 		FROM follow_records fr
 		JOIN customers c ON fr.customer_id = c.id
-		WHERE fr.user_id = $1 AND fr.customer_name = $2
+		WHERE fr.user_id = $1 AND fr.customer_name = $2 AND fr.deleted_at IS NULL
 		AND (COALESCE($3, '') = '' OR COALESCE(fr.follow_content, '') = $3)
 		ORDER BY fr.follow_time DESC`
 	executor := r.getExecer(ctx)
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"records/internal/models"
+	"records/internal/phonecrypt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// 出站 Webhook 事务性发件箱：事件与业务写入在同一事务内插入 webhook_outbox，由 webhook.Dispatcher 分发给订阅
+
+// WebhookRecordData 跟进记录事件的 data：写入后的记录与操作来源
+type WebhookRecordData struct {
+	Record  *models.FollowRecord `json:"record"`
+	Source  string               `json:"source"`
+	ActorID *string              `json:"actor_id,omitempty"`
+}
+
+// WebhookMergeData customer.merged 事件的 data：源客户并入目标客户
+type WebhookMergeData struct {
+	SourceID   uuid.UUID   `json:"source_customer_id"`
+	SourceName string      `json:"source_customer_name"`
+	TargetID   uuid.UUID   `json:"target_customer_id"`
+	TargetName string      `json:"target_customer_name"`
+	RecordIDs  []uuid.UUID `json:"record_ids"` // 迁移到目标客户的跟进记录
+}
+
+// EnqueueWebhookEvent 写入一条待分发事件；ctx 带事务时随事务提交
+func (r *Repository) EnqueueWebhookEvent(ctx context.Context, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal webhook event %s: %w", event, err)
+	}
+	_, err = r.getExecer(ctx).ExecContext(ctx, `INSERT INTO webhook_outbox (id, event, payload) VALUES ($1, $2, $3)`, uuid.New(), event, payload)
+	if err != nil {
+		return fmt.Errorf("enqueue webhook event %s: %w", event, err)
+	}
+	return nil
+}
+
+// enqueueRecordEvent 写入跟进记录事件，来源与操作人取自 WithAudit；联系电话脱敏后下发
+func (r *Repository) enqueueRecordEvent(ctx context.Context, event string, record *models.FollowRecord) error {
+	a := auditFromContext(ctx)
+	masked := *record
+	masked.ContactPhone = phonecrypt.RevealPtr(record.ContactPhone, false)
+	return r.EnqueueWebhookEvent(ctx, event, WebhookRecordData{Record: &masked, Source: a.Source, ActorID: a.actorArg()})
+}
+
+// enqueueRecordEventByID 读取写入后的记录（含已删除）并写入事件
+func (r *Repository) enqueueRecordEventByID(ctx context.Context, event string, id uuid.UUID) error {
+	record, err := r.GetFollowRecordIncludingDeleted(ctx, id)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return fmt.Errorf("enqueue webhook event %s: follow record id=%s not found", event, id)
+	}
+	return r.enqueueRecordEvent(ctx, event, record)
+}
+
+// inTx 在 ctx 已有事务时直接执行，否则开启新事务；用于保证记录写入与发件箱事件同时提交
+func (r *Repository) inTx(ctx context.Context, fn func(context.Context) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok && tx != nil {
+		return fn(ctx)
+	}
+	return r.WithTx(ctx, fn)
+}
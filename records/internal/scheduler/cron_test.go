@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+// at 构造本地时区的分钟精度时间
+func at(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"1-x * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q): expected error", expr)
+		}
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	// 2026-03-02 为周一，2026-03-01 为周日，2026-03-15 为周日
+	cases := []struct {
+		name string
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"every minute", "* * * * *", at(2026, 3, 2, 13, 37), true},
+		{"fixed time hit", "30 2 * * *", at(2026, 3, 2, 2, 30), true},
+		{"fixed time miss", "30 2 * * *", at(2026, 3, 2, 2, 31), false},
+		{"list hit", "0 9,18 * * *", at(2026, 3, 2, 18, 0), true},
+		{"list miss", "0 9,18 * * *", at(2026, 3, 2, 12, 0), false},
+		{"range lower bound", "0 9-17 * * *", at(2026, 3, 2, 9, 0), true},
+		{"range upper bound", "0 9-17 * * *", at(2026, 3, 2, 17, 0), true},
+		{"range outside", "0 9-17 * * *", at(2026, 3, 2, 18, 0), false},
+		{"star step hit", "*/10 * * * *", at(2026, 3, 2, 5, 40), true},
+		{"star step miss", "*/10 * * * *", at(2026, 3, 2, 5, 45), false},
+		{"range step hit", "1-9/4 * * * *", at(2026, 3, 2, 5, 5), true},
+		{"range step miss", "1-9/4 * * * *", at(2026, 3, 2, 5, 7), false},
+		{"value step from value", "5/15 * * * *", at(2026, 3, 2, 5, 50), true},
+		{"value step before value", "5/15 * * * *", at(2026, 3, 2, 5, 0), false},
+		{"month restricted", "0 0 1 6 *", at(2026, 3, 1, 0, 0), false},
+		{"sunday as 0", "0 8 * * 0", at(2026, 3, 1, 8, 0), true},
+		{"sunday as 7", "0 8 * * 7", at(2026, 3, 1, 8, 0), true},
+		{"weekday range miss on sunday", "0 8 * * 1-5", at(2026, 3, 1, 8, 0), false},
+		{"weekday range hit on monday", "0 8 * * 1-5", at(2026, 3, 2, 8, 0), true},
+		// 日、周均有限定时满足其一即可
+		{"dom and dow both set, dom only", "0 0 2 * 0", at(2026, 3, 2, 0, 0), true},
+		{"dom and dow both set, dow only", "0 0 2 * 0", at(2026, 3, 15, 0, 0), true},
+		{"dom and dow both set, neither", "0 0 2 * 0", at(2026, 3, 3, 0, 0), false},
+		// 任一字段为 * 时两者须同时满足
+		{"dom star, dow set", "0 0 * * 1", at(2026, 3, 3, 0, 0), false},
+		{"dom set, dow star", "0 0 15 * *", at(2026, 3, 2, 0, 0), false},
+		{"dom step counts as star", "0 0 */2 * 1", at(2026, 3, 3, 0, 0), false},
+		{"dom step and dow both hit", "0 0 */2 * 1", at(2026, 3, 9, 0, 0), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ParseCron(tc.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tc.expr, err)
+			}
+			if got := s.Matches(tc.t); got != tc.want {
+				t.Errorf("%q.Matches(%s) = %v, want %v", tc.expr, tc.t.Format("2006-01-02 15:04 Mon"), got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	cases := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"*/10 * * * *", at(2026, 3, 2, 5, 40), at(2026, 3, 2, 5, 50)},
+		{"*/10 * * * *", at(2026, 3, 2, 5, 41).Add(30 * time.Second), at(2026, 3, 2, 5, 50)},
+		{"30 2 * * *", at(2026, 3, 2, 3, 0), at(2026, 3, 3, 2, 30)},
+		{"0 8 * * 1", at(2026, 3, 2, 8, 0), at(2026, 3, 9, 8, 0)},
+		{"0 0 1 * *", at(2026, 12, 15, 0, 0), at(2027, 1, 1, 0, 0)},
+		{"0 0 29 2 *", at(2026, 3, 1, 0, 0), time.Time{}},
+	}
+	for _, tc := range cases {
+		s, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tc.expr, err)
+		}
+		if got := s.Next(tc.after); !got.Equal(tc.want) {
+			t.Errorf("%q.Next(%s) = %s, want %s", tc.expr, tc.after, got, tc.want)
+		}
+	}
+}
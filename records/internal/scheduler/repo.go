@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// 运行状态
+const (
+	RunStatusRunning = "running"
+	RunStatusSuccess = "success"
+	RunStatusFailed  = "failed"
+	RunStatusSkipped = "skipped"
+)
+
+// 触发方式
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// JobRun job_runs 表一行
+type JobRun struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	JobName    string     `db:"job_name" json:"job_name"`
+	TriggerBy  string     `db:"trigger_by" json:"trigger_by"`
+	Status     string     `db:"status" json:"status"`
+	Instance   string     `db:"instance" json:"instance"`
+	StartedAt  time.Time  `db:"started_at" json:"started_at"`
+	FinishedAt *time.Time `db:"finished_at" json:"finished_at,omitempty"`
+	DurationMs *int64     `db:"duration_ms" json:"duration_ms,omitempty"`
+	Error      *string    `db:"error" json:"error,omitempty"`
+}
+
+// Repo 任务运行历史数据访问
+type Repo struct {
+	db *sqlx.DB
+}
+
+// NewRepo 创建 Repo
+func NewRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+// InsertRun 写入一条运行中的记录
+func (r *Repo) InsertRun(ctx context.Context, run *JobRun) error {
+	query := `INSERT INTO job_runs (id, job_name, trigger_by, status, instance, started_at) VALUES (:id, :job_name, :trigger_by, :status, :instance, :started_at)`
+	if _, err := r.db.NamedExecContext(ctx, query, run); err != nil {
+		return fmt.Errorf("insert job run job=%s: %w", run.JobName, err)
+	}
+	return nil
+}
+
+// FinishRun 更新运行结果与耗时
+func (r *Repo) FinishRun(ctx context.Context, id uuid.UUID, status string, finishedAt time.Time, duration time.Duration, errMsg *string) error {
+	query := `UPDATE job_runs SET status = $1, finished_at = $2, duration_ms = $3, error = $4 WHERE id = $5`
+	if _, err := r.db.ExecContext(ctx, query, status, finishedAt, duration.Milliseconds(), errMsg, id); err != nil {
+		return fmt.Errorf("finish job run id=%s: %w", id, err)
+	}
+	return nil
+}
+
+// LastRun 取任务最近一次运行；无记录返回 nil
+func (r *Repo) LastRun(ctx context.Context, jobName string) (*JobRun, error) {
+	var run JobRun
+	query := `SELECT id, job_name, trigger_by, status, instance, started_at, finished_at, duration_ms, error FROM job_runs WHERE job_name = $1 ORDER BY started_at DESC LIMIT 1`
+	if err := r.db.GetContext(ctx, &run, query, jobName); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("last job run job=%s: %w", jobName, err)
+	}
+	return &run, nil
+}
+
+// ListRuns 按开始时间倒序返回任务运行历史
+func (r *Repo) ListRuns(ctx context.Context, jobName string, limit int) ([]*JobRun, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	var runs []*JobRun
+	query := `SELECT id, job_name, trigger_by, status, instance, started_at, finished_at, duration_ms, error FROM job_runs WHERE job_name = $1 ORDER BY started_at DESC LIMIT $2`
+	if err := r.db.SelectContext(ctx, &runs, query, jobName, limit); err != nil {
+		return nil, fmt.Errorf("list job runs job=%s: %w", jobName, err)
+	}
+	return runs, nil
+}
+
+// MarkAbandonedRuns 将任务遗留的 running 记录标记为失败。
+// 仅在已持有该任务的执行锁时调用：此时不可能有其他实例在执行，遗留的 running 记录必为实例异常退出所致
+func (r *Repo) MarkAbandonedRuns(ctx context.Context, jobName string) (int64, error) {
+	query := `UPDATE job_runs SET status = $1, finished_at = NOW(), error = 'abandoned: instance stopped before finishing' WHERE job_name = $2 AND status = $3`
+	result, err := r.db.ExecContext(ctx, query, RunStatusFailed, jobName, RunStatusRunning)
+	if err != nil {
+		return 0, fmt.Errorf("mark abandoned job runs job=%s: %w", jobName, err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
@@ -0,0 +1,368 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"records/internal/config"
+	"records/internal/repository"
+	"records/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// JobFunc 任务执行函数；返回 ErrSkipped 表示本次无需执行（记为 skipped）
+type JobFunc func(ctx context.Context) error
+
+var (
+	// ErrSkipped 任务判定本次无需执行
+	ErrSkipped = errors.New("job skipped")
+	// ErrJobRunning 任务正在本实例或其他实例执行
+	ErrJobRunning = errors.New("job is already running")
+	// ErrJobNotFound 任务未注册
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// leaderLockKey 调度主节点选举租约
+const leaderLockKey = "scheduler:leader"
+
+// Job 已注册的任务
+type Job struct {
+	Name        string
+	Description string
+	Schedule    *Schedule
+	Enabled     bool
+	fn          JobFunc
+}
+
+// JobStatus 任务状态（供管理 API 查看）
+type JobStatus struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Cron        string     `json:"cron"`
+	Enabled     bool       `json:"enabled"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
+	RunningHere bool       `json:"running_here"` // 本实例是否正在执行
+	LastRun     *JobRun    `json:"last_run,omitempty"`
+}
+
+// Scheduler 分布式任务调度器：各实例通过租约锁（leases，与用户级锁共用）选举主节点，仅主节点按 cron 触发任务；
+// 每次执行另持有任务级租约，保证同一任务（定时或手动触发）全局同时只有一个在执行，运行历史写入 job_runs。
+// 租约不占用数据库连接；任务级租约续约失败时取消任务 context，任务应随之退出
+type Scheduler struct {
+	leases   *repository.Repository
+	repo     *Repo
+	cfg      config.Scheduler
+	logger   logger.Logger
+	instance string
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	running map[string]bool
+	leader  *repository.Lease
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 创建调度器
+func New(db *sqlx.DB, cfg config.Scheduler, logger logger.Logger) *Scheduler {
+	host, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		leases:   repository.New(db),
+		repo:     NewRepo(db),
+		cfg:      cfg,
+		logger:   logger,
+		instance: fmt.Sprintf("%s:%d", host, os.Getpid()),
+		jobs:     make(map[string]*Job),
+		running:  make(map[string]bool),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Register 注册任务；defaultCron 为默认计划，可被配置 scheduler.jobs.{name}.cron 覆盖，配置 enabled: false 可停用定时触发（仍可手动触发）
+func (s *Scheduler) Register(name, description, defaultCron string, fn JobFunc) error {
+	expr := defaultCron
+	enabled := true
+	if jc, ok := s.cfg.Jobs[name]; ok {
+		if jc.Cron != "" {
+			expr = jc.Cron
+		}
+		if jc.Enabled != nil {
+			enabled = *jc.Enabled
+		}
+	}
+	sched, err := ParseCron(expr)
+	if err != nil {
+		return fmt.Errorf("register job %s: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("register job %s: already registered", name)
+	}
+	s.jobs[name] = &Job{Name: name, Description: description, Schedule: sched, Enabled: enabled, fn: fn}
+	s.logger.Info("Job registered", "job", name, "cron", expr, "enabled", enabled)
+	return nil
+}
+
+// Start 启动调度循环（每分钟整点检查一次）
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop 停止调度并等待执行中的任务退出，释放主节点租约
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+	s.mu.Lock()
+	if s.leader != nil {
+		s.leader.Release()
+		s.leader = nil
+	}
+	s.mu.Unlock()
+}
+
+// IsLeader 本实例当前是否为调度主节点
+func (s *Scheduler) IsLeader() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leader != nil && s.leader.Context().Err() == nil
+}
+
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.tick(next)
+	}
+}
+
+// tick 在分钟边界执行：维持主节点身份，主节点触发命中 cron 的任务
+func (s *Scheduler) tick(t time.Time) {
+	if !s.ensureLeader() {
+		return
+	}
+	for _, job := range s.sortedJobs() {
+		if !job.Enabled || !job.Schedule.Matches(t) {
+			continue
+		}
+		if _, err := s.run(job, TriggerSchedule); err != nil {
+			if errors.Is(err, ErrJobRunning) {
+				s.logger.Info("Job still running, skip scheduled run", "job", job.Name)
+			} else {
+				s.logger.Error("Failed to start scheduled job", "job", job.Name, "error", err)
+			}
+		}
+	}
+}
+
+// ensureLeader 校验或争抢主节点租约；续约失败（租约 context 已取消）即视为失去主节点身份
+func (s *Scheduler) ensureLeader() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.leader != nil {
+		if s.leader.Context().Err() == nil {
+			return true
+		}
+		s.logger.Warn("Scheduler leader lease lost", "instance", s.instance, "error", context.Cause(s.leader.Context()))
+		s.leader.Release()
+		s.leader = nil
+	}
+	lease, ok, err := s.leases.TryAcquireLease(s.ctx, leaderLockKey)
+	if err != nil {
+		s.logger.Error("Scheduler leader election failed", "error", err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	s.leader = lease
+	s.logger.Info("Scheduler became leader", "instance", s.instance)
+	return true
+}
+
+// Trigger 手动触发任务（不要求本实例为主节点）；任务异步执行，返回本次运行记录
+func (s *Scheduler) Trigger(name string) (*JobRun, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return s.run(job, TriggerManual)
+}
+
+// run 获取任务级租约、写入运行记录后异步执行任务；任务在租约 context 下执行，失去租约时随之取消
+func (s *Scheduler) run(job *Job, trigger string) (*JobRun, error) {
+	lease, ok, err := s.leases.TryAcquireLease(s.ctx, "job:"+job.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrJobRunning
+	}
+	if n, err := s.repo.MarkAbandonedRuns(s.ctx, job.Name); err != nil {
+		s.logger.Error("Failed to mark abandoned job runs", "job", job.Name, "error", err)
+	} else if n > 0 {
+		s.logger.Warn("Marked abandoned job runs as failed", "job", job.Name, "count", n)
+	}
+
+	run := &JobRun{
+		ID:        uuid.New(),
+		JobName:   job.Name,
+		TriggerBy: trigger,
+		Status:    RunStatusRunning,
+		Instance:  s.instance,
+		StartedAt: time.Now(),
+	}
+	if err := s.repo.InsertRun(s.ctx, run); err != nil {
+		lease.Release()
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.running[job.Name] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer lease.Release()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, job.Name)
+			s.mu.Unlock()
+		}()
+
+		s.logger.Info("Job started", "job", job.Name, "trigger", trigger, "run_id", run.ID)
+		err := s.invoke(lease.Context(), job)
+		if cause := context.Cause(lease.Context()); err != nil && errors.Is(cause, repository.ErrLeaseLost) {
+			err = cause
+		}
+		finishedAt := time.Now()
+		duration := finishedAt.Sub(run.StartedAt)
+
+		status := RunStatusSuccess
+		var errMsg *string
+		switch {
+		case err == nil:
+			s.logger.Info("Job completed", "job", job.Name, "duration", duration)
+		case errors.Is(err, ErrSkipped):
+			status = RunStatusSkipped
+			s.logger.Info("Job skipped", "job", job.Name, "duration", duration)
+		default:
+			status = RunStatusFailed
+			msg := err.Error()
+			errMsg = &msg
+			s.logger.Error("Job failed", "job", job.Name, "duration", duration, "error", err)
+		}
+		// 使用独立 context：调度器停止时也要落库运行结果
+		finishCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.repo.FinishRun(finishCtx, run.ID, status, finishedAt, duration, errMsg); err != nil {
+			s.logger.Error("Failed to record job run result", "job", job.Name, "error", err)
+		}
+	}()
+	return run, nil
+}
+
+// invoke 执行任务函数，panic 转为错误
+func (s *Scheduler) invoke(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+	return job.fn(ctx)
+}
+
+// Statuses 返回所有任务的配置、下次触发时间与最近一次运行
+func (s *Scheduler) Statuses(ctx context.Context) ([]*JobStatus, error) {
+	now := time.Now()
+	var out []*JobStatus
+	for _, job := range s.sortedJobs() {
+		st, err := s.status(ctx, job, now)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// Status 返回单个任务状态
+func (s *Scheduler) Status(ctx context.Context, name string) (*JobStatus, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return s.status(ctx, job, time.Now())
+}
+
+// Runs 返回任务运行历史
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) ([]*JobRun, error) {
+	s.mu.Lock()
+	_, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return s.repo.ListRuns(ctx, name, limit)
+}
+
+func (s *Scheduler) status(ctx context.Context, job *Job, now time.Time) (*JobStatus, error) {
+	last, err := s.repo.LastRun(ctx, job.Name)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	runningHere := s.running[job.Name]
+	s.mu.Unlock()
+	st := &JobStatus{
+		Name:        job.Name,
+		Description: job.Description,
+		Cron:        job.Schedule.String(),
+		Enabled:     job.Enabled,
+		RunningHere: runningHere,
+		LastRun:     last,
+	}
+	if job.Enabled {
+		if next := job.Schedule.Next(now); !next.IsZero() {
+			st.NextRunAt = &next
+		}
+	}
+	return st, nil
+}
+
+func (s *Scheduler) sortedJobs() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
@@ -0,0 +1,117 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetRunes 高亮片段中命中处前后保留的字符数
+const snippetRunes = 30
+
+// highlightRecord 为命中记录的各搜索字段生成高亮片段，未逐字命中的字段不返回
+func highlightRecord(h *Hit, terms []string) map[string]string {
+	fields := map[string]*string{
+		"follow_content": h.FollowContent,
+		"follow_goal":    h.FollowGoal,
+		"follow_result":  h.FollowResult,
+		"risk_content":   h.RiskContent,
+		"next_plan":      h.NextPlan,
+	}
+	out := make(map[string]string)
+	for name, v := range fields {
+		if v == nil {
+			continue
+		}
+		if snippet, ok := Highlight(*v, terms); ok {
+			out[name] = snippet
+		}
+	}
+	return out
+}
+
+// span 命中区间（字节偏移）
+type span struct{ start, end int }
+
+// Highlight 在 text 中查找关键词（不区分大小写），返回以首个命中为中心的片段：
+// 文本已 HTML 转义，命中处以 <mark></mark> 包裹，截断处以 … 表示；无命中返回 false
+func Highlight(text string, terms []string) (string, bool) {
+	spans := findSpans(text, terms)
+	if len(spans) == 0 {
+		return "", false
+	}
+
+	// 以首个命中为中心截取片段（按字符计）
+	start := spans[0].start
+	for i := 0; i < snippetRunes && start > 0; i++ {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+	}
+	end := spans[0].end
+	for i := 0; i < snippetRunes*2 && end < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, sp := range spans {
+		if sp.start < pos || sp.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:sp.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[sp.start:sp.end]))
+		b.WriteString("</mark>")
+		pos = sp.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// findSpans 返回所有关键词命中区间，按起点排序并合并重叠
+func findSpans(text string, terms []string) []span {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// 个别字符小写后字节长度变化，偏移无法对应原文，退化为区分大小写匹配
+		lower = text
+	}
+	var spans []span
+	for _, t := range terms {
+		t = strings.ToLower(t)
+		if t == "" {
+			continue
+		}
+		for off := 0; ; {
+			i := strings.Index(lower[off:], t)
+			if i < 0 {
+				break
+			}
+			spans = append(spans, span{off + i, off + i + len(t)})
+			off += i + len(t)
+		}
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := spans[:1]
+	for _, sp := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if sp.start <= last.end {
+			if sp.end > last.end {
+				last.end = sp.end
+			}
+			continue
+		}
+		merged = append(merged, sp)
+	}
+	return merged
+}
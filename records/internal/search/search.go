@@ -0,0 +1,213 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"records/internal/phonecrypt"
+	"records/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// 查询限制
+const (
+	MaxQueryRunes = 100 // 搜索词最大长度
+	MaxTerms      = 5   // 最多关键词数（空白分隔，需全部命中）
+	DefaultLimit  = 20
+	MaxLimit      = 100
+)
+
+// searchDocument 搜索文档表达式，须与 sql/search.sql 中 trgm 索引表达式完全一致才能走索引
+const searchDocument = `(COALESCE(fr.follow_content, '') || ' ' || COALESCE(fr.follow_goal, '') || ' ' || COALESCE(fr.follow_result, '') || ' ' ||
+     COALESCE(fr.risk_content, '') || ' ' || COALESCE(fr.next_plan, ''))`
+
+// fieldWeights 各字段命中时的排序权重
+var fieldWeights = []struct {
+	Column string
+	Weight int
+}{
+	{"follow_content", 3},
+	{"follow_goal", 2},
+	{"follow_result", 2},
+	{"risk_content", 1},
+	{"next_plan", 1},
+}
+
+// phoneWeight 联系电话（盲索引）命中时的排序权重
+const phoneWeight = 3
+
+// Query 搜索条件；零值字段表示不限
+type Query struct {
+	Text         string     // 搜索词，空白分隔多个关键词
+	Fuzzy        bool       // 模糊匹配：关键词未逐字出现时按 trgm 词相似度匹配（容忍错别字）
+	UserIDs      []string   // 调用方可见的销售范围；nil 表示全部用户，空切片表示无数据
+	CustomerID   *uuid.UUID // 指定客户
+	CustomerName string     // 客户名模糊匹配
+	FollowMethod string     // 跟进方式（精确）
+	From         *time.Time // 跟进时间下限（含）
+	To           *time.Time // 跟进时间上限（不含）
+	Limit        int
+	Offset       int
+}
+
+// Hit 一条命中记录
+type Hit struct {
+	repository.FollowRecordWithCustomerID
+	UserName   string            `db:"user_name"`
+	Rank       float64           `db:"rank"`
+	Highlights map[string]string `db:"-"` // 字段 -> 高亮片段（已 HTML 转义，命中处以 <mark> 包裹）
+}
+
+// Result 搜索结果
+type Result struct {
+	Total int      // 命中总数
+	Terms []string // 实际使用的关键词
+	Hits  []*Hit
+}
+
+// Searcher 跟进记录搜索
+type Searcher struct {
+	db *sqlx.DB
+}
+
+// New 创建 Searcher
+func New(db *sqlx.DB) *Searcher {
+	return &Searcher{db: db}
+}
+
+// ParseTerms 拆分搜索词：按空白分隔、去重，最多 MaxTerms 个
+func ParseTerms(text string) ([]string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("搜索词不能为空")
+	}
+	if len([]rune(text)) > MaxQueryRunes {
+		return nil, fmt.Errorf("搜索词不能超过 %d 个字符", MaxQueryRunes)
+	}
+	var terms []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Fields(text) {
+		key := strings.ToLower(t)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		terms = append(terms, t)
+	}
+	if len(terms) > MaxTerms {
+		return nil, fmt.Errorf("关键词不能超过 %d 个", MaxTerms)
+	}
+	return terms, nil
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Search 在调用方范围内搜索跟进记录：所有关键词均需命中（模糊模式下允许近似命中）；
+// 按字段权重 + 词相似度排序，相同得分按跟进时间倒序
+func (s *Searcher) Search(ctx context.Context, q Query) (*Result, error) {
+	terms, err := ParseTerms(q.Text)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Terms: terms}
+	if q.UserIDs != nil && len(q.UserIDs) == 0 {
+		return res, nil
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conds := []string{"fr.deleted_at IS NULL"}
+	var ranks []string
+	for _, t := range terms {
+		pattern := arg("%" + escapeLike(t) + "%")
+		match := searchDocument + " ILIKE " + pattern
+		if q.Fuzzy {
+			match = "(" + match + " OR " + arg(t) + " <% " + searchDocument + ")"
+		}
+		// 号码形式的关键词同时按联系电话盲索引精确匹配（联系电话加密存储，无法模糊匹配）
+		if bidx := phonecrypt.BlindIndex(t); bidx != "" && phonecrypt.LooksLikePhone(t) {
+			bidxArg := arg(bidx)
+			match = "(" + match + " OR fr.contact_phone_bidx = " + bidxArg + ")"
+			ranks = append(ranks, fmt.Sprintf("CASE WHEN fr.contact_phone_bidx = %s THEN %d ELSE 0 END", bidxArg, phoneWeight))
+		}
+		conds = append(conds, match)
+		for _, f := range fieldWeights {
+			ranks = append(ranks, fmt.Sprintf("CASE WHEN fr.%s ILIKE %s THEN %d ELSE 0 END", f.Column, pattern, f.Weight))
+		}
+	}
+
+	if q.UserIDs != nil {
+		conds = append(conds, "fr.user_id = ANY("+arg(pq.Array(q.UserIDs))+")")
+	}
+	if q.CustomerID != nil {
+		conds = append(conds, "fr.customer_id = "+arg(*q.CustomerID))
+	}
+	if q.CustomerName != "" {
+		conds = append(conds, "fr.customer_name ILIKE "+arg("%"+escapeLike(q.CustomerName)+"%"))
+	}
+	if q.FollowMethod != "" {
+		conds = append(conds, "fr.follow_method = "+arg(q.FollowMethod))
+	}
+	if q.From != nil {
+		conds = append(conds, "fr.follow_time >= "+arg(*q.From))
+	}
+	if q.To != nil {
+		conds = append(conds, "fr.follow_time < "+arg(*q.To))
+	}
+	condArgs := len(args)
+	ranks = append(ranks, "word_similarity("+arg(strings.Join(terms, " "))+", "+searchDocument+")")
+
+	query := `SELECT fr.id, fr.user_id, fr.customer_id, fr.customer_name, fr.contact_person, fr.contact_phone, fr.contact_role,
+		fr.follow_time, fr.follow_method, fr.follow_content, fr.follow_goal, fr.follow_result, fr.risk_content, fr.next_plan, fr.ai, fr.version, fr.created_at,
+		fr.customer_id::text AS customer_id_str, COALESCE(u.name, fr.user_id) AS user_name,
+		(` + strings.Join(ranks, " + ") + `)::float8 AS rank,
+		COUNT(*) OVER () AS total
+		FROM follow_records fr
+		LEFT JOIN users u ON u.id = fr.user_id
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY rank DESC, fr.follow_time DESC, fr.id
+		LIMIT ` + arg(q.Limit) + ` OFFSET ` + arg(q.Offset)
+
+	var rows []struct {
+		Hit
+		Total int `db:"total"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("search follow records: %w", err)
+	}
+	res.Hits = make([]*Hit, 0, len(rows))
+	for i := range rows {
+		h := rows[i].Hit
+		h.Highlights = highlightRecord(&h, terms)
+		res.Hits = append(res.Hits, &h)
+		res.Total = rows[i].Total
+	}
+	if len(rows) == 0 && q.Offset > 0 {
+		// 越过末页时 COUNT(*) OVER () 无行可取，单独统计总数
+		countQuery := `SELECT COUNT(*) FROM follow_records fr WHERE ` + strings.Join(conds, " AND ")
+		if err := s.db.GetContext(ctx, &res.Total, countQuery, args[:condArgs]...); err != nil {
+			return nil, fmt.Errorf("count search results: %w", err)
+		}
+	}
+	return res, nil
+}
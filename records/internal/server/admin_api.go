@@ -0,0 +1,111 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"records/internal/models"
+	"records/internal/scheduler"
+)
+
+// adminUserIDFromRequest 校验请求者拥有 admin 权限（管理授权与定时任务）。
+// 返回 (userID, true) 表示通过；否则已写入 401/403/500 响应。
+func (s *Server) adminUserIDFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, _, ok := s.requirePermission(w, r, models.PermAdmin)
+	return userID, ok
+}
+
+// adminJobsHandler 处理 GET {apiP}/admin/jobs：任务列表（cron、下次触发时间、最近一次运行）
+func (s *Server) adminJobsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path != s.apiPrefix()+"/admin/jobs" {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := s.adminUserIDFromRequest(w, r); !ok {
+		return
+	}
+	list, err := s.scheduler.Statuses(r.Context())
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List job statuses failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取任务列表失败"})
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"leader": s.scheduler.IsLeader(),
+		"jobs":   list,
+	}})
+}
+
+// adminJobsSubHandler 处理 {apiP}/admin/jobs/{name}（GET 状态）、/{name}/runs（GET 运行历史）、/{name}/run（POST 手动触发）
+func (s *Server) adminJobsSubHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/admin/jobs/"), "/")
+	parts := strings.Split(path, "/")
+	if path == "" || len(parts) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+	name := parts[0]
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	if _, ok := s.adminUserIDFromRequest(w, r); !ok {
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		st, err := s.scheduler.Status(r.Context(), name)
+		if err != nil {
+			s.writeJobError(w, err, "获取任务状态失败")
+			return
+		}
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: st})
+
+	case action == "runs" && r.Method == http.MethodGet:
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit <= 0 || limit > 200 {
+			limit = 20
+		}
+		runs, err := s.scheduler.Runs(r.Context(), name, limit)
+		if err != nil {
+			s.writeJobError(w, err, "获取运行历史失败")
+			return
+		}
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: runs})
+
+	case action == "run" && r.Method == http.MethodPost:
+		run, err := s.scheduler.Trigger(name)
+		if err != nil {
+			s.writeJobError(w, err, "触发任务失败")
+			return
+		}
+		s.writePageJSON(w, http.StatusAccepted, pageAPIResponse{Success: true, Data: run})
+
+	case action == "" || action == "runs" || action == "run":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writeJobError 将调度器错误映射为 HTTP 响应
+func (s *Server) writeJobError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "任务不存在"})
+	case errors.Is(err, scheduler.ErrJobRunning):
+		s.writePageJSON(w, http.StatusConflict, pageAPIResponse{Success: false, Message: "任务正在执行中"})
+	default:
+		s.logger.Error("Job operation failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: fallback})
+	}
+}
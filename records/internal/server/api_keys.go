@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"records/internal/apikey"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAPIKeyRateLimit = 60   // 每分钟请求数默认上限
+	maxAPIKeyRateLimit     = 6000 // 每分钟请求数可配置上限
+
+	// apiKeyUsageRetention 按分钟的调用计数保留时长
+	apiKeyUsageRetention = 7 * 24 * time.Hour
+)
+
+type apiKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	RateLimit int        `json:"rate_limit"` // 0 表示默认值
+	ExpiresAt *time.Time `json:"expires_at"` // 仅创建时有效，空表示不过期
+}
+
+// apiKeyView 管理页展示的密钥：近 24 小时调用次数与是否有效
+type apiKeyView struct {
+	*apikey.Key
+	Active      bool `json:"active"`
+	Requests24h int  `json:"requests_24h"`
+}
+
+// validate 校验并规范化请求，返回错误提示
+func (req *apiKeyRequest) validate() string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > 100 {
+		return "名称不能为空且不超过 100 字"
+	}
+	if len(req.Scopes) == 0 {
+		return "至少选择一个权限范围"
+	}
+	var scopes []string
+	for _, sc := range req.Scopes {
+		if !apikey.ValidScope(sc) {
+			return "未知的权限范围：" + sc
+		}
+		if !containsString(scopes, sc) {
+			scopes = append(scopes, sc)
+		}
+	}
+	req.Scopes = scopes
+	if req.RateLimit == 0 {
+		req.RateLimit = defaultAPIKeyRateLimit
+	}
+	if req.RateLimit < 1 || req.RateLimit > maxAPIKeyRateLimit {
+		return "每分钟请求数上限须在 1～6000 之间"
+	}
+	return ""
+}
+
+// adminAPIKeysHandler GET {apiP}/admin/api_keys 列出密钥（含近 24 小时调用次数与可选 scope）；POST 创建密钥，明文仅在响应中返回一次
+func (s *Server) adminAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path != s.apiPrefix()+"/admin/api_keys" {
+		http.NotFound(w, r)
+		return
+	}
+	adminID, ok := s.adminUserIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	if r.Method == http.MethodPost {
+		var req apiKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+			return
+		}
+		if msg := req.validate(); msg != "" {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: msg})
+			return
+		}
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "过期时间须晚于当前时间"})
+			return
+		}
+		key := &apikey.Key{Name: req.Name, Scopes: req.Scopes, RateLimit: req.RateLimit, CreatedBy: adminID, ExpiresAt: req.ExpiresAt}
+		plain, err := s.apiKeys.Create(ctx, key)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Create api key failed", "error", err, "name", req.Name)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "创建 API Key 失败"})
+			return
+		}
+		s.logger.WithContext(r.Context()).Info("API key created", "key", key.Prefix, "name", key.Name, "scopes", strings.Join(key.Scopes, ","), "by", adminID)
+		s.writePageJSON(w, http.StatusCreated, pageAPIResponse{Success: true, Data: map[string]interface{}{
+			"key":    apiKeyView{Key: key, Active: true},
+			"secret": plain,
+		}, Message: "请立即保存密钥，关闭后无法再次查看"})
+		return
+	}
+
+	keys, err := s.apiKeys.List(ctx)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List api keys failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询 API Key 失败"})
+		return
+	}
+	usage, err := s.apiKeys.Usage(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Query api key usage failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询 API Key 失败"})
+		return
+	}
+	now := time.Now()
+	views := make([]apiKeyView, 0, len(keys))
+	for _, k := range keys {
+		views = append(views, apiKeyView{Key: k, Active: k.Active(now), Requests24h: usage[k.ID]})
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"keys":    views,
+		"scopes":  apikey.AllScopes,
+		"openapi": s.apiPrefix() + "/v1/openapi.json",
+	}})
+}
+
+// adminAPIKeySubHandler PUT {apiP}/admin/api_keys/{id} 修改名称、scope 与限流；DELETE 撤销密钥（立即生效，不可恢复）
+func (s *Server) adminAPIKeySubHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := uuid.Parse(strings.Trim(strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/admin/api_keys/"), "/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	adminID, ok := s.adminUserIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	var changed bool
+	if r.Method == http.MethodPut {
+		var req apiKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+			return
+		}
+		if msg := req.validate(); msg != "" {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: msg})
+			return
+		}
+		changed, err = s.apiKeys.Update(ctx, &apikey.Key{ID: id, Name: req.Name, Scopes: req.Scopes, RateLimit: req.RateLimit})
+	} else {
+		changed, err = s.apiKeys.Revoke(ctx, id, adminID)
+	}
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Change api key failed", "error", err, "id", id, "method", r.Method)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "保存 API Key 失败"})
+		return
+	}
+	if !changed {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "API Key 不存在或已撤销"})
+		return
+	}
+	key, err := s.apiKeys.Get(ctx, id)
+	if err != nil || key == nil {
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true})
+		return
+	}
+	if r.Method == http.MethodDelete {
+		s.logger.WithContext(r.Context()).Info("API key revoked", "key", key.Prefix, "by", adminID)
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: apiKeyView{Key: key, Active: key.Active(time.Now())}})
+}
+
+// runAPIKeyUsageCleanup 删除过期的 API Key 调用计数
+func (s *Server) runAPIKeyUsageCleanup(ctx context.Context) error {
+	deleted, err := s.apiKeys.CleanupUsage(ctx, time.Now().Add(-apiKeyUsageRetention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.logger.WithContext(ctx).Info("Cleaned up api key usage", "deleted", deleted)
+	}
+	return nil
+}
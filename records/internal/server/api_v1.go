@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"records/internal/apikey"
+
+	"github.com/google/uuid"
+)
+
+// 对外 REST API（{apiP}/v1）：供 ERP、财务、CRM 等内部系统读写客户、联系人与跟进记录。
+// 认证为 API Key（Authorization: Bearer sk_… 或 X-API-Key），按 scope 授权、按密钥每分钟限流；
+// 路由表同时用于分发与生成 OpenAPI 文档（GET {apiP}/v1/openapi.json，无需密钥）
+
+// v1Param 路由的查询参数说明（用于 OpenAPI）
+type v1Param struct {
+	Name        string
+	Type        string // string/integer/boolean
+	Format      string // uuid/date-time 等，可空
+	Description string
+}
+
+// v1Route 对外 API 路由；Path 为 OpenAPI 路径模板，{id} 段匹配 UUID
+type v1Route struct {
+	Method   string
+	Path     string
+	Scope    string // 所需 scope
+	Tag      string
+	Summary  string
+	Query    []v1Param
+	Request  interface{} // 请求体类型的零值，nil 表示无请求体
+	Response interface{} // 响应 data 类型的零值
+	List     bool        // data 为 {items, next_cursor}
+	ETag     bool        // 响应带 ETag；PATCH 要求 If-Match，DELETE 可选
+	handler  func(w http.ResponseWriter, r *http.Request, c *v1Call)
+}
+
+// v1Call 单次调用的上下文：调用方密钥与路径中的 {id}
+type v1Call struct {
+	Key *apikey.Key
+	ID  uuid.UUID
+}
+
+// actor 写入审计的操作人
+func (c *v1Call) actor() string {
+	return "api_key:" + c.Key.Prefix
+}
+
+// match 路径是否匹配路由模板，匹配时返回 {id}
+func (rt *v1Route) match(path string) (uuid.UUID, bool) {
+	want := strings.Split(strings.Trim(rt.Path, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return uuid.Nil, false
+	}
+	var id uuid.UUID
+	for i, seg := range want {
+		if seg == "{id}" {
+			v, err := uuid.Parse(got[i])
+			if err != nil {
+				return uuid.Nil, false
+			}
+			id = v
+			continue
+		}
+		if seg != got[i] {
+			return uuid.Nil, false
+		}
+	}
+	return id, true
+}
+
+// apiV1Handler 处理 {apiP}/v1/*：匹配路由、校验密钥与 scope、限流后分发
+func (s *Server) apiV1Handler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/v1")
+	if path == "/openapi.json" {
+		s.apiV1OpenAPIHandler(w, r)
+		return
+	}
+	var route *v1Route
+	var id uuid.UUID
+	methodMismatch := false
+	routes := s.v1Routes()
+	for i := range routes {
+		v, ok := routes[i].match(path)
+		if !ok {
+			continue
+		}
+		if routes[i].Method != r.Method {
+			methodMismatch = true
+			continue
+		}
+		route, id = &routes[i], v
+		break
+	}
+	if route == nil {
+		if methodMismatch {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "接口不存在"})
+		return
+	}
+
+	key, ok := s.apiKeyFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !key.HasScope(route.Scope) {
+		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "API Key 缺少权限：" + route.Scope})
+		return
+	}
+	if !s.apiRateLimit(w, r.Context(), key) {
+		return
+	}
+	route.handler(w, r, &v1Call{Key: key, ID: id})
+}
+
+// apiKeyFromRequest 校验请求携带的 API Key；失败时已写响应
+func (s *Server) apiKeyFromRequest(w http.ResponseWriter, r *http.Request) (*apikey.Key, bool) {
+	plain := strings.TrimSpace(r.Header.Get("X-API-Key"))
+	if plain == "" {
+		plain = bearerToken(r)
+	}
+	if plain == "" {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "缺少 API Key"})
+		return nil, false
+	}
+	key, err := s.apiKeys.FindByPlain(r.Context(), plain)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Find api key failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "API Key 校验失败"})
+		return nil, false
+	}
+	if key == nil || !key.Active(time.Now()) {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "无效的 API Key"})
+		return nil, false
+	}
+	return key, true
+}
+
+// apiRateLimit 按密钥每分钟请求数限流（多实例共享计数），写入 X-RateLimit-* 头；超限时已写 429 响应。
+// 计数失败时放行并记录日志，避免数据库抖动导致对外接口整体不可用
+func (s *Server) apiRateLimit(w http.ResponseWriter, ctx context.Context, key *apikey.Key) bool {
+	if key.RateLimit <= 0 {
+		return true
+	}
+	count, resetAt, err := s.apiKeys.CountRequest(ctx, key.ID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Count api key request failed", "error", err, "key", key.Prefix)
+		return true
+	}
+	remaining := key.RateLimit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(key.RateLimit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+	if count > key.RateLimit {
+		retry := int(time.Until(resetAt).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		s.writePageJSON(w, http.StatusTooManyRequests, pageAPIResponse{Success: false, Message: "请求过于频繁，请稍后重试"})
+		return false
+	}
+	return true
+}
+
+// v1Routes 对外 API 路由表
+func (s *Server) v1Routes() []v1Route {
+	pageParams := []v1Param{
+		{Name: "limit", Type: "integer", Description: "每页条数，默认 50，最大 200"},
+		{Name: "cursor", Type: "string", Description: "上一页返回的 next_cursor"},
+		{Name: "updated_since", Type: "string", Format: "date-time", Description: "只返回该时间（含）之后更新的数据，RFC3339"},
+	}
+	return []v1Route{
+		{Method: http.MethodGet, Path: "/customers", Scope: apikey.ScopeCustomersRead, Tag: "customers",
+			Summary: "客户列表（按更新时间升序，游标分页）", Query: append([]v1Param{{Name: "name", Type: "string", Description: "客户名模糊匹配"}}, pageParams...),
+			Response: v1Customer{}, List: true, handler: s.v1ListCustomers},
+		{Method: http.MethodPost, Path: "/customers", Scope: apikey.ScopeCustomersWrite, Tag: "customers",
+			Summary: "新建客户（同名客户已存在时返回 409 与已有客户）", Request: v1CustomerInput{}, Response: v1Customer{}, handler: s.v1CreateCustomer},
+		{Method: http.MethodGet, Path: "/customers/{id}", Scope: apikey.ScopeCustomersRead, Tag: "customers",
+			Summary: "客户详情", Response: v1Customer{}, handler: s.v1GetCustomer},
+		{Method: http.MethodPatch, Path: "/customers/{id}", Scope: apikey.ScopeCustomersWrite, Tag: "customers",
+			Summary: "修改客户名称或分级（改名同步到跟进记录）", Request: v1CustomerInput{}, Response: v1Customer{}, handler: s.v1UpdateCustomer},
+		{Method: http.MethodGet, Path: "/customers/{id}/contacts", Scope: apikey.ScopeContactsRead, Tag: "contacts",
+			Summary: "客户联系人：主联系人与跟进记录中出现过的联系人", Response: []v1Contact{}, handler: s.v1ListContacts},
+		{Method: http.MethodPut, Path: "/customers/{id}/contacts/primary", Scope: apikey.ScopeContactsWrite, Tag: "contacts",
+			Summary: "设置客户主联系人", Request: v1ContactInput{}, Response: v1Contact{}, handler: s.v1SetPrimaryContact},
+		{Method: http.MethodGet, Path: "/follow_records", Scope: apikey.ScopeRecordsRead, Tag: "follow_records",
+			Summary: "跟进记录列表（按更新时间升序，游标分页）",
+			Query: append([]v1Param{
+				{Name: "customer_id", Type: "string", Format: "uuid", Description: "指定客户"},
+				{Name: "user_id", Type: "string", Description: "指定销售（union_id）"},
+				{Name: "include_deleted", Type: "boolean", Description: "包含已删除的记录（deleted_at 非空），用于同步删除"},
+			}, pageParams...),
+			Response: v1Record{}, List: true, handler: s.v1ListRecords},
+		{Method: http.MethodPost, Path: "/follow_records", Scope: apikey.ScopeRecordsWrite, Tag: "follow_records",
+			Summary: "新建跟进记录（customer_id 或 customer_name 二选一，按名称匹配不到时新建客户）", Request: v1RecordInput{}, Response: v1Record{}, ETag: true, handler: s.v1CreateRecord},
+		{Method: http.MethodGet, Path: "/follow_records/{id}", Scope: apikey.ScopeRecordsRead, Tag: "follow_records",
+			Summary: "跟进记录详情", Response: v1Record{}, ETag: true, handler: s.v1GetRecord},
+		{Method: http.MethodPatch, Path: "/follow_records/{id}", Scope: apikey.ScopeRecordsWrite, Tag: "follow_records",
+			Summary: "修改跟进记录（仅更新请求中出现的字段，需 If-Match）", Request: v1RecordInput{}, Response: v1Record{}, ETag: true, handler: s.v1UpdateRecord},
+		{Method: http.MethodDelete, Path: "/follow_records/{id}", Scope: apikey.ScopeRecordsWrite, Tag: "follow_records",
+			Summary: "删除跟进记录（软删除，可在网页端恢复）", ETag: true, handler: s.v1DeleteRecord},
+	}
+}
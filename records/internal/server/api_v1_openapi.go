@@ -0,0 +1,257 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"records/internal/apikey"
+
+	"github.com/google/uuid"
+)
+
+// OpenAPI 3.0 文档：路径与参数取自 v1Routes，请求/响应 schema 由处理函数使用的结构体反射生成（json 标签为字段名、doc 标签为说明）
+
+// apiV1OpenAPIHandler GET {apiP}/v1/openapi.json
+func (s *Server) apiV1OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, s.openAPISpec())
+}
+
+// openAPISchemas 反射生成的组件 schema
+type openAPISchemas map[string]interface{}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// schemaFor 返回类型的 schema；结构体注册为组件并返回引用
+func (c openAPISchemas) schemaFor(t reflect.Type) map[string]interface{} {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t, nullable = t.Elem(), true
+	}
+	var sch map[string]interface{}
+	switch {
+	case t == timeType:
+		sch = map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == uuidType:
+		sch = map[string]interface{}{"type": "string", "format": "uuid"}
+	case t.Kind() == reflect.Struct:
+		name := strings.TrimPrefix(t.Name(), "v1")
+		if _, ok := c[name]; !ok {
+			c[name] = nil // 先占位，防止自引用递归
+			c[name] = c.objectSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	case t.Kind() == reflect.Slice:
+		sch = map[string]interface{}{"type": "array", "items": c.schemaFor(t.Elem())}
+	case t.Kind() == reflect.String:
+		sch = map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		sch = map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		sch = map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		sch = map[string]interface{}{"type": "number"}
+	default:
+		sch = map[string]interface{}{}
+	}
+	if nullable {
+		sch["nullable"] = true
+	}
+	return sch
+}
+
+// objectSchema 结构体的 object schema；输入类型（名称以 Input 结尾）不列 required
+func (c openAPISchemas) objectSchema(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	input := strings.HasSuffix(t.Name(), "Input")
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		p := c.schemaFor(f.Type)
+		if doc := f.Tag.Get("doc"); doc != "" {
+			if _, isRef := p["$ref"]; isRef {
+				p = map[string]interface{}{"allOf": []interface{}{p}, "description": doc}
+			} else {
+				p["description"] = doc
+			}
+		}
+		props[name] = p
+		if !input && f.Type.Kind() != reflect.Ptr && !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	sch := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sch["required"] = required
+	}
+	return sch
+}
+
+// envelope 统一响应包装：{success, data, message}
+func envelope(data map[string]interface{}) map[string]interface{} {
+	props := map[string]interface{}{
+		"success": map[string]interface{}{"type": "boolean"},
+		"message": map[string]interface{}{"type": "string"},
+	}
+	if data != nil {
+		props["data"] = data
+	}
+	return map[string]interface{}{"type": "object", "properties": props, "required": []string{"success"}}
+}
+
+// operationID 由方法与路径生成，如 GET /customers/{id} -> getCustomersById
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, seg := range strings.Split(strings.Trim(path, "/"), "/") {
+		if seg == "{id}" {
+			b.WriteString("ById")
+			continue
+		}
+		for _, part := range strings.Split(seg, "_") {
+			if part != "" {
+				b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+			}
+		}
+	}
+	return b.String()
+}
+
+// openAPISpec 生成 OpenAPI 文档
+func (s *Server) openAPISpec() map[string]interface{} {
+	schemas := openAPISchemas{}
+	errorResp := func(desc string) map[string]interface{} {
+		return map[string]interface{}{"description": desc, "content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}},
+		}}
+	}
+	rateHeaders := map[string]interface{}{
+		"X-RateLimit-Limit":     map[string]interface{}{"description": "每分钟请求数上限", "schema": map[string]interface{}{"type": "integer"}},
+		"X-RateLimit-Remaining": map[string]interface{}{"description": "当前分钟剩余请求数", "schema": map[string]interface{}{"type": "integer"}},
+		"X-RateLimit-Reset":     map[string]interface{}{"description": "计数窗口重置时间（Unix 秒）", "schema": map[string]interface{}{"type": "integer"}},
+	}
+
+	paths := map[string]interface{}{}
+	for _, rt := range s.v1Routes() {
+		var params []interface{}
+		if strings.Contains(rt.Path, "{id}") {
+			params = append(params, map[string]interface{}{"name": "id", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string", "format": "uuid"}})
+		}
+		for _, q := range rt.Query {
+			sch := map[string]interface{}{"type": q.Type}
+			if q.Format != "" {
+				sch["format"] = q.Format
+			}
+			params = append(params, map[string]interface{}{"name": q.Name, "in": "query", "description": q.Description, "schema": sch})
+		}
+		if rt.ETag && (rt.Method == http.MethodPatch || rt.Method == http.MethodDelete) {
+			params = append(params, map[string]interface{}{"name": "If-Match", "in": "header", "required": rt.Method == http.MethodPatch,
+				"description": "读取时的 ETag；* 表示不校验版本", "schema": map[string]interface{}{"type": "string"}})
+		}
+
+		var data map[string]interface{}
+		if rt.Response != nil {
+			data = schemas.schemaFor(reflect.TypeOf(rt.Response))
+			if rt.List {
+				data = map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+					"items":       map[string]interface{}{"type": "array", "items": data},
+					"next_cursor": map[string]interface{}{"type": "string", "description": "下一页游标，为空表示没有更多"},
+				}}
+			}
+		}
+		okHeaders := map[string]interface{}{}
+		for k, v := range rateHeaders {
+			okHeaders[k] = v
+		}
+		if rt.ETag && rt.Method != http.MethodDelete {
+			okHeaders["ETag"] = map[string]interface{}{"description": "记录版本", "schema": map[string]interface{}{"type": "string"}}
+		}
+		okCode := "200"
+		if rt.Method == http.MethodPost {
+			okCode = "201"
+		}
+		responses := map[string]interface{}{
+			okCode: map[string]interface{}{"description": "成功", "headers": okHeaders, "content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": envelope(data)},
+			}},
+			"400": errorResp("参数错误"),
+			"401": errorResp("缺少或无效的 API Key"),
+			"403": errorResp("API Key 缺少所需 scope"),
+			"429": errorResp("超过每分钟请求数上限，见 Retry-After"),
+		}
+		if strings.Contains(rt.Path, "{id}") {
+			responses["404"] = errorResp("资源不存在")
+		}
+		if rt.Method == http.MethodPost || rt.ETag && rt.Method != http.MethodGet {
+			responses["409"] = errorResp("冲突：同名客户已存在或记录版本不一致（data 为服务端当前内容）")
+		}
+		if rt.ETag && rt.Method == http.MethodGet {
+			responses["304"] = map[string]interface{}{"description": "If-None-Match 与当前版本一致"}
+		}
+
+		op := map[string]interface{}{
+			"operationId":      operationID(rt.Method, rt.Path),
+			"tags":             []string{rt.Tag},
+			"summary":          rt.Summary,
+			"description":      "所需 scope：" + rt.Scope,
+			"x-required-scope": rt.Scope,
+			"responses":        responses,
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if rt.Request != nil {
+			op["requestBody"] = map[string]interface{}{"required": true, "content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(rt.Request))},
+			}}
+		}
+		item, _ := paths[rt.Path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[rt.Path] = item
+		}
+		item[strings.ToLower(rt.Method)] = op
+	}
+	schemas["Error"] = envelope(map[string]interface{}{"description": "冲突时为服务端当前内容，其余为空"})
+
+	scopes := make([]string, 0, len(apikey.AllScopes))
+	for _, sc := range apikey.AllScopes {
+		scopes = append(scopes, sc.Name+"："+sc.Description)
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "销售跟进记录对外 API",
+			"version": "1.0.0",
+			"description": "供内部系统读写客户、联系人与跟进记录。API Key 由管理员在 /admin/api_keys 创建，" +
+				"通过 Authorization: Bearer 或 X-API-Key 携带；列表按 updated_at 升序游标分页，可用 updated_since 增量同步。可授予的 scope：" +
+				strings.Join(scopes, "；"),
+		},
+		"servers": []interface{}{map[string]interface{}{"url": s.apiPrefix() + "/v1"}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerKey": map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"headerKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerKey": []string{}}, map[string]interface{}{"headerKey": []string{}}},
+	}
+}
@@ -0,0 +1,726 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"records/internal/apikey"
+	"records/internal/models"
+	"records/internal/phonecrypt"
+	"records/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// v1Customer 客户
+type v1Customer struct {
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name"`
+	Tier           *string    `json:"tier" doc:"客户分级，空为默认级别"`
+	RecordCount    int        `json:"record_count" doc:"未删除的跟进记录数"`
+	LastFollowTime *time.Time `json:"last_follow_time"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// v1CustomerInput 新建/修改客户；修改时仅更新出现的字段
+type v1CustomerInput struct {
+	Name *string `json:"name" doc:"客户名称，新建时必填，最长 255 字"`
+	Tier *string `json:"tier" doc:"客户分级，须为 stale_customer.tier_days 中已定义的级别，空串为默认级别"`
+}
+
+// v1Contact 客户联系人
+type v1Contact struct {
+	ContactPerson  string     `json:"contact_person"`
+	ContactRole    *string    `json:"contact_role"`
+	ContactPhone   *string    `json:"contact_phone" doc:"具备 phones:read 时为明文，否则脱敏"`
+	Primary        bool       `json:"primary" doc:"是否为客户主联系人"`
+	RecordCount    int        `json:"record_count" doc:"出现在多少条跟进记录中"`
+	LastFollowTime *time.Time `json:"last_follow_time"`
+}
+
+// v1ContactInput 设置主联系人
+type v1ContactInput struct {
+	ContactPerson string  `json:"contact_person" doc:"必填，最长 255 字"`
+	ContactRole   *string `json:"contact_role"`
+	ContactPhone  *string `json:"contact_phone"`
+}
+
+// v1Record 跟进记录
+type v1Record struct {
+	ID            uuid.UUID  `json:"id"`
+	UserID        string     `json:"user_id" doc:"所属销售（union_id）"`
+	CustomerID    uuid.UUID  `json:"customer_id"`
+	CustomerName  string     `json:"customer_name"`
+	ContactPerson *string    `json:"contact_person"`
+	ContactRole   *string    `json:"contact_role"`
+	ContactPhone  *string    `json:"contact_phone,omitempty" doc:"仅 contacts:read 可见；具备 phones:read 时为明文，否则脱敏"`
+	FollowTime    time.Time  `json:"follow_time"`
+	FollowMethod  *string    `json:"follow_method"`
+	FollowContent *string    `json:"follow_content"`
+	FollowGoal    *string    `json:"follow_goal"`
+	FollowResult  *string    `json:"follow_result"`
+	RiskContent   *string    `json:"risk_content"`
+	NextPlan      *string    `json:"next_plan"`
+	AI            bool       `json:"ai" doc:"是否由机器人对话录入"`
+	Version       int        `json:"version" doc:"版本号，与 ETag 一致"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" doc:"已删除时间，仅 include_deleted=true 时出现"`
+}
+
+// v1RecordInput 新建/修改跟进记录；修改时仅更新出现的字段，不支持修改 user_id 与客户
+type v1RecordInput struct {
+	UserID        *string    `json:"user_id" doc:"所属销售（union_id），新建时必填，须为在职用户"`
+	CustomerID    *uuid.UUID `json:"customer_id" doc:"已有客户 ID，与 customer_name 二选一"`
+	CustomerName  *string    `json:"customer_name" doc:"客户名称，按名称匹配，匹配不到时新建客户"`
+	ContactPerson *string    `json:"contact_person"`
+	ContactRole   *string    `json:"contact_role"`
+	ContactPhone  *string    `json:"contact_phone"`
+	FollowTime    *time.Time `json:"follow_time" doc:"RFC3339，新建时默认当前时间"`
+	FollowMethod  *string    `json:"follow_method" doc:"默认「线上」"`
+	FollowContent *string    `json:"follow_content" doc:"跟进事项，新建时必填"`
+	FollowGoal    *string    `json:"follow_goal"`
+	FollowResult  *string    `json:"follow_result"`
+	RiskContent   *string    `json:"risk_content"`
+	NextPlan      *string    `json:"next_plan"`
+}
+
+// 对外 API 字段长度上限（与表结构一致）
+const (
+	v1NameMax = 255
+	v1TextMax = 2000
+)
+
+func toV1Customer(c *repository.APICustomer) v1Customer {
+	return v1Customer{ID: c.ID, Name: c.Name, Tier: c.Tier, RecordCount: c.RecordCount, LastFollowTime: c.LastFollowTime, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+}
+
+// toV1Record 转为对外表示；联系电话仅对 contacts:read 返回，phones:read 才返回明文
+func toV1Record(rec *repository.APIRecord, key *apikey.Key) v1Record {
+	v := v1Record{
+		ID: rec.ID, UserID: rec.UserID, CustomerID: rec.CustomerID, CustomerName: rec.CustomerName,
+		ContactPerson: rec.ContactPerson, ContactRole: rec.ContactRole,
+		FollowTime: rec.FollowTime, FollowMethod: rec.FollowMethod, FollowContent: rec.FollowContent,
+		FollowGoal: rec.FollowGoal, FollowResult: rec.FollowResult, RiskContent: rec.RiskContent, NextPlan: rec.NextPlan,
+		AI: rec.AI, Version: rec.Version, CreatedAt: rec.CreatedAt, UpdatedAt: rec.UpdatedAt, DeletedAt: rec.DeletedAt,
+	}
+	if key.HasScope(apikey.ScopeContactsRead) {
+		v.ContactPhone = phonecrypt.RevealPtr(rec.ContactPhone, key.HasScope(apikey.ScopePhonesRead))
+	}
+	return v
+}
+
+// v1List 列表响应
+func v1List(items interface{}, nextCursor string) map[string]interface{} {
+	return map[string]interface{}{"items": items, "next_cursor": nextCursor}
+}
+
+// parseUpdatedSince 解析 updated_since（RFC3339）
+func parseUpdatedSince(q url.Values) (*time.Time, error) {
+	v := q.Get("updated_since")
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("updated_since 应为 RFC3339 时间")
+	}
+	return &t, nil
+}
+
+// checkLen 校验可选字段长度（按字符数）
+func checkLen(field string, v *string, max int) error {
+	if v != nil && utf8.RuneCountInString(*v) > max {
+		return fmt.Errorf("%s 超过 %d 字", field, max)
+	}
+	return nil
+}
+
+// decodeV1Body 解析请求体，拒绝未知字段；失败时已写响应
+func (s *Server) decodeV1Body(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体：" + err.Error()})
+		return false
+	}
+	return true
+}
+
+// v1ListCustomers GET /v1/customers
+func (s *Server) v1ListCustomers(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	q := r.URL.Query()
+	opts := repository.CustomerListOptions{Name: strings.TrimSpace(q.Get("name")), Cursor: q.Get("cursor")}
+	var err error
+	if opts.UpdatedSince, err = parseUpdatedSince(q); err == nil {
+		opts.Limit, err = parseLimit(q)
+	}
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	page, err := repository.New(s.db).ListCustomersPage(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+			return
+		}
+		s.logger.WithContext(r.Context()).Error("List customers for api failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询客户失败"})
+		return
+	}
+	items := make([]v1Customer, len(page.Items))
+	for i, cu := range page.Items {
+		items[i] = toV1Customer(cu)
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: v1List(items, page.NextCursor)})
+}
+
+// v1GetCustomer GET /v1/customers/{id}
+func (s *Server) v1GetCustomer(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	cu, err := repository.New(s.db).GetAPICustomer(r.Context(), c.ID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get customer for api failed", "error", err, "customer_id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询客户失败"})
+		return
+	}
+	if cu == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "客户不存在"})
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: toV1Customer(cu)})
+}
+
+// validateCustomerInput 规范并校验客户输入
+func (s *Server) validateCustomerInput(in *v1CustomerInput) error {
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		if name == "" {
+			return fmt.Errorf("name 不能为空")
+		}
+		in.Name = &name
+	}
+	if err := checkLen("name", in.Name, v1NameMax); err != nil {
+		return err
+	}
+	if in.Tier != nil {
+		tier := strings.TrimSpace(*in.Tier)
+		if !s.stale.ValidTier(tier) {
+			return fmt.Errorf("未定义的客户分级：%s", tier)
+		}
+		in.Tier = &tier
+	}
+	return nil
+}
+
+// v1CreateCustomer POST /v1/customers
+func (s *Server) v1CreateCustomer(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	var in v1CustomerInput
+	if !s.decodeV1Body(w, r, &in) {
+		return
+	}
+	if in.Name == nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "缺少 name"})
+		return
+	}
+	if err := s.validateCustomerInput(&in); err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	ctx := r.Context()
+	repo := repository.New(s.db)
+	existing, err := repo.GetCustomerByName(ctx, *in.Name)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get customer by name failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "新建客户失败"})
+		return
+	}
+	if existing != nil {
+		cu, err := repo.GetAPICustomer(ctx, existing.ID)
+		if err != nil || cu == nil {
+			s.writePageJSON(w, http.StatusConflict, pageAPIResponse{Success: false, Message: "同名客户已存在"})
+			return
+		}
+		s.writePageJSON(w, http.StatusConflict, pageAPIResponse{Success: false, Data: toV1Customer(cu), Message: "同名客户已存在"})
+		return
+	}
+	customer := &models.Customer{ID: uuid.New(), Name: *in.Name}
+	if err := repo.CreateCustomer(ctx, customer); err != nil {
+		s.logger.WithContext(r.Context()).Error("Create customer for api failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "新建客户失败"})
+		return
+	}
+	if in.Tier != nil && *in.Tier != "" {
+		if err := s.stale.Repo().SetCustomerTier(ctx, customer.ID, *in.Tier); err != nil {
+			s.logger.WithContext(r.Context()).Error("Set customer tier failed", "error", err, "customer_id", customer.ID)
+		}
+	}
+	s.logger.WithContext(r.Context()).Info("Customer created via api", "customer_id", customer.ID, "key", c.Key.Prefix)
+	cu, err := repo.GetAPICustomer(ctx, customer.ID)
+	if err != nil || cu == nil {
+		s.writePageJSON(w, http.StatusCreated, pageAPIResponse{Success: true, Data: map[string]interface{}{"id": customer.ID}})
+		return
+	}
+	s.writePageJSON(w, http.StatusCreated, pageAPIResponse{Success: true, Data: toV1Customer(cu)})
+}
+
+// v1UpdateCustomer PATCH /v1/customers/{id}
+func (s *Server) v1UpdateCustomer(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	var in v1CustomerInput
+	if !s.decodeV1Body(w, r, &in) {
+		return
+	}
+	if err := s.validateCustomerInput(&in); err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	ctx := r.Context()
+	repo := repository.New(s.db)
+	cu, err := repo.GetAPICustomer(ctx, c.ID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get customer for api failed", "error", err, "customer_id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "修改客户失败"})
+		return
+	}
+	if cu == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "客户不存在"})
+		return
+	}
+	if in.Name != nil && *in.Name != cu.Name {
+		other, err := repo.GetCustomerByName(ctx, *in.Name)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Get customer by name failed", "error", err)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "修改客户失败"})
+			return
+		}
+		if other != nil {
+			s.writePageJSON(w, http.StatusConflict, pageAPIResponse{Success: false, Message: "同名客户已存在"})
+			return
+		}
+		if err := repo.WithTx(ctx, func(txCtx context.Context) error {
+			return repo.RenameCustomer(txCtx, c.ID, *in.Name)
+		}); err != nil {
+			s.logger.WithContext(r.Context()).Error("Rename customer failed", "error", err, "customer_id", c.ID)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "修改客户失败"})
+			return
+		}
+	}
+	if in.Tier != nil {
+		if err := s.stale.Repo().SetCustomerTier(ctx, c.ID, *in.Tier); err != nil {
+			s.logger.WithContext(r.Context()).Error("Set customer tier failed", "error", err, "customer_id", c.ID)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "修改客户失败"})
+			return
+		}
+	}
+	s.v1GetCustomer(w, r, c)
+}
+
+// v1ListContacts GET /v1/customers/{id}/contacts：主联系人在前，其余按最近跟进时间
+func (s *Server) v1ListContacts(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	ctx := r.Context()
+	repo := repository.New(s.db)
+	customer, err := repo.GetCustomer(ctx, c.ID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get customer failed", "error", err, "customer_id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询联系人失败"})
+		return
+	}
+	if customer == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "客户不存在"})
+		return
+	}
+	seen, err := repo.ListCustomerContacts(ctx, c.ID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List customer contacts failed", "error", err, "customer_id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询联系人失败"})
+		return
+	}
+	showPhone := c.Key.HasScope(apikey.ScopePhonesRead)
+	list := []v1Contact{}
+	primary := ""
+	if customer.ContactPerson != nil && *customer.ContactPerson != "" {
+		primary = *customer.ContactPerson
+		list = append(list, v1Contact{ContactPerson: primary, ContactRole: customer.ContactRole, ContactPhone: phonecrypt.RevealPtr(customer.ContactPhone, showPhone), Primary: true})
+	}
+	for _, ct := range seen {
+		if primary != "" && ct.ContactPerson == primary {
+			list[0].RecordCount, list[0].LastFollowTime = ct.RecordCount, ct.LastFollowTime
+			continue
+		}
+		list = append(list, v1Contact{ContactPerson: ct.ContactPerson, ContactRole: ct.ContactRole, ContactPhone: phonecrypt.RevealPtr(ct.ContactPhone, showPhone), RecordCount: ct.RecordCount, LastFollowTime: ct.LastFollowTime})
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: list})
+}
+
+// v1SetPrimaryContact PUT /v1/customers/{id}/contacts/primary
+func (s *Server) v1SetPrimaryContact(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	var in v1ContactInput
+	if !s.decodeV1Body(w, r, &in) {
+		return
+	}
+	in.ContactPerson = strings.TrimSpace(in.ContactPerson)
+	if in.ContactPerson == "" {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "缺少 contact_person"})
+		return
+	}
+	for _, f := range []struct {
+		name string
+		v    *string
+	}{{"contact_person", &in.ContactPerson}, {"contact_role", in.ContactRole}, {"contact_phone", in.ContactPhone}} {
+		if err := checkLen(f.name, f.v, v1NameMax); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+			return
+		}
+	}
+	ctx := r.Context()
+	repo := repository.New(s.db)
+	customer, err := repo.GetCustomer(ctx, c.ID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get customer failed", "error", err, "customer_id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "设置联系人失败"})
+		return
+	}
+	if customer == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "客户不存在"})
+		return
+	}
+	customer.ContactPerson, customer.ContactRole, customer.ContactPhone = &in.ContactPerson, in.ContactRole, in.ContactPhone
+	if err := repo.UpdateCustomer(ctx, customer); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			s.writePageJSON(w, http.StatusConflict, pageAPIResponse{Success: false, Message: "客户已被修改，请重试"})
+			return
+		}
+		s.logger.WithContext(r.Context()).Error("Update customer contact failed", "error", err, "customer_id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "设置联系人失败"})
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: v1Contact{
+		ContactPerson: in.ContactPerson, ContactRole: in.ContactRole, ContactPhone: in.ContactPhone, Primary: true,
+	}})
+}
+
+// v1ListRecords GET /v1/follow_records
+func (s *Server) v1ListRecords(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	q := r.URL.Query()
+	opts := repository.APIRecordListOptions{UserID: strings.TrimSpace(q.Get("user_id")), Cursor: q.Get("cursor")}
+	bad := func(msg string) {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: msg})
+	}
+	if v := q.Get("customer_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			bad("customer_id 应为 UUID")
+			return
+		}
+		opts.CustomerID = &id
+	}
+	if v := q.Get("include_deleted"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			bad("include_deleted 应为 true 或 false")
+			return
+		}
+		opts.IncludeDeleted = b
+	}
+	var err error
+	if opts.UpdatedSince, err = parseUpdatedSince(q); err == nil {
+		opts.Limit, err = parseLimit(q)
+	}
+	if err != nil {
+		bad(err.Error())
+		return
+	}
+	page, err := repository.New(s.db).ListAPIRecordsPage(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			bad(err.Error())
+			return
+		}
+		s.logger.WithContext(r.Context()).Error("List follow records for api failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询记录失败"})
+		return
+	}
+	items := make([]v1Record, len(page.Items))
+	for i, rec := range page.Items {
+		items[i] = toV1Record(rec, c.Key)
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: v1List(items, page.NextCursor)})
+}
+
+// v1GetRecord GET /v1/follow_records/{id}
+func (s *Server) v1GetRecord(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	s.writeV1Record(w, r, c, http.StatusOK)
+}
+
+// writeV1Record 读取记录并以 ETag 返回；支持 If-None-Match
+func (s *Server) writeV1Record(w http.ResponseWriter, r *http.Request, c *v1Call, status int) {
+	rec, err := repository.New(s.db).GetAPIRecord(r.Context(), c.ID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get follow record for api failed", "error", err, "id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询记录失败"})
+		return
+	}
+	if rec == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
+		return
+	}
+	setRecordETag(w, rec.Version)
+	if r.Method == http.MethodGet {
+		if v, ok := parseETag(r.Header.Get("If-None-Match")); ok && v == rec.Version {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	s.writePageJSON(w, status, pageAPIResponse{Success: true, Data: toV1Record(rec, c.Key)})
+}
+
+// validateRecordInput 校验记录输入的字段长度
+func validateRecordInput(in *v1RecordInput) error {
+	for _, f := range []struct {
+		name string
+		v    *string
+		max  int
+	}{
+		{"customer_name", in.CustomerName, v1NameMax},
+		{"contact_person", in.ContactPerson, v1NameMax},
+		{"contact_role", in.ContactRole, v1NameMax},
+		{"contact_phone", in.ContactPhone, v1NameMax},
+		{"follow_method", in.FollowMethod, v1NameMax},
+		{"follow_content", in.FollowContent, v1TextMax},
+		{"follow_goal", in.FollowGoal, v1TextMax},
+		{"follow_result", in.FollowResult, v1TextMax},
+		{"risk_content", in.RiskContent, v1TextMax},
+		{"next_plan", in.NextPlan, v1TextMax},
+	} {
+		if err := checkLen(f.name, f.v, f.max); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyRecordInput 将输入中出现的字段写入记录
+func applyRecordInput(rec *models.FollowRecord, in *v1RecordInput) {
+	if in.ContactPerson != nil {
+		rec.ContactPerson = in.ContactPerson
+	}
+	if in.ContactRole != nil {
+		rec.ContactRole = in.ContactRole
+	}
+	if in.ContactPhone != nil {
+		rec.ContactPhone = in.ContactPhone
+	}
+	if in.FollowTime != nil {
+		rec.FollowTime = *in.FollowTime
+	}
+	if in.FollowMethod != nil {
+		rec.FollowMethod = in.FollowMethod
+	}
+	if in.FollowContent != nil {
+		rec.FollowContent = in.FollowContent
+	}
+	if in.FollowGoal != nil {
+		rec.FollowGoal = in.FollowGoal
+	}
+	if in.FollowResult != nil {
+		rec.FollowResult = in.FollowResult
+	}
+	if in.RiskContent != nil {
+		rec.RiskContent = in.RiskContent
+	}
+	if in.NextPlan != nil {
+		rec.NextPlan = in.NextPlan
+	}
+}
+
+// v1CreateRecord POST /v1/follow_records
+func (s *Server) v1CreateRecord(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	var in v1RecordInput
+	if !s.decodeV1Body(w, r, &in) {
+		return
+	}
+	bad := func(msg string) {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: msg})
+	}
+	if err := validateRecordInput(&in); err != nil {
+		bad(err.Error())
+		return
+	}
+	if in.UserID == nil || strings.TrimSpace(*in.UserID) == "" {
+		bad("缺少 user_id")
+		return
+	}
+	if in.FollowContent == nil || strings.TrimSpace(*in.FollowContent) == "" {
+		bad("缺少 follow_content")
+		return
+	}
+	customerName := ""
+	if in.CustomerName != nil {
+		customerName = strings.TrimSpace(*in.CustomerName)
+	}
+	if in.CustomerID == nil && customerName == "" {
+		bad("customer_id 与 customer_name 至少提供一个")
+		return
+	}
+
+	ctx := repository.WithAudit(r.Context(), c.actor(), models.RecordSourceAPI)
+	repo := repository.New(s.db)
+	userID := strings.TrimSpace(*in.UserID)
+	user, err := repo.GetUser(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get user failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "新建记录失败"})
+		return
+	}
+	if user == nil || user.Status != 0 {
+		bad("user_id 不存在或已离职")
+		return
+	}
+
+	method := "线上"
+	record := &models.FollowRecord{ID: uuid.New(), UserID: userID, FollowTime: time.Now(), FollowMethod: &method}
+	applyRecordInput(record, &in)
+	err = repo.WithTx(ctx, func(txCtx context.Context) error {
+		if in.CustomerID != nil {
+			customer, err := repo.GetCustomer(txCtx, *in.CustomerID)
+			if err != nil {
+				return err
+			}
+			if customer == nil {
+				return errV1CustomerNotFound
+			}
+			record.CustomerID, record.CustomerName = customer.ID, customer.Name
+		} else {
+			id, _, err := s.customerMatcher().FindOrCreateCustomer(txCtx, customerName)
+			if err != nil {
+				return err
+			}
+			record.CustomerID, record.CustomerName = id, customerName
+		}
+		return repo.CreateFollowRecord(txCtx, record)
+	})
+	if err != nil {
+		if errors.Is(err, errV1CustomerNotFound) {
+			bad("客户不存在")
+			return
+		}
+		s.logger.WithContext(r.Context()).Error("Create follow record for api failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "新建记录失败"})
+		return
+	}
+	s.logger.WithContext(r.Context()).Info("Follow record created via api", "id", record.ID, "user_id", userID, "key", c.Key.Prefix)
+	c.ID = record.ID
+	s.writeV1Record(w, r, c, http.StatusCreated)
+}
+
+// errV1CustomerNotFound 指定的 customer_id 不存在
+var errV1CustomerNotFound = errors.New("customer not found")
+
+// v1UpdateRecord PATCH /v1/follow_records/{id}：需 If-Match（或 * 表示不校验版本）
+func (s *Server) v1UpdateRecord(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	var in v1RecordInput
+	if !s.decodeV1Body(w, r, &in) {
+		return
+	}
+	if in.UserID != nil || in.CustomerID != nil || in.CustomerName != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "不支持修改 user_id 与客户，请删除后重新创建"})
+		return
+	}
+	if err := validateRecordInput(&in); err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		s.writePageJSON(w, http.StatusPreconditionRequired, pageAPIResponse{Success: false, Message: "缺少 If-Match"})
+		return
+	}
+	ctx := repository.WithAudit(r.Context(), c.actor(), models.RecordSourceAPI)
+	repo := repository.New(s.db)
+	record, err := repo.GetFollowRecordByID(ctx, c.ID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get follow record failed", "error", err, "id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "修改记录失败"})
+		return
+	}
+	if record == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
+		return
+	}
+	if ifMatch != "*" {
+		v, ok := parseETag(ifMatch)
+		if !ok {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的 If-Match"})
+			return
+		}
+		if v != record.Version {
+			s.writeV1Conflict(w, r, c)
+			return
+		}
+	}
+	applyRecordInput(record, &in)
+	if err := repo.UpdateFollowRecord(ctx, record); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			s.writeV1Conflict(w, r, c)
+			return
+		}
+		s.logger.WithContext(r.Context()).Error("Update follow record for api failed", "error", err, "id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "修改记录失败"})
+		return
+	}
+	s.writeV1Record(w, r, c, http.StatusOK)
+}
+
+// writeV1Conflict 返回 409 与服务端当前版本
+func (s *Server) writeV1Conflict(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	rec, _ := repository.New(s.db).GetAPIRecord(r.Context(), c.ID)
+	if rec == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
+		return
+	}
+	setRecordETag(w, rec.Version)
+	s.writePageJSON(w, http.StatusConflict, pageAPIResponse{Success: false, Data: toV1Record(rec, c.Key), Message: "记录已被修改，请基于最新版本重试"})
+}
+
+// v1DeleteRecord DELETE /v1/follow_records/{id}：带 If-Match 时校验版本
+func (s *Server) v1DeleteRecord(w http.ResponseWriter, r *http.Request, c *v1Call) {
+	ctx := repository.WithAudit(r.Context(), c.actor(), models.RecordSourceAPI)
+	repo := repository.New(s.db)
+	record, err := repo.GetFollowRecordByID(ctx, c.ID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get follow record failed", "error", err, "id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "删除记录失败"})
+		return
+	}
+	if record == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
+		return
+	}
+	if ifMatch := strings.TrimSpace(r.Header.Get("If-Match")); ifMatch != "" && ifMatch != "*" {
+		if v, ok := parseETag(ifMatch); !ok || v != record.Version {
+			s.writeV1Conflict(w, r, c)
+			return
+		}
+	}
+	ok, err := repo.DeleteFollowRecord(ctx, c.ID, record.UserID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Delete follow record for api failed", "error", err, "id", c.ID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "删除记录失败"})
+		return
+	}
+	if !ok {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
+		return
+	}
+	s.logger.WithContext(r.Context()).Info("Follow record deleted via api", "id", c.ID, "key", c.Key.Prefix)
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Message: "删除成功"})
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"records/internal/auth"
+	"records/internal/config"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 页面登录会话：短期访问令牌 + 服务端存储并轮换的刷新令牌；退出登录与用户离职时撤销会话。
+// x-user-id 回退仅在 dev 构建或经 loopback_listen 回环监听进入的请求中可用
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	// authSessionRetention 过期或撤销的会话保留时长，超过后由清理任务删除
+	authSessionRetention = 7 * 24 * time.Hour
+)
+
+// loopbackListenerKey 请求上下文标记：请求经回环监听进入
+type loopbackListenerKey struct{}
+
+// newSessions 按配置创建登录会话管理，有效期未配置时取默认值
+func newSessions(db *sqlx.DB, cfg config.Server) *auth.Sessions {
+	accessTTL := cfg.AccessTokenTTL
+	if accessTTL <= 0 {
+		accessTTL = defaultAccessTokenTTL
+	}
+	refreshTTL := cfg.RefreshTokenTTL
+	if refreshTTL <= 0 {
+		refreshTTL = defaultRefreshTokenTTL
+	}
+	return auth.NewSessions(db, cfg.JWTSecret, accessTTL, refreshTTL)
+}
+
+// allowXUserID 请求是否可用 x-user-id 标识用户：经回环监听进入，或 dev 构建下开启回退/未配置 JWT
+func (s *Server) allowXUserID(r *http.Request) bool {
+	if loopback, _ := r.Context().Value(loopbackListenerKey{}).(bool); loopback {
+		return true
+	}
+	return devBuild && (s.config.Server.AllowXUserIDFallback || s.config.Server.JWTSecret == "")
+}
+
+// warnAuthConfig 启动时提示与构建方式不符的认证配置
+func (s *Server) warnAuthConfig() {
+	if s.config.Server.AllowXUserIDFallback && !devBuild {
+		s.logger.Warn("allow_x_user_id_fallback 仅在 dev 构建（-tags dev）生效，已忽略；本机调试请配置 server.loopback_listen")
+	}
+	if s.config.Server.JWTSecret == "" && !devBuild && s.config.Server.LoopbackListen == "" {
+		s.logger.Warn("server.jwt_secret 未配置：page API 将拒绝全部请求")
+	}
+}
+
+// isLoopbackAddr 监听地址的主机部分是否为回环地址（127.0.0.0/8、::1 或 localhost）
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// startLoopbackListener 启动回环监听：与主监听共用 handler，请求带回环标记（允许 x-user-id）
+func (s *Server) startLoopbackListener(handler http.Handler) error {
+	addr := strings.TrimSpace(s.config.Server.LoopbackListen)
+	if addr == "" {
+		return nil
+	}
+	if !isLoopbackAddr(addr) {
+		return fmt.Errorf("server.loopback_listen must be a loopback address, got %q", addr)
+	}
+	s.loopbackServer = &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), loopbackListenerKey{}, true)))
+		}),
+		ReadTimeout:  s.config.Server.ReadTimeout,
+		WriteTimeout: s.config.Server.WriteTimeout,
+		IdleTimeout:  s.config.Server.IdleTimeout,
+	}
+	go func() {
+		s.logger.Info("Starting loopback HTTP server (x-user-id allowed)", "addr", addr)
+		if err := s.loopbackServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Loopback HTTP server stopped with error", "error", err)
+		}
+	}()
+	return nil
+}
+
+// bearerToken 返回 Authorization: Bearer 令牌，无则为空
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+}
+
+// tokensData 登录/刷新响应中的令牌字段
+func tokensData(t *auth.Tokens) map[string]interface{} {
+	return map[string]interface{}{
+		"token":         t.AccessToken,
+		"refresh_token": t.RefreshToken,
+		"expires_in":    t.ExpiresIn,
+	}
+}
+
+type authRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// authRefreshHandler POST {apiP}/auth/refresh 用刷新令牌换取新的访问令牌，刷新令牌同时轮换（旧令牌作废）
+func (s *Server) authRefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.config.Server.JWTSecret == "" {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "未配置 JWT，无需刷新"})
+		return
+	}
+	var req authRefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+		return
+	}
+	tokens, err := s.sessions.Refresh(r.Context(), req.RefreshToken, s.clientIP(r), truncateRunes(r.UserAgent(), 512))
+	if err != nil {
+		if errors.Is(err, auth.ErrRefreshReused) {
+			s.logger.WithContext(r.Context()).Warn("Refresh token reused, session revoked", "ip", s.clientIP(r))
+		} else if !errors.Is(err, auth.ErrSessionInvalid) {
+			s.logger.WithContext(r.Context()).Error("Refresh auth session failed", "error", err)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "刷新登录失败"})
+			return
+		}
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录"})
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: tokensData(tokens)})
+}
+
+type authLogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"` // 撤销当前用户全部会话（所有设备），需有效的访问令牌
+}
+
+// authLogoutHandler POST {apiP}/auth/logout 撤销当前会话；访问令牌已过期时按请求体中的刷新令牌撤销
+func (s *Server) authLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req authLogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+			return
+		}
+	}
+	ctx := r.Context()
+	token := bearerToken(r)
+	var revoked int64
+	if req.All {
+		userID, err := s.sessions.Authenticate(ctx, token)
+		if err != nil {
+			s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录"})
+			return
+		}
+		n, err := s.sessions.RevokeUser(ctx, userID, auth.RevokeLogoutAll)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Revoke user auth sessions failed", "error", err, "user_id", userID)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "退出登录失败"})
+			return
+		}
+		revoked = n
+	} else {
+		if sid, err := s.sessions.SessionID(token); err == nil {
+			ok, err := s.sessions.Revoke(ctx, sid, auth.RevokeLogout)
+			if err != nil {
+				s.logger.WithContext(r.Context()).Error("Revoke auth session failed", "error", err, "session_id", sid)
+				s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "退出登录失败"})
+				return
+			}
+			if ok {
+				revoked++
+			}
+		}
+		if req.RefreshToken != "" {
+			ok, err := s.sessions.RevokeByRefresh(ctx, req.RefreshToken, auth.RevokeLogout)
+			if err != nil {
+				s.logger.WithContext(r.Context()).Error("Revoke auth session by refresh token failed", "error", err)
+				s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "退出登录失败"})
+				return
+			}
+			if ok {
+				revoked++
+			}
+		}
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{"revoked": revoked}})
+}
+
+// runAuthSessionsCleanup 撤销离职用户的会话并删除过期已久的会话
+func (s *Server) runAuthSessionsCleanup(ctx context.Context) error {
+	revoked, err := s.sessions.RevokeResigned(ctx)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.sessions.Cleanup(ctx, authSessionRetention)
+	if err != nil {
+		return err
+	}
+	if revoked > 0 || deleted > 0 {
+		s.logger.WithContext(ctx).Info("Cleaned up auth sessions", "revoked_resigned", revoked, "deleted", deleted)
+	}
+	return nil
+}
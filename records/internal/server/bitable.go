@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"records/internal/bitable"
+	"records/internal/config"
+	"records/internal/scheduler"
+	"records/pkg/logger"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 飞书多维表格同步：把客户与跟进记录增量同步到配置的多维表格，供习惯在多维表格中查看的主管使用
+
+// newBitableSyncer 按配置创建同步器；未单独配置应用凭证时使用销售助手机器人应用
+func newBitableSyncer(db *sqlx.DB, cfg *config.Config, log logger.Logger) *bitable.Syncer {
+	b := cfg.Bitable
+	appID, appSecret := b.AppID, b.AppSecret
+	if appID == "" {
+		appID, appSecret = cfg.Feishu.SaleAgent.AppID, cfg.Feishu.SaleAgent.AppSecret
+	}
+	client := bitable.NewClient(b.BaseURL, appID, appSecret, b.AppToken, nil)
+	return bitable.NewSyncer(db, client, bitable.Config{
+		Customers:     bitable.TableConfig{TableID: b.Customers.TableID, Fields: b.Customers.Fields},
+		Records:       bitable.TableConfig{TableID: b.FollowRecords.TableID, Fields: b.FollowRecords.Fields},
+		BatchSize:     b.BatchSize,
+		Conflict:      b.Conflict,
+		DeleteRemoved: b.DeleteRemoved,
+		Overlap:       b.Overlap,
+	}, log)
+}
+
+// runBitableSyncJob 增量同步到多维表格；未启用或没有新变更时记为跳过
+func (s *Server) runBitableSyncJob(ctx context.Context) error {
+	if !s.config.Bitable.Enabled {
+		return scheduler.ErrSkipped
+	}
+	if s.config.Bitable.AppToken == "" {
+		return errors.New("bitable.app_token is required")
+	}
+	res, err := s.bitable.Run(ctx)
+	if res != nil && res.Customers+res.Records > 0 {
+		s.logger.WithContext(ctx).Info("Bitable sync finished", "customers", res.Customers, "records", res.Records)
+	}
+	if err != nil {
+		return err
+	}
+	if res.Customers+res.Records == 0 {
+		return scheduler.ErrSkipped
+	}
+	return nil
+}
+
+// adminBitableHandler GET {apiP}/admin/bitable?limit= 返回各表的同步状态与最近的冲突；
+// 手动同步通过 POST {apiP}/admin/jobs/bitable_sync/run 触发
+func (s *Server) adminBitableHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := s.adminUserIDFromRequest(w, r); !ok {
+		return
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	if limit == 0 || limit > 200 {
+		limit = 50
+	}
+	repo := s.bitable.Repo()
+	states := []*bitable.State{}
+	for _, kind := range []string{bitable.KindCustomer, bitable.KindRecord} {
+		tableID := s.bitable.TableID(kind)
+		if tableID == "" {
+			continue
+		}
+		st, err := repo.GetState(r.Context(), kind, tableID)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Get bitable sync state failed", "error", err, "kind", kind)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取同步状态失败"})
+			return
+		}
+		states = append(states, st)
+	}
+	conflicts, err := repo.ListConflicts(r.Context(), limit)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List bitable conflicts failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取冲突记录失败"})
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"enabled":   s.config.Bitable.Enabled,
+		"states":    states,
+		"conflicts": conflicts,
+	}})
+}
+
+// adminBitableResetHandler POST {apiP}/admin/bitable/reset?kind=customer|follow_record 清空游标，下次从头全量同步
+func (s *Server) adminBitableResetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	adminID, ok := s.adminUserIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	kind := r.URL.Query().Get("kind")
+	tableID := s.bitable.TableID(kind)
+	if tableID == "" {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "kind 应为已配置的 customer 或 follow_record"})
+		return
+	}
+	if err := s.bitable.Repo().ResetCursor(r.Context(), kind, tableID); err != nil {
+		s.logger.WithContext(r.Context()).Error("Reset bitable cursor failed", "error", err, "kind", kind)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "重置失败"})
+		return
+	}
+	s.logger.WithContext(r.Context()).Info("Bitable cursor reset", "kind", kind, "admin_id", adminID)
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true})
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"records/internal/repository"
+)
+
+// defaultProcessedEventRetention 飞书事件去重记录默认保留时长，覆盖飞书约 7.1 小时的重推窗口
+const defaultProcessedEventRetention = 8 * time.Hour
+
+// lockUser 获取用户级锁：先取进程内互斥锁，再取数据库租约锁（leases），保证多实例下同一用户的消息串行处理。
+// 先取进程内锁可避免同一实例内同一用户的多个请求反复争抢租约。返回的 lockCtx 在租约丢失时取消，持锁期间的处理须使用它。
+func (s *Server) lockUser(ctx context.Context, userID string) (lockCtx context.Context, unlock func(), err error) {
+	mu := s.getUserLock(userID)
+	mu.Lock()
+
+	lockCtx, dbUnlock, err := repository.New(s.db).AcquireUserLock(ctx, userID)
+	if err != nil {
+		mu.Unlock()
+		return nil, nil, err
+	}
+	return lockCtx, func() {
+		dbUnlock()
+		mu.Unlock()
+	}, nil
+}
+
+// runProcessedEventsCleanup 清理过期的飞书事件去重记录（processed_events），由定时任务调度
+func (s *Server) runProcessedEventsCleanup(ctx context.Context) error {
+	retention := s.config.System.ProcessedEventRetention
+	if retention <= 0 {
+		retention = defaultProcessedEventRetention
+	}
+	n, err := repository.New(s.db).CleanupProcessedEvents(ctx, retention)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.logger.WithContext(ctx).Debug("Cleaned up processed events", "count", n)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"records/internal/comments"
+	"records/internal/feishu"
+	"records/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// commentRequest 发表评论请求体；parent_id 为话题内任一评论，mentions 为被 @ 用户的 user_id
+type commentRequest struct {
+	Content  string   `json:"content"`
+	ParentID string   `json:"parent_id"`
+	Mentions []string `json:"mentions"`
+}
+
+// canViewRecordsOf 评论权限：本人记录或 view 权限范围内销售的记录
+func (s *Server) canViewRecordsOf(ctx context.Context, userID, ownerID string) (bool, error) {
+	return s.canAccessUser(ctx, userID, models.PermView, ownerID)
+}
+
+// pageRecordCommentsHandler 处理 GET {apiP}/records/{id}/comments（话题列表，并将当前用户在该记录上的评论标记已读）
+// 与 POST（发表评论或回复，通知记录所属销售、被 @ 者与话题参与者）
+func (s *Server) pageRecordCommentsHandler(w http.ResponseWriter, r *http.Request, path string) {
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[1] != "comments" {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := uuid.Parse(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var req commentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+			return
+		}
+		in := &comments.NewComment{RecordID: id, AuthorID: userID, Content: req.Content, Mentions: req.Mentions, Source: comments.SourcePage}
+		if req.ParentID != "" {
+			parentID, err := uuid.Parse(req.ParentID)
+			if err != nil {
+				s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的 parent_id"})
+				return
+			}
+			in.ParentID = &parentID
+		}
+		c, err := s.comments.Post(r.Context(), in)
+		if err != nil {
+			s.writeCommentError(w, err, id)
+			return
+		}
+		s.writePageJSON(w, http.StatusCreated, pageAPIResponse{Success: true, Data: c, Message: "评论成功"})
+		return
+	}
+
+	repo := s.comments.Repo()
+	record, err := repo.GetRecordInfo(r.Context(), id)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get record for comments failed", "error", err, "id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取评论失败"})
+		return
+	}
+	if record == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
+		return
+	}
+	if allowed, err := s.canViewRecordsOf(r.Context(), userID, record.UserID); err != nil || !allowed {
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Check view permission failed", "error", err, "user_id", userID)
+		}
+		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限查看此记录"})
+		return
+	}
+	threads, err := repo.ListThreads(r.Context(), id, userID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List comments failed", "error", err, "id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取评论失败"})
+		return
+	}
+	if _, err := repo.MarkRead(r.Context(), userID, id); err != nil {
+		s.logger.WithContext(r.Context()).Error("Mark comments read failed", "error", err, "id", id, "user_id", userID)
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: threads})
+}
+
+// writeCommentError 将发表评论的错误转为响应
+func (s *Server) writeCommentError(w http.ResponseWriter, err error, id uuid.UUID) {
+	switch {
+	case errors.Is(err, comments.ErrRecordNotFound), errors.Is(err, comments.ErrParentNotFound):
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, comments.ErrForbidden), errors.Is(err, comments.ErrMentionDenied):
+		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, comments.ErrEmptyContent), errors.Is(err, comments.ErrContentTooLong), errors.Is(err, comments.ErrTooManyMention):
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+	default:
+		s.logger.Error("Post comment failed", "error", err, "id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "评论失败"})
+	}
+}
+
+// commentsUnreadHandler GET {apiP}/comments/unread 当前用户的未读评论总数及按记录的明细
+func (s *Server) commentsUnreadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	list, err := s.comments.Repo().ListUnread(r.Context(), userID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List unread comments failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取未读评论失败"})
+		return
+	}
+	total := 0
+	for _, u := range list {
+		total += u.Unread
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"total":   total,
+		"records": list,
+	}})
+}
+
+// withUnreadComments 为记录列表项（followRecordToPageMap 结果）补充当前用户的未读评论数 unread_comments；查询失败时不补充
+func (s *Server) withUnreadComments(ctx context.Context, userID string, items []map[string]interface{}) {
+	ids := make([]uuid.UUID, len(items))
+	for i, m := range items {
+		ids[i], _ = uuid.Parse(m["id"].(string))
+	}
+	counts, err := s.comments.Repo().UnreadCounts(ctx, userID, ids)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Count unread comments failed", "error", err, "user_id", userID)
+		return
+	}
+	for i, m := range items {
+		m["unread_comments"] = counts[ids[i]]
+	}
+}
+
+// handleCommentReply 用户在机器人会话中回复评论通知消息时，作为话题回复发表；返回 false 表示被回复的不是评论通知，按普通消息处理
+func (s *Server) handleCommentReply(ctx context.Context, msg *feishu.Message) bool {
+	var messageIDs []string
+	for _, id := range []string{msg.ParentID, msg.RootID} {
+		if id != "" && !containsString(messageIDs, id) {
+			messageIDs = append(messageIDs, id)
+		}
+	}
+	c, err := s.comments.ReplyByMessage(ctx, msg.UserID, messageIDs, msg.Content)
+	if err == nil && c == nil {
+		return false
+	}
+	reply := "已回复评论"
+	switch {
+	case errors.Is(err, comments.ErrEmptyContent), errors.Is(err, comments.ErrContentTooLong),
+		errors.Is(err, comments.ErrRecordNotFound), errors.Is(err, comments.ErrForbidden):
+		reply = "回复失败：" + err.Error()
+	case err != nil:
+		s.logger.WithContext(ctx).Error("Reply comment from bot failed", "error", err, "user_id", msg.UserID)
+		reply = s.config.Messages.SystemError
+	}
+	if err := s.feishuClient.SendMessage(ctx, msg.ChatID, reply); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to send reply", "error", err, "chat_id", msg.ChatID)
+	}
+	return true
+}
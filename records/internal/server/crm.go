@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"records/internal/config"
+	"records/internal/crmsync"
+	"records/internal/importer"
+	"records/internal/orchestrator"
+	"records/internal/repository"
+	"records/internal/scheduler"
+	"records/pkg/logger"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 公司 CRM 双向同步：客户与 CRM 账户互相同步、跟进记录推送为 CRM 活动，对话中优先匹配 CRM 主数据账户
+
+// customerMatcher 按客户名匹配或新建客户：启用 CRM 同步时优先匹配 CRM 主数据账户，
+// 对话、对外 API 与导入共用，避免绕过主数据新建重复客户
+func (s *Server) customerMatcher() orchestrator.CustomerMatcher {
+	if s.crm != nil {
+		return s.crm
+	}
+	return repository.New(s.db)
+}
+
+// newImporter 创建导入器；启用 CRM 同步时按 CRM 主数据匹配客户，与对话中的客户匹配一致
+func (s *Server) newImporter() *importer.Importer {
+	im := importer.New(s.db, s.logger)
+	if s.crm != nil {
+		im.SetCustomerMatcher(s.crm)
+	}
+	return im
+}
+
+// newCRMSyncer 按配置创建同步器；未启用或连接器类型不支持时返回 nil
+func newCRMSyncer(db *sqlx.DB, cfg config.CRM, log logger.Logger) *crmsync.Syncer {
+	if !cfg.Enabled {
+		return nil
+	}
+	var conn crmsync.Connector
+	switch cfg.Connector {
+	case "", "rest":
+		r := cfg.REST
+		conn = crmsync.NewREST(crmsync.RESTConfig{
+			BaseURL:        r.BaseURL,
+			Headers:        r.Headers,
+			Timeout:        r.Timeout,
+			SearchPath:     r.SearchPath,
+			AccountsPath:   r.AccountsPath,
+			ActivitiesPath: r.ActivitiesPath,
+			ChangesPath:    r.ChangesPath,
+			ItemsPath:      r.ItemsPath,
+			NextCursorPath: r.NextCursorPath,
+			HasMorePath:    r.HasMorePath,
+			IDPath:         r.IDPath,
+			AccountFields:  r.AccountFields,
+			ActivityFields: r.ActivityFields,
+		})
+	default:
+		log.Error("Unsupported crm connector, crm sync disabled", "connector", cfg.Connector)
+		return nil
+	}
+	return crmsync.NewSyncer(db, conn, crmsync.Config{BatchSize: cfg.BatchSize, MatchTimeout: cfg.MatchTimeout}, log)
+}
+
+// runCRMSyncJob 与 CRM 双向同步；未启用或没有变更时记为跳过
+func (s *Server) runCRMSyncJob(ctx context.Context) error {
+	if s.crm == nil {
+		return scheduler.ErrSkipped
+	}
+	res, err := s.crm.Run(ctx)
+	if res != nil && res.Pulled+res.Unlinked+res.AccountsPushed+res.ActivitiesPushed+res.Failed > 0 {
+		s.logger.WithContext(ctx).Info("CRM sync finished", "pulled", res.Pulled, "unlinked", res.Unlinked,
+			"accounts_pushed", res.AccountsPushed, "activities_pushed", res.ActivitiesPushed, "failed", res.Failed)
+	}
+	if err != nil {
+		return err
+	}
+	if res.Pulled+res.Unlinked+res.AccountsPushed+res.ActivitiesPushed == 0 {
+		return scheduler.ErrSkipped
+	}
+	return nil
+}
+
+// adminCRMHandler GET {apiP}/admin/crm 返回 CRM 同步状态（拉取游标、最近运行时间与错误）；
+// 手动同步通过 POST {apiP}/admin/jobs/crm_sync/run 触发
+func (s *Server) adminCRMHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := s.adminUserIDFromRequest(w, r); !ok {
+		return
+	}
+	data := map[string]interface{}{"enabled": s.crm != nil}
+	if s.crm != nil {
+		st, err := s.crm.Repo().GetState(r.Context(), crmsync.PullState)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Get crm sync state failed", "error", err)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取同步状态失败"})
+			return
+		}
+		data["state"] = st
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: data})
+}
@@ -0,0 +1,6 @@
+//go:build !dev
+
+package server
+
+// devBuild 是否为开发构建（go build -tags dev）；生产构建下 allow_x_user_id_fallback 不生效
+const devBuild = false
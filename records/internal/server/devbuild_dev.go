@@ -0,0 +1,6 @@
+//go:build dev
+
+package server
+
+// devBuild 是否为开发构建（go build -tags dev）；开发构建下 allow_x_user_id_fallback 生效
+const devBuild = true
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"records/internal/config"
+	"records/internal/digest"
+	"records/internal/repository"
+	"records/internal/scheduler"
+)
+
+// 跟进摘要：销售个人小结与主管团队概览，按用户订阅的频率以消息卡片推送
+
+// digestConfig 将配置转换为摘要服务配置；weekly_weekday 1-7 对应周一至周日，未配置默认周五
+func digestConfig(cfg config.Digest) digest.Config {
+	weekday := time.Friday
+	if cfg.WeeklyWeekday >= 1 && cfg.WeeklyWeekday <= 7 {
+		weekday = time.Weekday(cfg.WeeklyWeekday % 7)
+	}
+	return digest.Config{QuietDays: cfg.QuietDays, WeeklyWeekday: weekday}
+}
+
+// runDigestJob 推送当前到期的摘要，由定时任务调度；无到期摘要时记为跳过
+func (s *Server) runDigestJob(ctx context.Context) error {
+	sent, err := s.digest.RunDue(ctx, time.Now())
+	if sent > 0 {
+		s.logger.WithContext(ctx).Info("Digests sent", "count", sent)
+	}
+	if err != nil {
+		return err
+	}
+	if sent == 0 {
+		return scheduler.ErrSkipped
+	}
+	return nil
+}
+
+type digestSettingsRequest struct {
+	RepFrequency     *string `json:"rep_frequency"`
+	ManagerFrequency *string `json:"manager_frequency"`
+}
+
+// digestSettingsHandler GET/PUT {api_prefix}/digest/settings 查看或修改当前用户的摘要订阅；
+// 频率取值 off/daily/weekly，manager_frequency 仅主管可开启
+func (s *Server) digestSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	ctx := r.Context()
+	isManager, err := repository.New(s.db).IsManager(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("IsManager failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取摘要设置失败"})
+		return
+	}
+	digestRepo := s.digest.Repo()
+	sub, err := digestRepo.GetSubscription(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get digest subscription failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取摘要设置失败"})
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var req digestSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+			return
+		}
+		if req.RepFrequency != nil {
+			if !digest.ValidFrequency(*req.RepFrequency) {
+				s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的推送频率"})
+				return
+			}
+			sub.RepFrequency = *req.RepFrequency
+		}
+		if req.ManagerFrequency != nil {
+			if !digest.ValidFrequency(*req.ManagerFrequency) {
+				s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的推送频率"})
+				return
+			}
+			if *req.ManagerFrequency != digest.FrequencyOff && !isManager {
+				s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "仅主管可订阅团队摘要"})
+				return
+			}
+			sub.ManagerFrequency = *req.ManagerFrequency
+		}
+		if _, err := s.ensureUserExists(ctx, userID, false); err != nil {
+			s.logger.WithContext(r.Context()).Error("Ensure user exists failed", "error", err, "user_id", userID)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "保存摘要设置失败"})
+			return
+		}
+		if err := digestRepo.UpsertSubscription(ctx, userID, sub.RepFrequency, sub.ManagerFrequency); err != nil {
+			s.logger.WithContext(r.Context()).Error("Upsert digest subscription failed", "error", err, "user_id", userID)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "保存摘要设置失败"})
+			return
+		}
+	}
+
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"rep_frequency":     sub.RepFrequency,
+		"manager_frequency": sub.ManagerFrequency,
+		"is_manager":        isManager,
+	}})
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"records/internal/scheduler"
+)
+
+// runDirectorySyncJob 同步飞书通讯录部门树、负责人与成员归属；未启用时记为跳过
+func (s *Server) runDirectorySyncJob(ctx context.Context) error {
+	if !s.config.Directory.Enabled {
+		return scheduler.ErrSkipped
+	}
+	if _, err := s.directory.Run(ctx); err != nil {
+		return err
+	}
+	// 同步后标记为离职的用户立即失去页面登录会话
+	n, err := s.sessions.RevokeResigned(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.logger.WithContext(ctx).Info("Revoked auth sessions of resigned users", "count", n)
+	}
+	return nil
+}
+
+// adminDepartmentsHandler GET {apiP}/admin/departments 列出已同步的部门（部门链、负责人、在职直属成员数），供配置 department/department_tree 授权范围；
+// 手动同步通过 POST {apiP}/admin/jobs/directory_sync/run 触发
+func (s *Server) adminDepartmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := s.adminUserIDFromRequest(w, r); !ok {
+		return
+	}
+	list, err := s.directory.Repo().ListDepartments(r.Context())
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List departments failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取部门失败"})
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"departments": list,
+		"enabled":     s.config.Directory.Enabled,
+	}})
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"records/internal/models"
+	"records/internal/phonecrypt"
+	"records/internal/repository"
+	"records/internal/spreadsheet"
+
+	"github.com/google/uuid"
+)
+
+// 跟进记录导出：CSV / XLSX 流式输出，列与 followRecordToPageMap 一致（另加销售姓名与联系电话）
+
+// exportColumn 导出列：key 与 page API 字段同名
+type exportColumn struct {
+	Key   string
+	Title string
+}
+
+var exportColumns = []exportColumn{
+	{"id", "记录ID"},
+	{"customer_id", "客户ID"},
+	{"customer_name", "客户名称"},
+	{"user_name", "销售"},
+	{"follow_time", "跟进时间"},
+	{"follow_method", "跟进方式"},
+	{"contact_person", "联系人"},
+	{"contact_phone", "联系电话"},
+	{"contact_role", "联系人角色"},
+	{"follow_goal", "跟进目标"},
+	{"follow_content", "跟进内容"},
+	{"follow_result", "跟进结果"},
+	{"risk_content", "风险"},
+	{"next_plan", "下一步计划"},
+	{"ai", "AI 录入"},
+	{"created_at", "创建时间"},
+}
+
+// exportViewer 导出请求方的身份，用于判断联系电话是否脱敏
+type exportViewer struct {
+	UserID string
+	// PhoneGranted 拥有 view_phone 权限，PhoneScope 为其范围（nil 表示全部）
+	PhoneGranted bool
+	PhoneScope   []string
+}
+
+// canViewPhone 判断请求方能否查看某条记录的明文联系电话：本人记录，或在其 view_phone 权限范围内
+func (s *Server) canViewPhone(v exportViewer, recordUserID string) bool {
+	if recordUserID == v.UserID {
+		return true
+	}
+	return v.PhoneGranted && (v.PhoneScope == nil || containsString(v.PhoneScope, recordUserID))
+}
+
+// setPagePhone 为记录 map 补充联系电话：可查看明文时解密，否则脱敏
+func (s *Server) setPagePhone(m map[string]interface{}, v exportViewer, rec *models.FollowRecord) {
+	m["contact_phone"] = phonecrypt.Reveal(rec.ContactPhone, s.canViewPhone(v, rec.UserID))
+}
+
+// parseExportColumns 解析 columns 参数（逗号分隔的字段名），为空时导出全部列
+func parseExportColumns(param string) ([]exportColumn, error) {
+	if strings.TrimSpace(param) == "" {
+		return exportColumns, nil
+	}
+	byKey := make(map[string]exportColumn, len(exportColumns))
+	for _, c := range exportColumns {
+		byKey[c.Key] = c
+	}
+	var cols []exportColumn
+	for _, key := range strings.Split(param, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		c, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("不支持的列：%s", key)
+		}
+		cols = append(cols, c)
+	}
+	if len(cols) == 0 {
+		return exportColumns, nil
+	}
+	return cols, nil
+}
+
+// parseExportFilter 解析通用筛选参数：from/to（YYYY-MM-DD，含）、customer_id、customer_name
+func parseExportFilter(q url.Values) (repository.ExportFilter, error) {
+	var f repository.ExportFilter
+	if v := q.Get("from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return f, fmt.Errorf("from 日期格式应为 YYYY-MM-DD")
+		}
+		f.From = &t
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return f, fmt.Errorf("to 日期格式应为 YYYY-MM-DD")
+		}
+		t = t.AddDate(0, 0, 1)
+		f.To = &t
+	}
+	if v := q.Get("customer_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return f, fmt.Errorf("无效的 customer_id")
+		}
+		f.CustomerID = &id
+	}
+	f.CustomerName = strings.TrimSpace(q.Get("customer_name"))
+	return f, nil
+}
+
+// exportFormat 解析 format 参数，默认 csv
+func exportFormat(q url.Values) (string, error) {
+	switch f := q.Get("format"); f {
+	case "", spreadsheet.FormatCSV:
+		return spreadsheet.FormatCSV, nil
+	case spreadsheet.FormatXLSX:
+		return f, nil
+	default:
+		return "", fmt.Errorf("format 仅支持 csv 或 xlsx")
+	}
+}
+
+// pageRecordsExportHandler GET {apiP}/records/export 导出当前用户自己的跟进记录
+func (s *Server) pageRecordsExportHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	f, err := parseExportFilter(r.URL.Query())
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	f.UserIDs = []string{userID}
+	s.streamFollowRecordsExport(w, r, f, exportViewer{UserID: userID}, "我的跟进记录")
+}
+
+// managerExportHandler GET {apiP}/manager/export[?user_id=] 导出主管范围内（可指定某销售）的跟进记录
+func (s *Server) managerExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, scope, ok := s.requirePermission(w, r, models.PermExport)
+	if !ok {
+		return
+	}
+	f, err := parseExportFilter(r.URL.Query())
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	f.UserIDs = scope
+	if target := r.URL.Query().Get("user_id"); target != "" {
+		if scope != nil && !containsString(scope, target) {
+			s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限查看该用户"})
+			return
+		}
+		f.UserIDs = []string{target}
+	}
+	viewer, err := s.newRecordViewer(r.Context(), userID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Resolve viewer failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+		return
+	}
+	s.streamFollowRecordsExport(w, r, f, viewer, "团队跟进记录")
+}
+
+// managerCustomerExportHandler GET {apiP}/manager/customers/{customer_id}/export 导出主管范围内某客户的跟进记录
+func (s *Server) managerCustomerExportHandler(w http.ResponseWriter, r *http.Request, customerID uuid.UUID) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, scope, ok := s.requirePermission(w, r, models.PermExport)
+	if !ok {
+		return
+	}
+	f, err := parseExportFilter(r.URL.Query())
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	f.UserIDs = scope
+	f.CustomerID = &customerID
+	viewer, err := s.newRecordViewer(r.Context(), userID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Resolve viewer failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+		return
+	}
+	s.streamFollowRecordsExport(w, r, f, viewer, "客户跟进记录")
+}
+
+// streamFollowRecordsExport 校验格式与列后边查边写；开始输出后出错只能中断响应并记录日志
+func (s *Server) streamFollowRecordsExport(w http.ResponseWriter, r *http.Request, f repository.ExportFilter, viewer exportViewer, title string) {
+	q := r.URL.Query()
+	format, err := exportFormat(q)
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	cols, err := parseExportColumns(q.Get("columns"))
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	fileName := fmt.Sprintf("%s_%s.%s", title, time.Now().Format("20060102"), format)
+	w.Header().Set("Content-Type", spreadsheet.ContentType(format))
+	w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(fileName))
+	sw, err := spreadsheet.NewWriter(w, format, title)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Create export writer failed", "error", err)
+		return
+	}
+
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.Title
+	}
+	if err := sw.WriteRow(header); err != nil {
+		s.logger.WithContext(r.Context()).Error("Write export header failed", "error", err)
+		return
+	}
+
+	count := 0
+	err = repository.New(s.db).IterateFollowRecordsForExport(r.Context(), f, func(rec *repository.FollowRecordForExport) error {
+		m := followRecordToPageMap(&rec.FollowRecord, rec.CustomerIDStr)
+		m["user_name"] = rec.UserName
+		s.setPagePhone(m, viewer, &rec.FollowRecord)
+
+		row := make([]string, len(cols))
+		for i, c := range cols {
+			row[i] = exportCellValue(c.Key, m[c.Key])
+		}
+		count++
+		return sw.WriteRow(row)
+	})
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Export follow records failed", "error", err, "user_id", viewer.UserID, "rows", count)
+		return
+	}
+	if err := sw.Close(); err != nil {
+		s.logger.WithContext(r.Context()).Error("Close export writer failed", "error", err)
+		return
+	}
+	s.logger.WithContext(r.Context()).Info("Follow records exported", "user_id", viewer.UserID, "format", format, "rows", count)
+}
+
+// exportCellValue 将 page map 中的值转为单元格文本：时间转为本地时间，布尔转为 是/否
+func exportCellValue(key string, v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case bool:
+		if val {
+			return "是"
+		}
+		return "否"
+	case string:
+		if key == "follow_time" || key == "created_at" {
+			if t, err := time.Parse(time.RFC3339, val); err == nil {
+				return t.In(time.Local).Format("2006-01-02 15:04")
+			}
+		}
+		return val
+	default:
+		return fmt.Sprint(val)
+	}
+}
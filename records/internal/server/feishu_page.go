@@ -4,9 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 
-	"records/internal/auth"
 	"records/internal/feishu"
 	"records/internal/repository"
 )
@@ -47,13 +45,13 @@ func (s *Server) feishuAuthHandler(w http.ResponseWriter, r *http.Request) {
 		redirectURI = s.config.Feishu.SaleLogs.RedirectURI
 	}
 	if redirectURI == "" {
-		s.logger.Error("redirect_uri not configured for sale_logs")
+		s.logger.WithContext(r.Context()).Error("redirect_uri not configured for sale_logs")
 		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "OAuth 配置错误：请检查 config.yml 中 feishu.sale_logs.redirect_uri"})
 		return
 	}
 	accessToken, _, _, userInfo, err := feishu.ExchangeCodeForUserToken(r.Context(), s.config.Feishu.SaleLogs, req.Code, redirectURI)
 	if err != nil {
-		s.logger.Error("Feishu exchange code failed", "error", err)
+		s.logger.WithContext(r.Context()).Error("Feishu exchange code failed", "error", err)
 		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: err.Error()})
 		return
 	}
@@ -61,7 +59,7 @@ func (s *Server) feishuAuthHandler(w http.ResponseWriter, r *http.Request) {
 	if userInfo == nil {
 		userInfo, err = feishu.GetOAuthUserInfo(r.Context(), accessToken)
 		if err != nil {
-			s.logger.Error("Feishu get user info failed", "error", err)
+			s.logger.WithContext(r.Context()).Error("Feishu get user info failed", "error", err)
 			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: err.Error()})
 			return
 		}
@@ -70,11 +68,11 @@ func (s *Server) feishuAuthHandler(w http.ResponseWriter, r *http.Request) {
 	// 使用 union_id 作为 user_id，与机器人（sale_agent）解析后的 ID 一致，实现跨应用统一
 	userID := userInfo.UnionID
 	if userID == "" {
-		s.logger.Error("Feishu OAuth returned no union_id")
+		s.logger.WithContext(r.Context()).Error("Feishu OAuth returned no union_id")
 		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "认证失败：无法获取 union_id，请检查飞书应用权限配置"})
 		return
 	}
-	s.logger.Info("Feishu OAuth user info", "union_id", userID)
+	s.logger.WithContext(r.Context()).Info("Feishu OAuth user info", "union_id", userID)
 
 	avatarURL := (*string)(nil)
 	if userInfo.AvatarURL != "" {
@@ -83,24 +81,32 @@ func (s *Server) feishuAuthHandler(w http.ResponseWriter, r *http.Request) {
 
 	repo := repository.New(s.db)
 	if err := repo.EnsureUserFromOAuth(r.Context(), userID, userInfo.Name, avatarURL); err != nil {
-		s.logger.Error("Ensure user from OAuth failed", "error", err, "user_id", userID)
+		s.logger.WithContext(r.Context()).Error("Ensure user from OAuth failed", "error", err, "user_id", userID)
 		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "认证失败"})
 		return
 	}
+	// 离职用户（通讯录同步标记 status=1）不再签发会话
+	if user, err := repo.GetUser(r.Context(), userID); err == nil && user != nil && user.Status != 0 {
+		s.logger.WithContext(r.Context()).Warn("Resigned user login rejected", "user_id", userID)
+		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "账号已停用"})
+		return
+	}
 
 	data := map[string]interface{}{
 		"userId": userID,
 		"name":   userInfo.Name,
 		"avatar": userInfo.AvatarURL,
 	}
-	if secret := s.config.Server.JWTSecret; secret != "" {
-		token, err := auth.Issue(secret, userID)
+	if s.config.Server.JWTSecret != "" {
+		tokens, err := s.sessions.Login(r.Context(), userID, s.clientIP(r), truncateRunes(r.UserAgent(), 512))
 		if err != nil {
-			s.logger.Error("JWT issue failed", "error", err)
+			s.logger.WithContext(r.Context()).Error("Create auth session failed", "error", err, "user_id", userID)
 			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "认证失败"})
 			return
 		}
-		data["token"] = token
+		for k, v := range tokensData(tokens) {
+			data[k] = v
+		}
 	}
 
 	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: data})
@@ -112,29 +118,8 @@ func (s *Server) userInfoHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := ""
-	if secret := s.config.Server.JWTSecret; secret != "" {
-		authHeader := r.Header.Get("Authorization")
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			uid, err := auth.Validate(secret, tokenString)
-			if err == nil {
-				userID = uid
-			}
-		}
-		if userID == "" && s.config.Server.AllowXUserIDFallback {
-			uid := r.Header.Get("x-user-id")
-			if !isInvalidUserID(uid) {
-				userID = uid
-			}
-		}
-	} else {
-		uid := r.Header.Get("x-user-id")
-		if !isInvalidUserID(uid) {
-			userID = uid
-		}
-	}
-	if userID == "" || isInvalidUserID(userID) {
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
 		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录"})
 		return
 	}
@@ -150,13 +135,28 @@ func (s *Server) userInfoHandler(w http.ResponseWriter, r *http.Request) {
 	if user.AvatarURL != nil {
 		avatarURL = *user.AvatarURL
 	}
+	perms, err := repo.UserPermissions(r.Context(), userID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List user permissions failed", "error", err, "user_id", userID)
+		perms = []string{}
+	}
+	unread := 0
+	if list, err := s.comments.Repo().ListUnread(r.Context(), userID); err != nil {
+		s.logger.WithContext(r.Context()).Error("List unread comments failed", "error", err, "user_id", userID)
+	} else {
+		for _, u := range list {
+			unread += u.Unread
+		}
+	}
 
 	s.writePageJSON(w, http.StatusOK, pageAPIResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"user_id":    user.ID,
-			"name":       user.Name,
-			"avatar_url": avatarURL,
+			"user_id":         user.ID,
+			"name":            user.Name,
+			"avatar_url":      avatarURL,
+			"permissions":     perms,
+			"unread_comments": unread,
 		},
 	})
 }
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"records/internal/feishu"
+	"records/internal/models"
+	"records/internal/orchestrator"
+	"records/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// 跟进待办提醒：到期待办以消息卡片推送给销售，卡片按钮支持 已完成 / 稍后提醒 / 记录跟进
+
+const (
+	defaultFollowTaskSnooze     = 24 * time.Hour
+	defaultFollowTaskMaxOverdue = 72 * time.Hour
+
+	// 卡片按钮 value 中的 kind，用于区分卡片回调来源
+	cardKindFollowTask = "follow_task"
+
+	followTaskOpDone   = "done"   // 标记完成并开启预填的记录会话
+	followTaskOpSnooze = "snooze" // 延后提醒
+	followTaskOpLog    = "log"    // 开启预填的记录会话，待办在新记录落库后被新计划取代
+)
+
+// runFollowTaskReminders 推送到期的跟进待办，由定时任务调度
+func (s *Server) runFollowTaskReminders(ctx context.Context) error {
+	maxOverdue := s.config.FollowTask.MaxOverdue
+	if maxOverdue <= 0 {
+		maxOverdue = defaultFollowTaskMaxOverdue
+	}
+	repo := repository.New(s.db)
+	now := time.Now()
+	// 超过 max_overdue 仍未提醒的待办标记为已过期，不再停留在 pending
+	if n, err := repo.ExpireOverdueFollowTasks(ctx, now.Add(-maxOverdue)); err != nil {
+		return err
+	} else if n > 0 {
+		s.logger.WithContext(ctx).Info("Expired overdue follow tasks", "count", n)
+	}
+	tasks, err := repo.ListDueFollowTasks(ctx, now, now.Add(-maxOverdue), 200)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, task := range tasks {
+		if err := s.feishuClient.SendCardToUser(ctx, task.UserID, followTaskCard(task, "")); err != nil {
+			s.logger.WithContext(ctx).Error("Failed to send follow task reminder", "task_id", task.ID, "user_id", task.UserID, "error", err)
+			failed++
+			continue
+		}
+		if err := repo.MarkFollowTaskReminded(ctx, task.ID); err != nil {
+			s.logger.WithContext(ctx).Error("Failed to mark follow task reminded", "task_id", task.ID, "error", err)
+		}
+	}
+	if len(tasks) > 0 {
+		s.logger.WithContext(ctx).Info("Follow task reminders sent", "total", len(tasks), "failed", failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d follow task reminders failed", failed, len(tasks))
+	}
+	return nil
+}
+
+// HandleCardAction 实现 feishu.MessageHandler 接口；按按钮 value 中的 kind 分发
+func (s *Server) HandleCardAction(ctx context.Context, action *feishu.CardAction) (*feishu.CardActionResult, error) {
+	kind, _ := action.Value["kind"].(string)
+	switch kind {
+	case cardKindFollowTask:
+		return s.handleFollowTaskAction(ctx, action)
+	default:
+		s.logger.WithContext(ctx).Warn("Unknown card action", "kind", kind, "user_id", action.UserID)
+		return nil, nil
+	}
+}
+
+// handleFollowTaskAction 处理跟进待办卡片按钮。卡片回调需在 3 秒内响应，开启记录会话（需调用大模型）异步执行
+func (s *Server) handleFollowTaskAction(ctx context.Context, action *feishu.CardAction) (*feishu.CardActionResult, error) {
+	op, _ := action.Value["op"].(string)
+	taskIDStr, _ := action.Value["task_id"].(string)
+	taskID, err := uuid.Parse(taskIDStr)
+	if err != nil {
+		return &feishu.CardActionResult{Toast: "无效的待办", ToastType: "error"}, nil
+	}
+
+	repo := repository.New(s.db)
+	task, err := repo.GetFollowTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil || task.UserID != action.UserID {
+		return &feishu.CardActionResult{Toast: "待办不存在或无权操作", ToastType: "error"}, nil
+	}
+	if task.Status != models.FollowTaskPending && task.Status != models.FollowTaskReminded {
+		return &feishu.CardActionResult{Toast: "该待办已处理", Card: followTaskCard(task, followTaskStatusNote(task.Status))}, nil
+	}
+
+	switch op {
+	case followTaskOpDone:
+		// 条件更新保证只有一次点击生效：重复点击或并发回调不再开启第二个记录会话
+		completed, err := repo.CompleteFollowTask(ctx, taskID, action.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !completed {
+			return s.followTaskHandledResult(ctx, task)
+		}
+		s.startFollowTaskSession(task, action.ChatID)
+		return &feishu.CardActionResult{
+			Toast:     "已完成，我们来记录一下这次跟进",
+			ToastType: "success",
+			Card:      followTaskCard(task, "✅ 已完成，请在对话中补充本次跟进"),
+		}, nil
+
+	case followTaskOpSnooze:
+		snooze := s.config.FollowTask.Snooze
+		if snooze <= 0 {
+			snooze = defaultFollowTaskSnooze
+		}
+		dueAt := time.Now().Add(snooze)
+		snoozed, err := repo.SnoozeFollowTask(ctx, taskID, action.UserID, dueAt)
+		if err != nil {
+			return nil, err
+		}
+		if !snoozed {
+			return s.followTaskHandledResult(ctx, task)
+		}
+		return &feishu.CardActionResult{
+			Toast: "好的，到时再提醒你",
+			Card:  followTaskCard(task, "⏰ 已延后至 "+dueAt.Format("01-02 15:04")),
+		}, nil
+
+	case followTaskOpLog:
+		s.startFollowTaskSession(task, action.ChatID)
+		return &feishu.CardActionResult{Toast: "好的，我们来记录这次跟进"}, nil
+
+	default:
+		return &feishu.CardActionResult{Toast: "不支持的操作", ToastType: "error"}, nil
+	}
+}
+
+// followTaskHandledResult 待办已被其他回调处理时，按最新状态刷新卡片
+func (s *Server) followTaskHandledResult(ctx context.Context, task *repository.FollowTaskWithCustomer) (*feishu.CardActionResult, error) {
+	current, err := repository.New(s.db).GetFollowTask(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return &feishu.CardActionResult{Toast: "待办不存在或无权操作", ToastType: "error"}, nil
+	}
+	return &feishu.CardActionResult{Toast: "该待办已处理", Card: followTaskCard(current, followTaskStatusNote(current.Status))}, nil
+}
+
+// startFollowTaskSession 异步为待办客户开启预填的记录会话，并把机器人首句发到卡片所在会话
+func (s *Server) startFollowTaskSession(task *repository.FollowTaskWithCustomer, chatID string) {
+	if chatID == "" {
+		s.logger.Warn("Card action without chat id, skip starting session", "task_id", task.ID)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		lockCtx, unlock, err := s.lockUser(ctx, task.UserID)
+		if err != nil {
+			s.logger.Error("Failed to acquire user lock", "error", err, "user_id", task.UserID)
+			_ = s.feishuClient.SendMessage(ctx, chatID, s.config.Messages.SystemError)
+			return
+		}
+		defer unlock()
+
+		// 预填：沿用上次跟进的目标（业务目标通常延续），客户由会话聚焦
+		prefill := map[string]interface{}{}
+		if task.FollowRecordID != nil {
+			record, err := repository.New(s.db).GetFollowRecordByID(lockCtx, *task.FollowRecordID)
+			if err != nil {
+				s.logger.Error("Failed to load follow record for task", "task_id", task.ID, "error", err)
+			} else if record != nil && record.FollowGoal != nil && *record.FollowGoal != "" {
+				prefill["follow_goal"] = *record.FollowGoal
+			}
+		}
+		userInput := fmt.Sprintf("我跟进了%s，完成了计划：%s，帮我记录一下这次跟进", task.CustomerName, task.Action)
+
+		reply, err := s.orchestrator.StartPrefilledSession(lockCtx, task.UserID, task.CustomerID, prefill, userInput)
+		switch {
+		case errors.Is(err, orchestrator.ErrSessionInProgress):
+			reply = s.config.Messages.FollowTaskSessionBusy
+			if reply == "" {
+				reply = "你还有一条正在整理的跟进记录，先把它说完，再来记录这条待办吧~"
+			}
+		case err != nil:
+			s.logger.Error("Failed to start prefilled session", "task_id", task.ID, "error", err)
+			reply = s.config.Messages.SystemError
+		}
+		if err := s.feishuClient.SendMessage(ctx, chatID, reply); err != nil {
+			s.logger.Error("Failed to send reply", "error", err, "chat_id", chatID)
+		}
+	}()
+}
+
+// followTaskCard 构建跟进待办提醒卡片；note 非空时以说明文字替换操作按钮（待办已处理）
+func followTaskCard(task *repository.FollowTaskWithCustomer, note string) map[string]interface{} {
+	content := fmt.Sprintf("**客户**：%s\n**计划**：%s\n**时间**：%s",
+		task.CustomerName, task.Action, task.DueAt.In(time.Local).Format("2006-01-02 15:04"))
+
+	button := func(text, op, typ string) map[string]interface{} {
+		return map[string]interface{}{
+			"tag":  "button",
+			"text": map[string]interface{}{"tag": "plain_text", "content": text},
+			"type": typ,
+			"value": map[string]interface{}{
+				"kind":    cardKindFollowTask,
+				"op":      op,
+				"task_id": task.ID.String(),
+			},
+		}
+	}
+
+	var footer map[string]interface{}
+	if note == "" {
+		footer = map[string]interface{}{
+			"tag": "action",
+			"actions": []interface{}{
+				button("已完成", followTaskOpDone, "primary"),
+				button("稍后提醒", followTaskOpSnooze, "default"),
+				button("记录跟进", followTaskOpLog, "default"),
+			},
+		}
+	} else {
+		footer = map[string]interface{}{
+			"tag":      "note",
+			"elements": []interface{}{map[string]interface{}{"tag": "plain_text", "content": note}},
+		}
+	}
+
+	return map[string]interface{}{
+		"config": map[string]interface{}{"wide_screen_mode": true},
+		"header": map[string]interface{}{
+			"template": "blue",
+			"title":    map[string]interface{}{"tag": "plain_text", "content": "跟进提醒"},
+		},
+		"elements": []interface{}{
+			map[string]interface{}{
+				"tag":  "div",
+				"text": map[string]interface{}{"tag": "lark_md", "content": content},
+			},
+			footer,
+		},
+	}
+}
+
+// followTaskStatusNote 已结束待办在卡片上的说明
+func followTaskStatusNote(status string) string {
+	switch status {
+	case models.FollowTaskDone:
+		return "✅ 已完成"
+	case models.FollowTaskSuperseded:
+		return "该客户已有新的跟进记录，此待办已更新"
+	case models.FollowTaskExpired:
+		return "该待办已过期"
+	default:
+		return "该待办已处理"
+	}
+}
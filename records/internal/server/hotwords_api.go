@@ -18,7 +18,7 @@ func (s *Server) hotwordsStatsHandler(w http.ResponseWriter, r *http.Request) {
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	data, err := hotwords.BuildPageDataByDate(r.Context(), s.db, today)
 	if err != nil {
-		s.logger.Error("hotwords BuildPageDataByDate failed", "error", err)
+		s.logger.WithContext(r.Context()).Error("hotwords BuildPageDataByDate failed", "error", err)
 		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取热词统计失败"})
 		return
 	}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"records/internal/importer"
+	"records/internal/models"
+	"records/internal/repository"
+	"records/internal/spreadsheet"
+
+	"github.com/google/uuid"
+)
+
+// importsHandler 处理 {apiP}/imports：
+// GET 返回当前用户最近的导入批次；
+// POST multipart 上传 CSV/XLSX（字段 file），dry_run=true（默认）仅预览校验结果，dry_run=false 提交导入；
+// 可选 mapping（JSON，表头 -> 字段）、skip_invalid=true（跳过失败行）。主管可为范围内销售导入，其余用户仅能导入自己的记录
+func (s *Server) importsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != s.apiPrefix()+"/imports" {
+		http.NotFound(w, r)
+		return
+	}
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := repository.New(s.db).ListImportBatches(r.Context(), userID, 50)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("ListImportBatches failed", "error", err, "user_id", userID)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取导入记录失败"})
+			return
+		}
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: list})
+	case http.MethodPost:
+		s.importUploadHandler(w, r, userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) importUploadHandler(w http.ResponseWriter, r *http.Request, userID string) {
+	r.Body = http.MaxBytesReader(w, r.Body, spreadsheet.MaxReadSize+1<<20)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的上传文件"})
+		return
+	}
+	file, fh, err := r.FormFile("file")
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "缺少上传文件 file"})
+		return
+	}
+	defer file.Close()
+	if spreadsheet.FormatFromFileName(fh.Filename) == "" {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "仅支持 .csv 或 .xlsx 文件"})
+		return
+	}
+
+	opts := importer.Options{
+		FileName:    fh.Filename,
+		ImporterID:  userID,
+		SkipInvalid: r.FormValue("skip_invalid") == "true",
+	}
+	if m := r.FormValue("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &opts.Mapping); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "mapping 须为 JSON 对象（表头 -> 字段）"})
+			return
+		}
+		for h, f := range opts.Mapping {
+			if !importer.ValidField(f) {
+				s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "列 " + h + " 映射到了不支持的字段：" + f})
+				return
+			}
+		}
+	}
+
+	// 可归属的销售：edit 权限范围及本人（nil 表示全部）
+	if _, err := s.ensureUserExists(r.Context(), userID, false); err != nil {
+		s.logger.WithContext(r.Context()).Error("Ensure user exists failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "导入失败"})
+		return
+	}
+	scope, err := s.scopeWithSelf(r.Context(), userID, models.PermEdit)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Resolve edit scope failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+		return
+	}
+	opts.AllowedUserIDs = scope
+
+	im := s.newImporter()
+	dryRun := r.FormValue("dry_run") != "false"
+	if dryRun {
+		res, err := im.Preview(r.Context(), file, opts)
+		if err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "解析文件失败：" + err.Error()})
+			return
+		}
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: res})
+		return
+	}
+
+	res, err := im.Commit(r.Context(), file, opts)
+	switch {
+	case errors.Is(err, importer.ErrHasInvalidRows):
+		s.writePageJSON(w, http.StatusUnprocessableEntity, pageAPIResponse{Success: false, Data: res, Message: "存在校验失败的行，请修正后重试或选择跳过失败行"})
+	case err != nil && res != nil:
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Data: res, Message: "没有可导入的记录"})
+	case err != nil:
+		s.logger.WithContext(r.Context()).Error("Import follow records failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "导入失败：" + err.Error()})
+	default:
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: res})
+	}
+}
+
+// importsSubHandler 处理 POST {apiP}/imports/{batch_id}/rollback：回滚本人提交的导入批次
+func (s *Server) importsSubHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/imports/"), "/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[1] != "rollback" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	batchID, err := uuid.Parse(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	batch, err := repository.New(s.db).GetImportBatch(r.Context(), batchID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("GetImportBatch failed", "error", err, "batch_id", batchID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "回滚失败"})
+		return
+	}
+	if batch == nil || batch.UserID != userID {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "导入批次不存在"})
+		return
+	}
+
+	ctx := repository.WithAudit(r.Context(), userID, models.RecordSourceImport)
+	records, customers, err := importer.New(s.db, s.logger).Rollback(ctx, batchID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Rollback import batch failed", "error", err, "batch_id", batchID)
+		s.writePageJSON(w, http.StatusConflict, pageAPIResponse{Success: false, Message: "回滚失败：" + err.Error()})
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"batch_id":          batchID.String(),
+		"deleted_records":   records,
+		"deleted_customers": customers,
+	}})
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+
+	"records/internal/hotwords"
+	"records/internal/scheduler"
+)
+
+// registerJobs 注册所有定时任务；cron 可在 config.yml 的 scheduler.jobs 中覆盖
+func (s *Server) registerJobs() {
+	jobs := []struct {
+		name, description, cron string
+		fn                      scheduler.JobFunc
+	}{
+		{"hotwords", "热词流水线：抽取跟进日志关键词并生成当日热词统计", "5 0 * * *", s.runHotwordsJob},
+		{"processed_events_cleanup", "清理过期的飞书事件去重记录", "*/10 * * * *", s.runProcessedEventsCleanup},
+		{"follow_task_reminders", "推送到期的跟进待办提醒", "*/5 * * * *", s.runFollowTaskReminders},
+		{"digest", "推送订阅的跟进日报/周报摘要", "0 18 * * *", s.runDigestJob},
+		{"stale_customers", "提醒销售跟进久未联系的客户", "0 10 * * *", s.runStaleCustomersJob},
+		{"directory_sync", "同步飞书通讯录部门、负责人与成员归属", "30 2 * * *", s.runDirectorySyncJob},
+		{"auth_sessions_cleanup", "撤销离职用户的登录会话并清理过期会话", "15 * * * *", s.runAuthSessionsCleanup},
+		{"api_key_usage_cleanup", "清理 7 天前的 API Key 调用计数", "45 * * * *", s.runAPIKeyUsageCleanup},
+		{"webhook_cleanup", "清理超过保留时长的 Webhook 事件与投递记录", "50 3 * * *", s.runWebhookCleanup},
+		{"bitable_sync", "增量同步客户与跟进记录到飞书多维表格", "*/10 * * * *", s.runBitableSyncJob},
+		{"crm_sync", "与公司 CRM 双向同步客户账户，并推送跟进记录为 CRM 活动", "*/15 * * * *", s.runCRMSyncJob},
+		{"phone_encrypt", "加密存量明文联系电话，并将旧主密钥加密的号码改用当前主密钥", "20 4 * * *", s.runPhoneEncryptJob},
+	}
+	for _, j := range jobs {
+		if err := s.scheduler.Register(j.name, j.description, j.cron, j.fn); err != nil {
+			s.logger.Error("Failed to register job", "job", j.name, "error", err)
+		}
+	}
+}
+
+// runHotwordsJob 执行热词流水线；当日已生成统计则跳过
+func (s *Server) runHotwordsJob(ctx context.Context) error {
+	extractor := hotwords.NewExtractor(hotwords.ExtractorConfig{
+		APIKey:              s.config.AI.OpenAI.APIKey,
+		BaseURL:             s.config.AI.OpenAI.BaseURL,
+		ModelName:           s.config.AI.OpenAI.ModelName,
+		Temperature:         0.3,
+		MaxCompletionTokens: s.config.AI.OpenAI.MaxCompletionTokens,
+		SystemPrompt:        s.config.Prompts.HotwordsExtractor,
+		Redactor:            s.redactor,
+	}, s.logger)
+	pipe := hotwords.NewPipeline(s.db, extractor, hotwords.PipelineConfig{
+		BatchSize:        20,
+		LimitPerCategory: 50,
+		IncrementalSince: nil,
+	}, s.logger)
+
+	ran, err := hotwords.RunIfNotGeneratedToday(ctx, s.db, pipe.Run)
+	if err != nil {
+		return err
+	}
+	if !ran {
+		s.logger.WithContext(ctx).Info("hotwords pipeline: 当日已有热词统计，跳过")
+		return scheduler.ErrSkipped
+	}
+	s.logger.WithContext(ctx).Info("hotwords pipeline: 已生成当日热词统计")
+	return nil
+}
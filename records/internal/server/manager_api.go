@@ -8,7 +8,7 @@ import (
 	"records/internal/repository"
 )
 
-// managerUsersHandler 处理 GET {apiP}/manager/users
+// managerUsersHandler 处理 GET {apiP}/manager/users；带 limit/cursor 时分页返回
 func (s *Server) managerUsersHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -19,26 +19,18 @@ func (s *Server) managerUsersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := s.pageUserIDFromRequest(r)
+	userID, _, ok := s.requireManager(w, r)
 	if !ok {
-		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
 		return
 	}
-	repo := repository.New(s.db)
-	isManager, err := repo.IsManager(r.Context(), userID)
-	if err != nil {
-		s.logger.Error("IsManager failed", "error", err, "user_id", userID)
-		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
-		return
-	}
-	if !isManager {
-		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限查看"})
+
+	if wantsPage(r.URL.Query()) {
+		s.managerUsersListHandler(w, r, userID)
 		return
 	}
-
-	list, err := repo.ListUsersForManager(r.Context(), userID)
+	list, err := repository.New(s.db).ListUsersForManager(r.Context(), userID)
 	if err != nil {
-		s.logger.Error("ListUsersForManager failed", "error", err)
+		s.logger.WithContext(r.Context()).Error("ListUsersForManager failed", "error", err)
 		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取用户列表失败"})
 		return
 	}
@@ -71,39 +63,12 @@ func (s *Server) managerUsersSubHandler(w http.ResponseWriter, r *http.Request)
 	targetUserID := parts[2]
 	action := parts[3]
 
-	userID, ok := s.pageUserIDFromRequest(r)
+	managerID, scopeIDs, ok := s.requireManager(w, r)
 	if !ok {
-		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
 		return
 	}
 	repo := repository.New(s.db)
-	isManager, err := repo.IsManager(r.Context(), userID)
-	if err != nil {
-		s.logger.Error("IsManager failed", "error", err, "user_id", userID)
-		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
-		return
-	}
-	if !isManager {
-		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限查看"})
-		return
-	}
-
-	scopeIDs, err := repo.GetManagerScopeUserIDs(r.Context(), userID)
-	if err != nil {
-		s.logger.Error("GetManagerScopeUserIDs failed", "error", err)
-		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取范围失败"})
-		return
-	}
-	allowed := scopeIDs == nil // nil 表示全部
-	if !allowed {
-		for _, id := range scopeIDs {
-			if id == targetUserID {
-				allowed = true
-				break
-			}
-		}
-	}
-	if !allowed {
+	if scopeIDs != nil && !containsString(scopeIDs, targetUserID) { // nil 表示全部
 		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限查看该用户"})
 		return
 	}
@@ -112,7 +77,7 @@ func (s *Server) managerUsersSubHandler(w http.ResponseWriter, r *http.Request)
 	case "groups":
 		list, err := repo.ListCustomerFollowGroupsForManager(r.Context(), targetUserID)
 		if err != nil {
-			s.logger.Error("ListCustomerFollowGroupsForManager failed", "error", err)
+			s.logger.WithContext(r.Context()).Error("ListCustomerFollowGroupsForManager failed", "error", err)
 			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取分组失败"})
 			return
 		}
@@ -135,14 +100,22 @@ func (s *Server) managerUsersSubHandler(w http.ResponseWriter, r *http.Request)
 		}
 		list, err := repo.ListFollowRecordsForManager(r.Context(), targetUserID, customerName, followContent)
 		if err != nil {
-			s.logger.Error("ListFollowRecordsForManager failed", "error", err)
+			s.logger.WithContext(r.Context()).Error("ListFollowRecordsForManager failed", "error", err)
 			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取记录失败"})
 			return
 		}
+		viewer, err := s.newRecordViewer(r.Context(), managerID)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Resolve viewer failed", "error", err, "user_id", managerID)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+			return
+		}
 		data := make([]map[string]interface{}, len(list))
 		for i, rec := range list {
 			data[i] = followRecordToPageMap(&rec.FollowRecord, rec.CustomerIDStr)
+			s.setPagePhone(data[i], viewer, &rec.FollowRecord)
 		}
+		s.withUnreadComments(r.Context(), managerID, data)
 		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: data})
 		return
 	default:
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"records/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registerMetrics 注册抓取时取值的指标：输出队列长度与数据库连接池状态
+func (s *Server) registerMetrics() {
+	metrics.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "records_output_queue_depth",
+		Help: "Output tasks waiting in the worker queue.",
+	}, func() float64 { return float64(s.outputWorker.QueueLen()) }))
+	metrics.Register(collectors.NewDBStatsCollector(s.db.DB, "records"))
+}
+
+// metricsHandler Prometheus 抓取端点，输出默认注册表（含 Go 运行时与进程指标）；配置了 server.metrics_token 时校验 Bearer 令牌
+func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if token := s.config.Server.MetricsToken; token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	promhttp.Handler().ServeHTTP(w, r)
+}
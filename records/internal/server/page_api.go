@@ -1,12 +1,14 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
-	"records/internal/auth"
 	"records/internal/models"
 	"records/internal/repository"
 
@@ -48,28 +50,20 @@ type updateRecordRequest struct {
 	NextPlan      string  `json:"next_plan"`
 }
 
-// pageUserIDFromRequest 从请求获取已认证用户 ID。优先验证 JWT；无 JWT 或失效时，若允许则回退到 x-user-id。
-// 返回 (userID, true) 表示已认证；(_, false) 表示需返回 401。
+// pageUserIDFromRequest 从请求获取已认证用户 ID。优先验证访问令牌（含会话撤销与离职校验）；
+// 无令牌或令牌失效时，仅 dev 构建或回环监听允许回退到 x-user-id。
 func (s *Server) pageUserIDFromRequest(r *http.Request) (string, bool) {
-	secret := s.config.Server.JWTSecret
-	if secret != "" {
-		authHeader := r.Header.Get("Authorization")
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			uid, err := auth.Validate(secret, tokenString)
+	if s.config.Server.JWTSecret != "" {
+		if token := bearerToken(r); token != "" {
+			uid, err := s.sessions.Authenticate(r.Context(), token)
 			if err == nil && !isInvalidUserID(uid) {
 				return uid, true
 			}
 		}
-		if s.config.Server.AllowXUserIDFallback {
-			uid := r.Header.Get("x-user-id")
-			if uid != "" && !isInvalidUserID(uid) {
-				return uid, true
-			}
-		}
+	}
+	if !s.allowXUserID(r) {
 		return "", false
 	}
-	// 未配置 JWT 时回退到 x-user-id（不推荐，存在冒充风险）
 	uid := r.Header.Get("x-user-id")
 	if uid == "" || isInvalidUserID(uid) {
 		return "", false
@@ -98,10 +92,32 @@ func (s *Server) pageAPIRootHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// pageAPISubHandler 处理 /api/records/:id（PUT 更新、DELETE 删除）
+// pageAPISubHandler 处理 /api/records/:id（PUT 更新、DELETE 删除）、GET /api/records/export（导出）、
+// GET /api/records/deleted（已删除记录）、GET /api/records/:id/history（版本历史）、POST /api/records/:id/restore（恢复）
+// 与 GET/POST /api/records/:id/comments（评论）
 func (s *Server) pageAPISubHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/records/"), "/")
+	switch {
+	case path == "export", path == "deleted":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if path == "export" {
+			s.pageRecordsExportHandler(w, r)
+		} else {
+			s.pageDeletedRecordsHandler(w, r)
+		}
+		return
+	case strings.HasSuffix(path, "/history"), strings.HasSuffix(path, "/restore"):
+		s.pageRecordVersionsHandler(w, r, path)
+		return
+	case strings.HasSuffix(path, "/comments"):
+		s.pageRecordCommentsHandler(w, r, path)
+		return
+	}
 	switch r.Method {
-	case http.MethodPut, http.MethodDelete:
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
 		s.pageRecordsByIDHandler(w, r)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -120,31 +136,38 @@ func (s *Server) pageRecordsHandler(w http.ResponseWriter, r *http.Request) {
 		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
 		return
 	}
-	s.logger.Info("pageRecordsHandler", "user_id", userID, "auth_header", r.Header.Get("Authorization") != "", "x_user_id", r.Header.Get("x-user-id"))
+	s.logger.WithContext(r.Context()).Info("pageRecordsHandler", "user_id", userID, "auth_header", r.Header.Get("Authorization") != "", "x_user_id", r.Header.Get("x-user-id"))
 	repo := repository.New(s.db)
 	// 打开跟进记录页时，若 users 表中无该用户，则根据飞书用户信息创建；userID 为 union_id
 	if _, err := s.ensureUserExists(r.Context(), userID, false); err != nil {
-		s.logger.Error("Ensure user exists from Feishu failed", "error", err, "user_id", userID)
+		s.logger.WithContext(r.Context()).Error("Ensure user exists from Feishu failed", "error", err, "user_id", userID)
 		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取用户信息失败"})
 		return
 	}
+	if wantsPage(r.URL.Query()) {
+		s.pageRecordsListHandler(w, r, userID)
+		return
+	}
 	records, err := repo.ListFollowRecordsForPage(r.Context(), userID)
 	if err != nil {
-		s.logger.Error("List follow records for page failed", "error", err)
+		s.logger.WithContext(r.Context()).Error("List follow records for page failed", "error", err)
 		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询记录失败"})
 		return
 	}
-	s.logger.Info("pageRecordsHandler: query result", "user_id", userID, "record_count", len(records))
+	s.logger.WithContext(r.Context()).Info("pageRecordsHandler: query result", "user_id", userID, "record_count", len(records))
 	if len(records) == 0 {
 		if ids, err := repo.GetDistinctUserIDsInFollowRecords(r.Context()); err == nil {
-			s.logger.Info("pageRecordsHandler: distinct user_ids in follow_records (for debug)", "user_ids", ids)
+			s.logger.WithContext(r.Context()).Info("pageRecordsHandler: distinct user_ids in follow_records (for debug)", "user_ids", ids)
 		}
 	}
 
+	viewer := exportViewer{UserID: userID}
 	data := make([]map[string]interface{}, len(records))
 	for i, rec := range records {
 		data[i] = followRecordToPageMap(&rec.FollowRecord, rec.CustomerIDStr)
+		s.setPagePhone(data[i], viewer, &rec.FollowRecord)
 	}
+	s.withUnreadComments(r.Context(), userID, data)
 	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: data})
 }
 
@@ -167,16 +190,16 @@ func (s *Server) pageRecordsCreateHandler(w http.ResponseWriter, r *http.Request
 
 	repo := repository.New(s.db)
 	if _, err := s.ensureUserExists(r.Context(), userID, false); err != nil {
-		s.logger.Error("Ensure user exists failed", "error", err, "user_id", userID)
+		s.logger.WithContext(r.Context()).Error("Ensure user exists failed", "error", err, "user_id", userID)
 		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "创建记录失败"})
 		return
 	}
-	record, err := repo.CreateFollowRecordForPage(r.Context(),
+	record, err := repo.CreateFollowRecordForPage(repository.WithAudit(r.Context(), userID, models.RecordSourcePage),
 		userID, req.CustomerID, req.CustomerName, req.FollowContent, followTime,
 		req.FollowMethod, req.ContactPerson, req.ContactRole,
 		req.FollowGoal, req.FollowResult, req.RiskContent, req.NextPlan)
 	if err != nil {
-		s.logger.Error("Create follow record for page failed", "error", err)
+		s.logger.WithContext(r.Context()).Error("Create follow record for page failed", "error", err)
 		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "创建记录失败"})
 		return
 	}
@@ -201,6 +224,39 @@ func (s *Server) pageRecordsByIDHandler(w http.ResponseWriter, r *http.Request)
 	repo := repository.New(s.db)
 
 	switch r.Method {
+	case http.MethodGet:
+		userID, authOk := s.pageUserIDFromRequest(r)
+		if !authOk {
+			s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+			return
+		}
+		record, err := repo.GetFollowRecordByID(r.Context(), id)
+		if err != nil || record == nil {
+			s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
+			return
+		}
+		if allowed, err := s.canAccessUser(r.Context(), userID, models.PermView, record.UserID); err != nil || !allowed {
+			if err != nil {
+				s.logger.WithContext(r.Context()).Error("Check view permission failed", "error", err, "user_id", userID)
+			}
+			s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限查看此记录"})
+			return
+		}
+		setRecordETag(w, record.Version)
+		if v, ok := parseETag(r.Header.Get("If-None-Match")); ok && v == record.Version {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		viewer, err := s.pageViewerForRecord(r.Context(), userID, record)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Resolve viewer failed", "error", err, "user_id", userID)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+			return
+		}
+		data := followRecordToPageMap(record, record.CustomerID.String())
+		s.setPagePhone(data, viewer, record)
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: data})
+
 	case http.MethodPut:
 		var req updateRecordRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -218,10 +274,28 @@ func (s *Server) pageRecordsByIDHandler(w http.ResponseWriter, r *http.Request)
 			s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
 			return
 		}
-		if record.UserID != userID {
+		if allowed, err := s.canAccessUser(r.Context(), userID, models.PermEdit, record.UserID); err != nil || !allowed {
+			if err != nil {
+				s.logger.WithContext(r.Context()).Error("Check edit permission failed", "error", err, "user_id", userID)
+			}
 			s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限操作此记录"})
 			return
 		}
+		// 乐观并发：If-Match 须为读取时的 ETag；不接受 *，缺少具体版本时要求客户端先读取
+		ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+		if ifMatch == "" || ifMatch == "*" {
+			s.writePageJSON(w, http.StatusPreconditionRequired, pageAPIResponse{Success: false, Message: "缺少 If-Match，请刷新后重试"})
+			return
+		}
+		v, ok := parseETag(ifMatch)
+		if !ok {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的 If-Match"})
+			return
+		}
+		if v != record.Version {
+			s.writeRecordConflict(r.Context(), w, userID, record)
+			return
+		}
 
 		record.CustomerName = req.CustomerName
 		fc := req.FollowContent
@@ -234,12 +308,21 @@ func (s *Server) pageRecordsByIDHandler(w http.ResponseWriter, r *http.Request)
 		record.RiskContent = req.RiskContent
 		record.NextPlan = &req.NextPlan
 
-		if err := repo.UpdateFollowRecord(r.Context(), record); err != nil {
-			s.logger.Error("Update follow record failed", "error", err, "id", id)
+		if err := repo.UpdateFollowRecord(repository.WithAudit(r.Context(), userID, models.RecordSourcePage), record); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				if current, _ := repo.GetFollowRecordByID(r.Context(), id); current != nil {
+					s.writeRecordConflict(r.Context(), w, userID, current)
+					return
+				}
+				s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
+				return
+			}
+			s.logger.WithContext(r.Context()).Error("Update follow record failed", "error", err, "id", id)
 			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "更新记录失败"})
 			return
 		}
 
+		setRecordETag(w, record.Version)
 		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: followRecordToPageMap(record, record.CustomerID.String())})
 
 	case http.MethodDelete:
@@ -249,13 +332,20 @@ func (s *Server) pageRecordsByIDHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		record, _ := repo.GetFollowRecordByID(r.Context(), id)
-		if record != nil && record.UserID != userID {
+		if record == nil {
+			s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
+			return
+		}
+		if allowed, err := s.canAccessUser(r.Context(), userID, models.PermEdit, record.UserID); err != nil || !allowed {
+			if err != nil {
+				s.logger.WithContext(r.Context()).Error("Check edit permission failed", "error", err, "user_id", userID)
+			}
 			s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限操作此记录"})
 			return
 		}
-		ok, err := repo.DeleteFollowRecord(r.Context(), id, userID)
+		ok, err := repo.DeleteFollowRecord(repository.WithAudit(r.Context(), userID, models.RecordSourcePage), id, record.UserID)
 		if err != nil {
-			s.logger.Error("Delete follow record failed", "error", err, "id", id)
+			s.logger.WithContext(r.Context()).Error("Delete follow record failed", "error", err, "id", id)
 			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "删除记录失败"})
 			return
 		}
@@ -270,6 +360,47 @@ func (s *Server) pageRecordsByIDHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// setRecordETag 以记录版本号作为 ETag
+func setRecordETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// parseETag 解析 If-Match / If-None-Match 中的版本号（兼容弱校验前缀 W/）
+func parseETag(v string) (int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+	n, err := strconv.Atoi(strings.Trim(v, `"`))
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// pageViewerForRecord 解析查看者的电话可见范围：本人记录无需查询权限
+func (s *Server) pageViewerForRecord(ctx context.Context, userID string, record *models.FollowRecord) (exportViewer, error) {
+	if record.UserID == userID {
+		return exportViewer{UserID: userID}, nil
+	}
+	return s.newRecordViewer(ctx, userID)
+}
+
+// writeRecordConflict 返回 409 与服务端当前版本（联系电话按查看者权限解密或脱敏），前端据此提示并刷新
+func (s *Server) writeRecordConflict(ctx context.Context, w http.ResponseWriter, userID string, current *models.FollowRecord) {
+	data := followRecordToPageMap(current, current.CustomerID.String())
+	viewer, err := s.pageViewerForRecord(ctx, userID, current)
+	if err != nil {
+		// 权限解析失败时按本人视角脱敏，不影响冲突提示
+		s.logger.WithContext(ctx).Error("Resolve viewer failed", "error", err, "user_id", userID)
+		viewer = exportViewer{UserID: userID}
+	}
+	s.setPagePhone(data, viewer, current)
+	setRecordETag(w, current.Version)
+	s.writePageJSON(w, http.StatusConflict, pageAPIResponse{
+		Success: false,
+		Data:    data,
+		Message: "记录已被修改，请确认最新内容后重试",
+	})
+}
+
 // followRecordToPageMap 将 FollowRecord 转为前端期望的 map（snake_case，id 为 UUID 字符串）
 func followRecordToPageMap(r *models.FollowRecord, customerIDStr string) map[string]interface{} {
 	m := map[string]interface{}{
@@ -279,6 +410,7 @@ func followRecordToPageMap(r *models.FollowRecord, customerIDStr string) map[str
 		"follow_time":   r.FollowTime.Format(time.RFC3339),
 		"created_at":    r.CreatedAt.Format(time.RFC3339),
 		"ai":            r.AI,
+		"version":       r.Version,
 	}
 	if r.FollowContent != nil {
 		m["follow_content"] = *r.FollowContent
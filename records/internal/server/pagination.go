@@ -0,0 +1,149 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"records/internal/repository"
+)
+
+// wantsPage 请求带 limit 或 cursor 时按分页返回；否则保持原有的全量数组响应，兼容旧前端
+func wantsPage(q url.Values) bool {
+	return q.Has("limit") || q.Has("cursor")
+}
+
+// parseSort 解析 sort 参数：字段名，前缀 - 表示倒序；为空时使用默认值
+func parseSort(param, def string) (field string, desc bool) {
+	if param == "" {
+		param = def
+	}
+	if strings.HasPrefix(param, "-") {
+		return param[1:], true
+	}
+	return param, false
+}
+
+// parseRecordListOptions 解析跟进记录列表参数：limit、cursor、sort（follow_time/created_at，默认 -follow_time）、
+// customer_id、customer_name、follow_method、from/to（YYYY-MM-DD，含）、ai（true/false）
+func parseRecordListOptions(q url.Values) (repository.RecordListOptions, error) {
+	var opts repository.RecordListOptions
+	f, err := parseExportFilter(q)
+	if err != nil {
+		return opts, err
+	}
+	opts.CustomerID, opts.CustomerName, opts.From, opts.To = f.CustomerID, f.CustomerName, f.From, f.To
+	opts.FollowMethod = strings.TrimSpace(q.Get("follow_method"))
+	opts.Sort, opts.Desc = parseSort(q.Get("sort"), "-"+repository.SortFollowTime)
+	if opts.Sort != repository.SortFollowTime && opts.Sort != repository.SortCreatedAt {
+		return opts, fmt.Errorf("sort 仅支持 follow_time 或 created_at（前缀 - 表示倒序）")
+	}
+	if v := q.Get("ai"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("ai 应为 true 或 false")
+		}
+		opts.AI = &b
+	}
+	if opts.Limit, err = parseLimit(q); err != nil {
+		return opts, err
+	}
+	opts.Cursor = q.Get("cursor")
+	return opts, nil
+}
+
+// parseUserListOptions 解析主管用户列表参数：limit、cursor、sort（last_record_at，默认倒序）、name
+func parseUserListOptions(q url.Values) (repository.UserListOptions, error) {
+	var opts repository.UserListOptions
+	sort, desc := parseSort(q.Get("sort"), "-last_record_at")
+	if sort != "last_record_at" {
+		return opts, fmt.Errorf("sort 仅支持 last_record_at（前缀 - 表示倒序）")
+	}
+	opts.Desc = desc
+	opts.Name = strings.TrimSpace(q.Get("name"))
+	var err error
+	if opts.Limit, err = parseLimit(q); err != nil {
+		return opts, err
+	}
+	opts.Cursor = q.Get("cursor")
+	return opts, nil
+}
+
+func parseLimit(q url.Values) (int, error) {
+	v := q.Get("limit")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("limit 应为正整数")
+	}
+	return n, nil
+}
+
+// pageRecordsListHandler GET {apiP}/records?limit= 按游标分页返回当前用户的跟进记录
+func (s *Server) pageRecordsListHandler(w http.ResponseWriter, r *http.Request, userID string) {
+	opts, err := parseRecordListOptions(r.URL.Query())
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	page, err := repository.New(s.db).ListFollowRecordsPageForUser(r.Context(), userID, opts)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+			return
+		}
+		s.logger.WithContext(r.Context()).Error("List follow records page failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询记录失败"})
+		return
+	}
+	viewer := exportViewer{UserID: userID}
+	items := make([]map[string]interface{}, len(page.Items))
+	for i, rec := range page.Items {
+		items[i] = followRecordToPageMap(&rec.FollowRecord, rec.CustomerIDStr)
+		s.setPagePhone(items[i], viewer, &rec.FollowRecord)
+	}
+	s.withUnreadComments(r.Context(), userID, items)
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"items":       items,
+		"next_cursor": page.NextCursor,
+		"total":       page.Total,
+	}})
+}
+
+// managerUsersListHandler GET {apiP}/manager/users?limit= 按游标分页返回主管可查看的用户
+func (s *Server) managerUsersListHandler(w http.ResponseWriter, r *http.Request, managerID string) {
+	opts, err := parseUserListOptions(r.URL.Query())
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	page, err := repository.New(s.db).ListUsersPageForManager(r.Context(), managerID, opts)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+			return
+		}
+		s.logger.WithContext(r.Context()).Error("List users page for manager failed", "error", err, "user_id", managerID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取用户列表失败"})
+		return
+	}
+	items := make([]map[string]interface{}, len(page.Items))
+	for i, u := range page.Items {
+		var lastRecordAt interface{} = nil
+		if u.LastRecordAt != nil {
+			lastRecordAt = u.LastRecordAt.Format(time.RFC3339)
+		}
+		items[i] = map[string]interface{}{"user_id": u.UserID, "name": u.Name, "record_count": u.RecordCount, "last_record_at": lastRecordAt}
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"items":       items,
+		"next_cursor": page.NextCursor,
+		"total":       page.Total,
+	}})
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"records/internal/phonecrypt"
+	"records/internal/repository"
+	"records/internal/scheduler"
+)
+
+// phoneResealBatch 联系电话重新加密每批读取的行数
+const phoneResealBatch = 200
+
+// runPhoneEncryptJob 将明文或旧主密钥加密的联系电话（客户、跟进记录与版本快照）改用当前主密钥加密；未启用加密时跳过。
+// 启用加密或轮换主密钥后可通过 POST {apiP}/admin/jobs/phone_encrypt/run 立即执行
+func (s *Server) runPhoneEncryptJob(ctx context.Context) error {
+	k := phonecrypt.Default()
+	if k == nil {
+		return scheduler.ErrSkipped
+	}
+	repo := repository.New(s.db)
+	resealed, failed := 0, 0
+	for _, table := range []string{repository.PhoneTableCustomers, repository.PhoneTableFollowRecords, repository.PhoneTableVersions} {
+		after := ""
+		for {
+			list, err := repo.ListPhonesToReseal(ctx, table, k.ActivePrefix(), after, phoneResealBatch)
+			if err != nil {
+				return err
+			}
+			for _, p := range list {
+				after = p.ID
+				stored, bidx, err := k.Reseal(p.Phone)
+				if err != nil {
+					failed++
+					s.logger.WithContext(ctx).Warn("Reseal contact phone failed", "table", table, "id", p.ID, "error", err)
+					continue
+				}
+				ok, err := repo.ResealPhone(ctx, table, p.ID, p.Phone, stored, bidx)
+				if err != nil {
+					return err
+				}
+				if ok {
+					resealed++
+				}
+			}
+			if len(list) < phoneResealBatch {
+				break
+			}
+		}
+	}
+	if resealed+failed > 0 {
+		s.logger.WithContext(ctx).Info("Contact phone encryption finished", "resealed", resealed, "failed", failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d contact phones could not be decrypted", failed)
+	}
+	if resealed == 0 {
+		return scheduler.ErrSkipped
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"records/internal/models"
+	"records/internal/repository"
+)
+
+// 角色权限：主管/管理员能力由 rbac_user_roles 授予，本人数据始终可访问
+
+// requirePermission 校验当前用户拥有某权限，返回用户 ID 与该权限的数据范围（nil 表示全部用户）；失败时已写响应
+func (s *Server) requirePermission(w http.ResponseWriter, r *http.Request, perm string) (string, []string, bool) {
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return "", nil, false
+	}
+	repo := repository.New(s.db)
+	granted, err := repo.HasPermission(r.Context(), userID, perm)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("HasPermission failed", "error", err, "user_id", userID, "perm", perm)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+		return "", nil, false
+	}
+	if !granted {
+		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限操作"})
+		return "", nil, false
+	}
+	scope, err := repo.PermissionScope(r.Context(), userID, perm)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("PermissionScope failed", "error", err, "user_id", userID, "perm", perm)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+		return "", nil, false
+	}
+	return userID, scope, true
+}
+
+// scopeWithSelf 返回用户在某权限下可访问的 user_id（含本人）；nil 表示全部
+func (s *Server) scopeWithSelf(ctx context.Context, userID, perm string) ([]string, error) {
+	scope, err := repository.New(s.db).PermissionScope(ctx, userID, perm)
+	if err != nil || scope == nil {
+		return scope, err
+	}
+	if !containsString(scope, userID) {
+		scope = append(scope, userID)
+	}
+	return scope, nil
+}
+
+// canAccessUser 判断用户能否在某权限下访问 ownerID 的数据（本人始终可以）
+func (s *Server) canAccessUser(ctx context.Context, userID, perm, ownerID string) (bool, error) {
+	if ownerID == userID {
+		return true, nil
+	}
+	scope, err := repository.New(s.db).PermissionScope(ctx, userID, perm)
+	if err != nil {
+		return false, err
+	}
+	return scope == nil || containsString(scope, ownerID), nil
+}
+
+// newRecordViewer 构造记录查看方身份，并解析其 view_phone 范围
+func (s *Server) newRecordViewer(ctx context.Context, userID string) (exportViewer, error) {
+	v := exportViewer{UserID: userID}
+	repo := repository.New(s.db)
+	granted, err := repo.HasPermission(ctx, userID, models.PermViewPhone)
+	if err != nil || !granted {
+		return v, err
+	}
+	if v.PhoneScope, err = repo.PermissionScope(ctx, userID, models.PermViewPhone); err != nil {
+		return v, err
+	}
+	v.PhoneGranted = true
+	return v, nil
+}
+
+// builtinAdminRole 内置管理员角色，不可删除，避免无人可管理授权
+const builtinAdminRole = "admin"
+
+// roleNamePattern 角色名：字母开头，字母数字下划线与连字符
+var roleNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,63}$`)
+
+type roleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// adminRolesHandler GET/POST {apiP}/admin/roles 列出角色（含全部权限点）/ 创建或更新角色
+func (s *Server) adminRolesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path != s.apiPrefix()+"/admin/roles" {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := s.adminUserIDFromRequest(w, r); !ok {
+		return
+	}
+	repo := repository.New(s.db)
+	if r.Method == http.MethodPost {
+		var req roleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+			return
+		}
+		role := &models.Role{Name: strings.TrimSpace(req.Name), Description: strings.TrimSpace(req.Description), Permissions: []string{}}
+		if !roleNamePattern.MatchString(role.Name) {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的角色名"})
+			return
+		}
+		for _, p := range req.Permissions {
+			if !containsString(models.Permissions, p) {
+				s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "未知的权限点：" + p})
+				return
+			}
+			if !containsString(role.Permissions, p) {
+				role.Permissions = append(role.Permissions, p)
+			}
+		}
+		if err := repo.SaveRole(r.Context(), role); err != nil {
+			s.logger.WithContext(r.Context()).Error("Save role failed", "error", err, "role", role.Name)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "保存角色失败"})
+			return
+		}
+	}
+	roles, err := repo.ListRoles(r.Context())
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List roles failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取角色失败"})
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"roles":       roles,
+		"permissions": models.Permissions,
+	}})
+}
+
+// adminRoleSubHandler DELETE {apiP}/admin/roles/{name} 删除角色及其授权
+func (s *Server) adminRoleSubHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/admin/roles/"), "/")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := s.adminUserIDFromRequest(w, r); !ok {
+		return
+	}
+	if name == builtinAdminRole {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "内置管理员角色不可删除"})
+		return
+	}
+	deleted, err := repository.New(s.db).DeleteRole(r.Context(), name)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Delete role failed", "error", err, "role", name)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "删除角色失败"})
+		return
+	}
+	if !deleted {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "角色不存在"})
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true})
+}
+
+type roleAssignmentRequest struct {
+	UserID      string   `json:"user_id"`
+	Role        string   `json:"role"`
+	ScopeType   string   `json:"scope_type"`
+	ScopeValues []string `json:"scope_values"`
+}
+
+// adminRoleAssignmentsHandler GET {apiP}/admin/role_assignments[?user_id=] 列出授权；POST 新增授权
+func (s *Server) adminRoleAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path != s.apiPrefix()+"/admin/role_assignments" {
+		http.NotFound(w, r)
+		return
+	}
+	adminID, ok := s.adminUserIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	repo := repository.New(s.db)
+	if r.Method == http.MethodGet {
+		list, err := repo.ListRoleAssignments(r.Context(), strings.TrimSpace(r.URL.Query().Get("user_id")))
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("List role assignments failed", "error", err)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取授权失败"})
+			return
+		}
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: list})
+		return
+	}
+
+	var req roleAssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+		return
+	}
+	a := &models.RoleAssignment{
+		UserID:    strings.TrimSpace(req.UserID),
+		Role:      strings.TrimSpace(req.Role),
+		ScopeType: strings.TrimSpace(req.ScopeType),
+		CreatedBy: &adminID,
+	}
+	for _, v := range req.ScopeValues {
+		if v = strings.TrimSpace(v); v != "" && !containsString(a.ScopeValues, v) {
+			a.ScopeValues = append(a.ScopeValues, v)
+		}
+	}
+	switch a.ScopeType {
+	case models.ScopeAll:
+		a.ScopeValues = nil
+	case models.ScopeUsers, models.ScopeDepartment, models.ScopeDepartmentTree, models.ScopeOrg:
+		if len(a.ScopeValues) == 0 {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "缺少 scope_values"})
+			return
+		}
+	default:
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "scope_type 须为 all/users/department/department_tree/org"})
+		return
+	}
+	if a.UserID == "" || a.Role == "" {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "缺少 user_id 或 role"})
+		return
+	}
+	user, err := repo.GetUser(r.Context(), a.UserID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get user failed", "error", err, "user_id", a.UserID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "保存授权失败"})
+		return
+	}
+	if user == nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "用户不存在"})
+		return
+	}
+	roles, err := repo.ListRoles(r.Context())
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List roles failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "保存授权失败"})
+		return
+	}
+	found := false
+	for _, role := range roles {
+		found = found || role.Name == a.Role
+	}
+	if !found {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "角色不存在"})
+		return
+	}
+	if err := repo.CreateRoleAssignment(r.Context(), a); err != nil {
+		s.logger.WithContext(r.Context()).Error("Create role assignment failed", "error", err, "user_id", a.UserID, "role", a.Role)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "保存授权失败"})
+		return
+	}
+	s.logger.WithContext(r.Context()).Info("Role assigned", "admin_id", adminID, "user_id", a.UserID, "role", a.Role, "scope_type", a.ScopeType)
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: a})
+}
+
+// adminRoleAssignmentSubHandler DELETE {apiP}/admin/role_assignments/{id} 撤销授权
+func (s *Server) adminRoleAssignmentSubHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/admin/role_assignments/"), "/"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	adminID, ok := s.adminUserIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	deleted, err := repository.New(s.db).DeleteRoleAssignment(r.Context(), id)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Delete role assignment failed", "error", err, "id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "撤销授权失败"})
+		return
+	}
+	if !deleted {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "授权不存在"})
+		return
+	}
+	s.logger.WithContext(r.Context()).Info("Role assignment revoked", "admin_id", adminID, "id", id)
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true})
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"records/internal/models"
+	"records/internal/phonecrypt"
+	"records/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// historyFields 版本历史中比较差异的字段（顺序即展示顺序），标题沿用导出列
+var historyFields = []string{
+	"customer_id", "customer_name", "follow_time", "follow_method", "contact_person", "contact_phone", "contact_role",
+	"follow_goal", "follow_content", "follow_result", "risk_content", "next_plan",
+}
+
+// fieldChange 字段级差异
+type fieldChange struct {
+	Field string      `json:"field"`
+	Title string      `json:"title"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// versionView 版本历史中的一项
+type versionView struct {
+	Version   int           `json:"version"`
+	Op        string        `json:"op"`
+	Source    string        `json:"source"`
+	ActorID   *string       `json:"actor_id"`
+	ActorName *string       `json:"actor_name"`
+	CreatedAt string        `json:"created_at"`
+	Changes   []fieldChange `json:"changes"`
+}
+
+// pageRecordVersionsHandler 处理 GET {apiP}/records/{id}/history 与 POST {apiP}/records/{id}/restore
+func (s *Server) pageRecordVersionsHandler(w http.ResponseWriter, r *http.Request, path string) {
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := uuid.Parse(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case parts[1] == "history" && r.Method == http.MethodGet:
+		s.pageRecordHistoryHandler(w, r, id)
+	case parts[1] == "restore" && r.Method == http.MethodPost:
+		s.pageRecordRestoreHandler(w, r, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// pageRecordHistoryHandler 返回记录的版本历史及相邻版本间的字段差异；记录所属销售与范围内主管可查看（含已删除记录）
+func (s *Server) pageRecordHistoryHandler(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	repo := repository.New(s.db)
+	record, err := repo.GetFollowRecordIncludingDeleted(r.Context(), id)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get follow record failed", "error", err, "id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取历史失败"})
+		return
+	}
+	// 已物理删除（如导入回滚）的记录仅保留历史，按快照中的所属销售鉴权
+	versions, err := repo.ListFollowRecordVersions(r.Context(), id)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List follow record versions failed", "error", err, "id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取历史失败"})
+		return
+	}
+	ownerID := ""
+	if record != nil {
+		ownerID = record.UserID
+	} else if len(versions) > 0 {
+		var snap struct {
+			UserID string `json:"user_id"`
+		}
+		_ = json.Unmarshal(versions[len(versions)-1].Snapshot, &snap)
+		ownerID = snap.UserID
+	}
+	if ownerID == "" {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在"})
+		return
+	}
+
+	if allowed, err := s.canAccessUser(r.Context(), userID, models.PermView, ownerID); err != nil || !allowed {
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Check view permission failed", "error", err, "user_id", userID)
+		}
+		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限查看此记录"})
+		return
+	}
+	viewer := exportViewer{UserID: userID}
+	if ownerID != userID {
+		if viewer, err = s.newRecordViewer(r.Context(), userID); err != nil {
+			s.logger.WithContext(r.Context()).Error("Resolve viewer failed", "error", err, "user_id", userID)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+			return
+		}
+	}
+
+	items := buildVersionViews(versions, s.canViewPhone(viewer, ownerID))
+	data := map[string]interface{}{
+		"record_id": id.String(),
+		"exists":    record != nil,
+		"deleted":   record == nil || record.DeletedAt != nil,
+		"versions":  items,
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: data})
+}
+
+// buildVersionViews 计算每个版本相对上一版本的字段差异；首个版本与空记录比较
+func buildVersionViews(versions []*models.FollowRecordVersion, showPhone bool) []versionView {
+	titles := make(map[string]string, len(exportColumns)+1)
+	for _, c := range exportColumns {
+		titles[c.Key] = c.Title
+	}
+	titles["customer_id"] = "客户 ID"
+
+	views := make([]versionView, 0, len(versions))
+	var prev map[string]interface{}
+	for _, v := range versions {
+		var snap map[string]interface{}
+		if err := json.Unmarshal(v.Snapshot, &snap); err != nil {
+			snap = map[string]interface{}{}
+		}
+		// 快照中的号码为密文（每次加密结果不同），解密后再比较
+		if p, ok := snap["contact_phone"].(string); ok {
+			snap["contact_phone"] = phonecrypt.Reveal(&p, showPhone)
+		}
+		changes := []fieldChange{}
+		for _, f := range historyFields {
+			var old interface{}
+			if prev != nil {
+				old = prev[f]
+			}
+			cur := snap[f]
+			if emptyValue(old) && emptyValue(cur) || jsonEqual(old, cur) {
+				continue
+			}
+			changes = append(changes, fieldChange{Field: f, Title: titles[f], Old: old, New: cur})
+		}
+		views = append(views, versionView{
+			Version:   v.Version,
+			Op:        v.Op,
+			Source:    v.Source,
+			ActorID:   v.ActorID,
+			ActorName: v.ActorName,
+			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+			Changes:   changes,
+		})
+		prev = snap
+	}
+	return views
+}
+
+func emptyValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	s, ok := v.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
+
+func jsonEqual(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+// pageRecordRestoreHandler 恢复已删除的记录（本人或 edit 权限范围内）
+func (s *Server) pageRecordRestoreHandler(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	repo := repository.New(s.db)
+	deleted, err := repo.GetFollowRecordIncludingDeleted(r.Context(), id)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get follow record failed", "error", err, "id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "恢复记录失败"})
+		return
+	}
+	if deleted == nil || deleted.DeletedAt == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在或未被删除"})
+		return
+	}
+	if allowed, err := s.canAccessUser(r.Context(), userID, models.PermEdit, deleted.UserID); err != nil || !allowed {
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Check edit permission failed", "error", err, "user_id", userID)
+		}
+		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限操作此记录"})
+		return
+	}
+	restored, err := repo.RestoreFollowRecord(repository.WithAudit(r.Context(), userID, models.RecordSourcePage), id, deleted.UserID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Restore follow record failed", "error", err, "id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "恢复记录失败"})
+		return
+	}
+	if !restored {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "记录不存在或未被删除"})
+		return
+	}
+	record, err := repo.GetFollowRecordByID(r.Context(), id)
+	if err != nil || record == nil {
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Message: "恢复成功"})
+		return
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: followRecordToPageMap(record, record.CustomerID.String()), Message: "恢复成功"})
+}
+
+// pageDeletedRecordsHandler GET {apiP}/records/deleted 返回本人已删除（可恢复）的记录，按删除时间倒序
+func (s *Server) pageDeletedRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	limit, err := parseLimit(r.URL.Query())
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	list, err := repository.New(s.db).ListDeletedFollowRecords(r.Context(), userID, limit)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List deleted follow records failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询记录失败"})
+		return
+	}
+	viewer := exportViewer{UserID: userID}
+	data := make([]map[string]interface{}, len(list))
+	for i, rec := range list {
+		m := followRecordToPageMap(rec, rec.CustomerID.String())
+		s.setPagePhone(m, viewer, rec)
+		if rec.DeletedAt != nil {
+			m["deleted_at"] = rec.DeletedAt.Format(time.RFC3339)
+		}
+		data[i] = m
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: data})
+}
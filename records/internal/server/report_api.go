@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"records/internal/report"
+	"records/internal/repository"
+)
+
+const (
+	reportFormatMarkdown = "markdown"
+	reportFormatDOCX     = "docx"
+
+	// maxReportDays 单份报告最长覆盖天数，限制大模型调用量
+	maxReportDays = 31
+	// reportTimeout 生成报告（含逐客户大模型总结）的超时时间，含排队等待槽位的时间
+	reportTimeout = 5 * time.Minute
+	// maxConcurrentReports 同时生成的报告数上限，超出的请求排队等待槽位
+	maxConcurrentReports = 2
+)
+
+type weeklyReportRequest struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Format string `json:"format"`
+}
+
+// managerWeeklyReportHandler 团队周报（仅主管，范围为 view 权限范围）：
+// GET {apiP}/manager/reports/weekly?from=YYYY-MM-DD&to=YYYY-MM-DD&format=markdown|docx 直接下载；
+// POST 同名参数（JSON 请求体）异步生成并以飞书文件发送给当前主管。from/to 默认最近 7 天，均包含
+func (s *Server) managerWeeklyReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	isManager, err := repository.New(s.db).IsManager(r.Context(), userID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("IsManager failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+		return
+	}
+	if !isManager {
+		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限查看"})
+		return
+	}
+
+	var req weeklyReportRequest
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+			return
+		}
+	} else {
+		q := r.URL.Query()
+		req = weeklyReportRequest{From: q.Get("from"), To: q.Get("to"), Format: q.Get("format")}
+	}
+	from, to, err := parseReportRange(req.From, req.To)
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	format := req.Format
+	if format == "" {
+		format = reportFormatMarkdown
+	}
+	if format != reportFormatMarkdown && format != reportFormatDOCX {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "format 仅支持 markdown 或 docx"})
+		return
+	}
+
+	// 同一主管同时只生成一份报告，重复提交直接拒绝
+	if _, loaded := s.reportInFlight.LoadOrStore(userID, struct{}{}); loaded {
+		s.writePageJSON(w, http.StatusTooManyRequests, pageAPIResponse{Success: false, Message: "已有报告正在生成，请完成后再试"})
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		go func() {
+			defer s.reportInFlight.Delete(userID)
+			s.sendWeeklyReport(userID, from, to, format)
+		}()
+		s.writePageJSON(w, http.StatusAccepted, pageAPIResponse{Success: true, Message: "报告生成中，完成后将通过飞书发送给你"})
+		return
+	}
+	defer s.reportInFlight.Delete(userID)
+
+	ctx, cancel := context.WithTimeout(r.Context(), reportTimeout)
+	defer cancel()
+	name, data, contentType, err := s.buildWeeklyReport(ctx, userID, from, to, format)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Build weekly report failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "生成报告失败"})
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(name))
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	_, _ = w.Write(data)
+}
+
+// buildWeeklyReport 生成并渲染报告，返回文件名、内容与 Content-Type；生成前等待并发槽位，ctx 到期仍未轮到时返回错误
+func (s *Server) buildWeeklyReport(ctx context.Context, managerID string, from, to time.Time, format string) (string, []byte, string, error) {
+	select {
+	case s.reportSlots <- struct{}{}:
+		defer func() { <-s.reportSlots }()
+	case <-ctx.Done():
+		return "", nil, "", fmt.Errorf("wait for report slot: %w", ctx.Err())
+	}
+	rep, err := report.NewGenerator(s.db, s.aiClient, s.logger).Generate(ctx, managerID, from, to)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if format == reportFormatDOCX {
+		data, err := report.RenderDOCX(rep)
+		if err != nil {
+			return "", nil, "", err
+		}
+		return rep.FileName() + ".docx", data, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", nil
+	}
+	return rep.FileName() + ".md", report.RenderMarkdown(rep), "text/markdown; charset=utf-8", nil
+}
+
+// sendWeeklyReport 后台生成报告并以飞书文件私聊发送给主管；失败时发送文字提示
+func (s *Server) sendWeeklyReport(managerID string, from, to time.Time, format string) {
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+
+	name, data, _, err := s.buildWeeklyReport(ctx, managerID, from, to, format)
+	if err == nil {
+		err = s.feishuClient.SendFileToUser(ctx, managerID, name, data)
+	}
+	if err != nil {
+		s.logger.Error("Send weekly report failed", "error", err, "user_id", managerID)
+		card := map[string]interface{}{
+			"elements": []interface{}{map[string]interface{}{
+				"tag":  "div",
+				"text": map[string]interface{}{"tag": "plain_text", "content": "团队周报生成失败，请稍后重试"},
+			}},
+		}
+		_ = s.feishuClient.SendCardToUser(ctx, managerID, card)
+		return
+	}
+	s.logger.Info("Weekly report sent", "user_id", managerID, "file", name)
+}
+
+// parseReportRange 解析报告日期区间（本地日期，均包含）；缺省为截至今天的最近 7 天
+func parseReportRange(fromStr, toStr string) (time.Time, time.Time, error) {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if toStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", toStr, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to 日期格式应为 YYYY-MM-DD")
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -6)
+	if fromStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", fromStr, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from 日期格式应为 YYYY-MM-DD")
+		}
+		from = t
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from 不能晚于 to")
+	}
+	if to.Sub(from) >= maxReportDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("报告区间不能超过 %d 天", maxReportDays)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"records/internal/models"
+	"records/internal/search"
+)
+
+// searchHandler GET {apiP}/search?q= 全文 / 模糊搜索跟进内容、目标、结果、风险与下一步计划。
+// 范围：view 权限范围内销售及本人，未授权用户仅本人；
+// 可选筛选 user_id、customer_id、customer_name、follow_method、from/to（YYYY-MM-DD，含）；fuzzy=true 容忍错别字；limit/offset 分页
+func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	q := r.URL.Query()
+	if _, err := search.ParseTerms(q.Get("q")); err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	f, err := parseExportFilter(q)
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	sq := search.Query{
+		Text:         q.Get("q"),
+		Fuzzy:        q.Get("fuzzy") == "true",
+		CustomerID:   f.CustomerID,
+		CustomerName: f.CustomerName,
+		FollowMethod: strings.TrimSpace(q.Get("follow_method")),
+		From:         f.From,
+		To:           f.To,
+	}
+	sq.Limit, _ = strconv.Atoi(q.Get("limit"))
+	sq.Offset, _ = strconv.Atoi(q.Get("offset"))
+
+	// 可见范围：view 权限范围内销售及本人（nil 表示全部）
+	if sq.UserIDs, err = s.scopeWithSelf(r.Context(), userID, models.PermView); err != nil {
+		s.logger.WithContext(r.Context()).Error("Resolve view scope failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+		return
+	}
+	if target := q.Get("user_id"); target != "" {
+		if sq.UserIDs != nil && !containsString(sq.UserIDs, target) {
+			s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限查看该用户"})
+			return
+		}
+		sq.UserIDs = []string{target}
+	}
+
+	res, err := search.New(s.db).Search(r.Context(), sq)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Search follow records failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "搜索失败"})
+		return
+	}
+	viewer, err := s.newRecordViewer(r.Context(), userID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Resolve viewer failed", "error", err, "user_id", userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+		return
+	}
+	items := make([]map[string]interface{}, 0, len(res.Hits))
+	for _, h := range res.Hits {
+		m := followRecordToPageMap(&h.FollowRecord, h.CustomerIDStr)
+		s.setPagePhone(m, viewer, &h.FollowRecord)
+		m["user_id"] = h.UserID
+		m["user_name"] = h.UserName
+		m["rank"] = h.Rank
+		m["highlights"] = h.Highlights
+		items = append(items, m)
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"total": res.Total,
+		"terms": res.Terms,
+		"items": items,
+	}})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,16 +12,31 @@ import (
 	"time"
 
 	"records/internal/ai"
+	"records/internal/apikey"
+	"records/internal/auth"
+	"records/internal/bitable"
+	"records/internal/comments"
 	"records/internal/config"
+	"records/internal/crmsync"
+	"records/internal/digest"
+	"records/internal/directory"
 	"records/internal/engine"
 	"records/internal/feishu"
 	"records/internal/models"
 	"records/internal/orchestrator"
+	"records/internal/redact"
 	"records/internal/repository"
+	"records/internal/scheduler"
+	"records/internal/stale"
+	"records/internal/tracing"
+	"records/internal/webhook"
 	"records/internal/worker"
 	"records/pkg/logger"
 
 	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // Server 服务器
@@ -33,8 +49,22 @@ type Server struct {
 	outputWorker   *worker.OutputWorker
 	logger         logger.Logger
 	httpServer     *http.Server
-	userLocks      sync.Map   // 用户级锁，key: userID, value: *sync.Mutex
-	hotwordsCancel context.CancelFunc // 热词定时任务取消函数
+	userLocks      sync.Map             // 用户级锁，key: userID, value: *sync.Mutex
+	reportInFlight sync.Map             // 正在生成团队周报的主管，key: userID；同一主管同时只生成一份
+	reportSlots    chan struct{}        // 团队周报生成的并发槽位（maxConcurrentReports）
+	scheduler      *scheduler.Scheduler // 定时任务调度器（多实例主节点选举）
+	digest         *digest.Service      // 跟进摘要推送
+	stale          *stale.Detector      // 久未跟进客户检测
+	directory      *directory.Syncer    // 飞书通讯录同步
+	comments       *comments.Service    // 跟进记录评论
+	sessions       *auth.Sessions       // 页面登录会话（访问/刷新令牌）
+	apiKeys        *apikey.Repo         // 对外 REST API 密钥
+	webhooks       *webhook.Dispatcher  // 出站 Webhook 分发
+	bitable        *bitable.Syncer      // 飞书多维表格同步
+	crm            *crmsync.Syncer      // CRM 双向同步，未启用时为 nil
+	redactor       *redact.Redactor     // 敏感信息脱敏，未启用时为 nil
+	loopbackServer *http.Server         // 可选回环监听（loopback_listen），经此进入的请求允许 x-user-id
+	trustedProxies []netip.Prefix       // 可信反向代理（trusted_proxies），仅其转发的 X-Forwarded-For 可信
 }
 
 // apiPrefix 返回 API 路径前缀，已规范化（无尾部斜杠，空则默认 /api）
@@ -60,10 +90,11 @@ func New(
 	cfg *config.Config,
 	db *sqlx.DB,
 	feishuClient feishu.Client,
+	redactor *redact.Redactor,
 	logger logger.Logger,
 ) *Server {
 	// 初始化AI客户端
-	aiClient := ai.NewOpenAIClient(cfg.AI, cfg.Prompts, logger)
+	aiClient := ai.NewOpenAIClient(cfg.AI, cfg.Prompts, redactor, logger)
 
 	// 初始化规则引擎
 	ruleEngine := engine.NewRuleEngine(logger)
@@ -72,22 +103,52 @@ func New(
 	repo := repository.New(db)
 
 	// 初始化输出工作器（异步处理 OUTPUTTING 阶段）
-	outputWorker := worker.NewOutputWorker(db, aiClient, repo, logger, 5)
+	outputWorker := worker.NewOutputWorker(db, aiClient, repo, cfg.FollowTask, logger, 5)
 
 	// 初始化编排器
 	orch := orchestrator.NewTurnOrchestrator(db, aiClient, ruleEngine, repo, outputWorker, logger,
 		cfg.Messages.AskingOtherCustomers, cfg.Messages.OutputtingConfirm, cfg.Messages.OutputtingEnded,
 		cfg.Messages.CollectingAbortConfirm, cfg.Messages.CollectingAborted, cfg.Messages.SystemError)
 
-	return &Server{
+	s := &Server{
 		config:       cfg,
 		db:           db,
 		feishuClient: feishuClient,
+		redactor:     redactor,
 		aiClient:     aiClient,
 		orchestrator: orch,
 		outputWorker: outputWorker,
 		logger:       logger,
+		reportSlots:  make(chan struct{}, maxConcurrentReports),
+		scheduler:    scheduler.New(db, cfg.Scheduler, logger),
+		digest:       digest.NewService(db, feishuClient, digestConfig(cfg.Digest), logger),
+		stale: stale.NewDetector(db, feishuClient, stale.Config{
+			DefaultDays:  cfg.Stale.DefaultDays,
+			TierDays:     cfg.Stale.TierDays,
+			LookbackDays: cfg.Stale.LookbackDays,
+			Renotify:     cfg.Stale.Renotify,
+		}, logger),
+		directory: directory.NewSyncer(db, feishuClient, directory.Config{
+			RootDepartmentID: cfg.Directory.RootDepartmentID,
+			LeaderRole:       cfg.Directory.LeaderRole,
+		}, logger),
+		sessions: newSessions(db, cfg.Server),
+		apiKeys:  apikey.NewRepo(db),
+		webhooks: webhook.NewDispatcher(db, webhook.Config{
+			PollInterval: cfg.Webhook.PollInterval,
+			Timeout:      cfg.Webhook.Timeout,
+			MaxAttempts:  cfg.Webhook.MaxAttempts,
+			BackoffBase:  cfg.Webhook.BackoffBase,
+			BackoffMax:   cfg.Webhook.BackoffMax,
+		}, logger),
 	}
+	s.trustedProxies = parseTrustedProxies(cfg.Server.TrustedProxies, logger)
+	s.bitable = newBitableSyncer(db, cfg, logger)
+	s.crm = newCRMSyncer(db, cfg.CRM, logger)
+	orch.SetCustomerMatcher(s.customerMatcher())
+	s.comments = comments.NewService(db, feishuClient, s.canViewRecordsOf, logger)
+	s.registerMetrics()
+	return s
 }
 
 // securityHeadersMiddleware 为响应添加安全头，防 Clickjacking 等
@@ -100,6 +161,20 @@ func securityHeadersMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// tracingMiddleware 为每个请求开启 span（沿用请求头 traceparent 中的上游链路），并以 X-Trace-Id 响应头返回 trace_id 便于排查
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(ctx, r.Method+" "+r.URL.Path,
+			attribute.String("http.method", r.Method), attribute.String("http.target", r.URL.Path))
+		defer span.End()
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			w.Header().Set("X-Trace-Id", traceID)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // Start 启动服务器
 func (s *Server) Start() error {
 	// 启动输出工作器（异步处理 OUTPUTTING 阶段）
@@ -114,14 +189,18 @@ func (s *Server) Start() error {
 		}
 	}()
 
-	// 热词流水线：每日 00:05 执行，与主程序统一启动
-	hotwordsCtx, hotwordsCancel := context.WithCancel(context.Background())
-	s.hotwordsCancel = hotwordsCancel
-	go s.runHotwordsScheduler(hotwordsCtx)
+	// 定时任务（热词流水线、事件去重记录清理等）：多实例下仅数据库锁选举出的主节点按 cron 触发
+	s.registerJobs()
+	s.scheduler.Start()
+	s.logger.Info("Job scheduler started")
+
+	s.webhooks.Start()
+	s.logger.Info("Webhook dispatcher started")
 
 	// 启动HTTP服务器（健康检查、page API、静态文件）
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.healthHandler)
+	mux.HandleFunc("/metrics", s.metricsHandler)
 
 	apiP := s.apiPrefix()
 	webP := s.webPrefix()
@@ -131,17 +210,61 @@ func (s *Server) Start() error {
 	// Page API：与 page/index.html 前端配套（含飞书 OAuth）
 	mux.HandleFunc(apiP+"/feishu/auth", s.feishuAuthHandler)
 	mux.HandleFunc(apiP+"/user/info", s.userInfoHandler)
+	mux.HandleFunc(apiP+"/auth/refresh", s.authRefreshHandler)
+	mux.HandleFunc(apiP+"/auth/logout", s.authLogoutHandler)
 	mux.HandleFunc(apiP+"/records", s.pageAPIRootHandler)
 	mux.HandleFunc(apiP+"/records/", s.pageAPISubHandler)
+	mux.HandleFunc(apiP+"/imports", s.importsHandler)
+	mux.HandleFunc(apiP+"/imports/", s.importsSubHandler)
+	mux.HandleFunc(apiP+"/search", s.searchHandler)
+	mux.HandleFunc(apiP+"/comments/unread", s.commentsUnreadHandler)
 
-	// Manager 页面 API（仅管理员，只读）
+	// 客户跟进时间线分享链接：创建/列表/撤销/访问审计；/share/{token} 为无需登录的公开只读页
+	mux.HandleFunc(apiP+"/shares", s.sharesHandler)
+	mux.HandleFunc(apiP+"/shares/", s.shareSubHandler)
+	mux.HandleFunc("/share/", s.publicShareHandler)
+
+	// Manager 页面 API（view 权限，导出需 export 权限）；团队周报可下载或发送到飞书
 	mux.HandleFunc(apiP+"/manager/users", s.managerUsersHandler)
 	mux.HandleFunc(apiP+"/manager/users/", s.managerUsersSubHandler)
+	mux.HandleFunc(apiP+"/manager/reports/weekly", s.managerWeeklyReportHandler)
+	mux.HandleFunc(apiP+"/manager/at_risk", s.managerAtRiskHandler)
+	mux.HandleFunc(apiP+"/manager/customers/", s.managerCustomersSubHandler)
+	mux.HandleFunc(apiP+"/manager/export", s.managerExportHandler)
 
 	// 热词统计 API（供 pages/hot_words.html 与 manager 热词区拉取）
 	mux.HandleFunc(apiP+"/hotwords/stats", s.hotwordsStatsHandler)
 	mux.HandleFunc(apiP+"/hotwords/run_dates", s.hotwordsRunDatesHandler)
 
+	// 跟进摘要订阅设置（当前用户）
+	mux.HandleFunc(apiP+"/digest/settings", s.digestSettingsHandler)
+
+	// 定时任务管理 API（admin 权限）：查看任务与最近运行、手动触发、运行历史
+	mux.HandleFunc(apiP+"/admin/jobs", s.adminJobsHandler)
+	mux.HandleFunc(apiP+"/admin/jobs/", s.adminJobsSubHandler)
+
+	// 角色权限管理 API（admin 权限）：角色与权限点、用户授权及其数据范围、已同步的飞书部门
+	mux.HandleFunc(apiP+"/admin/roles", s.adminRolesHandler)
+	mux.HandleFunc(apiP+"/admin/roles/", s.adminRoleSubHandler)
+	mux.HandleFunc(apiP+"/admin/role_assignments", s.adminRoleAssignmentsHandler)
+	mux.HandleFunc(apiP+"/admin/role_assignments/", s.adminRoleAssignmentSubHandler)
+	mux.HandleFunc(apiP+"/admin/departments", s.adminDepartmentsHandler)
+
+	// 对外 REST API（API Key 认证，scope 授权，按密钥限流）及密钥管理（admin 权限）
+	mux.HandleFunc(apiP+"/v1/", s.apiV1Handler)
+	mux.HandleFunc(apiP+"/admin/api_keys", s.adminAPIKeysHandler)
+	mux.HandleFunc(apiP+"/admin/api_keys/", s.adminAPIKeySubHandler)
+
+	// 出站 Webhook 管理（admin 权限）：订阅、签名密钥、测试事件、投递记录与重试
+	mux.HandleFunc(apiP+"/admin/webhooks", s.adminWebhooksHandler)
+	mux.HandleFunc(apiP+"/admin/webhooks/", s.adminWebhookSubHandler)
+	mux.HandleFunc(apiP+"/admin/webhook_deliveries/", s.adminWebhookDeliverySubHandler)
+	// 飞书多维表格同步（admin 权限）：同步状态、冲突日志与重置游标
+	mux.HandleFunc(apiP+"/admin/bitable", s.adminBitableHandler)
+	mux.HandleFunc(apiP+"/admin/bitable/reset", s.adminBitableResetHandler)
+	// CRM 双向同步状态（admin 权限）
+	mux.HandleFunc(apiP+"/admin/crm", s.adminCRMHandler)
+
 	// 静态页面（records/pages 目录）
 	staticDir := s.config.Server.StaticDir
 	if staticDir == "" {
@@ -185,8 +308,13 @@ func (s *Server) Start() error {
 		}
 	}
 
-	// 安全头中间件：防 Clickjacking、XSS 等
-	handler := securityHeadersMiddleware(mux)
+	// 安全头中间件：防 Clickjacking、XSS 等；链路追踪中间件为每个请求开启 span
+	handler := tracingMiddleware(securityHeadersMiddleware(mux))
+
+	s.warnAuthConfig()
+	if err := s.startLoopbackListener(handler); err != nil {
+		return err
+	}
 
 	s.httpServer = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port),
@@ -202,13 +330,19 @@ func (s *Server) Start() error {
 
 // Shutdown 优雅关闭服务器
 func (s *Server) Shutdown(ctx context.Context) error {
-	if s.hotwordsCancel != nil {
-		s.hotwordsCancel()
-	}
-	s.logger.Info("Shutting down output worker...")
+	s.logger.WithContext(ctx).Info("Stopping job scheduler...")
+	s.scheduler.Stop()
+	s.logger.WithContext(ctx).Info("Stopping webhook dispatcher...")
+	s.webhooks.Stop()
+	s.logger.WithContext(ctx).Info("Shutting down output worker...")
 	s.outputWorker.Stop()
-	s.logger.Info("Output worker stopped")
+	s.logger.WithContext(ctx).Info("Output worker stopped")
 
+	if s.loopbackServer != nil {
+		if err := s.loopbackServer.Shutdown(ctx); err != nil {
+			s.logger.WithContext(ctx).Error("Loopback HTTP server shutdown failed", "error", err)
+		}
+	}
 	if s.httpServer != nil {
 		return s.httpServer.Shutdown(ctx)
 	}
@@ -217,20 +351,33 @@ func (s *Server) Shutdown(ctx context.Context) error {
 
 // HandleMessage 实现 feishu.MessageHandler 接口；msg.UserID 已为 union_id（由 feishu 客户端在事件入口解析）
 func (s *Server) HandleMessage(ctx context.Context, msg *feishu.Message) error {
-	s.logger.Info("Processing message", "user_id", msg.UserID, "chat_id", msg.ChatID, "content", msg.Content)
+	ctx, span := tracing.Start(ctx, "server.HandleMessage", attribute.String("user_id", msg.UserID))
+	defer span.End()
+
+	s.logger.WithContext(ctx).Info("Processing message", "user_id", msg.UserID, "chat_id", msg.ChatID, "content", msg.Content)
 
-	mu := s.getUserLock(msg.UserID)
-	mu.Lock()
-	defer mu.Unlock()
+	// 用户级锁（进程内 + 数据库租约锁），多实例下同一用户的消息串行处理；
+	// 持锁期间的处理使用 lockCtx，租约丢失时随之取消，回复仍用 ctx 发出
+	lockCtx, unlock, err := s.lockUser(ctx, msg.UserID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to acquire user lock", "error", err, "user_id", msg.UserID)
+		return s.feishuClient.SendMessage(ctx, msg.ChatID, s.config.Messages.SystemError)
+	}
+	defer unlock()
 
-	if _, err := s.ensureUserExists(ctx, msg.UserID, false); err != nil {
-		s.logger.Error("Failed to ensure user exists", "error", err, "user_id", msg.UserID)
+	if _, err := s.ensureUserExists(lockCtx, msg.UserID, false); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to ensure user exists", "error", err, "user_id", msg.UserID)
 		return s.feishuClient.SendMessage(ctx, msg.ChatID, s.config.Messages.SystemError)
 	}
 
-	reply, err := s.orchestrator.ProcessTurn(ctx, msg.UserID, msg.Content)
+	// 回复评论通知消息：作为评论话题的回复，不进入记录会话
+	if msg.ParentID != "" && s.handleCommentReply(lockCtx, msg) {
+		return nil
+	}
+
+	reply, err := s.orchestrator.ProcessTurn(lockCtx, msg.UserID, msg.Content)
 	if err != nil {
-		s.logger.Error("Failed to process turn", "error", err, "user_id", msg.UserID)
+		s.logger.WithContext(ctx).Error("Failed to process turn", "error", err, "user_id", msg.UserID)
 		reply = s.config.Messages.ProcessError
 	}
 	// 空回复表示本次请求被去重（重复消息），无需再向用户发送任何内容
@@ -239,14 +386,14 @@ func (s *Server) HandleMessage(ctx context.Context, msg *feishu.Message) error {
 	}
 
 	if err := s.feishuClient.SendMessage(ctx, msg.ChatID, reply); err != nil {
-		s.logger.Error("Failed to send reply", "error", err, "chat_id", msg.ChatID)
+		s.logger.WithContext(ctx).Error("Failed to send reply", "error", err, "chat_id", msg.ChatID)
 		return err
 	}
 
 	return nil
 }
 
-// getUserLock 获取或创建用户级锁
+// getUserLock 获取或创建进程内用户级锁（跨实例串行由 lockUser 中的租约锁保证）
 func (s *Server) getUserLock(userID string) *sync.Mutex {
 	// 尝试加载已存在的锁
 	if lock, ok := s.userLocks.Load(userID); ok {
@@ -265,17 +412,17 @@ func (s *Server) getUserLock(userID string) *sync.Mutex {
 
 // HandleUserEnter 实现 feishu.MessageHandler 接口；userID 已为 union_id（由 feishu 客户端在事件入口解析）
 func (s *Server) HandleUserEnter(ctx context.Context, userID, chatID string) error {
-	s.logger.Info("User entered chat", "user_id", userID, "chat_id", chatID)
+	s.logger.WithContext(ctx).Info("User entered chat", "user_id", userID, "chat_id", chatID)
 
 	if _, err := s.ensureUserExists(ctx, userID, true); err != nil {
-		s.logger.Error("Failed to ensure user exists", "error", err, "user_id", userID)
+		s.logger.WithContext(ctx).Error("Failed to ensure user exists", "error", err, "user_id", userID)
 		return s.feishuClient.SendMessage(ctx, chatID, s.config.Messages.SystemError)
 	}
 
 	repo := repository.New(s.db)
 	user, err := repo.GetUser(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to get user", "error", err, "user_id", userID)
+		s.logger.WithContext(ctx).Error("Failed to get user", "error", err, "user_id", userID)
 		return s.feishuClient.SendMessage(ctx, chatID, s.config.Messages.SystemError)
 	}
 
@@ -284,12 +431,12 @@ func (s *Server) HandleUserEnter(ctx context.Context, userID, chatID string) err
 		// 用户每天第一次进入对话时
 		welcomeMsg = s.config.Messages.NewUser
 		if err := repo.UpdateUserStartLark(ctx, userID); err != nil {
-			s.logger.Error("Failed to update user start lark", "error", err, "user_id", userID)
+			s.logger.WithContext(ctx).Error("Failed to update user start lark", "error", err, "user_id", userID)
 		}
 	} else {
 		session, err := repo.GetActiveSession(ctx, userID)
 		if err != nil {
-			s.logger.Error("Failed to get active session", "error", err, "user_id", userID)
+			s.logger.WithContext(ctx).Error("Failed to get active session", "error", err, "user_id", userID)
 			welcomeMsg = s.config.Messages.WelcomeBack
 		} else if session != nil {
 			welcomeMsg = s.config.Messages.ContinueSession
@@ -346,6 +493,7 @@ func (s *Server) ensureUserExists(ctx context.Context, userID string, checkStatu
 				return "", fmt.Errorf("failed to update user: %w", err)
 			}
 		}
+		s.syncUserDepartments(ctx, user.ID, userInfo.DepartmentIDs)
 		return user.ID, nil
 	}
 
@@ -366,15 +514,23 @@ func (s *Server) ensureUserExists(ctx context.Context, userID string, checkStatu
 		return "", fmt.Errorf("failed to create user: %w", err)
 	}
 
-	s.logger.Info("Created new user", "user_id", userID, "name", userInfo.Name)
+	s.syncUserDepartments(ctx, userID, userInfo.DepartmentIDs)
+	s.logger.WithContext(ctx).Info("Created new user", "user_id", userID, "name", userInfo.Name)
 	return userID, nil
 }
 
+// syncUserDepartments 更新用户所属飞书部门（按部门授权的数据范围依赖此表）；失败仅记录日志
+func (s *Server) syncUserDepartments(ctx context.Context, userID string, departmentIDs []string) {
+	if err := repository.New(s.db).SetUserDepartments(ctx, userID, departmentIDs); err != nil {
+		s.logger.WithContext(ctx).Error("Set user departments failed", "error", err, "user_id", userID)
+	}
+}
+
 // healthHandler 健康检查处理器
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	// 检查数据库连接
 	if err := s.db.Ping(); err != nil {
-		s.logger.Error("Database health check failed", "error", err)
+		s.logger.WithContext(r.Context()).Error("Database health check failed", "error", err)
 		w.WriteHeader(http.StatusServiceUnavailable)
 		w.Write([]byte("Database unavailable"))
 		return
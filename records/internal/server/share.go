@@ -0,0 +1,440 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"records/internal/auth"
+	"records/internal/models"
+	"records/internal/phonecrypt"
+	"records/internal/repository"
+	"records/internal/share"
+	"records/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// 客户跟进时间线分享链接：服务端签名令牌，可设有效期、撤销并统计访问；公开页只读且联系电话始终脱敏
+
+const (
+	defaultShareTTL = 72 * time.Hour
+	defaultShareMax = 30 * 24 * time.Hour
+
+	// maxShareItems 公开页最多展示的跟进条数（按跟进时间倒序）
+	maxShareItems = 500
+)
+
+// errShareItemsFull 公开页条数已满，中止遍历
+var errShareItemsFull = errors.New("share items full")
+
+// shareRequest 创建分享请求体；user_id 为空时分享创建人可查看范围内该客户的全部跟进，expires_in_hours 为空使用默认有效期
+type shareRequest struct {
+	CustomerID     string `json:"customer_id"`
+	UserID         string `json:"user_id"`
+	ExpiresInHours int    `json:"expires_in_hours"`
+}
+
+// sharesHandler GET {apiP}/shares[?customer_id=&all=true] 列出本人创建的分享（all=true 且为管理员时列出全部）；POST 创建分享
+func (s *Server) sharesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != s.apiPrefix()+"/shares" {
+		http.NotFound(w, r)
+		return
+	}
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		s.createShareHandler(w, r, userID)
+	case http.MethodGet:
+		createdBy := userID
+		if r.URL.Query().Get("all") == "true" {
+			if isAdmin, err := repository.New(s.db).HasPermission(r.Context(), userID, models.PermAdmin); err != nil {
+				s.logger.WithContext(r.Context()).Error("Check admin permission failed", "error", err, "user_id", userID)
+				s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "权限检查失败"})
+				return
+			} else if isAdmin {
+				createdBy = ""
+			}
+		}
+		var customerID *uuid.UUID
+		if v := r.URL.Query().Get("customer_id"); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的 customer_id"})
+				return
+			}
+			customerID = &id
+		}
+		list, err := share.NewRepo(s.db).List(r.Context(), createdBy, customerID, 200)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("List shares failed", "error", err, "user_id", userID)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取分享失败"})
+			return
+		}
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: list})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createShareHandler 创建分享：客户须在创建人可查看范围内有跟进记录；返回令牌与公开页路径
+func (s *Server) createShareHandler(w http.ResponseWriter, r *http.Request, userID string) {
+	secret := s.config.Server.JWTSecret
+	if secret == "" {
+		s.writePageJSON(w, http.StatusServiceUnavailable, pageAPIResponse{Success: false, Message: "未配置 jwt_secret，无法创建分享链接"})
+		return
+	}
+	var req shareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+		return
+	}
+	customerID, err := uuid.Parse(req.CustomerID)
+	if err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的 customer_id"})
+		return
+	}
+	ttl := s.config.Share.DefaultTTL
+	if ttl <= 0 {
+		ttl = defaultShareTTL
+	}
+	maxTTL := s.config.Share.MaxTTL
+	if maxTTL <= 0 {
+		maxTTL = defaultShareMax
+	}
+	if req.ExpiresInHours < 0 {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的有效期"})
+		return
+	}
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	if ttl > maxTTL {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "有效期不能超过 " + strconv.Itoa(int(maxTTL.Hours())) + " 小时"})
+		return
+	}
+
+	customer, err := repository.New(s.db).GetCustomer(r.Context(), customerID)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get customer failed", "error", err, "customer_id", customerID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "创建分享失败"})
+		return
+	}
+	if customer == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "客户不存在"})
+		return
+	}
+	sh := &share.Share{
+		ID:           uuid.New(),
+		CustomerID:   customerID,
+		CustomerName: customer.Name,
+		CreatedBy:    userID,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	if req.UserID = strings.TrimSpace(req.UserID); req.UserID != "" {
+		sh.UserID = &req.UserID
+	}
+	items, err := s.shareTimelineItems(r.Context(), sh)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Load share timeline failed", "error", err, "customer_id", customerID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "创建分享失败"})
+		return
+	}
+	if items == nil {
+		s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限分享该销售的记录"})
+		return
+	}
+	if len(items) == 0 {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "可查看范围内没有该客户的跟进记录"})
+		return
+	}
+
+	repo := share.NewRepo(s.db)
+	if err := repo.Create(r.Context(), sh); err != nil {
+		s.logger.WithContext(r.Context()).Error("Create share failed", "error", err, "customer_id", customerID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "创建分享失败"})
+		return
+	}
+	token, err := auth.IssueShare(secret, sh.ID.String(), sh.ExpiresAt)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Issue share token failed", "error", err, "share_id", sh.ID)
+		_, _ = repo.Revoke(r.Context(), sh.ID, userID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "创建分享失败"})
+		return
+	}
+	s.logger.WithContext(r.Context()).Info("Share created", "share_id", sh.ID, "customer_id", customerID, "user_id", userID, "expires_at", sh.ExpiresAt)
+	s.writePageJSON(w, http.StatusCreated, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"share": sh,
+		"token": token,
+		"path":  "/share/" + token,
+	}})
+}
+
+// shareSubHandler DELETE {apiP}/shares/{id} 撤销分享；GET {apiP}/shares/{id}/access_logs 访问审计。仅创建人或管理员
+func (s *Server) shareSubHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/shares/"), "/"), "/")
+	id, err := uuid.Parse(parts[0])
+	if err != nil || len(parts) > 2 || (len(parts) == 2 && parts[1] != "access_logs") {
+		http.NotFound(w, r)
+		return
+	}
+	if (len(parts) == 1 && r.Method != http.MethodDelete) || (len(parts) == 2 && r.Method != http.MethodGet) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := s.pageUserIDFromRequest(r)
+	if !ok {
+		s.writePageJSON(w, http.StatusUnauthorized, pageAPIResponse{Success: false, Message: "未登录或登录已过期"})
+		return
+	}
+	repo := share.NewRepo(s.db)
+	sh, err := repo.Get(r.Context(), id)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get share failed", "error", err, "share_id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取分享失败"})
+		return
+	}
+	if sh == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "分享不存在"})
+		return
+	}
+	if sh.CreatedBy != userID {
+		isAdmin, err := repository.New(s.db).HasPermission(r.Context(), userID, models.PermAdmin)
+		if err != nil || !isAdmin {
+			if err != nil {
+				s.logger.WithContext(r.Context()).Error("Check admin permission failed", "error", err, "user_id", userID)
+			}
+			s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限操作此分享"})
+			return
+		}
+	}
+
+	if len(parts) == 2 {
+		logs, err := repo.ListAccessLogs(r.Context(), id, 500)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("List share access logs failed", "error", err, "share_id", id)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取访问记录失败"})
+			return
+		}
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{"share": sh, "logs": logs}})
+		return
+	}
+	if _, err := repo.Revoke(r.Context(), id, userID); err != nil {
+		s.logger.WithContext(r.Context()).Error("Revoke share failed", "error", err, "share_id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "撤销分享失败"})
+		return
+	}
+	s.logger.WithContext(r.Context()).Info("Share revoked", "share_id", id, "by", userID)
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Message: "已撤销"})
+}
+
+// publicShareHandler GET /share/{token} 公开只读页：校验令牌与分享状态，按创建人当前权限渲染时间线（联系电话脱敏）；每次访问写审计
+func (s *Server) publicShareHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+
+	ctx := r.Context()
+	repo := share.NewRepo(s.db)
+	entry := &share.AccessLog{IP: s.clientIP(r), UserAgent: truncateRunes(r.UserAgent(), 512)}
+	status, message := http.StatusOK, ""
+	defer func() {
+		if err := repo.LogAccess(context.WithoutCancel(ctx), entry); err != nil {
+			s.logger.WithContext(r.Context()).Error("Log share access failed", "error", err, "share_id", entry.ShareID)
+		}
+	}()
+	deny := func(outcome string, code int, msg string) {
+		entry.Outcome, status, message = outcome, code, msg
+	}
+
+	token := strings.Trim(strings.TrimPrefix(r.URL.Path, "/share/"), "/")
+	sid, tokenErr := auth.ValidateShare(s.config.Server.JWTSecret, token)
+	var sh *share.Share
+	var err error
+	if tokenErr != nil && !errors.Is(tokenErr, auth.ErrShareExpired) {
+		deny(share.OutcomeInvalid, http.StatusNotFound, "分享链接无效")
+	} else if id, perr := uuid.Parse(sid); perr != nil {
+		deny(share.OutcomeInvalid, http.StatusNotFound, "分享链接无效")
+	} else {
+		entry.ShareID = &id
+		if sh, err = repo.Get(ctx, id); err != nil {
+			s.logger.WithContext(r.Context()).Error("Get share failed", "error", err, "share_id", id)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			entry.Outcome = share.OutcomeError
+			return
+		}
+		switch {
+		case sh == nil:
+			entry.ShareID = nil
+			deny(share.OutcomeNotFound, http.StatusNotFound, "分享链接无效")
+		case sh.RevokedAt != nil:
+			deny(share.OutcomeRevoked, http.StatusGone, "分享链接已被撤销")
+		case tokenErr != nil, !sh.Active(time.Now()):
+			deny(share.OutcomeExpired, http.StatusGone, "分享链接已过期")
+		case sh.CreatorState != 0:
+			deny(share.OutcomeInactive, http.StatusGone, "分享链接已失效")
+		}
+	}
+
+	if entry.Outcome == "" {
+		items, err := s.shareTimelineItems(ctx, sh)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Load share timeline failed", "error", err, "share_id", sh.ID)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			entry.Outcome = share.OutcomeError
+			return
+		}
+		if items == nil {
+			deny(share.OutcomeInactive, http.StatusGone, "分享链接已失效")
+		} else if counted, err := repo.CountView(ctx, sh.ID); err != nil {
+			s.logger.WithContext(r.Context()).Error("Count share view failed", "error", err, "share_id", sh.ID)
+		} else if !counted {
+			deny(share.OutcomeExpired, http.StatusGone, "分享链接已过期")
+		}
+		if entry.Outcome == "" {
+			entry.Outcome, entry.Records = share.OutcomeOK, len(items)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := share.RenderTimeline(w, &share.Timeline{
+				CustomerName: sh.CustomerName,
+				CreatorName:  sh.CreatorName,
+				ExpiresAt:    sh.ExpiresAt,
+				Items:        items,
+			}); err != nil {
+				s.logger.WithContext(r.Context()).Error("Render share page failed", "error", err, "share_id", sh.ID)
+			}
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := share.RenderMessage(w, message); err != nil {
+		s.logger.WithContext(r.Context()).Error("Render share page failed", "error", err)
+	}
+}
+
+// shareTimelineItems 按创建人当前的 view 范围读取分享客户的跟进（联系电话脱敏）；指定销售已不在其范围内时返回 nil
+func (s *Server) shareTimelineItems(ctx context.Context, sh *share.Share) ([]share.TimelineItem, error) {
+	scope, err := s.scopeWithSelf(ctx, sh.CreatedBy, models.PermView)
+	if err != nil {
+		return nil, err
+	}
+	if sh.UserID != nil {
+		if scope != nil && !containsString(scope, *sh.UserID) {
+			return nil, nil
+		}
+		scope = []string{*sh.UserID}
+	}
+	items := []share.TimelineItem{}
+	f := repository.ExportFilter{UserIDs: scope, CustomerID: &sh.CustomerID}
+	err = repository.New(s.db).IterateFollowRecordsForExport(ctx, f, func(rec *repository.FollowRecordForExport) error {
+		if len(items) >= maxShareItems {
+			return errShareItemsFull
+		}
+		str := func(p *string) string {
+			if p == nil {
+				return ""
+			}
+			return *p
+		}
+		items = append(items, share.TimelineItem{
+			FollowTime:    rec.FollowTime,
+			UserName:      rec.UserName,
+			FollowMethod:  str(rec.FollowMethod),
+			ContactPerson: str(rec.ContactPerson),
+			ContactPhone:  phonecrypt.Reveal(rec.ContactPhone, false),
+			ContactRole:   str(rec.ContactRole),
+			FollowGoal:    str(rec.FollowGoal),
+			FollowContent: str(rec.FollowContent),
+			FollowResult:  str(rec.FollowResult),
+			RiskContent:   str(rec.RiskContent),
+			NextPlan:      str(rec.NextPlan),
+		})
+		return nil
+	})
+	if err != nil && !errors.Is(err, errShareItemsFull) {
+		return nil, err
+	}
+	return items, nil
+}
+
+// clientIP 访问方 IP，用于审计与会话记录：对端为可信反向代理时，从 X-Forwarded-For 末尾向前跳过可信代理，
+// 取第一个不可信的地址；否则使用对端地址，不采信客户端自行设置的 X-Forwarded-For
+func (s *Server) clientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remote = host
+	}
+	if !s.trustedProxy(remote) {
+		return truncateRunes(remote, 64)
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !s.trustedProxy(hop) {
+			return truncateRunes(hop, 64)
+		}
+		remote = hop
+	}
+	return truncateRunes(remote, 64)
+}
+
+// trustedProxy 地址是否属于配置的可信反向代理
+func (s *Server) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range s.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies 解析 trusted_proxies 中的 IP 或 CIDR，无效项记录警告后忽略
+func parseTrustedProxies(list []string, log logger.Logger) []netip.Prefix {
+	var out []netip.Prefix
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if p, err := netip.ParsePrefix(v); err == nil {
+			out = append(out, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			log.Warn("Invalid trusted proxy, ignored", "value", v)
+			continue
+		}
+		addr = addr.Unmap()
+		out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return out
+}
+
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
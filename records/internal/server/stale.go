@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"records/internal/models"
+	"records/internal/scheduler"
+	"records/internal/stale"
+
+	"github.com/google/uuid"
+)
+
+// runStaleCustomersJob 提醒销售跟进久未联系的客户，由定时任务调度；无需提醒时记为跳过
+func (s *Server) runStaleCustomersJob(ctx context.Context) error {
+	sent, err := s.stale.Notify(ctx, time.Now())
+	if sent > 0 {
+		s.logger.WithContext(ctx).Info("Stale customer nudges sent", "users", sent)
+	}
+	if err != nil {
+		return err
+	}
+	if sent == 0 {
+		return scheduler.ErrSkipped
+	}
+	return nil
+}
+
+// requireManager 校验当前用户为主管（拥有 view 权限），返回用户 ID 与其数据范围（nil 表示全部用户）；失败时已写响应
+func (s *Server) requireManager(w http.ResponseWriter, r *http.Request) (string, []string, bool) {
+	return s.requirePermission(w, r, models.PermView)
+}
+
+// managerAtRiskHandler GET {apiP}/manager/at_risk[?user_id=] 按销售列出范围内久未跟进的客户
+func (s *Server) managerAtRiskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	_, scope, ok := s.requireManager(w, r)
+	if !ok {
+		return
+	}
+	if target := r.URL.Query().Get("user_id"); target != "" {
+		if scope != nil && !containsString(scope, target) {
+			s.writePageJSON(w, http.StatusForbidden, pageAPIResponse{Success: false, Message: "无权限查看该用户"})
+			return
+		}
+		scope = []string{target}
+	}
+
+	accounts, err := s.stale.AtRisk(r.Context(), scope, time.Now())
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List at-risk accounts failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "获取风险客户失败"})
+		return
+	}
+
+	type repGroup struct {
+		UserID   string           `json:"user_id"`
+		Name     string           `json:"name"`
+		Count    int              `json:"count"`
+		Accounts []*stale.Account `json:"accounts"`
+	}
+	var groups []*repGroup
+	byUser := make(map[string]*repGroup)
+	for _, a := range accounts {
+		g, ok := byUser[a.UserID]
+		if !ok {
+			g = &repGroup{UserID: a.UserID, Name: a.UserName}
+			byUser[a.UserID] = g
+			groups = append(groups, g)
+		}
+		g.Accounts = append(g.Accounts, a)
+		g.Count++
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Count > groups[j].Count })
+
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"default_days": s.stale.ThresholdDays(""),
+		"tier_days":    s.config.Stale.TierDays,
+		"reps":         groups,
+	}})
+}
+
+// managerCustomersSubHandler 处理 {apiP}/manager/customers/{customer_id}/tier 与 /manager/customers/{customer_id}/export
+func (s *Server) managerCustomersSubHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/manager/customers/"), "/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	customerID, err := uuid.Parse(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch parts[1] {
+	case "tier":
+		s.managerCustomerTierHandler(w, r, customerID)
+	case "export":
+		s.managerCustomerExportHandler(w, r, customerID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// managerCustomerTierHandler PUT {apiP}/manager/customers/{customer_id}/tier 设置客户分级（body: {"tier": "A"}，空为默认级别）
+func (s *Server) managerCustomerTierHandler(w http.ResponseWriter, r *http.Request, customerID uuid.UUID) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _, ok := s.requireManager(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Tier string `json:"tier"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+		return
+	}
+	req.Tier = strings.TrimSpace(req.Tier)
+	if !s.stale.ValidTier(req.Tier) {
+		s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "未定义的客户分级"})
+		return
+	}
+
+	repo := s.stale.Repo()
+	if _, found, err := repo.GetCustomerTier(r.Context(), customerID); err != nil {
+		s.logger.WithContext(r.Context()).Error("Get customer tier failed", "error", err, "customer_id", customerID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "设置客户分级失败"})
+		return
+	} else if !found {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "客户不存在"})
+		return
+	}
+	if err := repo.SetCustomerTier(r.Context(), customerID, req.Tier); err != nil {
+		s.logger.WithContext(r.Context()).Error("Set customer tier failed", "error", err, "customer_id", customerID)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "设置客户分级失败"})
+		return
+	}
+	s.logger.WithContext(r.Context()).Info("Customer tier updated", "customer_id", customerID, "tier", req.Tier, "by", userID)
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"customer_id":    customerID.String(),
+		"tier":           req.Tier,
+		"threshold_days": s.stale.ThresholdDays(req.Tier),
+	}})
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
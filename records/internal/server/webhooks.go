@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"records/internal/models"
+	"records/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+// 出站 Webhook 管理（admin 权限）：订阅增删改、轮换签名密钥、发送测试事件、查看投递记录与手动重试
+
+const defaultWebhookRetention = 30 * 24 * time.Hour
+
+type webhookRequest struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"` // 为空时创建默认启用、修改时保持不变
+}
+
+// webhookView 管理页展示的订阅：附投递统计
+type webhookView struct {
+	*webhook.Subscription
+	Stats webhook.Stats `json:"stats"`
+}
+
+// validate 校验并规范化请求，返回错误提示
+func (req *webhookRequest) validate() string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > 100 {
+		return "名称不能为空且不超过 100 字"
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(req.URL) > 2048 {
+		return "无效的回调地址，须为 http(s) URL"
+	}
+	if len(req.Events) == 0 {
+		return "至少选择一个事件"
+	}
+	var events []string
+	for _, e := range req.Events {
+		if !containsString(models.WebhookEvents, e) {
+			return "未知的事件：" + e
+		}
+		if !containsString(events, e) {
+			events = append(events, e)
+		}
+	}
+	req.Events = events
+	return ""
+}
+
+// adminWebhooksHandler GET {apiP}/admin/webhooks 列出订阅（含投递统计与可订阅事件）；POST 新建订阅，签名密钥仅在响应中返回一次
+func (s *Server) adminWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path != s.apiPrefix()+"/admin/webhooks" {
+		http.NotFound(w, r)
+		return
+	}
+	adminID, ok := s.adminUserIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	repo := s.webhooks.Repo()
+	if r.Method == http.MethodPost {
+		var req webhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+			return
+		}
+		if msg := req.validate(); msg != "" {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: msg})
+			return
+		}
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Generate webhook secret failed", "error", err)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "创建订阅失败"})
+			return
+		}
+		sub := &webhook.Subscription{Name: req.Name, URL: req.URL, Secret: secret, Events: req.Events, Active: req.Active == nil || *req.Active, CreatedBy: adminID}
+		if err := repo.CreateSubscription(ctx, sub); err != nil {
+			s.logger.WithContext(r.Context()).Error("Create webhook subscription failed", "error", err, "name", req.Name)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "创建订阅失败"})
+			return
+		}
+		s.logger.WithContext(r.Context()).Info("Webhook subscription created", "id", sub.ID, "url", sub.URL, "events", strings.Join(sub.Events, ","), "by", adminID)
+		s.writePageJSON(w, http.StatusCreated, pageAPIResponse{Success: true, Data: map[string]interface{}{
+			"subscription": webhookView{Subscription: sub},
+			"secret":       secret,
+		}, Message: "请立即保存签名密钥，关闭后无法再次查看"})
+		return
+	}
+
+	subs, err := repo.ListSubscriptions(ctx)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("List webhook subscriptions failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询订阅失败"})
+		return
+	}
+	stats, err := repo.SubscriptionStats(ctx)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Query webhook stats failed", "error", err)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询订阅失败"})
+		return
+	}
+	views := make([]webhookView, 0, len(subs))
+	for _, sub := range subs {
+		views = append(views, webhookView{Subscription: sub, Stats: stats[sub.ID]})
+	}
+	s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{
+		"subscriptions": views,
+		"events":        models.WebhookEvents,
+	}})
+}
+
+// adminWebhookSubHandler 处理 {apiP}/admin/webhooks/{id}（PUT 修改、DELETE 删除）、/{id}/rotate_secret（POST 轮换密钥）、
+// /{id}/test（POST 发送测试事件）、/{id}/deliveries（GET 投递记录，?status=&before=&limit=）
+func (s *Server) adminWebhookSubHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/admin/webhooks/"), "/"), "/")
+	if len(parts) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := uuid.Parse(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+	adminID, ok := s.adminUserIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	repo := s.webhooks.Repo()
+	sub, err := repo.GetSubscription(ctx, id)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Get webhook subscription failed", "error", err, "id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询订阅失败"})
+		return
+	}
+	if sub == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "订阅不存在"})
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodPut:
+		var req webhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的请求体"})
+			return
+		}
+		if msg := req.validate(); msg != "" {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: msg})
+			return
+		}
+		sub.Name, sub.URL, sub.Events = req.Name, req.URL, req.Events
+		if req.Active != nil {
+			sub.Active = *req.Active
+		}
+		if _, err := repo.UpdateSubscription(ctx, sub); err != nil {
+			s.logger.WithContext(r.Context()).Error("Update webhook subscription failed", "error", err, "id", id)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "保存订阅失败"})
+			return
+		}
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: webhookView{Subscription: sub}})
+
+	case action == "" && r.Method == http.MethodDelete:
+		if _, err := repo.DeleteSubscription(ctx, id); err != nil {
+			s.logger.WithContext(r.Context()).Error("Delete webhook subscription failed", "error", err, "id", id)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "删除订阅失败"})
+			return
+		}
+		s.logger.WithContext(r.Context()).Info("Webhook subscription deleted", "id", id, "url", sub.URL, "by", adminID)
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true})
+
+	case action == "rotate_secret" && r.Method == http.MethodPost:
+		secret, err := webhook.NewSecret()
+		if err == nil {
+			_, err = repo.RotateSecret(ctx, id, secret)
+		}
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Rotate webhook secret failed", "error", err, "id", id)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "轮换密钥失败"})
+			return
+		}
+		s.logger.WithContext(r.Context()).Info("Webhook secret rotated", "id", id, "by", adminID)
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: map[string]interface{}{"secret": secret},
+			Message: "请立即保存新的签名密钥，之后的投递（含重试）均使用新密钥签名"})
+
+	case action == "test" && r.Method == http.MethodPost:
+		payload, _ := json.Marshal(map[string]interface{}{"subscription_id": id, "triggered_by": adminID})
+		d, err := repo.EnqueuePing(ctx, id, payload)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("Enqueue webhook ping failed", "error", err, "id", id)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "发送测试事件失败"})
+			return
+		}
+		msg := "测试事件已加入投递队列"
+		if !sub.Active {
+			msg += "；订阅已停用，启用后才会投递"
+		}
+		s.writePageJSON(w, http.StatusAccepted, pageAPIResponse{Success: true, Data: d, Message: msg})
+
+	case action == "deliveries" && r.Method == http.MethodGet:
+		q := r.URL.Query()
+		limit, err := parseLimit(q)
+		if err != nil {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: err.Error()})
+			return
+		}
+		if limit == 0 || limit > 200 {
+			limit = 50
+		}
+		status := q.Get("status")
+		if status != "" && status != webhook.StatusPending && status != webhook.StatusSucceeded && status != webhook.StatusFailed {
+			s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的 status"})
+			return
+		}
+		var before int64
+		if v := q.Get("before"); v != "" {
+			if before, err = strconv.ParseInt(v, 10, 64); err != nil || before <= 0 {
+				s.writePageJSON(w, http.StatusBadRequest, pageAPIResponse{Success: false, Message: "无效的 before"})
+				return
+			}
+		}
+		list, err := repo.ListDeliveries(ctx, id, status, before, limit)
+		if err != nil {
+			s.logger.WithContext(r.Context()).Error("List webhook deliveries failed", "error", err, "id", id)
+			s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "查询投递记录失败"})
+			return
+		}
+		s.writePageJSON(w, http.StatusOK, pageAPIResponse{Success: true, Data: list})
+
+	case action == "" || action == "rotate_secret" || action == "test" || action == "deliveries":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// adminWebhookDeliverySubHandler POST {apiP}/admin/webhook_deliveries/{id}/retry 立即重试一次投递（含已成功或已放弃的）
+func (s *Server) adminWebhookDeliverySubHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, s.apiPrefix()+"/admin/webhook_deliveries/"), "/"), "/")
+	if len(parts) != 2 || parts[1] != "retry" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	adminID, ok := s.adminUserIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	d, err := s.webhooks.Repo().RetryDelivery(r.Context(), id)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Error("Retry webhook delivery failed", "error", err, "delivery_id", id)
+		s.writePageJSON(w, http.StatusInternalServerError, pageAPIResponse{Success: false, Message: "重试失败"})
+		return
+	}
+	if d == nil {
+		s.writePageJSON(w, http.StatusNotFound, pageAPIResponse{Success: false, Message: "投递记录不存在"})
+		return
+	}
+	s.logger.WithContext(r.Context()).Info("Webhook delivery retried", "delivery_id", id, "by", adminID)
+	s.writePageJSON(w, http.StatusAccepted, pageAPIResponse{Success: true, Data: d})
+}
+
+// runWebhookCleanup 删除超过保留时长的已投递事件及其投递记录
+func (s *Server) runWebhookCleanup(ctx context.Context) error {
+	retention := s.config.Webhook.Retention
+	if retention <= 0 {
+		retention = defaultWebhookRetention
+	}
+	deleted, err := s.webhooks.Repo().Cleanup(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.logger.WithContext(ctx).Info("Cleaned up webhook events", "deleted", deleted)
+	}
+	return nil
+}
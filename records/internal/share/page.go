@@ -0,0 +1,67 @@
+package share
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// pageTemplate 公开分享页：只读时间线，样式沿用 manager.html 跟进详情
+var pageTemplate = template.Must(template.New("share").Funcs(template.FuncMap{
+	"datetime": func(t time.Time) string { return t.In(time.Local).Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<meta name="robots" content="noindex, nofollow">
+<title>{{if .Timeline}}{{.Timeline.CustomerName}} · 跟进记录{{else}}分享链接{{end}}</title>
+<style>
+  body { margin: 0; font-family: -apple-system, BlinkMacSystemFont, "PingFang SC", "Microsoft YaHei", sans-serif; background: #f5f6f7; color: #1f2329; }
+  .page { max-width: 720px; margin: 0 auto; padding: 16px; }
+  .card { background: #fff; border-radius: 8px; padding: 16px; margin-bottom: 12px; }
+  .title { font-size: 18px; font-weight: 600; }
+  .meta { color: #8f959e; font-size: 13px; margin-top: 6px; }
+  .item-head { display: flex; justify-content: space-between; color: #646a73; font-size: 13px; margin-bottom: 8px; }
+  .field { font-size: 14px; line-height: 1.6; margin-top: 4px; white-space: pre-wrap; word-break: break-word; }
+  .label { color: #8f959e; }
+  .empty { text-align: center; color: #8f959e; padding: 48px 0; }
+</style>
+</head>
+<body>
+<div class="page">
+{{if .Timeline}}
+  <div class="card">
+    <div class="title">{{.Timeline.CustomerName}}</div>
+    <div class="meta">共 {{len .Timeline.Items}} 次跟进 · {{.Timeline.CreatorName}} 分享 · 有效期至 {{datetime .Timeline.ExpiresAt}}</div>
+  </div>
+  {{range .Timeline.Items}}
+  <div class="card">
+    <div class="item-head"><span>{{datetime .FollowTime}} · {{.FollowMethod}}</span><span>{{.UserName}}</span></div>
+    {{if .FollowContent}}<div class="field"><span class="label">跟进事项：</span>{{.FollowContent}}</div>{{end}}
+    {{if .ContactPerson}}<div class="field"><span class="label">联系人：</span>{{.ContactPerson}}{{if .ContactRole}}（{{.ContactRole}}）{{end}}{{if .ContactPhone}} {{.ContactPhone}}{{end}}</div>{{end}}
+    {{if .FollowGoal}}<div class="field"><span class="label">预期目标：</span>{{.FollowGoal}}</div>{{end}}
+    {{if .FollowResult}}<div class="field"><span class="label">实际结果：</span>{{.FollowResult}}</div>{{end}}
+    {{if .RiskContent}}<div class="field"><span class="label">存在风险：</span>{{.RiskContent}}</div>{{end}}
+    {{if .NextPlan}}<div class="field"><span class="label">下一步计划：</span>{{.NextPlan}}</div>{{end}}
+  </div>
+  {{else}}
+  <div class="card empty">暂无跟进记录</div>
+  {{end}}
+{{else}}
+  <div class="card empty">{{.Message}}</div>
+{{end}}
+</div>
+</body>
+</html>
+`))
+
+// RenderTimeline 渲染分享时间线
+func RenderTimeline(w io.Writer, t *Timeline) error {
+	return pageTemplate.Execute(w, map[string]interface{}{"Timeline": t})
+}
+
+// RenderMessage 渲染提示页（链接无效、已过期或已撤销）
+func RenderMessage(w io.Writer, message string) error {
+	return pageTemplate.Execute(w, map[string]interface{}{"Timeline": (*Timeline)(nil), "Message": message})
+}
@@ -0,0 +1,101 @@
+package share
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// shareColumns 分享查询列（s 为 record_shares，u 为创建人）
+const shareColumns = `s.id, s.customer_id, s.customer_name, s.user_id, s.created_by, COALESCE(u.name, s.created_by) AS creator_name,
+	COALESCE(u.status, 1) AS creator_status, s.expires_at, s.revoked_at, s.revoked_by, s.view_count, s.last_viewed_at, s.created_at`
+
+// Repo 分享链接数据访问
+type Repo struct {
+	db *sqlx.DB
+}
+
+// NewRepo 创建 Repo
+func NewRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+// Create 新建分享
+func (r *Repo) Create(ctx context.Context, s *Share) error {
+	query := `INSERT INTO record_shares (id, customer_id, customer_name, user_id, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at`
+	if err := r.db.GetContext(ctx, &s.CreatedAt, query, s.ID, s.CustomerID, s.CustomerName, s.UserID, s.CreatedBy, s.ExpiresAt); err != nil {
+		return fmt.Errorf("create share customer=%s: %w", s.CustomerID, err)
+	}
+	return nil
+}
+
+// Get 按 ID 查询分享，不存在返回 nil
+func (r *Repo) Get(ctx context.Context, id uuid.UUID) (*Share, error) {
+	var s Share
+	query := `SELECT ` + shareColumns + ` FROM record_shares s LEFT JOIN users u ON u.id = s.created_by WHERE s.id = $1`
+	if err := r.db.GetContext(ctx, &s, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get share id=%s: %w", id, err)
+	}
+	return &s, nil
+}
+
+// List 列出分享，最新在前；createdBy 为空表示全部创建人，customerID 为 nil 表示全部客户
+func (r *Repo) List(ctx context.Context, createdBy string, customerID *uuid.UUID, limit int) ([]*Share, error) {
+	list := []*Share{}
+	query := `SELECT ` + shareColumns + ` FROM record_shares s LEFT JOIN users u ON u.id = s.created_by
+		WHERE ($1 = '' OR s.created_by = $1) AND ($2::uuid IS NULL OR s.customer_id = $2)
+		ORDER BY s.created_at DESC LIMIT $3`
+	if err := r.db.SelectContext(ctx, &list, query, createdBy, customerID, limit); err != nil {
+		return nil, fmt.Errorf("list shares: %w", err)
+	}
+	return list, nil
+}
+
+// Revoke 撤销分享，已撤销的返回 false
+func (r *Repo) Revoke(ctx context.Context, id uuid.UUID, by string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE record_shares SET revoked_at = NOW(), revoked_by = $2 WHERE id = $1 AND revoked_at IS NULL`, id, by)
+	if err != nil {
+		return false, fmt.Errorf("revoke share id=%s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// CountView 访问计数加一；已撤销或已过期（以数据库时间为准）时返回 false
+func (r *Repo) CountView(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `UPDATE record_shares SET view_count = view_count + 1, last_viewed_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("count share view id=%s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// LogAccess 写入访问审计
+func (r *Repo) LogAccess(ctx context.Context, l *AccessLog) error {
+	query := `INSERT INTO record_share_access_logs (share_id, outcome, ip, user_agent, records) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := r.db.ExecContext(ctx, query, l.ShareID, l.Outcome, l.IP, l.UserAgent, l.Records); err != nil {
+		return fmt.Errorf("log share access: %w", err)
+	}
+	return nil
+}
+
+// ListAccessLogs 返回分享的访问审计，最新在前
+func (r *Repo) ListAccessLogs(ctx context.Context, shareID uuid.UUID, limit int) ([]*AccessLog, error) {
+	list := []*AccessLog{}
+	query := `SELECT id, share_id, outcome, ip, user_agent, records, accessed_at FROM record_share_access_logs
+		WHERE share_id = $1 ORDER BY accessed_at DESC, id DESC LIMIT $2`
+	if err := r.db.SelectContext(ctx, &list, query, shareID, limit); err != nil {
+		return nil, fmt.Errorf("list share access logs id=%s: %w", shareID, err)
+	}
+	return list, nil
+}
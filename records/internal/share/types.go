@@ -0,0 +1,74 @@
+package share
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 访问结果（审计）
+const (
+	OutcomeOK       = "ok"
+	OutcomeInvalid  = "invalid"   // 令牌签名或格式无效
+	OutcomeExpired  = "expired"   // 已过期
+	OutcomeRevoked  = "revoked"   // 已撤销
+	OutcomeNotFound = "not_found" // 分享记录不存在
+	OutcomeInactive = "inactive"  // 创建人已离职或已无权查看
+	OutcomeError    = "error"     // 服务端错误
+)
+
+// Share record_shares 表一行
+type Share struct {
+	ID           uuid.UUID  `db:"id" json:"id"`
+	CustomerID   uuid.UUID  `db:"customer_id" json:"customer_id"`
+	CustomerName string     `db:"customer_name" json:"customer_name"`
+	UserID       *string    `db:"user_id" json:"user_id,omitempty"`
+	CreatedBy    string     `db:"created_by" json:"created_by"`
+	CreatorName  string     `db:"creator_name" json:"creator_name"`
+	CreatorState int        `db:"creator_status" json:"-"`
+	ExpiresAt    time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt    *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	RevokedBy    *string    `db:"revoked_by" json:"revoked_by,omitempty"`
+	ViewCount    int        `db:"view_count" json:"view_count"`
+	LastViewedAt *time.Time `db:"last_viewed_at" json:"last_viewed_at,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Active 未撤销且未过期
+func (s *Share) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// AccessLog record_share_access_logs 表一行
+type AccessLog struct {
+	ID         int64      `db:"id" json:"id"`
+	ShareID    *uuid.UUID `db:"share_id" json:"share_id,omitempty"`
+	Outcome    string     `db:"outcome" json:"outcome"`
+	IP         string     `db:"ip" json:"ip"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	Records    int        `db:"records" json:"records"`
+	AccessedAt time.Time  `db:"accessed_at" json:"accessed_at"`
+}
+
+// TimelineItem 公开页中的一条跟进（联系电话已脱敏）
+type TimelineItem struct {
+	FollowTime    time.Time
+	UserName      string
+	FollowMethod  string
+	ContactPerson string
+	ContactPhone  string
+	ContactRole   string
+	FollowGoal    string
+	FollowContent string
+	FollowResult  string
+	RiskContent   string
+	NextPlan      string
+}
+
+// Timeline 公开页数据
+type Timeline struct {
+	CustomerName string
+	CreatorName  string
+	ExpiresAt    time.Time
+	Items        []TimelineItem
+}
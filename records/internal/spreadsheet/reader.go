@@ -0,0 +1,268 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MaxReadSize 读取表格文件的大小上限
+const MaxReadSize = 20 << 20
+
+// XLSX 解压与解析上限：MaxReadSize 只限制压缩后的文件大小，需另外限制单个压缩项解压后的大小与行列号，
+// 防止压缩炸弹或伪造的超大行号/列号耗尽内存
+const (
+	maxXLSXEntrySize = 100 << 20
+	maxXLSXColumns   = 16384 // Excel 最大列数（XFD）
+)
+
+// errEntryTooLarge 压缩项解压后超过 maxXLSXEntrySize
+var errEntryTooLarge = fmt.Errorf("xlsx entry exceeds %d MB uncompressed", maxXLSXEntrySize>>20)
+
+// limitedReader 与 io.LimitReader 类似，但超出上限时返回错误而非静默截断
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// 恰好读满上限时再探测一个字节，区分“正好到达上限”与“超出上限”
+		var b [1]byte
+		if n, _ := l.r.Read(b[:]); n > 0 {
+			return 0, errEntryTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// FormatFromFileName 按扩展名判断格式；无法识别时返回空
+func FormatFromFileName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	default:
+		return ""
+	}
+}
+
+// ReadAll 读取 CSV 或 XLSX（第一个工作表）的全部行；单元格均为文本，XLSX 中的日期为 Excel 序列号。
+// maxRows 为允许的最大行数（含表头），超出时返回错误
+func ReadAll(r io.Reader, format string, maxRows int) ([][]string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxReadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read spreadsheet: %w", err)
+	}
+	if len(data) > MaxReadSize {
+		return nil, fmt.Errorf("spreadsheet exceeds %d MB", MaxReadSize>>20)
+	}
+	switch format {
+	case FormatCSV:
+		return readCSV(data, maxRows)
+	case FormatXLSX:
+		return readXLSX(data, maxRows)
+	default:
+		return nil, fmt.Errorf("unsupported spreadsheet format: %s", format)
+	}
+}
+
+func readCSV(data []byte, maxRows int) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	var rows [][]string
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse csv: %w", err)
+		}
+		if len(rows) >= maxRows {
+			return nil, fmt.Errorf("spreadsheet exceeds %d rows", maxRows)
+		}
+		rows = append(rows, row)
+	}
+}
+
+type xlsxRels struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var sb strings.Builder
+	sb.WriteString(t.T)
+	for _, r := range t.Runs {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+type xlsxSST struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"` // 行号（从 1 开始），空行不出现在文件中
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(data []byte, maxRows int) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open xlsx: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	decode := func(name string, v interface{}) (bool, error) {
+		f, ok := files[name]
+		if !ok {
+			return false, nil
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return true, err
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(&limitedReader{r: rc, n: maxXLSXEntrySize}).Decode(v); err != nil {
+			return true, fmt.Errorf("parse %s: %w", name, err)
+		}
+		return true, nil
+	}
+
+	// 第一个工作表：workbook.xml 中的首个 sheet 经 workbook.xml.rels 定位
+	sheetPath := "xl/worksheets/sheet1.xml"
+	var wb xlsxWorkbook
+	var rels xlsxRels
+	if ok, err := decode("xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	} else if ok && len(wb.Sheets) > 0 {
+		if _, err := decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+			return nil, err
+		}
+		for _, rel := range rels.Relationships {
+			if rel.ID == wb.Sheets[0].RID {
+				if strings.HasPrefix(rel.Target, "/") {
+					sheetPath = strings.TrimPrefix(rel.Target, "/")
+				} else {
+					sheetPath = path.Join("xl", rel.Target)
+				}
+				break
+			}
+		}
+	}
+
+	var sst xlsxSST
+	if _, err := decode("xl/sharedStrings.xml", &sst); err != nil {
+		return nil, err
+	}
+	var sheet xlsxSheet
+	if ok, err := decode(sheetPath, &sheet); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("xlsx worksheet %s not found", sheetPath)
+	}
+
+	if len(sheet.Rows) > maxRows {
+		return nil, fmt.Errorf("spreadsheet exceeds %d rows", maxRows)
+	}
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		// 行号来自文件，补齐前先校验，避免伪造的超大行号导致无界分配
+		if row.Index > maxRows {
+			return nil, fmt.Errorf("spreadsheet exceeds %d rows", maxRows)
+		}
+		// 补齐被省略的空行，保证行号与 Excel 中一致
+		for row.Index > 0 && len(rows) < row.Index-1 {
+			rows = append(rows, nil)
+		}
+		var cells []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			if col >= maxXLSXColumns {
+				return nil, fmt.Errorf("xlsx cell %s exceeds %d columns", c.Ref, maxXLSXColumns)
+			}
+			for len(cells) < col {
+				cells = append(cells, "")
+			}
+			var v string
+			switch c.Type {
+			case "s":
+				var idx int
+				if _, err := fmt.Sscanf(c.Value, "%d", &idx); err == nil && idx >= 0 && idx < len(sst.Items) {
+					v = sst.Items[idx].String()
+				}
+			case "inlineStr":
+				v = c.Inline.String()
+			default:
+				v = c.Value
+			}
+			if col < len(cells) {
+				cells[col] = v
+			} else {
+				cells = append(cells, v)
+			}
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+// columnIndex 将单元格引用（如 "AB12"）的列字母转为从 0 开始的列号
+func columnIndex(ref string) int {
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' || n > maxXLSXColumns {
+			break
+		}
+		n = n*26 + int(ch-'A'+1)
+	}
+	return n - 1
+}
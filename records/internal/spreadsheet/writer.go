@@ -0,0 +1,213 @@
+// Package spreadsheet 提供流式的 CSV / XLSX 表格输出，逐行写入 io.Writer，不在内存中缓存整张表
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// 支持的格式
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// flushEvery 每写入多少行刷新一次底层 Writer（HTTP 响应可边查边发）
+const flushEvery = 200
+
+// maxCellRunes Excel 单元格最大字符数
+const maxCellRunes = 32767
+
+// Writer 表格逐行写入器；首行通常为表头
+type Writer interface {
+	WriteRow(cells []string) error
+	// Close 写出剩余内容（XLSX 的文件尾），不关闭底层 Writer
+	Close() error
+}
+
+// ContentType 返回格式对应的 Content-Type
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// NewWriter 按格式创建写入器；format 非 csv/xlsx 时返回错误
+func NewWriter(w io.Writer, format, sheetName string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatXLSX:
+		return newXLSXWriter(w, sheetName)
+	default:
+		return nil, fmt.Errorf("unsupported spreadsheet format: %s", format)
+	}
+}
+
+type flusher interface{ Flush() }
+
+// csvWriter 带 UTF-8 BOM 的 CSV，保证 Excel 直接打开时中文不乱码
+type csvWriter struct {
+	out  io.Writer
+	cw   *csv.Writer
+	rows int
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return nil, err
+	}
+	return &csvWriter{out: w, cw: csv.NewWriter(w)}, nil
+}
+
+func (c *csvWriter) WriteRow(cells []string) error {
+	if err := c.cw.Write(cells); err != nil {
+		return err
+	}
+	c.rows++
+	if c.rows%flushEvery == 0 {
+		c.cw.Flush()
+		if f, ok := c.out.(flusher); ok {
+			f.Flush()
+		}
+	}
+	return c.cw.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+// xlsxWriter 最小化的 XLSX：单工作表、内联字符串（无 sharedStrings），首行加粗
+type xlsxWriter struct {
+	out   io.Writer
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+// 样式：0 为默认，1 为加粗（表头）
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+</styleSheet>`
+
+func newXLSXWriter(w io.Writer, sheetName string) (*xlsxWriter, error) {
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	}
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="` + escape(sheetName) + `" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, p := range parts {
+		pw, err := zw.Create(p.name)
+		if err != nil {
+			return nil, fmt.Errorf("create xlsx part %s: %w", p.name, err)
+		}
+		if _, err := io.WriteString(pw, p.content); err != nil {
+			return nil, fmt.Errorf("write xlsx part %s: %w", p.name, err)
+		}
+	}
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("create xlsx sheet: %w", err)
+	}
+	if _, err := io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{out: w, zw: zw, sheet: sheet}, nil
+}
+
+func (x *xlsxWriter) WriteRow(cells []string) error {
+	style := ""
+	if x.rows == 0 {
+		style = ` s="1"`
+	}
+	var buf bytes.Buffer
+	buf.WriteString("<row>")
+	for _, c := range cells {
+		buf.WriteString(`<c t="inlineStr"` + style + `><is><t xml:space="preserve">`)
+		buf.WriteString(escape(truncateRunes(c, maxCellRunes)))
+		buf.WriteString(`</t></is></c>`)
+	}
+	buf.WriteString("</row>")
+	if _, err := x.sheet.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	x.rows++
+	if x.rows%flushEvery == 0 {
+		if err := x.zw.Flush(); err != nil {
+			return err
+		}
+		if f, ok := x.out.(flusher); ok {
+			f.Flush()
+		}
+	}
+	return nil
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// escape XML 转义；非法控制字符由 xml.EscapeText 替换为 U+FFFD
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func truncateRunes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,192 @@
+package stale
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"records/pkg/logger"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	defaultThresholdDays = 30
+	defaultLookbackDays  = 180
+	defaultRenotify      = 7 * 24 * time.Hour
+
+	// maxCardItems 单张提醒卡片最多列出的客户数
+	maxCardItems = 10
+)
+
+// Sender 卡片发送方（feishu.Client 实现）
+type Sender interface {
+	SendCardToUser(ctx context.Context, userID string, card map[string]interface{}) error
+}
+
+// Config 判定配置
+type Config struct {
+	DefaultDays  int            // 未分级或分级未配置时的判定天数，默认 30
+	TierDays     map[string]int // 按客户分级的判定天数
+	LookbackDays int            // 仅统计该天数内跟进过的客户，更早的视为已放弃，默认 180
+	Renotify     time.Duration  // 仍未跟进时再次提醒的间隔，默认 7 天
+}
+
+// Detector 久未跟进客户检测与提醒
+type Detector struct {
+	repo   *Repo
+	sender Sender
+	cfg    Config
+	log    logger.Logger
+}
+
+// NewDetector 创建检测器
+func NewDetector(db *sqlx.DB, sender Sender, cfg Config, log logger.Logger) *Detector {
+	if cfg.DefaultDays <= 0 {
+		cfg.DefaultDays = defaultThresholdDays
+	}
+	if cfg.LookbackDays <= 0 {
+		cfg.LookbackDays = defaultLookbackDays
+	}
+	if cfg.Renotify <= 0 {
+		cfg.Renotify = defaultRenotify
+	}
+	return &Detector{repo: NewRepo(db), sender: sender, cfg: cfg, log: log}
+}
+
+// Repo 返回数据访问（管理 API 设置客户分级）
+func (d *Detector) Repo() *Repo {
+	return d.repo
+}
+
+// ThresholdDays 返回客户分级对应的判定天数
+func (d *Detector) ThresholdDays(tier string) int {
+	if days, ok := d.cfg.TierDays[tier]; ok && days > 0 {
+		return days
+	}
+	return d.cfg.DefaultDays
+}
+
+// ValidTier 分级是否已在配置中定义（空表示默认级别）
+func (d *Detector) ValidTier(tier string) bool {
+	if tier == "" {
+		return true
+	}
+	_, ok := d.cfg.TierDays[tier]
+	return ok
+}
+
+// AtRisk 返回范围内超过判定天数未跟进的客户（按销售、最近跟进时间升序）；userIDs 为 nil 表示全部用户
+func (d *Detector) AtRisk(ctx context.Context, userIDs []string, now time.Time) ([]*Account, error) {
+	accounts, err := d.repo.ListAccounts(ctx, userIDs, now.AddDate(0, 0, -d.cfg.LookbackDays))
+	if err != nil {
+		return nil, err
+	}
+	var list []*Account
+	for _, a := range accounts {
+		a.ThresholdDays = d.ThresholdDays(a.Tier)
+		a.IdleDays = int(now.Sub(a.LastFollowAt).Hours() / 24)
+		if a.IdleDays >= a.ThresholdDays {
+			list = append(list, a)
+		}
+	}
+	return list, nil
+}
+
+// Notify 向各销售推送其久未跟进的客户（每人一张卡片）；同一客户在未被再次跟进前按 Renotify 间隔重复提醒。返回提醒的销售数
+func (d *Detector) Notify(ctx context.Context, now time.Time) (int, error) {
+	list, err := d.AtRisk(ctx, nil, now)
+	if err != nil {
+		return 0, err
+	}
+	byUser := make(map[string][]*Account)
+	var users []string
+	for _, a := range list {
+		if _, ok := byUser[a.UserID]; !ok {
+			users = append(users, a.UserID)
+		}
+		byUser[a.UserID] = append(byUser[a.UserID], a)
+	}
+
+	sent, failed := 0, 0
+	for _, userID := range users {
+		alerts, err := d.repo.GetAlerts(ctx, userID)
+		if err != nil {
+			return sent, err
+		}
+		var due []*Account
+		for _, a := range byUser[userID] {
+			prev, ok := alerts[a.CustomerID]
+			if ok && !prev.LastFollowAt.Before(a.LastFollowAt) && now.Sub(prev.NotifiedAt) < d.cfg.Renotify {
+				continue
+			}
+			due = append(due, a)
+		}
+		if len(due) == 0 {
+			continue
+		}
+		if err := d.sender.SendCardToUser(ctx, userID, staleCard(due)); err != nil {
+			d.log.WithContext(ctx).Error("Failed to send stale customer nudge", "user_id", userID, "error", err)
+			failed++
+			continue
+		}
+		sent++
+		for _, a := range due {
+			if err := d.repo.MarkNotified(ctx, userID, a.CustomerID, a.LastFollowAt, now); err != nil {
+				d.log.WithContext(ctx).Error("Failed to mark stale customer notified", "user_id", userID, "customer_id", a.CustomerID, "error", err)
+			}
+		}
+	}
+	if failed > 0 {
+		return sent, fmt.Errorf("%d stale customer nudges failed", failed)
+	}
+	return sent, nil
+}
+
+// staleCard 构建久未跟进提醒卡片：列出客户、未跟进天数、上次跟进结果与下一步计划
+func staleCard(accounts []*Account) map[string]interface{} {
+	elements := []interface{}{
+		map[string]interface{}{
+			"tag":  "div",
+			"text": map[string]interface{}{"tag": "lark_md", "content": fmt.Sprintf("以下 **%d** 个客户已经有一段时间没有跟进了，找时间联系一下吧~", len(accounts))},
+		},
+	}
+	for i, a := range accounts {
+		if i >= maxCardItems {
+			elements = append(elements, map[string]interface{}{
+				"tag":      "note",
+				"elements": []interface{}{map[string]interface{}{"tag": "plain_text", "content": fmt.Sprintf("还有 %d 个客户未列出", len(accounts)-maxCardItems)}},
+			})
+			break
+		}
+		lines := []string{fmt.Sprintf("**%s**　%d 天未跟进（上次 %s）", a.CustomerName, a.IdleDays, a.LastFollowAt.In(time.Local).Format("2006-01-02"))}
+		if s := trimPtr(a.FollowResult); s != "" {
+			lines = append(lines, "上次结果："+s)
+		}
+		if s := trimPtr(a.NextPlan); s != "" {
+			lines = append(lines, "计划下一步："+s)
+		}
+		elements = append(elements,
+			map[string]interface{}{"tag": "hr"},
+			map[string]interface{}{
+				"tag":  "div",
+				"text": map[string]interface{}{"tag": "lark_md", "content": strings.Join(lines, "\n")},
+			})
+	}
+	return map[string]interface{}{
+		"config": map[string]interface{}{"wide_screen_mode": true},
+		"header": map[string]interface{}{
+			"template": "orange",
+			"title":    map[string]interface{}{"tag": "plain_text", "content": "客户久未跟进提醒"},
+		},
+		"elements": elements,
+	}
+}
+
+func trimPtr(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
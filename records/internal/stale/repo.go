@@ -0,0 +1,95 @@
+package stale
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Repo 久未跟进客户相关数据访问
+type Repo struct {
+	db *sqlx.DB
+}
+
+// NewRepo 创建 Repo
+func NewRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+// ListAccounts 返回在职销售在 since 之后跟进过的客户及各自最近一次跟进；userIDs 为 nil 表示全部用户
+func (r *Repo) ListAccounts(ctx context.Context, userIDs []string, since time.Time) ([]*Account, error) {
+	args := []interface{}{since}
+	cond := ""
+	if userIDs != nil {
+		args = append(args, pq.Array(userIDs))
+		cond = " AND fr.user_id = ANY($2)"
+	}
+	query := `SELECT l.user_id, l.user_name, l.customer_id, c.name AS customer_name, COALESCE(c.tier, '') AS tier,
+		l.last_follow_at, l.follow_result, l.next_plan
+		FROM (
+			SELECT DISTINCT ON (fr.customer_id, fr.user_id)
+				fr.user_id, COALESCE(u.name, fr.user_id) AS user_name, fr.customer_id,
+				fr.follow_time AS last_follow_at, fr.follow_result, fr.next_plan
+			FROM follow_records fr JOIN users u ON u.id = fr.user_id AND u.status = 0
+			WHERE fr.follow_time >= $1 AND fr.deleted_at IS NULL` + cond + `
+			ORDER BY fr.customer_id, fr.user_id, fr.follow_time DESC
+		) l JOIN customers c ON c.id = l.customer_id
+		ORDER BY l.user_id, l.last_follow_at`
+	var list []*Account
+	if err := r.db.SelectContext(ctx, &list, query, args...); err != nil {
+		return nil, fmt.Errorf("list stale accounts: %w", err)
+	}
+	return list, nil
+}
+
+// GetAlerts 返回指定销售的提醒记录，key 为客户 ID
+func (r *Repo) GetAlerts(ctx context.Context, userID string) (map[uuid.UUID]*Alert, error) {
+	var list []*Alert
+	if err := r.db.SelectContext(ctx, &list, `SELECT user_id, customer_id, last_follow_at, notified_at FROM stale_customer_alerts WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("get stale alerts user=%s: %w", userID, err)
+	}
+	m := make(map[uuid.UUID]*Alert, len(list))
+	for _, a := range list {
+		m[a.CustomerID] = a
+	}
+	return m, nil
+}
+
+// MarkNotified 记录已提醒
+func (r *Repo) MarkNotified(ctx context.Context, userID string, customerID uuid.UUID, lastFollowAt, notifiedAt time.Time) error {
+	query := `INSERT INTO stale_customer_alerts (user_id, customer_id, last_follow_at, notified_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, customer_id) DO UPDATE SET last_follow_at = EXCLUDED.last_follow_at, notified_at = EXCLUDED.notified_at`
+	if _, err := r.db.ExecContext(ctx, query, userID, customerID, lastFollowAt, notifiedAt); err != nil {
+		return fmt.Errorf("mark stale alert user=%s customer=%s: %w", userID, customerID, err)
+	}
+	return nil
+}
+
+// GetCustomerTier 返回客户分级；客户不存在时 found 为 false
+func (r *Repo) GetCustomerTier(ctx context.Context, customerID uuid.UUID) (tier string, found bool, err error) {
+	err = r.db.GetContext(ctx, &tier, `SELECT COALESCE(tier, '') FROM customers WHERE id = $1`, customerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("get customer tier id=%s: %w", customerID, err)
+	}
+	return tier, true, nil
+}
+
+// SetCustomerTier 设置客户分级；tier 为空表示默认级别
+func (r *Repo) SetCustomerTier(ctx context.Context, customerID uuid.UUID, tier string) error {
+	var v interface{}
+	if tier != "" {
+		v = tier
+	}
+	if _, err := r.db.ExecContext(ctx, `UPDATE customers SET tier = $1, updated_at = NOW() WHERE id = $2`, v, customerID); err != nil {
+		return fmt.Errorf("set customer tier id=%s: %w", customerID, err)
+	}
+	return nil
+}
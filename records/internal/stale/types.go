@@ -0,0 +1,30 @@
+package stale
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Account 某销售名下的客户及其最近一次跟进（每个（客户, 销售）一条）
+type Account struct {
+	UserID       string    `db:"user_id" json:"user_id"`
+	UserName     string    `db:"user_name" json:"user_name"`
+	CustomerID   uuid.UUID `db:"customer_id" json:"customer_id"`
+	CustomerName string    `db:"customer_name" json:"customer_name"`
+	Tier         string    `db:"tier" json:"tier"`
+	LastFollowAt time.Time `db:"last_follow_at" json:"last_follow_at"`
+	FollowResult *string   `db:"follow_result" json:"follow_result,omitempty"`
+	NextPlan     *string   `db:"next_plan" json:"next_plan,omitempty"`
+
+	ThresholdDays int `db:"-" json:"threshold_days"` // 该客户级别的判定天数
+	IdleDays      int `db:"-" json:"idle_days"`      // 距最近跟进的天数
+}
+
+// Alert stale_customer_alerts 表一行
+type Alert struct {
+	UserID       string    `db:"user_id"`
+	CustomerID   uuid.UUID `db:"customer_id"`
+	LastFollowAt time.Time `db:"last_follow_at"`
+	NotifiedAt   time.Time `db:"notified_at"`
+}
@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"records/pkg/logger"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 出站 Webhook 分发：轮询发件箱展开为各订阅的投递，按 HMAC-SHA256 签名 POST JSON，失败按指数退避重试。
+// 多实例同时运行时以 FOR UPDATE SKIP LOCKED 分摊，同一投递不会被并发发送
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultTimeout      = 10 * time.Second
+	defaultMaxAttempts  = 8
+	defaultBackoffBase  = 30 * time.Second
+	defaultBackoffMax   = 6 * time.Hour
+
+	// batchSize 每轮分发的事件数与认领的投递数上限
+	batchSize = 50
+	// concurrency 每轮并发投递数
+	concurrency = 4
+	// maxResponseBytes 记录的响应体长度上限
+	maxResponseBytes = 1024
+)
+
+// 请求头
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Config 分发配置
+type Config struct {
+	PollInterval time.Duration // 轮询发件箱的间隔，默认 5s
+	Timeout      time.Duration // 单次投递超时，默认 10s
+	MaxAttempts  int           // 最多尝试次数（含首次），默认 8
+	BackoffBase  time.Duration // 首次重试等待，之后每次翻倍，默认 30s
+	BackoffMax   time.Duration // 重试等待上限，默认 6h
+}
+
+// Dispatcher 出站 Webhook 分发器
+type Dispatcher struct {
+	repo   *Repo
+	client *http.Client
+	cfg    Config
+	log    logger.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher 创建分发器，未配置的项取默认值
+func NewDispatcher(db *sqlx.DB, cfg Config, log logger.Logger) *Dispatcher {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = defaultBackoffBase
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = defaultBackoffMax
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		repo:   NewRepo(db),
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+		log:    log,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Repo 返回数据访问（管理 API 维护订阅与查看投递记录）
+func (d *Dispatcher) Repo() *Repo {
+	return d.repo
+}
+
+// Start 启动轮询
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go d.loop()
+}
+
+// Stop 停止轮询并等待进行中的投递结束
+func (d *Dispatcher) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) loop() {
+	defer d.wg.Done()
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := d.RunOnce(d.ctx); err != nil && d.ctx.Err() == nil {
+			d.log.Error("Webhook dispatch failed", "error", err)
+		}
+	}
+}
+
+// RunOnce 分发一轮：展开发件箱中的新事件，再投递到期的待投递
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	for {
+		n, err := d.repo.fanOut(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+		if n < batchSize {
+			break
+		}
+	}
+	// 认领期间推后下次尝试时间，超过单次超时仍未记录结果（如实例退出）的投递将被重新认领
+	due, err := d.repo.claimDue(ctx, batchSize, d.cfg.Timeout+time.Minute)
+	if err != nil {
+		return err
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, dd := range due {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(dd *dueDelivery) {
+			defer func() { <-sem; wg.Done() }()
+			d.deliver(ctx, dd)
+		}(dd)
+	}
+	wg.Wait()
+	return nil
+}
+
+// deliver 发送一次投递并记录结果
+func (d *Dispatcher) deliver(ctx context.Context, dd *dueDelivery) {
+	res := d.send(ctx, dd)
+	var retryAfter time.Duration
+	if res.Error != "" && dd.Attempts+1 < d.cfg.MaxAttempts {
+		retryAfter = Backoff(dd.Attempts+1, d.cfg.BackoffBase, d.cfg.BackoffMax)
+	}
+	// 停机时仍记录已完成的尝试
+	if err := d.repo.recordAttempt(context.WithoutCancel(ctx), dd.ID, res, retryAfter); err != nil {
+		d.log.WithContext(ctx).Error("Record webhook attempt failed", "error", err, "delivery_id", dd.ID)
+		return
+	}
+	if res.Error != "" {
+		d.log.WithContext(ctx).Warn("Webhook delivery failed", "delivery_id", dd.ID, "event", dd.Event, "attempt", dd.Attempts+1,
+			"status", res.StatusCode, "error", res.Error, "retry_after", retryAfter)
+	}
+}
+
+// send POST 事件到订阅地址，2xx 视为成功
+func (d *Dispatcher) send(ctx context.Context, dd *dueDelivery) attemptResult {
+	body, err := json.Marshal(Envelope{ID: dd.EventID, Event: dd.Event, OccurredAt: dd.OccurredAt, Data: dd.Payload})
+	if err != nil {
+		return attemptResult{Error: "marshal payload: " + err.Error()}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dd.URL, bytes.NewReader(body))
+	if err != nil {
+		return attemptResult{Error: "build request: " + err.Error()}
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "records-webhook/1.0")
+	req.Header.Set(HeaderEvent, dd.Event)
+	req.Header.Set(HeaderEventID, dd.EventID.String())
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(dd.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(dd.Secret, ts, body))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return attemptResult{Error: err.Error(), Duration: time.Since(start)}
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	res := attemptResult{StatusCode: resp.StatusCode, Response: string(bytes.ToValidUTF8(respBody, nil)), Duration: time.Since(start)}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		res.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+	return res
+}
+
+// Sign 计算签名：sha256=hex(HMAC-SHA256(secret, "{timestamp}.{body}"))。
+// 接收方应以同样方式计算并比对，同时校验时间戳以防重放
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff 第 attempt 次失败后的重试等待：base × 2^(attempt-1)，不超过 max
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < attempt && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
@@ -0,0 +1,243 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const subscriptionColumns = `id, name, url, secret, events, active, created_by, created_at, updated_at`
+
+const deliveryColumns = `id, subscription_id, event_id, event, status, attempts, next_attempt_at, last_status, last_error,
+	last_response, duration_ms, created_at, delivered_at, updated_at`
+
+// Repo 订阅、发件箱与投递数据访问
+type Repo struct {
+	db *sqlx.DB
+}
+
+// NewRepo 创建 Repo
+func NewRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+// NewSecret 生成签名密钥
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateSubscription 新建订阅
+func (r *Repo) CreateSubscription(ctx context.Context, s *Subscription) error {
+	s.ID = uuid.New()
+	query := `INSERT INTO webhook_subscriptions (id, name, url, secret, events, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at, updated_at`
+	if err := r.db.QueryRowxContext(ctx, query, s.ID, s.Name, s.URL, s.Secret, pq.Array([]string(s.Events)), s.Active, s.CreatedBy).
+		Scan(&s.CreatedAt, &s.UpdatedAt); err != nil {
+		return fmt.Errorf("create webhook subscription name=%s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// GetSubscription 按 ID 查询订阅，不存在返回 nil
+func (r *Repo) GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	var s Subscription
+	if err := r.db.GetContext(ctx, &s, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get webhook subscription id=%s: %w", id, err)
+	}
+	return &s, nil
+}
+
+// ListSubscriptions 列出全部订阅，最新在前
+func (r *Repo) ListSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	list := []*Subscription{}
+	if err := r.db.SelectContext(ctx, &list, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at DESC`); err != nil {
+		return nil, fmt.Errorf("list webhook subscriptions: %w", err)
+	}
+	return list, nil
+}
+
+// SubscriptionStats 返回各订阅的投递统计
+func (r *Repo) SubscriptionStats(ctx context.Context) (map[uuid.UUID]Stats, error) {
+	var rows []struct {
+		SubscriptionID uuid.UUID `db:"subscription_id"`
+		Stats
+	}
+	query := `SELECT subscription_id,
+		COUNT(*) FILTER (WHERE status = $1 AND updated_at >= NOW() - INTERVAL '24 hours') AS succeeded_24h,
+		COUNT(*) FILTER (WHERE status = $2 AND updated_at >= NOW() - INTERVAL '24 hours') AS failed_24h,
+		COUNT(*) FILTER (WHERE status = $3) AS pending
+		FROM webhook_deliveries WHERE status = $3 OR updated_at >= NOW() - INTERVAL '24 hours'
+		GROUP BY subscription_id`
+	if err := r.db.SelectContext(ctx, &rows, query, StatusSucceeded, StatusFailed, StatusPending); err != nil {
+		return nil, fmt.Errorf("webhook subscription stats: %w", err)
+	}
+	m := make(map[uuid.UUID]Stats, len(rows))
+	for _, row := range rows {
+		m[row.SubscriptionID] = row.Stats
+	}
+	return m, nil
+}
+
+// UpdateSubscription 修改名称、地址、事件与启用状态
+func (r *Repo) UpdateSubscription(ctx context.Context, s *Subscription) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE webhook_subscriptions SET name = $2, url = $3, events = $4, active = $5, updated_at = NOW() WHERE id = $1`,
+		s.ID, s.Name, s.URL, pq.Array([]string(s.Events)), s.Active)
+	if err != nil {
+		return false, fmt.Errorf("update webhook subscription id=%s: %w", s.ID, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RotateSecret 更换签名密钥，之后的投递使用新密钥
+func (r *Repo) RotateSecret(ctx context.Context, id uuid.UUID, secret string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE webhook_subscriptions SET secret = $2, updated_at = NOW() WHERE id = $1`, id, secret)
+	if err != nil {
+		return false, fmt.Errorf("rotate webhook secret id=%s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteSubscription 删除订阅及其投递记录
+func (r *Repo) DeleteSubscription(ctx context.Context, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("delete webhook subscription id=%s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// EnqueuePing 为订阅写入一条测试事件及其投递（不经发件箱分发）
+func (r *Repo) EnqueuePing(ctx context.Context, subscriptionID uuid.UUID, payload []byte) (*Delivery, error) {
+	eventID := uuid.New()
+	var d Delivery
+	query := `WITH e AS (INSERT INTO webhook_outbox (id, event, payload, dispatched_at) VALUES ($1, $2, $3, NOW()) RETURNING id, event)
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event) SELECT $4, e.id, e.event FROM e RETURNING ` + deliveryColumns
+	if err := r.db.GetContext(ctx, &d, query, eventID, EventPing, payload, subscriptionID); err != nil {
+		return nil, fmt.Errorf("enqueue webhook ping subscription=%s: %w", subscriptionID, err)
+	}
+	return &d, nil
+}
+
+// ListDeliveries 按 ID 倒序列出订阅的投递记录；status 为空表示全部，beforeID 为 0 表示从最新开始
+func (r *Repo) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, beforeID int64, limit int) ([]*Delivery, error) {
+	list := []*Delivery{}
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2) AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC LIMIT $4`
+	if err := r.db.SelectContext(ctx, &list, query, subscriptionID, status, beforeID, limit); err != nil {
+		return nil, fmt.Errorf("list webhook deliveries subscription=%s: %w", subscriptionID, err)
+	}
+	return list, nil
+}
+
+// RetryDelivery 将投递重新置为待投递并立即重试（失败后仍按剩余次数退避），不存在返回 nil
+func (r *Repo) RetryDelivery(ctx context.Context, id int64) (*Delivery, error) {
+	var d Delivery
+	query := `UPDATE webhook_deliveries SET status = $2, next_attempt_at = NOW(), updated_at = NOW() WHERE id = $1 RETURNING ` + deliveryColumns
+	if err := r.db.GetContext(ctx, &d, query, id, StatusPending); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("retry webhook delivery id=%d: %w", id, err)
+	}
+	return &d, nil
+}
+
+// fanOut 将未分发的事件展开为各订阅的投递并标记已分发；多实例下以 SKIP LOCKED 分摊，返回分发的事件数
+func (r *Repo) fanOut(ctx context.Context, limit int) (int64, error) {
+	query := `WITH e AS (
+			SELECT id, event FROM webhook_outbox WHERE dispatched_at IS NULL ORDER BY created_at LIMIT $1 FOR UPDATE SKIP LOCKED
+		), d AS (
+			INSERT INTO webhook_deliveries (subscription_id, event_id, event)
+			SELECT s.id, e.id, e.event FROM e JOIN webhook_subscriptions s ON s.active AND e.event = ANY(s.events)
+			ON CONFLICT (subscription_id, event_id) DO NOTHING
+		)
+		UPDATE webhook_outbox o SET dispatched_at = NOW() FROM e WHERE o.id = e.id`
+	res, err := r.db.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("fan out webhook events: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// claimDue 认领到期的待投递（仅启用的订阅），认领期间 next_attempt_at 推后 lease，避免其他实例重复投递
+func (r *Repo) claimDue(ctx context.Context, limit int, lease time.Duration) ([]*dueDelivery, error) {
+	list := []*dueDelivery{}
+	query := `WITH due AS (
+			SELECT d.id FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id AND s.active
+			WHERE d.status = $1 AND d.next_attempt_at <= NOW()
+			ORDER BY d.next_attempt_at LIMIT $2 FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = NOW() + $3 * INTERVAL '1 second'
+		FROM due, webhook_subscriptions s, webhook_outbox o
+		WHERE d.id = due.id AND s.id = d.subscription_id AND o.id = d.event_id
+		RETURNING d.id, d.event_id, d.event, d.attempts, s.url, s.secret, o.payload, o.created_at AS occurred_at`
+	if err := r.db.SelectContext(ctx, &list, query, StatusPending, limit, lease.Seconds()); err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	return list, nil
+}
+
+// attemptResult 一次投递尝试的结果
+type attemptResult struct {
+	StatusCode int    // 0 表示未收到响应
+	Error      string // 为空表示成功
+	Response   string
+	Duration   time.Duration
+}
+
+// recordAttempt 记录投递结果：成功置为 succeeded；失败时 retryAfter 为 0 表示重试耗尽（failed），否则等待后重试
+func (r *Repo) recordAttempt(ctx context.Context, id int64, res attemptResult, retryAfter time.Duration) error {
+	var status *int
+	if res.StatusCode > 0 {
+		status = &res.StatusCode
+	}
+	var query string
+	args := []interface{}{id, status, res.Response, res.Duration.Milliseconds()}
+	switch {
+	case res.Error == "":
+		query = `UPDATE webhook_deliveries SET status = $5, attempts = attempts + 1, last_status = $2, last_error = NULL,
+			last_response = $3, duration_ms = $4, delivered_at = NOW(), updated_at = NOW() WHERE id = $1`
+		args = append(args, StatusSucceeded)
+	case retryAfter <= 0:
+		query = `UPDATE webhook_deliveries SET status = $5, attempts = attempts + 1, last_status = $2, last_error = $6,
+			last_response = $3, duration_ms = $4, updated_at = NOW() WHERE id = $1`
+		args = append(args, StatusFailed, res.Error)
+	default:
+		query = `UPDATE webhook_deliveries SET attempts = attempts + 1, last_status = $2, last_error = $5,
+			last_response = $3, duration_ms = $4, next_attempt_at = NOW() + $6 * INTERVAL '1 second', updated_at = NOW() WHERE id = $1`
+		args = append(args, res.Error, retryAfter.Seconds())
+	}
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("record webhook attempt id=%d: %w", id, err)
+	}
+	return nil
+}
+
+// Cleanup 删除 before 之前且已分发、无待投递的事件（投递记录级联删除），返回删除的事件数
+func (r *Repo) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM webhook_outbox o WHERE o.created_at < $1 AND o.dispatched_at IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.event_id = o.id AND d.status = $2)`
+	res, err := r.db.ExecContext(ctx, query, before, StatusPending)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup webhook outbox: %w", err)
+	}
+	return res.RowsAffected()
+}
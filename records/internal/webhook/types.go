@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// 投递状态
+const (
+	StatusPending   = "pending"   // 待投递或等待重试
+	StatusSucceeded = "succeeded" // 对方返回 2xx
+	StatusFailed    = "failed"    // 重试次数耗尽
+)
+
+// EventPing 管理员发送的测试事件，仅投递给指定订阅
+const EventPing = "ping"
+
+// Subscription 订阅；Secret 仅在创建与轮换时返回
+type Subscription struct {
+	ID        uuid.UUID      `db:"id" json:"id"`
+	Name      string         `db:"name" json:"name"`
+	URL       string         `db:"url" json:"url"`
+	Secret    string         `db:"secret" json:"-"`
+	Events    pq.StringArray `db:"events" json:"events"`
+	Active    bool           `db:"active" json:"active"`
+	CreatedBy string         `db:"created_by" json:"created_by"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// Stats 订阅的投递统计（近 24 小时成功/失败数与当前待投递数）
+type Stats struct {
+	Succeeded24h int `db:"succeeded_24h" json:"succeeded_24h"`
+	Failed24h    int `db:"failed_24h" json:"failed_24h"`
+	Pending      int `db:"pending" json:"pending"`
+}
+
+// Delivery webhook_deliveries 表一行
+type Delivery struct {
+	ID             int64      `db:"id" json:"id"`
+	SubscriptionID uuid.UUID  `db:"subscription_id" json:"subscription_id"`
+	EventID        uuid.UUID  `db:"event_id" json:"event_id"`
+	Event          string     `db:"event" json:"event"`
+	Status         string     `db:"status" json:"status"`
+	Attempts       int        `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	LastStatus     *int       `db:"last_status" json:"last_status,omitempty"`     // 最近一次的 HTTP 状态码
+	LastError      *string    `db:"last_error" json:"last_error,omitempty"`       // 最近一次的失败原因
+	LastResponse   *string    `db:"last_response" json:"last_response,omitempty"` // 最近一次的响应体（截断）
+	DurationMs     *int       `db:"duration_ms" json:"duration_ms,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at" json:"delivered_at,omitempty"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// dueDelivery 已认领的待投递：投递、订阅地址与密钥、事件内容
+type dueDelivery struct {
+	ID         int64           `db:"id"`
+	EventID    uuid.UUID       `db:"event_id"`
+	Event      string          `db:"event"`
+	Attempts   int             `db:"attempts"`
+	URL        string          `db:"url"`
+	Secret     string          `db:"secret"`
+	Payload    json.RawMessage `db:"payload"`
+	OccurredAt time.Time       `db:"occurred_at"`
+}
+
+// Envelope 投递的请求体
+type Envelope struct {
+	ID         uuid.UUID       `json:"id"` // 事件 ID，重试时不变，接收方可据此去重
+	Event      string          `json:"event"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
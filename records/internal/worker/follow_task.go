@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"records/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// defaultFollowTaskRemindTime 计划仅含日期时的默认提醒时刻
+const defaultFollowTaskRemindTime = "09:00"
+
+// refreshFollowTask 跟进记录落库后刷新该客户的跟进待办：旧的未完成待办被新计划取代，新计划含日期时生成待办
+func (w *OutputWorker) refreshFollowTask(ctx context.Context, record *models.FollowRecord) {
+	if n, err := w.repo.SupersedeOpenFollowTasks(ctx, record.UserID, record.CustomerID); err != nil {
+		w.logger.WithContext(ctx).Error("Failed to supersede follow tasks", "customer_id", record.CustomerID, "error", err)
+	} else if n > 0 {
+		w.logger.WithContext(ctx).Info("Superseded open follow tasks", "customer_id", record.CustomerID, "count", n)
+	}
+
+	if record.NextPlan == nil || strings.TrimSpace(*record.NextPlan) == "" {
+		return
+	}
+	followTime := record.FollowTime.In(time.Local)
+	extracted, err := w.aiClient.ExtractNextPlanTask(ctx, *record.NextPlan, followTime)
+	if err != nil {
+		w.logger.WithContext(ctx).Error("Failed to extract next plan task", "follow_record_id", record.ID, "error", err)
+		return
+	}
+	if extracted == nil || !extracted.HasDate {
+		return
+	}
+	dueAt, ok := w.parseFollowTaskDue(extracted, followTime)
+	if !ok {
+		w.logger.WithContext(ctx).Warn("Invalid next plan task due date", "follow_record_id", record.ID, "due_date", extracted.DueDate, "due_time", extracted.DueTime)
+		return
+	}
+
+	action := strings.TrimSpace(extracted.Action)
+	if action == "" {
+		action = *record.NextPlan
+	}
+	recordID := record.ID
+	task := &models.FollowTask{
+		ID:             uuid.New(),
+		UserID:         record.UserID,
+		CustomerID:     record.CustomerID,
+		FollowRecordID: &recordID,
+		Action:         action,
+		DueAt:          dueAt,
+		Status:         models.FollowTaskPending,
+	}
+	if err := w.repo.CreateFollowTask(ctx, task); err != nil {
+		w.logger.WithContext(ctx).Error("Failed to create follow task", "follow_record_id", record.ID, "error", err)
+		return
+	}
+	w.logger.WithContext(ctx).Info("Follow task created", "task_id", task.ID, "customer_id", record.CustomerID, "due_at", dueAt)
+}
+
+// parseFollowTaskDue 将抽取结果换算为提醒时间；未给出时刻时使用配置的提醒时刻。早于跟进当天的日期视为抽取错误
+func (w *OutputWorker) parseFollowTaskDue(extracted *models.NextPlanTask, followTime time.Time) (time.Time, bool) {
+	clock := strings.TrimSpace(extracted.DueTime)
+	if clock == "" {
+		clock = w.followTaskCfg.RemindTime
+	}
+	if clock == "" {
+		clock = defaultFollowTaskRemindTime
+	}
+	dueAt, err := time.ParseInLocation("2006-01-02 15:04", strings.TrimSpace(extracted.DueDate)+" "+clock, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	followDay := time.Date(followTime.Year(), followTime.Month(), followTime.Day(), 0, 0, 0, 0, time.Local)
+	if dueAt.Before(followDay) {
+		return time.Time{}, false
+	}
+	return dueAt, true
+}
@@ -3,18 +3,24 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"records/internal/ai"
+	"records/internal/config"
+	"records/internal/metrics"
 	"records/internal/models"
 	"records/internal/normalization"
 	"records/internal/repository"
+	"records/internal/tracing"
 	"records/pkg/logger"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // OutputTask 输出任务
@@ -22,18 +28,21 @@ type OutputTask struct {
 	SessionID uuid.UUID
 	UserID    string
 	CreatedAt time.Time
+	Trace     trace.SpanContext // 提交任务的对话轮次所在链路，任务处理的 span 挂在其下
 }
 
 // OutputWorker 输出阶段异步工作器
 type OutputWorker struct {
-	db         *sqlx.DB
-	repo       *repository.Repository
-	normalizer *normalization.Normalizer
-	logger     logger.Logger
-	taskQueue  chan OutputTask
-	wg         sync.WaitGroup
-	stopCh     chan struct{}
-	workerSize int
+	db            *sqlx.DB
+	aiClient      ai.Client
+	repo          *repository.Repository
+	normalizer    *normalization.Normalizer
+	followTaskCfg config.FollowTask
+	logger        logger.Logger
+	taskQueue     chan OutputTask
+	wg            sync.WaitGroup
+	stopCh        chan struct{}
+	workerSize    int
 }
 
 // NewOutputWorker 创建输出工作器
@@ -41,6 +50,7 @@ func NewOutputWorker(
 	db *sqlx.DB,
 	aiClient ai.Client,
 	repo *repository.Repository,
+	followTaskCfg config.FollowTask,
 	logger logger.Logger,
 	workerSize int,
 ) *OutputWorker {
@@ -51,13 +61,15 @@ func NewOutputWorker(
 	normalizer := normalization.NewNormalizer(aiClient, repo, logger)
 
 	return &OutputWorker{
-		db:         db,
-		repo:       repo,
-		normalizer: normalizer,
-		logger:     logger,
-		taskQueue:  make(chan OutputTask, 100), // 缓冲队列，最多100个任务
-		stopCh:     make(chan struct{}),
-		workerSize: workerSize,
+		db:            db,
+		aiClient:      aiClient,
+		repo:          repo,
+		normalizer:    normalizer,
+		followTaskCfg: followTaskCfg,
+		logger:        logger,
+		taskQueue:     make(chan OutputTask, 100), // 缓冲队列，最多100个任务
+		stopCh:        make(chan struct{}),
+		workerSize:    workerSize,
 	}
 }
 
@@ -79,12 +91,13 @@ func (w *OutputWorker) Stop() {
 	w.logger.Info("Output worker stopped")
 }
 
-// SubmitTask 提交输出任务（非阻塞）
-func (w *OutputWorker) SubmitTask(sessionID uuid.UUID, userID string) error {
+// SubmitTask 提交输出任务（非阻塞）；ctx 仅用于延续链路，任务不随其取消
+func (w *OutputWorker) SubmitTask(ctx context.Context, sessionID uuid.UUID, userID string) error {
 	task := OutputTask{
 		SessionID: sessionID,
 		UserID:    userID,
 		CreatedAt: time.Now(),
+		Trace:     trace.SpanContextFromContext(ctx),
 	}
 
 	select {
@@ -93,10 +106,16 @@ func (w *OutputWorker) SubmitTask(sessionID uuid.UUID, userID string) error {
 		return nil
 	default:
 		// 队列已满，返回错误
+		metrics.OutputTasks.WithLabelValues("rejected").Inc()
 		return fmt.Errorf("output task queue is full")
 	}
 }
 
+// QueueLen 返回排队中的任务数
+func (w *OutputWorker) QueueLen() int {
+	return len(w.taskQueue)
+}
+
 // worker 工作协程
 func (w *OutputWorker) worker(id int) {
 	defer w.wg.Done()
@@ -110,19 +129,29 @@ func (w *OutputWorker) worker(id int) {
 			return
 
 		case task := <-w.taskQueue:
-			w.logger.Info("Processing output task",
+			ctx, span := tracing.Start(trace.ContextWithRemoteSpanContext(context.Background(), task.Trace),
+				"worker.OutputTask", attribute.String("session_id", task.SessionID.String()))
+			w.logger.WithContext(ctx).Info("Processing output task",
 				"worker_id", id,
 				"session_id", task.SessionID,
 				"user_id", task.UserID)
 
 			// 处理任务
-			if err := w.processTask(context.Background(), task); err != nil {
-				w.logger.Error("Failed to process output task",
+			err := w.processTask(ctx, task)
+			tracing.End(span, err)
+			outcome := "succeeded"
+			if err != nil {
+				outcome = "failed"
+			}
+			metrics.OutputTasks.WithLabelValues(outcome).Inc()
+			metrics.OutputTaskDuration.WithLabelValues(outcome).Observe(metrics.Since(task.CreatedAt))
+			if err != nil {
+				w.logger.WithContext(ctx).Error("Failed to process output task",
 					"worker_id", id,
 					"session_id", task.SessionID,
 					"error", err)
 			} else {
-				w.logger.Info("Output task completed",
+				w.logger.WithContext(ctx).Info("Output task completed",
 					"worker_id", id,
 					"session_id", task.SessionID,
 					"duration", time.Since(task.CreatedAt))
@@ -136,16 +165,16 @@ func (w *OutputWorker) processTask(ctx context.Context, task OutputTask) error {
 	// 使用事务确保数据一致性
 	return w.repo.WithTx(ctx, func(txCtx context.Context) error {
 		// 1. 执行客户/联系人归一处理
-		w.logger.Info("Starting entity normalization", "session_id", task.SessionID)
+		w.logger.WithContext(ctx).Info("Starting entity normalization", "session_id", task.SessionID)
 		mergeMap, err := w.normalizer.NormalizeEntities(ctx, task.SessionID)
 		if err != nil {
-			w.logger.Error("Entity normalization failed", "error", err)
+			w.logger.WithContext(ctx).Error("Entity normalization failed", "error", err)
 			// 归一失败不影响后续流程，继续执行
 		} else if len(mergeMap) > 0 {
-			w.logger.Info("Entity normalization completed", "merge_count", len(mergeMap))
+			w.logger.WithContext(ctx).Info("Entity normalization completed", "merge_count", len(mergeMap))
 			// 执行客户合并
 			if err := w.mergeCustomers(ctx, mergeMap); err != nil {
-				w.logger.Error("Failed to merge customers", "error", err)
+				w.logger.WithContext(ctx).Error("Failed to merge customers", "error", err)
 				// 合并失败不影响后续流程
 			}
 		}
@@ -157,13 +186,13 @@ func (w *OutputWorker) processTask(ctx context.Context, task OutputTask) error {
 
 		// 3. 删除该会话的 dialog 记录（需先于 session 删除以满足 FK 约束；按 session_id 删除，利用索引，不同会话无锁竞争）
 		if err := w.repo.DeleteDialogsBySession(ctx, task.SessionID); err != nil {
-			w.logger.Error("Failed to delete dialogs after outputting", "session_id", task.SessionID, "error", err)
+			w.logger.WithContext(ctx).Error("Failed to delete dialogs after outputting", "session_id", task.SessionID, "error", err)
 			// 删除失败不阻断流程，数据已持久化到 follow_records
 		}
 
 		// 4. 删除 session 记录（OUTPUTTING 已完成，释放存储；按主键删除，无锁竞争）
 		if err := w.repo.DeleteSession(ctx, task.SessionID); err != nil {
-			w.logger.Error("Failed to delete session after outputting", "session_id", task.SessionID, "error", err)
+			w.logger.WithContext(ctx).Error("Failed to delete session after outputting", "session_id", task.SessionID, "error", err)
 			// 兜底：删除失败时更新为 EXIT，避免 dialogs 已删但 session 仍为 OUTPUTTING 导致被误判为活跃
 			endTime := time.Now()
 			_ = w.repo.UpdateSession(ctx, &models.Session{ID: task.SessionID, Status: models.StatusExit, EndedAt: &endTime})
@@ -174,6 +203,7 @@ func (w *OutputWorker) processTask(ctx context.Context, task OutputTask) error {
 }
 
 func (w *OutputWorker) outputFollowRecords(ctx context.Context, sessionID uuid.UUID, userID string) error {
+	ctx = repository.WithAudit(ctx, userID, models.RecordSourceBot)
 	// 获取最新 dialog 的 runtime_snapshot，从中读取 pending_updates
 	latestDialog, err := w.repo.GetLatestDialog(ctx, sessionID)
 	if err != nil {
@@ -235,10 +265,13 @@ func (w *OutputWorker) outputFollowRecords(ctx context.Context, sessionID uuid.U
 			continue
 		}
 		successCount++
+
+		// 根据下一步计划生成跟进待办（失败不影响跟进记录落库）
+		w.refreshFollowTask(ctx, followRecord)
 	}
 
 	if len(batchErrors) > 0 {
-		w.logger.Warn("Output follow records completed with errors",
+		w.logger.WithContext(ctx).Warn("Output follow records completed with errors",
 			"session_id", sessionID,
 			"total", len(pendingUpdates),
 			"success", successCount,
@@ -317,67 +350,92 @@ func (w *OutputWorker) mergeCustomers(ctx context.Context, mergeMap map[uuid.UUI
 	successCount := 0
 
 	for sourceID, targetID := range mergeMap {
-		sourceCustomer, err := w.repo.GetCustomer(ctx, sourceID)
+		err := w.mergeCustomerWithRetry(ctx, sourceID, targetID)
 		if err != nil {
-			batchErrors = append(batchErrors, fmt.Sprintf("get source customer %s: %v", sourceID, err))
+			batchErrors = append(batchErrors, fmt.Sprintf("merge %s->%s: %v", sourceID, targetID, err))
 			continue
 		}
-
-		targetCustomer, err := w.repo.GetCustomer(ctx, targetID)
-		if err != nil {
-			batchErrors = append(batchErrors, fmt.Sprintf("get target customer %s: %v", targetID, err))
-			continue
-		}
-
-		if sourceCustomer == nil || targetCustomer == nil {
-			batchErrors = append(batchErrors, fmt.Sprintf("customer not found: source=%s, target=%s", sourceID, targetID))
-			continue
-		}
-
-		if targetCustomer.ContactPerson == nil && sourceCustomer.ContactPerson != nil {
-			targetCustomer.ContactPerson = sourceCustomer.ContactPerson
-		}
-		if targetCustomer.ContactPhone == nil && sourceCustomer.ContactPhone != nil {
-			targetCustomer.ContactPhone = sourceCustomer.ContactPhone
-		}
-		if targetCustomer.ContactRole == nil && sourceCustomer.ContactRole != nil {
-			targetCustomer.ContactRole = sourceCustomer.ContactRole
-		}
-
-		if err := w.repo.UpdateCustomer(ctx, targetCustomer); err != nil {
-			batchErrors = append(batchErrors, fmt.Sprintf("update target customer %s: %v", targetID, err))
-			continue
-		}
-
-		sourceRecords, err := w.repo.GetCustomerFollowRecords(ctx, sourceID)
-		if err != nil {
-			batchErrors = append(batchErrors, fmt.Sprintf("get source follow records %s: %v", sourceID, err))
-			continue
-		}
-
-		recordErrors := 0
-		for _, record := range sourceRecords {
-			record.CustomerID = targetID
-			record.CustomerName = targetCustomer.Name
-			if err := w.repo.UpdateFollowRecord(ctx, record); err != nil {
-				recordErrors++
-			}
-		}
-		if recordErrors > 0 {
-			batchErrors = append(batchErrors, fmt.Sprintf("merge %s->%s: %d follow record errors", sourceID, targetID, recordErrors))
-		}
-
 		successCount++
 	}
 
-	w.logger.Info("Customer merge batch completed",
+	w.logger.WithContext(ctx).Info("Customer merge batch completed",
 		"total", len(mergeMap),
 		"success", successCount,
 		"failed", len(batchErrors))
 
 	if len(batchErrors) > 0 {
-		w.logger.Warn("Some merges had errors", "first_error", batchErrors[0], "error_count", len(batchErrors))
+		w.logger.WithContext(ctx).Warn("Some merges had errors", "first_error", batchErrors[0], "error_count", len(batchErrors))
 	}
 
 	return nil
 }
+
+// maxMergeAttempts 合并客户遇到并发修改时的最大尝试次数
+const maxMergeAttempts = 3
+
+// mergeCustomerWithRetry 在事务内合并一对客户（目标客户、跟进记录、待办迁移及 customer.merged 事件）；
+// 期间目标客户或跟进记录被其他写入修改时回滚，重新读取后再合并
+func (w *OutputWorker) mergeCustomerWithRetry(ctx context.Context, sourceID, targetID uuid.UUID) error {
+	for attempt := 1; ; attempt++ {
+		err := w.repo.WithTx(ctx, func(txCtx context.Context) error {
+			return w.mergeCustomer(txCtx, sourceID, targetID)
+		})
+		if !errors.Is(err, repository.ErrVersionConflict) || attempt >= maxMergeAttempts {
+			return err
+		}
+		w.logger.WithContext(ctx).Warn("Customer modified concurrently, retrying merge",
+			"source_id", sourceID, "target_id", targetID, "attempt", attempt)
+	}
+}
+
+// mergeCustomer 将源客户并入目标客户：补全目标客户联系人，迁移跟进记录与待办，并写入 customer.merged 事件
+func (w *OutputWorker) mergeCustomer(ctx context.Context, sourceID, targetID uuid.UUID) error {
+	sourceCustomer, err := w.repo.GetCustomer(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("get source customer: %w", err)
+	}
+	targetCustomer, err := w.repo.GetCustomer(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("get target customer: %w", err)
+	}
+	if sourceCustomer == nil || targetCustomer == nil {
+		return fmt.Errorf("customer not found: source=%s, target=%s", sourceID, targetID)
+	}
+
+	if targetCustomer.ContactPerson == nil && sourceCustomer.ContactPerson != nil {
+		targetCustomer.ContactPerson = sourceCustomer.ContactPerson
+	}
+	if targetCustomer.ContactPhone == nil && sourceCustomer.ContactPhone != nil {
+		targetCustomer.ContactPhone = sourceCustomer.ContactPhone
+	}
+	if targetCustomer.ContactRole == nil && sourceCustomer.ContactRole != nil {
+		targetCustomer.ContactRole = sourceCustomer.ContactRole
+	}
+	if err := w.repo.UpdateCustomer(ctx, targetCustomer); err != nil {
+		return fmt.Errorf("update target customer: %w", err)
+	}
+
+	sourceRecords, err := w.repo.GetCustomerFollowRecords(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("get source follow records: %w", err)
+	}
+	recordIDs := make([]uuid.UUID, 0, len(sourceRecords))
+	for _, record := range sourceRecords {
+		record.CustomerID = targetID
+		record.CustomerName = targetCustomer.Name
+		if err := w.repo.UpdateFollowRecord(ctx, record); err != nil {
+			return fmt.Errorf("update follow record %s: %w", record.ID, err)
+		}
+		recordIDs = append(recordIDs, record.ID)
+	}
+	if err := w.repo.ReassignFollowTasks(ctx, sourceID, targetID); err != nil {
+		return err
+	}
+	return w.repo.EnqueueWebhookEvent(ctx, models.WebhookCustomerMerged, repository.WebhookMergeData{
+		SourceID:   sourceID,
+		SourceName: sourceCustomer.Name,
+		TargetID:   targetID,
+		TargetName: targetCustomer.Name,
+		RecordIDs:  recordIDs,
+	})
+}
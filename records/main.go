@@ -11,7 +11,11 @@ import (
 	"records/internal/config"
 	"records/internal/database"
 	"records/internal/feishu"
+	"records/internal/phonecrypt"
+	"records/internal/redact"
+	"records/internal/repository"
 	"records/internal/server"
+	"records/internal/tracing"
 	"records/pkg/logger"
 )
 
@@ -22,8 +26,31 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// 初始化敏感信息脱敏（大模型调用与日志）
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		log.Fatalf("Invalid redaction config: %v", err)
+	}
+
+	// 初始化联系电话加密密钥
+	keyring, err := phonecrypt.New(cfg.PhoneEncryption)
+	if err != nil {
+		log.Fatalf("Invalid phone encryption config: %v", err)
+	}
+	phonecrypt.SetDefault(keyring)
+
 	// 初始化日志
-	logger := logger.New(cfg.Logging)
+	var logOpts []logger.Option
+	if redactor != nil {
+		logOpts = append(logOpts, logger.WithRedactor(redactor.Mask))
+	}
+	logger := logger.New(cfg.Logging, logOpts...)
+
+	// 初始化链路追踪（未启用时仅生成 trace_id 写入日志）
+	shutdownTracing, err := tracing.Init(cfg.Tracing)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", "error", err)
+	}
 
 	// 初始化数据库
 	db, err := database.New(cfg.Database)
@@ -41,9 +68,11 @@ func main() {
 
 	// 初始化飞书客户端：通过 WebSocket 连接飞书机器人（feishu.sale_agent），接收和发送消息
 	feishuClient := feishu.NewClient(cfg.Feishu.SaleAgent, logger)
+	// 飞书事件去重使用数据库（processed_events），支持多实例部署
+	feishuClient.SetEventDeduper(repository.NewEventDeduper(db))
 
 	// 初始化服务器
-	srv := server.New(cfg, db, feishuClient, logger)
+	srv := server.New(cfg, db, feishuClient, redactor, logger)
 
 	// 启动服务器
 	go func() {
@@ -68,6 +97,9 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Error("Server forced to shutdown", "error", err)
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Failed to flush traces", "error", err)
+	}
 
 	logger.Info("Server exited")
 }
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,7 @@ import (
 	"records/internal/config"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Logger 日志接口
@@ -19,16 +21,30 @@ type Logger interface {
 	Warn(msg string, fields ...interface{})
 	Error(msg string, fields ...interface{})
 	Fatal(msg string, fields ...interface{})
+	// WithContext 返回附带 ctx 中 trace_id/span_id 的日志，用于关联同一次请求的日志；ctx 无链路时返回自身
+	WithContext(ctx context.Context) Logger
 }
 
 // logrusLogger logrus实现
 type logrusLogger struct {
 	logger       *logrus.Logger
 	reportCaller bool
+	fields       logrus.Fields // WithContext 附带的字段
+	redact       func(string) string
+}
+
+// Option 日志选项
+type Option func(*logrusLogger)
+
+// WithRedactor 写日志前对消息与字段值脱敏（如手机号、身份证号）
+func WithRedactor(fn func(string) string) Option {
+	return func(l *logrusLogger) {
+		l.redact = fn
+	}
 }
 
 // New 创建新的日志实例
-func New(cfg config.Logging) Logger {
+func New(cfg config.Logging, opts ...Option) Logger {
 	logger := logrus.New()
 
 	// 设置日志级别
@@ -62,37 +78,58 @@ func New(cfg config.Logging) Logger {
 		logger.SetOutput(os.Stdout)
 	}
 
-	return &logrusLogger{logger: logger, reportCaller: cfg.Caller}
+	l := &logrusLogger{logger: logger, reportCaller: cfg.Caller}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
 func (l *logrusLogger) Debug(msg string, fields ...interface{}) {
-	l.logger.WithFields(l.mergeFields(l.parseFields(fields...))).Debug(msg)
+	l.logger.WithFields(l.mergeFields(l.parseFields(fields...))).Debug(l.redactMsg(msg))
 }
 
 func (l *logrusLogger) Info(msg string, fields ...interface{}) {
-	l.logger.WithFields(l.mergeFields(l.parseFields(fields...))).Info(msg)
+	l.logger.WithFields(l.mergeFields(l.parseFields(fields...))).Info(l.redactMsg(msg))
 }
 
 func (l *logrusLogger) Warn(msg string, fields ...interface{}) {
-	l.logger.WithFields(l.mergeFields(l.parseFields(fields...))).Warn(msg)
+	l.logger.WithFields(l.mergeFields(l.parseFields(fields...))).Warn(l.redactMsg(msg))
 }
 
 func (l *logrusLogger) Error(msg string, fields ...interface{}) {
-	l.logger.WithFields(l.mergeFieldsWithStack(l.parseFields(fields...))).Error(msg)
+	l.logger.WithFields(l.mergeFieldsWithStack(l.parseFields(fields...))).Error(l.redactMsg(msg))
 }
 
 func (l *logrusLogger) Fatal(msg string, fields ...interface{}) {
-	l.logger.WithFields(l.mergeFieldsWithStack(l.parseFields(fields...))).Fatal(msg)
+	l.logger.WithFields(l.mergeFieldsWithStack(l.parseFields(fields...))).Fatal(l.redactMsg(msg))
+}
+
+func (l *logrusLogger) WithContext(ctx context.Context) Logger {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return l
+	}
+	fields := logrus.Fields{}
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	fields["trace_id"] = sc.TraceID().String()
+	fields["span_id"] = sc.SpanID().String()
+	return &logrusLogger{logger: l.logger, reportCaller: l.reportCaller, fields: fields, redact: l.redact}
 }
 
 // parseFields 解析字段参数
 func (l *logrusLogger) parseFields(fields ...interface{}) logrus.Fields {
 	logFields := logrus.Fields{}
+	for k, v := range l.fields {
+		logFields[k] = v
+	}
 
 	for i := 0; i < len(fields); i += 2 {
 		if i+1 < len(fields) {
 			if key, ok := fields[i].(string); ok {
-				logFields[key] = fields[i+1]
+				logFields[key] = l.redactValue(fields[i+1])
 			}
 		}
 	}
@@ -100,6 +137,33 @@ func (l *logrusLogger) parseFields(fields ...interface{}) logrus.Fields {
 	return logFields
 }
 
+func (l *logrusLogger) redactMsg(msg string) string {
+	if l.redact == nil {
+		return msg
+	}
+	return l.redact(msg)
+}
+
+// redactValue 字段值脱敏：字符串与 error 直接处理；其他类型按 %+v 格式化后含敏感信息时才替换为脱敏后的字符串
+func (l *logrusLogger) redactValue(v interface{}) interface{} {
+	if l.redact == nil || v == nil {
+		return v
+	}
+	switch val := v.(type) {
+	case string:
+		return l.redact(val)
+	case error:
+		return l.redact(val.Error())
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	}
+	s := fmt.Sprintf("%+v", v)
+	if masked := l.redact(s); masked != s {
+		return masked
+	}
+	return v
+}
+
 // mergeFields 合并 caller 信息，skip=3 跳过 getCaller/mergeFields/本包方法，得到真实调用位置
 func (l *logrusLogger) mergeFields(fields logrus.Fields) logrus.Fields {
 	if !l.reportCaller {
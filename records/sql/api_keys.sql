@@ -0,0 +1,31 @@
+SET search_path TO sale;
+
+-- 对外 REST API（/api/v1）密钥与限流（在 sale schema 下执行，可重复执行）
+
+-- API 密钥：明文只在创建时返回一次，库中存 SHA-256；scopes 为授予的权限范围，rate_limit 为每分钟请求数上限
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           UUID PRIMARY KEY,
+    name         VARCHAR(255) NOT NULL,
+    prefix       VARCHAR(16) NOT NULL,
+    key_hash     CHAR(64) NOT NULL UNIQUE,
+    scopes       TEXT[] NOT NULL DEFAULT '{}',
+    rate_limit   INTEGER NOT NULL DEFAULT 60,
+    created_by   VARCHAR(255) NOT NULL REFERENCES users(id),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ,
+    revoked_by   VARCHAR(255)
+);
+
+-- 限流计数：按密钥与自然分钟累加，多实例共享；过期窗口由清理任务删除
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id       UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    window_start TIMESTAMPTZ NOT NULL,
+    requests     INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, window_start)
+);
+
+-- 增量同步：按 updated_at 游标拉取客户与跟进记录
+CREATE INDEX IF NOT EXISTS idx_customers_updated_at ON customers(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_follow_records_updated_at ON follow_records(updated_at, id);
@@ -0,0 +1,21 @@
+SET search_path TO sale;
+
+-- 页面登录会话与刷新令牌（在 sale schema 下执行，可重复执行）
+
+-- 会话：访问令牌（JWT，jti 即 id）短期有效，每次请求校验会话未撤销且用户在职；
+-- 刷新令牌只存 SHA-256，每次刷新轮换，prev_refresh_hash 用于识别旧令牌被重放（整条会话撤销）
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    id                UUID PRIMARY KEY,
+    user_id           VARCHAR(255) NOT NULL REFERENCES users(id),
+    refresh_hash      CHAR(64) NOT NULL UNIQUE,
+    prev_refresh_hash CHAR(64),
+    expires_at        TIMESTAMPTZ NOT NULL,
+    rotated_at        TIMESTAMPTZ,
+    revoked_at        TIMESTAMPTZ,
+    revoked_reason    VARCHAR(32),  -- logout/logout_all/reused/resigned
+    ip                VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent        VARCHAR(512) NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions(user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_prev_hash ON auth_sessions(prev_refresh_hash);
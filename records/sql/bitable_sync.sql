@@ -0,0 +1,42 @@
+SET search_path TO sale;
+
+-- 飞书多维表格同步：记录映射、增量游标与冲突日志（在 sale schema 下执行，可重复执行）
+
+-- 记录映射：本地客户/跟进记录 ↔ 多维表格记录；remote_modified_at 为本端最近一次写入后多维表格的修改时间（毫秒）
+CREATE TABLE IF NOT EXISTS bitable_sync_map (
+    kind               VARCHAR(16) NOT NULL,
+    table_id           VARCHAR(64) NOT NULL,
+    local_id           UUID NOT NULL,
+    remote_record_id   VARCHAR(64) NOT NULL,
+    remote_modified_at BIGINT NOT NULL DEFAULT 0,
+    synced_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (kind, table_id, local_id)
+);
+-- local_updated_at：最近一次写入多维表格的本地版本（updated_at），回退重扫时据此跳过已同步的版本
+ALTER TABLE bitable_sync_map ADD COLUMN IF NOT EXISTS local_updated_at TIMESTAMPTZ;
+
+-- 增量游标：按 (updated_at, id) 记录已同步到的位置；更换 table_id 后从头同步
+CREATE TABLE IF NOT EXISTS bitable_sync_state (
+    kind        VARCHAR(16) NOT NULL,
+    table_id    VARCHAR(64) NOT NULL,
+    cursor      TEXT NOT NULL DEFAULT '',
+    synced      BIGINT NOT NULL DEFAULT 0,
+    last_run_at TIMESTAMPTZ,
+    last_error  TEXT,
+    PRIMARY KEY (kind, table_id)
+);
+
+-- 冲突日志：多维表格中的记录在上次同步后被手工修改或删除；resolution 为 overwritten/skipped/recreated
+CREATE TABLE IF NOT EXISTS bitable_sync_conflicts (
+    id               BIGSERIAL PRIMARY KEY,
+    kind             VARCHAR(16) NOT NULL,
+    table_id         VARCHAR(64) NOT NULL,
+    local_id         UUID NOT NULL,
+    remote_record_id VARCHAR(64) NOT NULL,
+    reason           VARCHAR(32) NOT NULL,
+    resolution       VARCHAR(16) NOT NULL,
+    local_fields     JSONB,
+    remote_fields    JSONB,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_bitable_sync_conflicts_created ON bitable_sync_conflicts(created_at DESC);
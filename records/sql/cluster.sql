@@ -0,0 +1,36 @@
+SET search_path TO sale;
+
+-- 多实例部署相关表（在 sale schema 下执行，可重复执行）
+
+-- 已处理的飞书事件（message_id），用于跨实例去重飞书超时重推；按 processed_at 定期清理
+-- 处理前以 status = 'processing' 占用，成功后置为 'done'；占用超时仍为 processing 的视为处理方崩溃，可被重推重新占用
+CREATE TABLE IF NOT EXISTS processed_events (
+    event_id     VARCHAR(255) PRIMARY KEY,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- 占用或完成时间
+    status       VARCHAR(16) NOT NULL DEFAULT 'done' -- processing/done
+);
+ALTER TABLE processed_events ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'done';
+CREATE INDEX IF NOT EXISTS idx_processed_events_processed_at ON processed_events(processed_at);
+
+-- 租约锁：同一用户的消息跨实例串行处理（user:{union_id}）、定时任务主节点选举与任务级互斥（scheduler:leader、job:{name}）；
+-- 持有方定期续约，实例崩溃后租约到期即可被其他实例获取
+CREATE TABLE IF NOT EXISTS leases (
+    name       VARCHAR(255) PRIMARY KEY,
+    owner      VARCHAR(64) NOT NULL,  -- 本次持有的随机标识
+    expires_at TIMESTAMPTZ NOT NULL
+);
+DROP TABLE IF EXISTS user_locks; -- 已由 leases 取代
+
+-- 定时任务运行历史：每次执行（定时或手动触发）一行
+CREATE TABLE IF NOT EXISTS job_runs (
+    id          UUID PRIMARY KEY,
+    job_name    VARCHAR(128) NOT NULL,
+    trigger_by  VARCHAR(32) NOT NULL,  -- schedule/manual
+    status      VARCHAR(32) NOT NULL,  -- running/success/failed/skipped
+    instance    VARCHAR(255) NOT NULL, -- 执行实例（hostname:pid）
+    started_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    duration_ms BIGINT,
+    error       TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_started ON job_runs(job_name, started_at DESC);
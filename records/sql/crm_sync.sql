@@ -0,0 +1,29 @@
+SET search_path TO sale;
+
+-- CRM 双向同步：账户/活动外部 ID 映射与拉取游标（在 sale schema 下执行，可重复执行）
+
+-- 账户映射：本地客户 ↔ CRM 账户；local_synced_at 为已与 CRM 一致的 customers.updated_at，之后的本地修改待推送
+CREATE TABLE IF NOT EXISTS crm_account_map (
+    customer_id       UUID PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+    external_id       VARCHAR(128) NOT NULL UNIQUE,
+    master            BOOLEAN NOT NULL DEFAULT FALSE, -- CRM 主数据账户，对话中匹配客户时优先
+    remote_updated_at TIMESTAMPTZ,                    -- 最近拉取到的 CRM 更新时间
+    local_synced_at   TIMESTAMPTZ NOT NULL,
+    synced_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 活动映射：跟进记录 → CRM 活动；version 为已推送的跟进记录版本，记录修改后重新推送
+CREATE TABLE IF NOT EXISTS crm_activity_map (
+    record_id   UUID PRIMARY KEY REFERENCES follow_records(id) ON DELETE CASCADE,
+    external_id VARCHAR(128) NOT NULL,
+    version     INT NOT NULL,
+    synced_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 同步状态：拉取游标与最近一次运行结果
+CREATE TABLE IF NOT EXISTS crm_sync_state (
+    name        VARCHAR(32) PRIMARY KEY,
+    cursor      TEXT NOT NULL DEFAULT '',
+    last_run_at TIMESTAMPTZ,
+    last_error  TEXT
+);
@@ -0,0 +1,14 @@
+SET search_path TO sale;
+
+-- 日报/周报推送订阅（在 sale schema 下执行，可重复执行）
+
+-- 摘要推送订阅：每用户一行，默认不订阅；rep 为个人摘要，manager 为团队摘要（仅管理员生效）
+CREATE TABLE IF NOT EXISTS digest_subscriptions (
+    user_id              VARCHAR(255) PRIMARY KEY REFERENCES users(id),
+    rep_frequency        VARCHAR(16) NOT NULL DEFAULT 'off', -- off/daily/weekly
+    manager_frequency    VARCHAR(16) NOT NULL DEFAULT 'off', -- off/daily/weekly
+    last_rep_sent_at     TIMESTAMPTZ,
+    last_manager_sent_at TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
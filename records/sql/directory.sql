@@ -0,0 +1,16 @@
+SET search_path TO sale;
+
+-- 飞书通讯录同步（在 sale schema 下执行，可重复执行）
+
+-- 部门：id 为 open_department_id，leader_ids 为负责人 union_id；ancestors 为自根向下的部门链（含自身），用于按部门及下级授权
+CREATE TABLE IF NOT EXISTS departments (
+    id         VARCHAR(64) PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL DEFAULT '',
+    parent_id  VARCHAR(64),
+    leader_ids TEXT[] NOT NULL DEFAULT '{}',
+    ancestors  TEXT[] NOT NULL DEFAULT '{}',
+    deleted_at TIMESTAMPTZ,  -- 通讯录中已不存在
+    synced_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_departments_parent ON departments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_departments_ancestors ON departments USING gin (ancestors);
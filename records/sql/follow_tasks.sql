@@ -0,0 +1,21 @@
+SET search_path TO sale;
+
+-- 跟进待办（在 sale schema 下执行，可重复执行）
+
+-- 跟进待办：从跟进记录的下一步计划中抽取带日期的行动，到期由机器人提醒
+CREATE TABLE IF NOT EXISTS follow_tasks (
+    id               UUID PRIMARY KEY,
+    user_id          VARCHAR(255) NOT NULL REFERENCES users(id),
+    customer_id      UUID NOT NULL REFERENCES customers(id),
+    follow_record_id UUID REFERENCES follow_records(id) ON DELETE SET NULL,
+    action           VARCHAR(2000) NOT NULL, -- 待办行动（如“带方案去演示”）
+    due_at           TIMESTAMPTZ NOT NULL,   -- 提醒时间（延后会推迟）
+    status           VARCHAR(32) NOT NULL,   -- pending/reminded/done/superseded/expired
+    snooze_count     INTEGER NOT NULL DEFAULT 0,
+    reminded_at      TIMESTAMPTZ,
+    completed_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_follow_tasks_status_due ON follow_tasks(status, due_at);
+CREATE INDEX IF NOT EXISTS idx_follow_tasks_user_customer ON follow_tasks(user_id, customer_id);
@@ -0,0 +1,23 @@
+SET search_path TO sale;
+
+-- 历史跟进记录批量导入（在 sale schema 下执行，可重复执行）
+
+-- 导入批次：每次提交导入生成一个批次，可整体回滚
+CREATE TABLE IF NOT EXISTS import_batches (
+    id               UUID PRIMARY KEY,
+    user_id          VARCHAR(255) NOT NULL REFERENCES users(id), -- 执行导入的用户
+    file_name        VARCHAR(500) NOT NULL,
+    status           VARCHAR(32) NOT NULL,  -- committed/rolled_back
+    total_rows       INTEGER NOT NULL DEFAULT 0,
+    imported_rows    INTEGER NOT NULL DEFAULT 0,
+    new_customers    INTEGER NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rolled_back_at   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_import_batches_user ON import_batches(user_id, created_at DESC);
+
+-- 导入的跟进记录与导入时新建的客户标记批次号，回滚时据此删除
+ALTER TABLE follow_records ADD COLUMN IF NOT EXISTS import_batch_id UUID;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS import_batch_id UUID;
+CREATE INDEX IF NOT EXISTS idx_follow_records_import_batch ON follow_records(import_batch_id) WHERE import_batch_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_customers_import_batch ON customers(import_batch_id) WHERE import_batch_id IS NOT NULL;
@@ -0,0 +1,6 @@
+SET search_path TO sale;
+
+-- 列表分页（在 sale schema 下执行，可重复执行）
+
+-- page 列表按 (follow_time, id) 游标分页；按 created_at 排序时使用已有的 idx_follow_records_user_id(user_id, created_at)
+CREATE INDEX IF NOT EXISTS idx_follow_records_user_follow_time ON follow_records(user_id, follow_time DESC, id DESC);
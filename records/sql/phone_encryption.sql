@@ -0,0 +1,24 @@
+SET search_path TO sale;
+
+-- 联系电话加密存储（在 sale schema 下执行，可重复执行）
+
+-- contact_phone 存信封加密后的密文（enc:v1:...），密文比明文长，改为 TEXT；
+-- contact_phone_bidx 为规范化号码的盲索引（HMAC），按号码检索时精确匹配
+-- 仅在列尚非 TEXT 时修改类型，避免每次启动重复改写表并持有排他锁
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'customers'
+                 AND column_name = 'contact_phone' AND data_type <> 'text') THEN
+        ALTER TABLE customers ALTER COLUMN contact_phone TYPE TEXT;
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'follow_records'
+                 AND column_name = 'contact_phone' AND data_type <> 'text') THEN
+        ALTER TABLE follow_records ALTER COLUMN contact_phone TYPE TEXT;
+    END IF;
+END $$;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS contact_phone_bidx VARCHAR(64);
+ALTER TABLE follow_records ADD COLUMN IF NOT EXISTS contact_phone_bidx VARCHAR(64);
+CREATE INDEX IF NOT EXISTS idx_customers_contact_phone_bidx ON customers(contact_phone_bidx) WHERE contact_phone_bidx IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_follow_records_contact_phone_bidx ON follow_records(contact_phone_bidx) WHERE contact_phone_bidx IS NOT NULL;
@@ -0,0 +1,76 @@
+SET search_path TO sale;
+
+-- 角色权限（在 sale schema 下执行，可重复执行）
+
+-- 权限点：view 查看 / export 导出 / edit 修改删除恢复与代录导入 / view_phone 明文联系电话 / admin 管理授权与定时任务
+CREATE TABLE IF NOT EXISTS rbac_permissions (
+    code        VARCHAR(32) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT ''
+);
+INSERT INTO rbac_permissions (code, description) VALUES
+    ('view', '查看范围内销售的跟进记录'),
+    ('export', '导出范围内销售的跟进记录'),
+    ('edit', '修改、删除、恢复范围内销售的跟进记录，代其导入'),
+    ('view_phone', '查看范围内记录的明文联系电话'),
+    ('admin', '管理角色授权与定时任务')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS rbac_roles (
+    name        VARCHAR(64) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS rbac_role_permissions (
+    role       VARCHAR(64) NOT NULL REFERENCES rbac_roles(name) ON DELETE CASCADE,
+    permission VARCHAR(32) NOT NULL REFERENCES rbac_permissions(code),
+    PRIMARY KEY (role, permission)
+);
+
+-- 用户授权：scope_type 为 all（全部用户）/ users（指定用户 id）/ department（飞书 open_department_id）/ org（orgname 及其下级，以 . 分隔）
+CREATE TABLE IF NOT EXISTS rbac_user_roles (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      VARCHAR(255) NOT NULL REFERENCES users(id),
+    role         VARCHAR(64) NOT NULL REFERENCES rbac_roles(name) ON DELETE CASCADE,
+    scope_type   VARCHAR(16) NOT NULL,
+    scope_values TEXT[] NOT NULL DEFAULT '{}',
+    created_by   VARCHAR(255),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_rbac_user_roles_user ON rbac_user_roles(user_id);
+
+-- 用户所属飞书部门（open_department_id），同步用户信息时更新
+CREATE TABLE IF NOT EXISTS user_departments (
+    user_id       VARCHAR(255) NOT NULL REFERENCES users(id),
+    department_id VARCHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, department_id)
+);
+CREATE INDEX IF NOT EXISTS idx_user_departments_department ON user_departments(department_id);
+
+-- 内置角色
+INSERT INTO rbac_roles (name, description) VALUES ('admin', '管理员'), ('manager', '主管') ON CONFLICT (name) DO NOTHING;
+INSERT INTO rbac_role_permissions (role, permission) VALUES
+    ('admin', 'view'), ('admin', 'export'), ('admin', 'edit'), ('admin', 'view_phone'), ('admin', 'admin'),
+    ('manager', 'view'), ('manager', 'export')
+ON CONFLICT DO NOTHING;
+
+-- 一次性数据迁移标记：name 为迁移名，存在即视为已执行
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    name       VARCHAR(128) PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 迁移 records_scope：原只能查看全部记录的 '0' 迁为 manager（view + export，全部用户），其余迁为 manager（指定用户）；
+-- admin 不由迁移授予，需显式授权，如 INSERT INTO rbac_user_roles (user_id, role, scope_type) VALUES ('<union_id>', 'admin', 'all');
+INSERT INTO rbac_user_roles (user_id, role, scope_type, scope_values)
+SELECT rs.manager_id,
+       'manager',
+       CASE WHEN TRIM(rs.user_id) = '0' THEN 'all' ELSE 'users' END,
+       CASE WHEN TRIM(rs.user_id) = '0' THEN '{}'::TEXT[]
+            ELSE array_remove(array_remove(string_to_array(regexp_replace(rs.user_id, '\s', '', 'g'), ','), ''), '0') END
+FROM records_scope rs
+WHERE NOT EXISTS (SELECT 1 FROM schema_migrations WHERE name = 'rbac_records_scope')
+  AND NOT EXISTS (SELECT 1 FROM rbac_user_roles);
+-- 仅执行一次：之后即使管理员撤销全部授权，也不会从 records_scope 重新生成
+INSERT INTO schema_migrations (name) VALUES ('rbac_records_scope') ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,31 @@
+SET search_path TO sale;
+
+-- 跟进记录评论（在 sale schema 下执行，可重复执行）
+
+-- 评论：parent_id 为空是话题首条，回复均挂在首条下（单层话题）；mentions 为 @ 的用户 union_id；source 为 page/bot
+CREATE TABLE IF NOT EXISTS record_comments (
+    id               UUID PRIMARY KEY,
+    follow_record_id UUID NOT NULL REFERENCES follow_records(id) ON DELETE CASCADE,
+    parent_id        UUID REFERENCES record_comments(id) ON DELETE CASCADE,
+    author_id        VARCHAR(255) NOT NULL REFERENCES users(id),
+    content          TEXT NOT NULL,
+    mentions         TEXT[] NOT NULL DEFAULT '{}',
+    source           VARCHAR(16) NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_record_comments_record ON record_comments(follow_record_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_record_comments_parent ON record_comments(parent_id) WHERE parent_id IS NOT NULL;
+
+-- 评论接收人：每条评论的记录所属销售、被 @ 者与话题参与者（不含作者）各一行；read_at 为空即未读；
+-- message_id 为机器人通知消息，用户在飞书中回复该消息即回复话题
+CREATE TABLE IF NOT EXISTS record_comment_recipients (
+    comment_id  UUID NOT NULL REFERENCES record_comments(id) ON DELETE CASCADE,
+    user_id     VARCHAR(255) NOT NULL REFERENCES users(id),
+    reason      VARCHAR(16) NOT NULL,  -- owner/mention/thread
+    read_at     TIMESTAMPTZ,
+    message_id  VARCHAR(255),
+    notified_at TIMESTAMPTZ,
+    PRIMARY KEY (comment_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_record_comment_recipients_unread ON record_comment_recipients(user_id) WHERE read_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_record_comment_recipients_message ON record_comment_recipients(message_id) WHERE message_id IS NOT NULL;
@@ -0,0 +1,33 @@
+SET search_path TO sale;
+
+-- 客户跟进时间线分享链接（在 sale schema 下执行，可重复执行）
+
+-- 分享：令牌为 JWT（sid 即 id），撤销与访问计数以本表为准；user_id 非空时仅分享该销售的记录，
+-- 为空时为创建人当前可查看范围内的记录；访问时按创建人当前权限重新计算
+CREATE TABLE IF NOT EXISTS record_shares (
+    id             UUID PRIMARY KEY,
+    customer_id    UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    customer_name  VARCHAR(255) NOT NULL,
+    user_id        VARCHAR(255) REFERENCES users(id),
+    created_by     VARCHAR(255) NOT NULL REFERENCES users(id),
+    expires_at     TIMESTAMPTZ NOT NULL,
+    revoked_at     TIMESTAMPTZ,
+    revoked_by     VARCHAR(255),
+    view_count     INTEGER NOT NULL DEFAULT 0,
+    last_viewed_at TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_record_shares_creator ON record_shares(created_by, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_record_shares_customer ON record_shares(customer_id);
+
+-- 分享访问审计：每次访问公开页一行（含被拒绝的访问）；令牌无法解析时 share_id 为空
+CREATE TABLE IF NOT EXISTS record_share_access_logs (
+    id          BIGSERIAL PRIMARY KEY,
+    share_id    UUID REFERENCES record_shares(id) ON DELETE CASCADE,
+    outcome     VARCHAR(16) NOT NULL,  -- ok/invalid/expired/revoked/not_found/inactive/error
+    ip          VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent  VARCHAR(512) NOT NULL DEFAULT '',
+    records     INTEGER NOT NULL DEFAULT 0,
+    accessed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_record_share_access_logs_share ON record_share_access_logs(share_id, accessed_at DESC);
@@ -0,0 +1,22 @@
+SET search_path TO sale;
+
+-- 跟进记录版本历史与软删除（在 sale schema 下执行，可重复执行）
+
+-- version：每次写入（新建/修改/删除/恢复）递增，与 follow_record_versions.version 对应；deleted_at 非空表示已软删除
+ALTER TABLE follow_records ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE follow_records ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE follow_records ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_follow_records_deleted ON follow_records(user_id, deleted_at DESC) WHERE deleted_at IS NOT NULL;
+
+-- 版本快照：每次写入后的整行快照；不设外键，记录被物理删除（如导入回滚）后历史仍保留
+CREATE TABLE IF NOT EXISTS follow_record_versions (
+    id         BIGSERIAL PRIMARY KEY,
+    record_id  UUID NOT NULL,
+    version    INTEGER NOT NULL,
+    op         VARCHAR(16) NOT NULL,  -- create/update/delete/restore
+    source     VARCHAR(16) NOT NULL,  -- bot/page/import/system/api
+    actor_id   VARCHAR(255),          -- 操作人，系统操作为空
+    snapshot   JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (record_id, version)
+);
@@ -25,6 +25,8 @@ CREATE TABLE IF NOT EXISTS customers (
     contact_person VARCHAR(255),
     contact_phone VARCHAR(255),
     contact_role VARCHAR(255),
+    tier VARCHAR(32),              -- 客户分级，决定久未跟进的判定天数
+    import_batch_id UUID,          -- 由批量导入新建时的导入批次
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
@@ -74,6 +76,7 @@ CREATE TABLE IF NOT EXISTS follow_records (
     risk_content VARCHAR(2000),
     next_plan VARCHAR(2000),
     ai BOOLEAN NOT NULL DEFAULT false,
+    import_batch_id UUID,          -- 批量导入的批次（import_batches），非导入为空
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
@@ -86,7 +89,7 @@ CREATE INDEX IF NOT EXISTS idx_follow_records_user_id ON follow_records(user_id,
 CREATE INDEX IF NOT EXISTS idx_follow_records_customer_id ON follow_records(customer_id);
 
 -- 管理员可查看的日志范围：manager_id 为可查看列表的用户，每管理员一行
--- user_id 存储以逗号分隔的被查看用户 id；若为 '0' 表示可看所有用户
+-- user_id 存储以逗号分隔的被查看用户 id；若为 '0' 表示可看所有用户（已由 rbac_user_roles 取代，仅在首次启用角色权限时迁移）
 CREATE TABLE IF NOT EXISTS records_scope (
     manager_id VARCHAR(255) PRIMARY KEY REFERENCES users(id),
     user_id    VARCHAR(2000) NOT NULL
@@ -125,3 +128,450 @@ CREATE TABLE IF NOT EXISTS sales_hot_words_stats (
 CREATE INDEX IF NOT EXISTS idx_sales_hot_words_stats_run_window ON sales_hot_words_stats(run_time, time_window_days);
 CREATE INDEX IF NOT EXISTS idx_sales_hot_words_stats_category_run ON sales_hot_words_stats(category, run_time);
 
+
+-- 已处理的飞书事件（message_id），用于跨实例去重飞书超时重推；按 processed_at 定期清理
+-- 处理前以 status = 'processing' 占用，成功后置为 'done'；占用超时仍为 processing 的视为处理方崩溃，可被重推重新占用
+CREATE TABLE IF NOT EXISTS processed_events (
+    event_id     VARCHAR(255) PRIMARY KEY,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- 占用或完成时间
+    status       VARCHAR(16) NOT NULL DEFAULT 'done' -- processing/done
+);
+ALTER TABLE processed_events ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'done';
+CREATE INDEX IF NOT EXISTS idx_processed_events_processed_at ON processed_events(processed_at);
+
+-- 租约锁：同一用户的消息跨实例串行处理（user:{union_id}）、定时任务主节点选举与任务级互斥（scheduler:leader、job:{name}）；
+-- 持有方定期续约，实例崩溃后租约到期即可被其他实例获取
+CREATE TABLE IF NOT EXISTS leases (
+    name       VARCHAR(255) PRIMARY KEY,
+    owner      VARCHAR(64) NOT NULL,  -- 本次持有的随机标识
+    expires_at TIMESTAMPTZ NOT NULL
+);
+DROP TABLE IF EXISTS user_locks; -- 已由 leases 取代
+
+-- 定时任务运行历史：每次执行（定时或手动触发）一行
+CREATE TABLE IF NOT EXISTS job_runs (
+    id          UUID PRIMARY KEY,
+    job_name    VARCHAR(128) NOT NULL,
+    trigger_by  VARCHAR(32) NOT NULL,  -- schedule/manual
+    status      VARCHAR(32) NOT NULL,  -- running/success/failed/skipped
+    instance    VARCHAR(255) NOT NULL, -- 执行实例（hostname:pid）
+    started_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    duration_ms BIGINT,
+    error       TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_started ON job_runs(job_name, started_at DESC);
+
+-- 跟进待办：从跟进记录的下一步计划中抽取带日期的行动，到期由机器人提醒
+CREATE TABLE IF NOT EXISTS follow_tasks (
+    id               UUID PRIMARY KEY,
+    user_id          VARCHAR(255) NOT NULL REFERENCES users(id),
+    customer_id      UUID NOT NULL REFERENCES customers(id),
+    follow_record_id UUID REFERENCES follow_records(id) ON DELETE SET NULL,
+    action           VARCHAR(2000) NOT NULL, -- 待办行动（如“带方案去演示”）
+    due_at           TIMESTAMPTZ NOT NULL,   -- 提醒时间（延后会推迟）
+    status           VARCHAR(32) NOT NULL,   -- pending/reminded/done/superseded/expired
+    snooze_count     INTEGER NOT NULL DEFAULT 0,
+    reminded_at      TIMESTAMPTZ,
+    completed_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_follow_tasks_status_due ON follow_tasks(status, due_at);
+CREATE INDEX IF NOT EXISTS idx_follow_tasks_user_customer ON follow_tasks(user_id, customer_id);
+
+-- 摘要推送订阅：每用户一行，默认不订阅；rep 为个人摘要，manager 为团队摘要（仅管理员生效）
+CREATE TABLE IF NOT EXISTS digest_subscriptions (
+    user_id              VARCHAR(255) PRIMARY KEY REFERENCES users(id),
+    rep_frequency        VARCHAR(16) NOT NULL DEFAULT 'off', -- off/daily/weekly
+    manager_frequency    VARCHAR(16) NOT NULL DEFAULT 'off', -- off/daily/weekly
+    last_rep_sent_at     TIMESTAMPTZ,
+    last_manager_sent_at TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 久未跟进提醒记录：每个（销售, 客户）一行，避免重复提醒；客户被再次跟进后重新计算
+CREATE TABLE IF NOT EXISTS stale_customer_alerts (
+    user_id        VARCHAR(255) NOT NULL REFERENCES users(id),
+    customer_id    UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    last_follow_at TIMESTAMPTZ NOT NULL, -- 提醒时该销售对该客户的最近跟进时间
+    notified_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, customer_id)
+);
+CREATE INDEX IF NOT EXISTS idx_follow_records_customer_user_time ON follow_records(customer_id, user_id, follow_time DESC);
+
+-- 导入批次：每次提交导入生成一个批次，可整体回滚
+CREATE TABLE IF NOT EXISTS import_batches (
+    id               UUID PRIMARY KEY,
+    user_id          VARCHAR(255) NOT NULL REFERENCES users(id), -- 执行导入的用户
+    file_name        VARCHAR(500) NOT NULL,
+    status           VARCHAR(32) NOT NULL,  -- committed/rolled_back
+    total_rows       INTEGER NOT NULL DEFAULT 0,
+    imported_rows    INTEGER NOT NULL DEFAULT 0,
+    new_customers    INTEGER NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rolled_back_at   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_import_batches_user ON import_batches(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_follow_records_import_batch ON follow_records(import_batch_id) WHERE import_batch_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_customers_import_batch ON customers(import_batch_id) WHERE import_batch_id IS NOT NULL;
+
+-- 跟进记录全文 / 模糊搜索：pg_trgm 三元组索引（表达式须与 internal/search 中 searchDocument 一致）
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_follow_records_search_trgm ON follow_records USING gin (
+    (COALESCE(follow_content, '') || ' ' || COALESCE(follow_goal, '') || ' ' || COALESCE(follow_result, '') || ' ' ||
+     COALESCE(risk_content, '') || ' ' || COALESCE(next_plan, '')) gin_trgm_ops
+);
+
+-- 列表分页：page 列表按 (follow_time, id) 游标分页
+CREATE INDEX IF NOT EXISTS idx_follow_records_user_follow_time ON follow_records(user_id, follow_time DESC, id DESC);
+
+-- 跟进记录版本历史与软删除：version 每次写入递增，deleted_at 非空表示已软删除
+ALTER TABLE follow_records ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE follow_records ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE follow_records ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_follow_records_deleted ON follow_records(user_id, deleted_at DESC) WHERE deleted_at IS NOT NULL;
+
+-- 跟进记录版本快照：每次写入后的整行快照；不设外键，记录被物理删除后历史仍保留
+CREATE TABLE IF NOT EXISTS follow_record_versions (
+    id         BIGSERIAL PRIMARY KEY,
+    record_id  UUID NOT NULL,
+    version    INTEGER NOT NULL,
+    op         VARCHAR(16) NOT NULL,  -- create/update/delete/restore
+    source     VARCHAR(16) NOT NULL,  -- bot/page/import/system/api
+    actor_id   VARCHAR(255),          -- 操作人，系统操作为空
+    snapshot   JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (record_id, version)
+);
+
+-- 角色权限：角色、权限点、用户授权（含数据范围）与用户所属飞书部门
+-- 权限点：view 查看 / export 导出 / edit 修改删除恢复与代录导入 / view_phone 明文联系电话 / admin 管理授权与定时任务
+CREATE TABLE IF NOT EXISTS rbac_permissions (
+    code        VARCHAR(32) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT ''
+);
+INSERT INTO rbac_permissions (code, description) VALUES
+    ('view', '查看范围内销售的跟进记录'),
+    ('export', '导出范围内销售的跟进记录'),
+    ('edit', '修改、删除、恢复范围内销售的跟进记录，代其导入'),
+    ('view_phone', '查看范围内记录的明文联系电话'),
+    ('admin', '管理角色授权与定时任务')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS rbac_roles (
+    name        VARCHAR(64) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS rbac_role_permissions (
+    role       VARCHAR(64) NOT NULL REFERENCES rbac_roles(name) ON DELETE CASCADE,
+    permission VARCHAR(32) NOT NULL REFERENCES rbac_permissions(code),
+    PRIMARY KEY (role, permission)
+);
+
+-- 用户授权：scope_type 为 all（全部用户）/ users（指定用户 id）/ department（飞书 open_department_id）/ org（orgname 及其下级，以 . 分隔）
+CREATE TABLE IF NOT EXISTS rbac_user_roles (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      VARCHAR(255) NOT NULL REFERENCES users(id),
+    role         VARCHAR(64) NOT NULL REFERENCES rbac_roles(name) ON DELETE CASCADE,
+    scope_type   VARCHAR(16) NOT NULL,
+    scope_values TEXT[] NOT NULL DEFAULT '{}',
+    created_by   VARCHAR(255),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_rbac_user_roles_user ON rbac_user_roles(user_id);
+
+-- 用户所属飞书部门（open_department_id），同步用户信息时更新
+CREATE TABLE IF NOT EXISTS user_departments (
+    user_id       VARCHAR(255) NOT NULL REFERENCES users(id),
+    department_id VARCHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, department_id)
+);
+CREATE INDEX IF NOT EXISTS idx_user_departments_department ON user_departments(department_id);
+
+-- 内置角色
+INSERT INTO rbac_roles (name, description) VALUES ('admin', '管理员'), ('manager', '主管') ON CONFLICT (name) DO NOTHING;
+INSERT INTO rbac_role_permissions (role, permission) VALUES
+    ('admin', 'view'), ('admin', 'export'), ('admin', 'edit'), ('admin', 'view_phone'), ('admin', 'admin'),
+    ('manager', 'view'), ('manager', 'export')
+ON CONFLICT DO NOTHING;
+
+-- 一次性数据迁移标记：name 为迁移名，存在即视为已执行
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    name       VARCHAR(128) PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 迁移 records_scope：原只能查看全部记录的 '0' 迁为 manager（view + export，全部用户），其余迁为 manager（指定用户）；
+-- admin 不由迁移授予，需显式授权，如 INSERT INTO rbac_user_roles (user_id, role, scope_type) VALUES ('<union_id>', 'admin', 'all');
+INSERT INTO rbac_user_roles (user_id, role, scope_type, scope_values)
+SELECT rs.manager_id,
+       'manager',
+       CASE WHEN TRIM(rs.user_id) = '0' THEN 'all' ELSE 'users' END,
+       CASE WHEN TRIM(rs.user_id) = '0' THEN '{}'::TEXT[]
+            ELSE array_remove(array_remove(string_to_array(regexp_replace(rs.user_id, '\s', '', 'g'), ','), ''), '0') END
+FROM records_scope rs
+WHERE NOT EXISTS (SELECT 1 FROM schema_migrations WHERE name = 'rbac_records_scope')
+  AND NOT EXISTS (SELECT 1 FROM rbac_user_roles);
+-- 仅执行一次：之后即使管理员撤销全部授权，也不会从 records_scope 重新生成
+INSERT INTO schema_migrations (name) VALUES ('rbac_records_scope') ON CONFLICT (name) DO NOTHING;
+
+-- 飞书通讯录同步：部门树与部门负责人
+-- 部门：id 为 open_department_id，leader_ids 为负责人 union_id；ancestors 为自根向下的部门链（含自身），用于按部门及下级授权
+CREATE TABLE IF NOT EXISTS departments (
+    id         VARCHAR(64) PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL DEFAULT '',
+    parent_id  VARCHAR(64),
+    leader_ids TEXT[] NOT NULL DEFAULT '{}',
+    ancestors  TEXT[] NOT NULL DEFAULT '{}',
+    deleted_at TIMESTAMPTZ,  -- 通讯录中已不存在
+    synced_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_departments_parent ON departments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_departments_ancestors ON departments USING gin (ancestors);
+
+-- 跟进记录评论
+-- 评论：parent_id 为空是话题首条，回复均挂在首条下（单层话题）；mentions 为 @ 的用户 union_id；source 为 page/bot
+CREATE TABLE IF NOT EXISTS record_comments (
+    id               UUID PRIMARY KEY,
+    follow_record_id UUID NOT NULL REFERENCES follow_records(id) ON DELETE CASCADE,
+    parent_id        UUID REFERENCES record_comments(id) ON DELETE CASCADE,
+    author_id        VARCHAR(255) NOT NULL REFERENCES users(id),
+    content          TEXT NOT NULL,
+    mentions         TEXT[] NOT NULL DEFAULT '{}',
+    source           VARCHAR(16) NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_record_comments_record ON record_comments(follow_record_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_record_comments_parent ON record_comments(parent_id) WHERE parent_id IS NOT NULL;
+
+-- 评论接收人：每条评论的记录所属销售、被 @ 者与话题参与者（不含作者）各一行；read_at 为空即未读；
+-- message_id 为机器人通知消息，用户在飞书中回复该消息即回复话题
+CREATE TABLE IF NOT EXISTS record_comment_recipients (
+    comment_id  UUID NOT NULL REFERENCES record_comments(id) ON DELETE CASCADE,
+    user_id     VARCHAR(255) NOT NULL REFERENCES users(id),
+    reason      VARCHAR(16) NOT NULL,  -- owner/mention/thread
+    read_at     TIMESTAMPTZ,
+    message_id  VARCHAR(255),
+    notified_at TIMESTAMPTZ,
+    PRIMARY KEY (comment_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_record_comment_recipients_unread ON record_comment_recipients(user_id) WHERE read_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_record_comment_recipients_message ON record_comment_recipients(message_id) WHERE message_id IS NOT NULL;
+
+-- 客户跟进时间线分享链接
+-- 分享：令牌为 JWT（sid 即 id），撤销与访问计数以本表为准；user_id 非空时仅分享该销售的记录，
+-- 为空时为创建人当前可查看范围内的记录；访问时按创建人当前权限重新计算
+CREATE TABLE IF NOT EXISTS record_shares (
+    id             UUID PRIMARY KEY,
+    customer_id    UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    customer_name  VARCHAR(255) NOT NULL,
+    user_id        VARCHAR(255) REFERENCES users(id),
+    created_by     VARCHAR(255) NOT NULL REFERENCES users(id),
+    expires_at     TIMESTAMPTZ NOT NULL,
+    revoked_at     TIMESTAMPTZ,
+    revoked_by     VARCHAR(255),
+    view_count     INTEGER NOT NULL DEFAULT 0,
+    last_viewed_at TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_record_shares_creator ON record_shares(created_by, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_record_shares_customer ON record_shares(customer_id);
+
+-- 分享访问审计：每次访问公开页一行（含被拒绝的访问）；令牌无法解析时 share_id 为空
+CREATE TABLE IF NOT EXISTS record_share_access_logs (
+    id          BIGSERIAL PRIMARY KEY,
+    share_id    UUID REFERENCES record_shares(id) ON DELETE CASCADE,
+    outcome     VARCHAR(16) NOT NULL,  -- ok/invalid/expired/revoked/not_found/inactive/error
+    ip          VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent  VARCHAR(512) NOT NULL DEFAULT '',
+    records     INTEGER NOT NULL DEFAULT 0,
+    accessed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_record_share_access_logs_share ON record_share_access_logs(share_id, accessed_at DESC);
+
+-- 页面登录会话与刷新令牌
+-- 会话：访问令牌（JWT，jti 即 id）短期有效，每次请求校验会话未撤销且用户在职；
+-- 刷新令牌只存 SHA-256，每次刷新轮换，prev_refresh_hash 用于识别旧令牌被重放（整条会话撤销）
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    id                UUID PRIMARY KEY,
+    user_id           VARCHAR(255) NOT NULL REFERENCES users(id),
+    refresh_hash      CHAR(64) NOT NULL UNIQUE,
+    prev_refresh_hash CHAR(64),
+    expires_at        TIMESTAMPTZ NOT NULL,
+    rotated_at        TIMESTAMPTZ,
+    revoked_at        TIMESTAMPTZ,
+    revoked_reason    VARCHAR(32),  -- logout/logout_all/reused/resigned
+    ip                VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent        VARCHAR(512) NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions(user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_prev_hash ON auth_sessions(prev_refresh_hash);
+
+-- 对外 REST API（/api/v1）密钥与限流
+-- API 密钥：明文只在创建时返回一次，库中存 SHA-256；scopes 为授予的权限范围，rate_limit 为每分钟请求数上限
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           UUID PRIMARY KEY,
+    name         VARCHAR(255) NOT NULL,
+    prefix       VARCHAR(16) NOT NULL,
+    key_hash     CHAR(64) NOT NULL UNIQUE,
+    scopes       TEXT[] NOT NULL DEFAULT '{}',
+    rate_limit   INTEGER NOT NULL DEFAULT 60,
+    created_by   VARCHAR(255) NOT NULL REFERENCES users(id),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ,
+    revoked_by   VARCHAR(255)
+);
+
+-- 限流计数：按密钥与自然分钟累加，多实例共享；过期窗口由清理任务删除
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id       UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    window_start TIMESTAMPTZ NOT NULL,
+    requests     INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, window_start)
+);
+
+-- 增量同步：按 updated_at 游标拉取客户与跟进记录
+CREATE INDEX IF NOT EXISTS idx_customers_updated_at ON customers(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_follow_records_updated_at ON follow_records(updated_at, id);
+
+-- 出站 Webhook：订阅、事务性发件箱与投递记录
+-- 订阅：events 为订阅的事件类型；secret 用于 HMAC-SHA256 签名，仅创建与轮换时返回明文
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id         UUID PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    url        TEXT NOT NULL,
+    secret     VARCHAR(128) NOT NULL,
+    events     TEXT[] NOT NULL DEFAULT '{}',
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(255) NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 发件箱：与业务写入在同一事务内插入；dispatched_at 为空表示尚未分发给订阅
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    id            UUID PRIMARY KEY,
+    event         VARCHAR(64) NOT NULL,
+    payload       JSONB NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    dispatched_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_pending ON webhook_outbox(created_at) WHERE dispatched_at IS NULL;
+
+-- 投递：每个事件 × 订阅一行；status 为 pending/succeeded/failed（重试耗尽），失败按指数退避重试
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id        UUID NOT NULL REFERENCES webhook_outbox(id) ON DELETE CASCADE,
+    event           VARCHAR(64) NOT NULL,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status     INTEGER,
+    last_error      TEXT,
+    last_response   TEXT,
+    duration_ms     INTEGER,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (subscription_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_sub ON webhook_deliveries(subscription_id, created_at DESC);
+
+-- 飞书多维表格同步：记录映射、增量游标与冲突日志
+-- 记录映射：本地客户/跟进记录 ↔ 多维表格记录；remote_modified_at 为本端最近一次写入后多维表格的修改时间（毫秒）
+CREATE TABLE IF NOT EXISTS bitable_sync_map (
+    kind               VARCHAR(16) NOT NULL,
+    table_id           VARCHAR(64) NOT NULL,
+    local_id           UUID NOT NULL,
+    remote_record_id   VARCHAR(64) NOT NULL,
+    remote_modified_at BIGINT NOT NULL DEFAULT 0,
+    synced_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (kind, table_id, local_id)
+);
+-- local_updated_at：最近一次写入多维表格的本地版本（updated_at），回退重扫时据此跳过已同步的版本
+ALTER TABLE bitable_sync_map ADD COLUMN IF NOT EXISTS local_updated_at TIMESTAMPTZ;
+
+-- 增量游标：按 (updated_at, id) 记录已同步到的位置；更换 table_id 后从头同步
+CREATE TABLE IF NOT EXISTS bitable_sync_state (
+    kind        VARCHAR(16) NOT NULL,
+    table_id    VARCHAR(64) NOT NULL,
+    cursor      TEXT NOT NULL DEFAULT '',
+    synced      BIGINT NOT NULL DEFAULT 0,
+    last_run_at TIMESTAMPTZ,
+    last_error  TEXT,
+    PRIMARY KEY (kind, table_id)
+);
+
+-- 冲突日志：多维表格中的记录在上次同步后被手工修改或删除；resolution 为 overwritten/skipped/recreated
+CREATE TABLE IF NOT EXISTS bitable_sync_conflicts (
+    id               BIGSERIAL PRIMARY KEY,
+    kind             VARCHAR(16) NOT NULL,
+    table_id         VARCHAR(64) NOT NULL,
+    local_id         UUID NOT NULL,
+    remote_record_id VARCHAR(64) NOT NULL,
+    reason           VARCHAR(32) NOT NULL,
+    resolution       VARCHAR(16) NOT NULL,
+    local_fields     JSONB,
+    remote_fields    JSONB,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_bitable_sync_conflicts_created ON bitable_sync_conflicts(created_at DESC);
+
+-- CRM 双向同步：账户/活动外部 ID 映射与拉取游标
+-- 账户映射：本地客户 ↔ CRM 账户；local_synced_at 为已与 CRM 一致的 customers.updated_at，之后的本地修改待推送
+CREATE TABLE IF NOT EXISTS crm_account_map (
+    customer_id       UUID PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+    external_id       VARCHAR(128) NOT NULL UNIQUE,
+    master            BOOLEAN NOT NULL DEFAULT FALSE, -- CRM 主数据账户，对话中匹配客户时优先
+    remote_updated_at TIMESTAMPTZ,                    -- 最近拉取到的 CRM 更新时间
+    local_synced_at   TIMESTAMPTZ NOT NULL,
+    synced_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 活动映射：跟进记录 → CRM 活动；version 为已推送的跟进记录版本，记录修改后重新推送
+CREATE TABLE IF NOT EXISTS crm_activity_map (
+    record_id   UUID PRIMARY KEY REFERENCES follow_records(id) ON DELETE CASCADE,
+    external_id VARCHAR(128) NOT NULL,
+    version     INT NOT NULL,
+    synced_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 同步状态：拉取游标与最近一次运行结果
+CREATE TABLE IF NOT EXISTS crm_sync_state (
+    name        VARCHAR(32) PRIMARY KEY,
+    cursor      TEXT NOT NULL DEFAULT '',
+    last_run_at TIMESTAMPTZ,
+    last_error  TEXT
+);
+
+-- 联系电话加密存储：密文与盲索引
+-- contact_phone 存信封加密后的密文（enc:v1:...），密文比明文长，改为 TEXT；
+-- contact_phone_bidx 为规范化号码的盲索引（HMAC），按号码检索时精确匹配
+-- 仅在列尚非 TEXT 时修改类型，避免每次启动重复改写表并持有排他锁
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'customers'
+                 AND column_name = 'contact_phone' AND data_type <> 'text') THEN
+        ALTER TABLE customers ALTER COLUMN contact_phone TYPE TEXT;
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'follow_records'
+                 AND column_name = 'contact_phone' AND data_type <> 'text') THEN
+        ALTER TABLE follow_records ALTER COLUMN contact_phone TYPE TEXT;
+    END IF;
+END $$;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS contact_phone_bidx VARCHAR(64);
+ALTER TABLE follow_records ADD COLUMN IF NOT EXISTS contact_phone_bidx VARCHAR(64);
+CREATE INDEX IF NOT EXISTS idx_customers_contact_phone_bidx ON customers(contact_phone_bidx) WHERE contact_phone_bidx IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_follow_records_contact_phone_bidx ON follow_records(contact_phone_bidx) WHERE contact_phone_bidx IS NOT NULL;
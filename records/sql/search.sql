@@ -0,0 +1,13 @@
+SET search_path TO sale;
+
+-- 跟进记录全文 / 模糊搜索（在 sale schema 下执行，可重复执行）
+
+-- pg_trgm 三元组索引：中文无分词，按字符 n-gram 匹配；需数据库 LC_CTYPE 为 UTF-8 类区域（非 C）才会为中文生成三元组。
+-- 若扩展已安装在其他 schema（如 public），需将其加入连接的 search_path（config database.schema，如 "sale,public"）
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- 搜索文档：跟进内容、目标、结果、风险、下一步计划拼接；表达式须与 internal/search 中 searchDocument 保持一致
+CREATE INDEX IF NOT EXISTS idx_follow_records_search_trgm ON follow_records USING gin (
+    (COALESCE(follow_content, '') || ' ' || COALESCE(follow_goal, '') || ' ' || COALESCE(follow_result, '') || ' ' ||
+     COALESCE(risk_content, '') || ' ' || COALESCE(next_plan, '')) gin_trgm_ops
+);
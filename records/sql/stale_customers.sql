@@ -0,0 +1,16 @@
+SET search_path TO sale;
+
+-- 久未跟进客户提醒（在 sale schema 下执行，可重复执行）
+
+-- 客户分级：决定久未跟进的判定天数（见 config.yml stale_customer.tier_days），空为默认级别
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS tier VARCHAR(32);
+
+-- 久未跟进提醒记录：每个（销售, 客户）一行，避免重复提醒；客户被再次跟进后重新计算
+CREATE TABLE IF NOT EXISTS stale_customer_alerts (
+    user_id        VARCHAR(255) NOT NULL REFERENCES users(id),
+    customer_id    UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    last_follow_at TIMESTAMPTZ NOT NULL, -- 提醒时该销售对该客户的最近跟进时间
+    notified_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, customer_id)
+);
+CREATE INDEX IF NOT EXISTS idx_follow_records_customer_user_time ON follow_records(customer_id, user_id, follow_time DESC);
@@ -0,0 +1,47 @@
+SET search_path TO sale;
+
+-- 出站 Webhook：订阅、事务性发件箱与投递记录（在 sale schema 下执行，可重复执行）
+
+-- 订阅：events 为订阅的事件类型；secret 用于 HMAC-SHA256 签名，仅创建与轮换时返回明文
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id         UUID PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    url        TEXT NOT NULL,
+    secret     VARCHAR(128) NOT NULL,
+    events     TEXT[] NOT NULL DEFAULT '{}',
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(255) NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 发件箱：与业务写入在同一事务内插入；dispatched_at 为空表示尚未分发给订阅
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    id            UUID PRIMARY KEY,
+    event         VARCHAR(64) NOT NULL,
+    payload       JSONB NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    dispatched_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_pending ON webhook_outbox(created_at) WHERE dispatched_at IS NULL;
+
+-- 投递：每个事件 × 订阅一行；status 为 pending/succeeded/failed（重试耗尽），失败按指数退避重试
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id        UUID NOT NULL REFERENCES webhook_outbox(id) ON DELETE CASCADE,
+    event           VARCHAR(64) NOT NULL,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status     INTEGER,
+    last_error      TEXT,
+    last_response   TEXT,
+    duration_ms     INTEGER,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (subscription_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_sub ON webhook_deliveries(subscription_id, created_at DESC);